@@ -7,6 +7,7 @@ import (
 
 	"github.com/coredns/caddy"
 	"github.com/coredns/coredns/plugin"
+	"github.com/coredns/coredns/plugin/pkg/proxyproto"
 )
 
 // Config configuration for a single server.
@@ -40,12 +41,18 @@ type Config struct {
 	// TLSConfig when listening for encrypted connections (gRPC, DNS-over-TLS).
 	TLSConfig *tls.Config
 
+	// ProxyProtocol, if not nil, makes the listeners accept PROXY protocol headers from trusted peers.
+	ProxyProtocol *proxyproto.Policy
+
 	// Plugin stack.
 	Plugin []plugin.Plugin
 
 	// Compiled plugin stack.
 	pluginChain plugin.Handler
 
+	// Names of the plugins in the compiled stack, in the order they handle a query.
+	pluginNames []string
+
 	// Plugin interested in announcing that they exist, so other plugin can call methods
 	// on them should register themselves here. The name should be the name as return by the
 	// Handler's Name method.
@@ -1,6 +1,7 @@
 package dnsserver
 
 import (
+	"crypto/tls"
 	"net"
 	"net/http"
 
@@ -28,3 +29,12 @@ func (d *DoHWriter) LocalAddr() net.Addr { return d.laddr }
 
 // Request returns the HTTP request
 func (d *DoHWriter) Request() *http.Request { return d.request }
+
+// ConnectionState returns the TLS connection state of the HTTP request, it implements the
+// dns.ConnectionStater interface.
+func (d *DoHWriter) ConnectionState() *tls.ConnectionState {
+	if d.request == nil {
+		return nil
+	}
+	return d.request.TLS
+}
@@ -45,6 +45,9 @@ type dnsContext struct {
 
 	// configs is the master list of all site configs.
 	configs []*Config
+
+	// dryRun is true when the Corefile is only validated.
+	dryRun bool
 }
 
 func (h *dnsContext) saveConfig(key string, cfg *Config) {
@@ -155,6 +158,7 @@ func (h *dnsContext) MakeServers() ([]caddy.Server, error) {
 		c.ListenHosts = c.firstConfigInBlock.ListenHosts
 		c.Debug = c.firstConfigInBlock.Debug
 		c.TLSConfig = c.firstConfigInBlock.TLSConfig
+		c.ProxyProtocol = c.firstConfigInBlock.ProxyProtocol
 	}
 
 	// we must map (group) each config to a bind address
@@ -231,6 +235,16 @@ func (c *Config) Handler(name string) plugin.Handler {
 	return nil
 }
 
+// Plugins returns the names of the plugins in the chain of c, in the order they handle a query. It is
+// only known once the servers are made, i.e. by the time the OnStartup functions run.
+func (c *Config) Plugins() []string { return c.pluginNames }
+
+// Configs returns the configs of all zones in the Corefile that c sets up.
+func Configs(c *caddy.Controller) []*Config {
+	ctx := c.Context().(*dnsContext)
+	return ctx.configs
+}
+
 // Handlers returns a slice of plugins that have been registered. This can be used to
 // inspect and interact with registered plugins but cannot be used to remove or add plugins.
 // Note that this is order dependent and the order is defined in directives.go, i.e. if your plugin
@@ -15,6 +15,7 @@ import (
 	"github.com/coredns/coredns/plugin/metrics/vars"
 	"github.com/coredns/coredns/plugin/pkg/edns"
 	"github.com/coredns/coredns/plugin/pkg/log"
+	"github.com/coredns/coredns/plugin/pkg/proxyproto"
 	"github.com/coredns/coredns/plugin/pkg/rcode"
 	"github.com/coredns/coredns/plugin/pkg/reuseport"
 	"github.com/coredns/coredns/plugin/pkg/trace"
@@ -42,6 +43,8 @@ type Server struct {
 	trace        trace.Trace        // the trace plugin for the server
 	debug        bool               // disable recover()
 	classChaos   bool               // allow non-INET class queries
+
+	proxyProtocol *proxyproto.Policy // accept PROXY protocol headers, if not nil
 }
 
 // NewServer returns a new CoreDNS server and compiles all plugins in to it. By default CH class
@@ -62,7 +65,13 @@ func NewServer(addr string, group []*Config) (*Server, error) {
 	// In a way, this kind of acts as a safety barrier.
 	s.dnsWg.Add(1)
 
-	for _, site := range group {
+	for i, site := range group {
+		// The PROXY protocol is handled by the listeners, which are shared by all zones on an address.
+		if i > 0 && !site.ProxyProtocol.Equal(group[0].ProxyProtocol) {
+			return nil, fmt.Errorf("cannot serve %s - zones %s and %s have different proxyproto settings", addr, group[0].Zone, site.Zone)
+		}
+		s.proxyProtocol = site.ProxyProtocol
+
 		if site.Debug {
 			s.debug = true
 			log.D.Set()
@@ -72,8 +81,10 @@ func NewServer(addr string, group []*Config) (*Server, error) {
 
 		// compile custom plugin for everything
 		var stack plugin.Handler
+		site.pluginNames = make([]string, len(site.Plugin))
 		for i := len(site.Plugin) - 1; i >= 0; i-- {
 			stack = site.Plugin[i](stack)
+			site.pluginNames[i] = stack.Name()
 
 			// register the *handler* also
 			site.registerHandler(stack)
@@ -107,6 +118,7 @@ var _ caddy.GracefulServer = &Server{}
 // Serve starts the server with an existing listener. It blocks until the server stops.
 // This implements caddy.TCPServer interface.
 func (s *Server) Serve(l net.Listener) error {
+	l = s.wrapProxyProtocol(l)
 	s.m.Lock()
 	s.server[tcp] = &dns.Server{Listener: l, Net: "tcp", Handler: dns.HandlerFunc(func(w dns.ResponseWriter, r *dns.Msg) {
 		ctx := context.WithValue(context.Background(), Key{}, s)
@@ -121,6 +133,9 @@ func (s *Server) Serve(l net.Listener) error {
 // ServePacket starts the server with an existing packetconn. It blocks until the server stops.
 // This implements caddy.UDPServer interface.
 func (s *Server) ServePacket(p net.PacketConn) error {
+	if s.proxyProtocol != nil {
+		p = s.proxyProtocol.PacketConn(p)
+	}
 	s.m.Lock()
 	s.server[udp] = &dns.Server{PacketConn: p, Net: "udp", Handler: dns.HandlerFunc(func(w dns.ResponseWriter, r *dns.Msg) {
 		ctx := context.WithValue(context.Background(), Key{}, s)
@@ -156,6 +171,15 @@ func (s *Server) ListenPacket() (net.PacketConn, error) {
 	return p, nil
 }
 
+// wrapProxyProtocol returns l, accepting PROXY protocol headers if that is configured. This is done
+// when serving and not in Listen, so the listener itself can be handed over on a reload.
+func (s *Server) wrapProxyProtocol(l net.Listener) net.Listener {
+	if s.proxyProtocol == nil {
+		return l
+	}
+	return s.proxyProtocol.Listener(l)
+}
+
 // Stop stops the server. It blocks until the server is
 // totally stopped. On POSIX systems, it will wait for
 // connections to close (up to a max timeout of a few
@@ -16,6 +16,7 @@ import (
 	"github.com/miekg/dns"
 	"github.com/opentracing/opentracing-go"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/peer"
 )
 
@@ -59,21 +60,24 @@ func (s *ServergRPC) Serve(l net.Listener) error {
 	s.listenAddr = l.Addr()
 	s.m.Unlock()
 
+	l = s.wrapProxyProtocol(l)
+
+	var opts []grpc.ServerOption
 	if s.Tracer() != nil {
 		onlyIfParent := func(parentSpanCtx opentracing.SpanContext, method string, req, resp interface{}) bool {
 			return parentSpanCtx != nil
 		}
 		intercept := otgrpc.OpenTracingServerInterceptor(s.Tracer(), otgrpc.IncludingSpans(onlyIfParent))
-		s.grpcServer = grpc.NewServer(grpc.UnaryInterceptor(intercept))
-	} else {
-		s.grpcServer = grpc.NewServer()
+		opts = append(opts, grpc.UnaryInterceptor(intercept))
+	}
+	// Let gRPC do the TLS handshake, so the connection state is available in Query.
+	if s.tlsConfig != nil {
+		opts = append(opts, grpc.Creds(credentials.NewTLS(s.tlsConfig)))
 	}
+	s.grpcServer = grpc.NewServer(opts...)
 
 	pb.RegisterDnsServiceServer(s.grpcServer, s)
 
-	if s.tlsConfig != nil {
-		l = tls.NewListener(l, s.tlsConfig)
-	}
 	return s.grpcServer.Serve(l)
 }
 
@@ -138,6 +142,9 @@ func (s *ServergRPC) Query(ctx context.Context, in *pb.DnsPacket) (*pb.DnsPacket
 	}
 
 	w := &gRPCresponse{localAddr: s.listenAddr, remoteAddr: a, Msg: msg}
+	if info, ok := p.AuthInfo.(credentials.TLSInfo); ok {
+		w.state = &info.State
+	}
 
 	dnsCtx := context.WithValue(ctx, Key{}, s.Server)
 	dnsCtx = context.WithValue(dnsCtx, LoopKey{}, 0)
@@ -162,6 +169,7 @@ func (s *ServergRPC) Shutdown() error {
 type gRPCresponse struct {
 	localAddr  net.Addr
 	remoteAddr net.Addr
+	state      *tls.ConnectionState
 	Msg        *dns.Msg
 }
 
@@ -181,3 +189,6 @@ func (r *gRPCresponse) Hijack()                   {}
 func (r *gRPCresponse) LocalAddr() net.Addr       { return r.localAddr }
 func (r *gRPCresponse) RemoteAddr() net.Addr      { return r.remoteAddr }
 func (r *gRPCresponse) WriteMsg(m *dns.Msg) error { r.Msg = m; return nil }
+
+// ConnectionState implements the dns.ConnectionStater interface.
+func (r *gRPCresponse) ConnectionState() *tls.ConnectionState { return r.state }
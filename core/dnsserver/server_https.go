@@ -78,6 +78,7 @@ func (s *ServerHTTPS) Serve(l net.Listener) error {
 	s.listenAddr = l.Addr()
 	s.m.Unlock()
 
+	l = s.wrapProxyProtocol(l)
 	if s.tlsConfig != nil {
 		l = tls.NewListener(l, s.tlsConfig)
 	}
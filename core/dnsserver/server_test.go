@@ -2,10 +2,13 @@ package dnsserver
 
 import (
 	"context"
+	"fmt"
 	"testing"
 
 	"github.com/coredns/coredns/plugin"
+	"github.com/coredns/coredns/plugin/pkg/cidr"
 	"github.com/coredns/coredns/plugin/pkg/log"
+	"github.com/coredns/coredns/plugin/pkg/proxyproto"
 	"github.com/coredns/coredns/plugin/test"
 
 	"github.com/miekg/dns"
@@ -76,6 +79,50 @@ func TestDebug(t *testing.T) {
 	}
 }
 
+func TestNewServerProxyProtocol(t *testing.T) {
+	policy := func(networks ...string) *proxyproto.Policy {
+		trusted, err := cidr.ParseNetworks(networks)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return &proxyproto.Policy{Trusted: trusted, Timeout: proxyproto.DefaultTimeout}
+	}
+
+	tests := []struct {
+		policies  []*proxyproto.Policy
+		shouldErr bool
+	}{
+		{[]*proxyproto.Policy{nil, nil}, false},
+		{[]*proxyproto.Policy{policy("10.0.0.0/24"), policy("10.0.0.0/24")}, false},
+		{[]*proxyproto.Policy{policy("10.0.0.0/24"), nil}, true},
+		{[]*proxyproto.Policy{nil, policy("10.0.0.0/24")}, true},
+		{[]*proxyproto.Policy{policy("10.0.0.0/24"), policy("10.0.1.0/24")}, true},
+	}
+	for i, tc := range tests {
+		group := []*Config{}
+		for j, p := range tc.policies {
+			c := testConfig("dns", testPlugin{})
+			c.Zone = fmt.Sprintf("example%d.com.", j)
+			c.ProxyProtocol = p
+			group = append(group, c)
+		}
+		s, err := NewServer("127.0.0.1:53", group)
+		if tc.shouldErr {
+			if err == nil {
+				t.Errorf("Test %d: expected error, got none", i)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Test %d: expected no error, got %s", i, err)
+			continue
+		}
+		if !s.proxyProtocol.Equal(tc.policies[0]) {
+			t.Errorf("Test %d: expected the policy of the zones, got %v", i, s.proxyProtocol)
+		}
+	}
+}
+
 func BenchmarkCoreServeDNS(b *testing.B) {
 	s, err := NewServer("127.0.0.1:53", []*Config{testConfig("dns", testPlugin{})})
 	if err != nil {
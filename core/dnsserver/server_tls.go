@@ -41,6 +41,7 @@ var _ caddy.GracefulServer = &Server{}
 
 // Serve implements caddy.TCPServer interface.
 func (s *ServerTLS) Serve(l net.Listener) error {
+	l = s.wrapProxyProtocol(l)
 	s.m.Lock()
 
 	if s.tlsConfig != nil {
@@ -0,0 +1,142 @@
+package dnsserver
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/coredns/caddy"
+	"github.com/coredns/caddy/caddyfile"
+	"github.com/coredns/coredns/plugin/pkg/log"
+)
+
+// DryRun returns true when the Corefile of c is only validated, i.e. the servers are never started.
+// Setup functions should then skip external side effects, like contacting remote APIs, and only
+// check their configuration.
+func DryRun(c *caddy.Controller) bool {
+	ctx, ok := c.Context().(*dnsContext)
+	return ok && ctx.dryRun
+}
+
+// Validate sets up all plugins in corefile, as if to start the servers, but without opening any
+// sockets; DryRun returns true for the setup functions. It returns the configs of each server
+// block in the order of corefile, and all errors found, not just the first one.
+func Validate(corefile caddy.Input) ([][]*Config, []error) {
+	sblocks, err := caddyfile.Parse(corefile.Path(), bytes.NewReader(corefile.Body()), Directives)
+	if err != nil {
+		return nil, []error{err}
+	}
+
+	// A test controller is the only way to get a caddy.Instance that isn't started, copies of it
+	// share that instance and so its context.
+	base := caddy.NewTestController(serverType, "")
+	ctx := base.Context().(*dnsContext)
+	ctx.dryRun = true
+
+	sblocks, err = ctx.InspectServerBlocks(corefile.Path(), sblocks)
+	if err != nil {
+		return nil, []error{err}
+	}
+
+	var (
+		errs  []error
+		lines []int
+	)
+	storages := make([]map[string]interface{}, len(sblocks))
+	onces := make([]sync.Once, len(sblocks))
+	for _, dir := range Directives {
+		for i, sb := range sblocks {
+			tokens, ok := sb.Tokens[dir]
+			if !ok {
+				continue
+			}
+			if storages[i] == nil {
+				storages[i] = make(map[string]interface{})
+			}
+			once := &onces[i]
+
+			c := *base
+			c.Dispenser = caddyfile.NewDispenserTokens(corefile.Path(), tokens)
+			c.Key = sb.Keys[0]
+			c.OncePerServerBlock = func(f func() error) (err error) {
+				once.Do(func() { err = f() })
+				return err
+			}
+			c.ServerBlockIndex = i
+			c.ServerBlockKeyIndex = 0
+			c.ServerBlockKeys = sb.Keys
+			c.ServerBlockStorage = storages[i][dir]
+
+			if err := setup(&c, dir); err != nil {
+				errs = append(errs, position(corefile.Path(), tokens[0].Line, err))
+				lines = append(lines, tokens[0].Line)
+			}
+			storages[i][dir] = c.ServerBlockStorage
+		}
+	}
+	if len(errs) > 0 {
+		// Directives are set up in the order of plugin.cfg, report the errors in the order of corefile.
+		sort.Stable(byLine{errs, lines})
+		return nil, errs
+	}
+
+	// Making the servers checks for overlapping zones and compiles the plugin chains. It sets the
+	// debug logging, which must be kept as is when validating in a running process.
+	debug := log.D.Value()
+	_, err = ctx.MakeServers()
+	if debug {
+		log.D.Set()
+	} else {
+		log.D.Clear()
+	}
+	if err != nil {
+		return nil, []error{err}
+	}
+
+	var blocks [][]*Config
+	for _, cfg := range ctx.configs {
+		if cfg.firstConfigInBlock == cfg {
+			blocks = append(blocks, nil)
+		}
+		blocks[len(blocks)-1] = append(blocks[len(blocks)-1], cfg)
+	}
+	return blocks, nil
+}
+
+// setup calls the setup function of directive dir, a panic in it is returned as an error.
+func setup(c *caddy.Controller, dir string) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("plugin/%s: panic during setup: %v", dir, r)
+		}
+	}()
+
+	action, err := caddy.DirectiveAction(serverType, dir)
+	if err != nil {
+		return err
+	}
+	return action(c)
+}
+
+// position prefixes err with file and line, unless err already has them, which is the case for
+// errors made with the controller's Err functions.
+func position(file string, line int, err error) error {
+	if strings.Contains(err.Error(), file+":") {
+		return err
+	}
+	return fmt.Errorf("%s:%d - %v", file, line, err)
+}
+
+type byLine struct {
+	errs  []error
+	lines []int
+}
+
+func (b byLine) Len() int           { return len(b.errs) }
+func (b byLine) Less(i, j int) bool { return b.lines[i] < b.lines[j] }
+func (b byLine) Swap(i, j int) {
+	b.errs[i], b.errs[j] = b.errs[j], b.errs[i]
+	b.lines[i], b.lines[j] = b.lines[j], b.lines[i]
+}
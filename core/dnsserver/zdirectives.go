@@ -14,6 +14,7 @@ var Directives = []string{
 	"geoip",
 	"cancel",
 	"tls",
+	"proxyproto",
 	"reload",
 	"nsid",
 	"bufsize",
@@ -23,6 +24,7 @@ var Directives = []string{
 	"trace",
 	"ready",
 	"health",
+	"admin",
 	"pprof",
 	"prometheus",
 	"errors",
@@ -31,9 +33,12 @@ var Directives = []string{
 	"local",
 	"dns64",
 	"acl",
+	"rpz",
+	"blocklist",
 	"any",
 	"chaos",
 	"loadbalance",
+	"geodns",
 	"cache",
 	"rewrite",
 	"header",
@@ -52,6 +57,7 @@ var Directives = []string{
 	"auto",
 	"secondary",
 	"etcd",
+	"sql",
 	"loop",
 	"forward",
 	"grpc",
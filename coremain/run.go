@@ -23,6 +23,7 @@ func init() {
 	flag.BoolVar(&plugins, "plugins", false, "List installed plugins")
 	flag.StringVar(&caddy.PidFile, "pidfile", "", "Path to write pid file")
 	flag.BoolVar(&version, "version", false, "Show version")
+	flag.BoolVar(&validate, "validate", false, "Validate the Corefile and show its server blocks, without starting any servers")
 	flag.BoolVar(&dnsserver.Quiet, "quiet", false, "Quiet mode (no initialization output)")
 
 	caddy.RegisterCaddyfileLoader("flag", caddy.LoaderFunc(confLoader))
@@ -59,6 +60,13 @@ func Run() {
 		mustLogFatal(err)
 	}
 
+	if validate {
+		if !validateCorefile(os.Stdout, os.Stderr, corefile) {
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+
 	// Start your engines
 	instance, err := caddy.Start(corefile)
 	if err != nil {
@@ -163,9 +171,10 @@ func setVersion() {
 
 // Flags that control program flow or startup
 var (
-	conf    string
-	version bool
-	plugins bool
+	conf     string
+	version  bool
+	plugins  bool
+	validate bool
 )
 
 // Build information obtained with the help of -ldflags
@@ -0,0 +1,48 @@
+package coremain
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/coredns/caddy"
+	"github.com/coredns/coredns/core/dnsserver"
+)
+
+// validateCorefile sets up all plugins in corefile without starting any servers. It prints all errors to
+// stderr, or, if there are none, the server blocks and their plugin chains to stdout. It returns false if
+// corefile is not valid.
+func validateCorefile(stdout, stderr io.Writer, corefile caddy.Input) bool {
+	blocks, errs := dnsserver.Validate(corefile)
+	if len(errs) > 0 {
+		for _, err := range errs {
+			fmt.Fprintln(stderr, err)
+		}
+		fmt.Fprintf(stderr, "%s is not valid: %d error(s)\n", corefile.Path(), len(errs))
+		return false
+	}
+
+	fmt.Fprintf(stdout, "%s is valid\n", corefile.Path())
+	for i, block := range blocks {
+		zones := make([]string, len(block))
+		for j, cfg := range block {
+			zones[j] = fmt.Sprintf("%s://%s", cfg.Transport, cfg.Zone)
+			if cfg.Port != "" {
+				zones[j] += ":" + cfg.Port
+			}
+		}
+		listen := make([]string, len(block[0].ListenHosts))
+		for j, h := range block[0].ListenHosts {
+			if h == "" {
+				h = "*"
+			}
+			listen[j] = h
+		}
+
+		fmt.Fprintf(stdout, "\nServer block %d\n", i+1)
+		fmt.Fprintf(stdout, "  zones:   %s\n", strings.Join(zones, ", "))
+		fmt.Fprintf(stdout, "  listen:  %s\n", strings.Join(listen, ", "))
+		fmt.Fprintf(stdout, "  plugins: %s\n", strings.Join(block[0].Plugins(), ", "))
+	}
+	return true
+}
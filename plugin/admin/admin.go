@@ -0,0 +1,203 @@
+// Package admin implements an HTTP API to inspect the running configuration and to reload it.
+package admin
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/coredns/caddy"
+	"github.com/coredns/coredns/core/dnsserver"
+	"github.com/coredns/coredns/plugin/pkg/reuseport"
+)
+
+// maxCorefile is the maximum size of a Corefile sent to admin.
+const maxCorefile = 1 << 20
+
+type admin struct {
+	Addr  string
+	token string
+	c     *caddy.Controller
+
+	ln      net.Listener
+	nlSetup bool
+	mux     *http.ServeMux
+	srv     *http.Server
+}
+
+func (a *admin) OnStartup() error {
+	ln, err := reuseport.Listen("tcp", a.Addr)
+	if err != nil {
+		return err
+	}
+
+	a.ln = ln
+	a.mux = http.NewServeMux()
+	a.nlSetup = true
+
+	a.mux.HandleFunc("/config", a.auth(http.MethodGet, a.config))
+	a.mux.HandleFunc("/corefile", a.auth(http.MethodGet, a.corefile))
+	a.mux.HandleFunc("/validate", a.auth(http.MethodPost, a.validate))
+	a.mux.HandleFunc("/reload", a.auth(http.MethodPost, a.reload))
+	a.mux.HandleFunc("/rollback", a.auth(http.MethodPost, a.rollback))
+
+	a.srv = &http.Server{Handler: a.mux}
+	go func() { a.srv.Serve(a.ln) }()
+	return nil
+}
+
+// OnFinalShutdown stops admin and forgets about the instance, as there isn't one to replace it.
+func (a *admin) OnFinalShutdown() error {
+	running.reset()
+	return a.OnRestart()
+}
+
+// OnRestart stops admin, the admin of the new instance takes over.
+func (a *admin) OnRestart() error {
+	if !a.nlSetup {
+		return nil
+	}
+	// The server isn't shut down, as a reload is done from one of its handlers that must still
+	// reply. Connections are closed once idle, so clients end up at the admin of the new instance.
+	a.srv.SetKeepAlivesEnabled(false)
+	a.ln.Close()
+	a.nlSetup = false
+	return nil
+}
+
+// auth only calls h for requests with the right token and method.
+func (a *admin) auth(method string, h http.HandlerFunc) http.HandlerFunc {
+	want := []byte("Bearer " + a.token)
+	return func(w http.ResponseWriter, r *http.Request) {
+		if subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), want) != 1 {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+			return
+		}
+		if r.Method != method {
+			w.Header().Set("Allow", method)
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+			return
+		}
+		h(w, r)
+	}
+}
+
+type configResponse struct {
+	Corefile   corefileInfo `json:"corefile"`
+	Rollback   bool         `json:"rollback_available"`
+	LastReload *result      `json:"last_reload,omitempty"`
+	Servers    []server     `json:"servers"`
+}
+
+type corefileInfo struct {
+	Path    string    `json:"path"`
+	SHA512  string    `json:"sha512"`
+	Started time.Time `json:"started"`
+}
+
+type server struct {
+	Zone        string   `json:"zone"`
+	Transport   string   `json:"transport"`
+	Port        string   `json:"port"`
+	ListenHosts []string `json:"listen"`
+	Plugins     []string `json:"plugins"`
+}
+
+// config shows the running Corefile and the plugin chain of each zone.
+func (a *admin) config(w http.ResponseWriter, r *http.Request) {
+	inst, started, previous, last := running.get()
+	resp := configResponse{Rollback: previous != nil, LastReload: last, Servers: []server{}}
+	if inst != nil {
+		resp.Corefile = corefileInfo{Path: inst.Caddyfile().Path(), SHA512: sum(inst.Caddyfile()), Started: started}
+	}
+	for _, c := range dnsserver.Configs(a.c) {
+		resp.Servers = append(resp.Servers, server{
+			Zone:        c.Zone,
+			Transport:   c.Transport,
+			Port:        c.Port,
+			ListenHosts: c.ListenHosts,
+			Plugins:     c.Plugins(),
+		})
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// corefile shows the running Corefile.
+func (a *admin) corefile(w http.ResponseWriter, r *http.Request) {
+	inst, _, _, _ := running.get()
+	if inst == nil {
+		http.Error(w, errNoInstance.Error(), http.StatusServiceUnavailable)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Write(inst.Caddyfile().Body())
+}
+
+// validate validates the Corefile in the request, or the one on disk if the request is empty.
+func (a *admin) validate(w http.ResponseWriter, r *http.Request) {
+	corefile, err := a.input(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	res := validate(corefile)
+	writeJSON(w, status(res), res)
+}
+
+// reload reloads with the Corefile in the request, or the one on disk if the request is empty.
+func (a *admin) reload(w http.ResponseWriter, r *http.Request) {
+	corefile, err := a.input(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	res, err := running.reload(corefile)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+	writeJSON(w, status(res), res)
+}
+
+// rollback reloads with the Corefile that ran before the running one.
+func (a *admin) rollback(w http.ResponseWriter, r *http.Request) {
+	res, err := running.rollback()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	writeJSON(w, status(res), res)
+}
+
+// input returns the Corefile in the body of r, or the one on disk if the body is empty.
+func (a *admin) input(r *http.Request) (caddy.Input, error) {
+	body, err := io.ReadAll(http.MaxBytesReader(nil, r.Body, maxCorefile))
+	if err != nil {
+		return nil, err
+	}
+	if len(body) == 0 {
+		return caddy.LoadCaddyfile(a.c.ServerType())
+	}
+	path := "Corefile"
+	if inst, _, _, _ := running.get(); inst != nil {
+		path = inst.Caddyfile().Path()
+	}
+	return caddy.CaddyfileInput{Contents: body, Filepath: path, ServerTypeName: a.c.ServerType()}, nil
+}
+
+func status(r *result) int {
+	if r.OK {
+		return http.StatusOK
+	}
+	return http.StatusUnprocessableEntity
+}
+
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(v)
+}
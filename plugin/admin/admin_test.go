@@ -0,0 +1,47 @@
+package admin
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAuth(t *testing.T) {
+	a := &admin{token: "s3cret"}
+	h := a.auth(http.MethodPost, func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusNoContent) })
+
+	tests := []struct {
+		method string
+		header string
+		code   int
+	}{
+		{http.MethodPost, "Bearer s3cret", http.StatusNoContent},
+		{http.MethodGet, "Bearer s3cret", http.StatusMethodNotAllowed},
+		{http.MethodPost, "", http.StatusUnauthorized},
+		{http.MethodPost, "Bearer s3cre", http.StatusUnauthorized},
+		{http.MethodPost, "s3cret", http.StatusUnauthorized},
+		{http.MethodGet, "Bearer wrong", http.StatusUnauthorized},
+	}
+
+	for i, tc := range tests {
+		r := httptest.NewRequest(tc.method, "/reload", nil)
+		if tc.header != "" {
+			r.Header.Set("Authorization", tc.header)
+		}
+		w := httptest.NewRecorder()
+		h(w, r)
+		if w.Code != tc.code {
+			t.Errorf("Test %d: expected status %d, got %d", i, tc.code, w.Code)
+		}
+	}
+}
+
+func TestRollbackWithoutPrevious(t *testing.T) {
+	a := &admin{token: "s3cret"}
+	r := httptest.NewRequest(http.MethodPost, "/rollback", nil)
+	w := httptest.NewRecorder()
+	a.rollback(w, r)
+	if w.Code != http.StatusConflict {
+		t.Errorf("Expected status %d, got %d", http.StatusConflict, w.Code)
+	}
+}
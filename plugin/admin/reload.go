@@ -0,0 +1,137 @@
+package admin
+
+import (
+	"crypto/sha512"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/coredns/caddy"
+	"github.com/coredns/coredns/core/dnsserver"
+)
+
+// running is what is known about the running instance. It is global as it is kept across reloads,
+// which make a new admin.
+var running = &instance{}
+
+type instance struct {
+	mu       sync.Mutex
+	inst     *caddy.Instance
+	started  time.Time
+	previous caddy.Input // Corefile of the instance that ran before, to roll back to
+	last     *result     // result of the last reload through admin
+
+	reloadMu sync.Mutex // one reload at a time
+}
+
+// result is the result of a reload or a validation.
+type result struct {
+	Time    time.Time `json:"time"`
+	SHA512  string    `json:"sha512"`
+	OK      bool      `json:"ok"`
+	Changed bool      `json:"changed"`
+	Error   string    `json:"error,omitempty"`
+}
+
+var (
+	errNoInstance = errors.New("no running instance")
+	errNoPrevious = errors.New("no previous Corefile to roll back to")
+)
+
+// storageKey marks instances that have admin configured in their storage.
+type storageKey struct{}
+
+// hook keeps track of the running instance, whether it was started by admin or not.
+func hook(event caddy.EventName, info interface{}) error {
+	if event != caddy.InstanceStartupEvent {
+		return nil
+	}
+	inst := info.(*caddy.Instance)
+	if inst.Storage[storageKey{}] == nil {
+		return nil
+	}
+
+	running.mu.Lock()
+	defer running.mu.Unlock()
+	if running.inst != nil && sum(running.inst.Caddyfile()) != sum(inst.Caddyfile()) {
+		running.previous = running.inst.Caddyfile()
+	}
+	running.inst = inst
+	running.started = time.Now()
+	return nil
+}
+
+func (i *instance) get() (*caddy.Instance, time.Time, caddy.Input, *result) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	return i.inst, i.started, i.previous, i.last
+}
+
+func (i *instance) reset() {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.inst = nil
+	i.previous = nil
+	i.last = nil
+}
+
+// validate sets up all plugins of corefile without starting it.
+func validate(corefile caddy.Input) *result {
+	r := &result{Time: time.Now(), SHA512: sum(corefile), OK: true}
+	if _, errs := dnsserver.Validate(corefile); len(errs) > 0 {
+		msgs := make([]string, len(errs))
+		for i, err := range errs {
+			msgs[i] = err.Error()
+		}
+		r.OK = false
+		r.Error = strings.Join(msgs, "\n")
+	}
+	return r
+}
+
+// reload validates corefile and, if it's valid and differs from the running one, restarts the
+// running instance with it. If the restart fails the running instance is kept.
+func (i *instance) reload(corefile caddy.Input) (*result, error) {
+	i.reloadMu.Lock()
+	defer i.reloadMu.Unlock()
+
+	inst, _, _, _ := i.get()
+	if inst == nil {
+		return nil, errNoInstance
+	}
+
+	r := validate(corefile)
+	if r.OK && r.SHA512 != sum(inst.Caddyfile()) {
+		r.Changed = true
+		if _, err := inst.Restart(corefile); err != nil {
+			r.OK = false
+			r.Error = err.Error()
+		}
+	}
+	if r.OK {
+		log.Infof("Reloaded Corefile, SHA512 = %s, changed: %t", r.SHA512, r.Changed)
+	} else {
+		log.Errorf("Failed to reload Corefile: %s", r.Error)
+	}
+
+	i.mu.Lock()
+	i.last = r
+	i.mu.Unlock()
+	return r, nil
+}
+
+// rollback reloads the Corefile of the instance that ran before the running one.
+func (i *instance) rollback() (*result, error) {
+	_, _, previous, _ := i.get()
+	if previous == nil {
+		return nil, errNoPrevious
+	}
+	return i.reload(previous)
+}
+
+func sum(corefile caddy.Input) string {
+	s := sha512.Sum512(corefile.Body())
+	return hex.EncodeToString(s[:])
+}
@@ -0,0 +1,92 @@
+package admin
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/coredns/caddy"
+	"github.com/coredns/coredns/core/dnsserver"
+	"github.com/coredns/coredns/plugin"
+	clog "github.com/coredns/coredns/plugin/pkg/log"
+)
+
+var log = clog.NewWithPlugin("admin")
+
+const defaultAddr = "localhost:8054"
+
+func init() { plugin.Register("admin", setup) }
+
+var hookOnce sync.Once
+
+func setup(c *caddy.Controller) error {
+	a, err := parse(c)
+	if err != nil {
+		return plugin.Error("admin", err)
+	}
+	a.c = c
+
+	hookOnce.Do(func() { caddy.RegisterEventHook("admin", hook) })
+	c.Set(storageKey{}, true)
+
+	c.OnStartup(a.OnStartup)
+	c.OnRestart(a.OnRestart)
+	c.OnFinalShutdown(a.OnFinalShutdown)
+	c.OnRestartFailed(a.OnStartup)
+
+	// Don't do AddPlugin, as admin is not *really* a plugin just a separate webserver running.
+	return nil
+}
+
+func parse(c *caddy.Controller) (*admin, error) {
+	a := &admin{Addr: defaultAddr}
+	i := 0
+	for c.Next() {
+		if i > 0 {
+			return nil, plugin.ErrOnce
+		}
+		i++
+
+		args := c.RemainingArgs()
+		switch len(args) {
+		case 0:
+		case 1:
+			a.Addr = args[0]
+			if _, _, err := net.SplitHostPort(a.Addr); err != nil {
+				return nil, err
+			}
+		default:
+			return nil, c.ArgErr()
+		}
+
+		for c.NextBlock() {
+			switch c.Val() {
+			case "token_file":
+				args := c.RemainingArgs()
+				if len(args) != 1 {
+					return nil, c.ArgErr()
+				}
+				path := args[0]
+				if !filepath.IsAbs(path) && dnsserver.GetConfig(c).Root != "" {
+					path = filepath.Join(dnsserver.GetConfig(c).Root, path)
+				}
+				b, err := os.ReadFile(filepath.Clean(path))
+				if err != nil {
+					return nil, c.Errf("unable to read token file: %s", err)
+				}
+				a.token = strings.TrimSpace(string(b))
+				if a.token == "" {
+					return nil, c.Errf("token file %s is empty", path)
+				}
+			default:
+				return nil, c.Errf("unknown property '%s'", c.Val())
+			}
+		}
+	}
+	if a.token == "" {
+		return nil, c.Err("a token_file is required")
+	}
+	return a, nil
+}
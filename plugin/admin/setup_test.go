@@ -0,0 +1,82 @@
+package admin
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/coredns/caddy"
+)
+
+func TestSetupAdmin(t *testing.T) {
+	dir := t.TempDir()
+	token := filepath.Join(dir, "token")
+	if err := os.WriteFile(token, []byte("s3cret\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	empty := filepath.Join(dir, "empty")
+	if err := os.WriteFile(empty, nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		input     string
+		shouldErr bool
+		addr      string
+	}{
+		{`admin {
+			token_file ` + token + `
+		}`, false, defaultAddr},
+		{`admin localhost:1234 {
+			token_file ` + token + `
+		}`, false, "localhost:1234"},
+
+		{`admin`, true, ""},
+		{`admin bla {
+			token_file ` + token + `
+		}`, true, ""},
+		{`admin localhost:1234 localhost:1235 {
+			token_file ` + token + `
+		}`, true, ""},
+		{`admin {
+			token_file
+		}`, true, ""},
+		{`admin {
+			token_file ` + filepath.Join(dir, "missing") + `
+		}`, true, ""},
+		{`admin {
+			token_file ` + empty + `
+		}`, true, ""},
+		{`admin {
+			token_file ` + token + `
+			tokens
+		}`, true, ""},
+		{`admin {
+			token_file ` + token + `
+		}
+		admin {
+			token_file ` + token + `
+		}`, true, ""},
+	}
+
+	for i, test := range tests {
+		c := caddy.NewTestController("dns", test.input)
+		a, err := parse(c)
+
+		if test.shouldErr && err == nil {
+			t.Errorf("Test %d: Expected error but found none for input %s", i, test.input)
+		}
+		if err != nil {
+			if !test.shouldErr {
+				t.Errorf("Test %d: Expected no error but found one for input %s. Error was: %v", i, test.input, err)
+			}
+			continue
+		}
+		if a.Addr != test.addr {
+			t.Errorf("Test %d: Expected address %q, got %q", i, test.addr, a.Addr)
+		}
+		if a.token != "s3cret" {
+			t.Errorf("Test %d: Expected token %q, got %q", i, "s3cret", a.token)
+		}
+	}
+}
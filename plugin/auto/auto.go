@@ -3,10 +3,12 @@ package auto
 
 import (
 	"context"
+	"net"
 	"regexp"
 	"time"
 
 	"github.com/coredns/coredns/plugin"
+	"github.com/coredns/coredns/plugin/dnstap"
 	"github.com/coredns/coredns/plugin/file"
 	"github.com/coredns/coredns/plugin/metrics"
 	"github.com/coredns/coredns/plugin/pkg/upstream"
@@ -34,6 +36,7 @@ type (
 
 		ReloadInterval time.Duration
 		upstream       *upstream.Upstream // Upstream for looking up names during the resolution process.
+		allowUpdate    []*net.IPNet       // networks that may send dynamic updates
 	}
 )
 
@@ -67,6 +70,15 @@ func (a Auto) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (i
 		return dns.RcodeRefused, nil
 	}
 
+	if r.Opcode == dns.OpcodeUpdate {
+		w.WriteMsg(z.DynamicUpdate(state, a.transfer))
+		return dns.RcodeSuccess, nil
+	}
+
+	if tapPlugin, ok := dnstap.FromContext(ctx); ok {
+		w = tapPlugin.TapAuth(ctx, w, r)
+	}
+
 	answer, ns, extra, result := z.Lookup(ctx, state, qname)
 
 	m := new(dns.Msg)
@@ -11,6 +11,7 @@ import (
 	"github.com/coredns/coredns/core/dnsserver"
 	"github.com/coredns/coredns/plugin"
 	"github.com/coredns/coredns/plugin/metrics"
+	"github.com/coredns/coredns/plugin/pkg/cidr"
 	clog "github.com/coredns/coredns/plugin/pkg/log"
 	"github.com/coredns/coredns/plugin/pkg/upstream"
 	"github.com/coredns/coredns/plugin/transfer"
@@ -151,6 +152,17 @@ func autoParse(c *caddy.Controller) (Auto, error) {
 				// remove soon
 				c.RemainingArgs() // eat remaining args
 
+			case "update":
+				t := c.RemainingArgs()
+				if len(t) == 0 {
+					return a, c.ArgErr()
+				}
+				networks, err := cidr.ParseNetworks(t)
+				if err != nil {
+					return a, c.Err(err.Error())
+				}
+				a.loader.allowUpdate = networks
+
 			default:
 				return Auto{}, c.Errf("unknown property '%s'", c.Val())
 			}
@@ -48,7 +48,22 @@ func TestAutoParse(t *testing.T) {
 			}`,
 			false, "/tmp", "bliep", `(.*)`, 10 * time.Second,
 		},
+		{
+			`auto {
+				directory /tmp
+				update 10.0.0.0/8
+			}`,
+			false, "/tmp", "${1}", `db\.(.*)`, 60 * time.Second,
+		},
 		// errors
+		// no networks to accept updates from.
+		{
+			`auto {
+				directory /tmp
+				update
+			}`,
+			true, "/tmp", "${1}", `db\.(.*)`, 60 * time.Second,
+		},
 		// NO_RELOAD has been deprecated.
 		{
 			`auto {
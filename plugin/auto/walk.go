@@ -4,6 +4,7 @@ import (
 	"os"
 	"path/filepath"
 	"regexp"
+	"strings"
 
 	"github.com/coredns/coredns/plugin/file"
 
@@ -24,6 +25,10 @@ func (a Auto) Walk() error {
 		if info == nil || info.IsDir() {
 			return nil
 		}
+		// Skip hidden files and the journals of dynamic updates.
+		if strings.HasPrefix(info.Name(), ".") || strings.HasSuffix(info.Name(), file.JournalSuffix) {
+			return nil
+		}
 
 		match, origin := matches(a.loader.re, info.Name(), a.loader.template)
 		if !match {
@@ -50,9 +55,11 @@ func (a Auto) Walk() error {
 			log.Warningf("Parse zone `%s': %v", origin, err)
 			return nil
 		}
+		zo.ReplayJournal()
 
 		zo.ReloadInterval = a.loader.ReloadInterval
 		zo.Upstream = a.loader.upstream
+		zo.AllowUpdate = a.loader.allowUpdate
 
 		a.Zones.Add(zo, origin, a.transfer)
 
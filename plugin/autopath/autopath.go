@@ -21,7 +21,10 @@ through the search path.
 It is assume the search path ordering is identical between server and client.
 
 Plugins implementing autopath, must have a function called `AutoPath` of type
-autopath.Func. Note the searchpath must be ending with the empty string.
+autopath.Func. Note the searchpath must be ending with the empty string. Such
+a plugin can use Subnets to select the search path based on the client's address.
+A search path can also be read from a file that maps subnets to search paths;
+it takes precedence over the other search path for clients in those subnets.
 
 I.e:
 
@@ -62,6 +65,7 @@ type AutoPath struct {
 	// Search always includes "" as the last element, so we try the base query with out any search paths added as well.
 	search     []string
 	searchFunc Func
+	subnets    *subnetFile
 }
 
 // ServeDNS implements the plugin.Handle interface.
@@ -73,12 +77,18 @@ func (a *AutoPath) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Ms
 		return plugin.NextOrFailure(a.Name(), a.Next, ctx, w, r)
 	}
 
-	// Check if autopath should be done, searchFunc takes precedence over the local configured search path.
+	// Check if autopath should be done, the search path of the client's subnet takes precedence over
+	// searchFunc, which takes precedence over the local configured search path.
 	var err error
-	searchpath := a.search
-
-	if a.searchFunc != nil {
-		searchpath = a.searchFunc(state)
+	var searchpath []string
+	if a.subnets != nil {
+		searchpath = a.subnets.AutoPath(state)
+	}
+	if searchpath == nil {
+		searchpath = a.search
+		if a.searchFunc != nil {
+			searchpath = a.searchFunc(state)
+		}
 	}
 
 	if len(searchpath) == 0 {
@@ -2,23 +2,35 @@ package autopath
 
 import (
 	"fmt"
+	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/coredns/caddy"
 	"github.com/coredns/coredns/core/dnsserver"
 	"github.com/coredns/coredns/plugin"
+	clog "github.com/coredns/coredns/plugin/pkg/log"
 
 	"github.com/miekg/dns"
 )
 
+var log = clog.NewWithPlugin("autopath")
+
 func init() { plugin.Register("autopath", setup) }
 
+const defaultReload = 30 * time.Second
+
 func setup(c *caddy.Controller) error {
 	ap, mw, err := autoPathParse(c)
 	if err != nil {
 		return plugin.Error("autopath", err)
 	}
 
+	if ap.subnets != nil {
+		c.OnStartup(ap.subnets.OnStartup)
+		c.OnShutdown(ap.subnets.OnShutdown)
+	}
+
 	// Do this in OnStartup, so all plugin has been initialized.
 	c.OnStartup(func() error {
 		m := dnsserver.GetConfig(c).Handler(mw)
@@ -59,12 +71,59 @@ func autoPathParse(c *caddy.Controller) (*AutoPath, string, error) {
 			if err != nil {
 				return ap, "", fmt.Errorf("failed to parse %q: %v", resolv, err)
 			}
-			ap.search = rc.Search
-			plugin.Zones(ap.search).Normalize()
-			ap.search = append(ap.search, "") // sentinel value as demanded.
+			if len(rc.Search) > 0 {
+				ap.search = rc.Search
+				plugin.Zones(ap.search).Normalize()
+				ap.search = append(ap.search, "") // sentinel value as demanded.
+			}
 		}
 		zones := zoneAndresolv[:len(zoneAndresolv)-1]
 		ap.Zones = plugin.OriginsFromArgsOrServerBlock(zones, c.ServerBlockKeys)
+
+		for c.NextBlock() {
+			switch c.Val() {
+			case "subnets":
+				args := c.RemainingArgs()
+				if len(args) != 1 {
+					return ap, "", c.ArgErr()
+				}
+				path := args[0]
+				config := dnsserver.GetConfig(c)
+				if !filepath.IsAbs(path) && config.Root != "" {
+					path = filepath.Join(config.Root, path)
+				}
+				if ap.subnets == nil {
+					ap.subnets = &subnetFile{reload: defaultReload}
+				}
+				ap.subnets.path = path
+			case "reload":
+				args := c.RemainingArgs()
+				if len(args) != 1 {
+					return ap, "", c.ArgErr()
+				}
+				d, err := time.ParseDuration(args[0])
+				if err != nil {
+					return ap, "", c.Errf("invalid duration for reload '%s'", args[0])
+				}
+				if d < 0 {
+					return ap, "", c.Errf("invalid negative duration for reload '%s'", args[0])
+				}
+				if ap.subnets == nil {
+					ap.subnets = &subnetFile{}
+				}
+				ap.subnets.reload = d
+			default:
+				return ap, "", c.Errf("unknown property '%s'", c.Val())
+			}
+		}
+	}
+	if ap.subnets != nil {
+		if ap.subnets.path == "" {
+			return ap, "", fmt.Errorf("reload needs a subnets file")
+		}
+		if err := ap.subnets.load(); err != nil {
+			return ap, "", fmt.Errorf("failed to read subnets: %v", err)
+		}
 	}
 	return ap, mw, nil
 }
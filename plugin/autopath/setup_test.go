@@ -2,9 +2,11 @@ package autopath
 
 import (
 	"os"
+	"path/filepath"
 	"reflect"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/coredns/caddy"
 	"github.com/coredns/coredns/plugin/test"
@@ -75,3 +77,63 @@ domain foo.com
 search bar.com baz.com
 options ndots:5
 `
+
+func TestSetupAutoPathSubnets(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "subnets")
+	if err := os.WriteFile(path, []byte("10.0.0.0/8 example.org\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		input          string
+		shouldErr      bool
+		expectedReload time.Duration
+	}{
+		{`autopath @kubernetes {
+			subnets ` + path + `
+		}`, false, defaultReload},
+		{`autopath @etcd {
+			subnets ` + path + `
+			reload 0
+		}`, false, 0},
+		{`autopath @etcd {
+			reload 1m
+			subnets ` + path + `
+		}`, false, time.Minute},
+		// negative
+		{`autopath @etcd {
+			subnets
+		}`, true, 0},
+		{`autopath @etcd {
+			subnets /does/not/exist
+		}`, true, 0},
+		{`autopath @etcd {
+			reload 1m
+		}`, true, 0},
+		{`autopath @etcd {
+			subnets ` + path + `
+			reload -1m
+		}`, true, 0},
+		{`autopath @etcd {
+			foo
+		}`, true, 0},
+	}
+
+	for i, test := range tests {
+		c := caddy.NewTestController("dns", test.input)
+		ap, _, err := autoPathParse(c)
+		if (err != nil) != test.shouldErr {
+			t.Errorf("Test %d: expected error %t, got %v for input %s", i, test.shouldErr, err, test.input)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		if ap.subnets.reload != test.expectedReload {
+			t.Errorf("Test %d: expected reload %s, got %s", i, test.expectedReload, ap.subnets.reload)
+		}
+		if ap.subnets.subnets.Len() != 1 {
+			t.Errorf("Test %d: expected 1 subnet, got %d", i, ap.subnets.subnets.Len())
+		}
+	}
+}
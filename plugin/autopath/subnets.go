@@ -0,0 +1,193 @@
+package autopath
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/coredns/coredns/plugin"
+	"github.com/coredns/coredns/plugin/pkg/cidr"
+	"github.com/coredns/coredns/request"
+)
+
+// Subnets maps client subnets to search paths. It can be used by plugins that implement AutoPather
+// to select a search path based on the address of the client.
+type Subnets struct {
+	entries []subnet // sorted from the most to the least specific subnet
+}
+
+type subnet struct {
+	net    *net.IPNet
+	search []string
+}
+
+// Add adds the search path search for clients in the subnet cidr, which may also be a single address.
+func (s *Subnets) Add(network string, search []string) error {
+	n, err := cidr.ParseNetwork(network)
+	if err != nil {
+		return err
+	}
+	if len(search) == 0 {
+		return fmt.Errorf("no search path for subnet %q", network)
+	}
+
+	sp := make([]string, len(search), len(search)+1)
+	copy(sp, search)
+	plugin.Zones(sp).Normalize()
+	sp = append(sp, "") // sentinel value as demanded.
+
+	s.entries = append(s.entries, subnet{net: n, search: sp})
+	sort.SliceStable(s.entries, func(i, j int) bool {
+		li, _ := s.entries[i].net.Mask.Size()
+		lj, _ := s.entries[j].net.Mask.Size()
+		return li > lj
+	})
+	return nil
+}
+
+// Search returns the search path of the most specific subnet that contains ip, or nil if there is
+// none.
+func (s *Subnets) Search(ip net.IP) []string {
+	if s == nil {
+		return nil
+	}
+	for _, e := range s.entries {
+		if e.net.Contains(ip) {
+			return e.search
+		}
+	}
+	return nil
+}
+
+// Len returns the number of subnets.
+func (s *Subnets) Len() int {
+	if s == nil {
+		return 0
+	}
+	return len(s.entries)
+}
+
+// ParseSubnets parses lines of the form "SUBNET DOMAIN..." from r. Empty lines and comments starting
+// with '#' are ignored.
+func ParseSubnets(r io.Reader) (*Subnets, error) {
+	s := &Subnets{}
+	scanner := bufio.NewScanner(r)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := scanner.Text()
+		if i := strings.Index(text, "#"); i >= 0 {
+			text = text[:i]
+		}
+		fields := strings.Fields(text)
+		if len(fields) == 0 {
+			continue
+		}
+		if err := s.Add(fields[0], fields[1:]); err != nil {
+			return nil, fmt.Errorf("line %d: %s", line, err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// subnetFile is a search path provider that reads the subnets from a file.
+type subnetFile struct {
+	path   string
+	reload time.Duration
+
+	mu      sync.RWMutex
+	subnets *Subnets
+	mtime   time.Time
+	size    int64
+
+	stop chan struct{}
+	wg   sync.WaitGroup
+}
+
+// AutoPath implements the AutoPather interface.
+func (f *subnetFile) AutoPath(state request.Request) []string {
+	ip := net.ParseIP(state.IP())
+	if ip == nil {
+		return nil
+	}
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return f.subnets.Search(ip)
+}
+
+// load reads the file if it changed since it was last read.
+func (f *subnetFile) load() error {
+	file, err := os.Open(f.path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	stat, err := file.Stat()
+	if err != nil {
+		return err
+	}
+	f.mu.RLock()
+	same := f.subnets != nil && f.mtime.Equal(stat.ModTime()) && f.size == stat.Size()
+	f.mu.RUnlock()
+	if same {
+		return nil
+	}
+
+	subnets, err := ParseSubnets(file)
+	if err != nil {
+		return fmt.Errorf("%s: %s", f.path, err)
+	}
+
+	f.mu.Lock()
+	f.subnets = subnets
+	f.mtime = stat.ModTime()
+	f.size = stat.Size()
+	f.mu.Unlock()
+	log.Infof("Loaded %d subnets from %s", subnets.Len(), f.path)
+	return nil
+}
+
+// OnStartup starts reloading the file periodically.
+func (f *subnetFile) OnStartup() error {
+	if f.reload == 0 {
+		return nil
+	}
+	f.stop = make(chan struct{})
+	f.wg.Add(1)
+	go func() {
+		defer f.wg.Done()
+		tick := time.NewTicker(f.reload)
+		defer tick.Stop()
+		for {
+			select {
+			case <-f.stop:
+				return
+			case <-tick.C:
+				if err := f.load(); err != nil {
+					log.Warningf("Failed to reload subnets: %s", err)
+				}
+			}
+		}
+	}()
+	return nil
+}
+
+// OnShutdown stops reloading the file.
+func (f *subnetFile) OnShutdown() error {
+	if f.stop != nil {
+		close(f.stop)
+		f.wg.Wait()
+		f.stop = nil
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+package autopath
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/coredns/coredns/plugin/pkg/dnstest"
+	"github.com/coredns/coredns/plugin/test"
+
+	"github.com/miekg/dns"
+)
+
+const subnetsFile = `# site subnets
+10.0.0.0/8      corp.example.org example.org
+10.240.0.0/16   vm.example.org  corp.example.org example.org # VMs
+2001:db8::1     v6.example.org
+`
+
+func TestParseSubnets(t *testing.T) {
+	s, err := ParseSubnets(strings.NewReader(subnetsFile))
+	if err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+	if s.Len() != 3 {
+		t.Fatalf("Expected 3 subnets, got %d", s.Len())
+	}
+
+	tests := []struct {
+		ip     string
+		search []string
+	}{
+		{"10.240.0.1", []string{"vm.example.org.", "corp.example.org.", "example.org.", ""}},
+		{"10.1.0.1", []string{"corp.example.org.", "example.org.", ""}},
+		{"2001:db8::1", []string{"v6.example.org.", ""}},
+		{"2001:db8::2", nil},
+		{"192.0.2.1", nil},
+	}
+	for _, tc := range tests {
+		if got := s.Search(net.ParseIP(tc.ip)); !reflect.DeepEqual(got, tc.search) {
+			t.Errorf("Expected search path %v for %s, got %v", tc.search, tc.ip, got)
+		}
+	}
+
+	for _, bad := range []string{"10.0.0.0/33 example.org", "foo example.org", "10.0.0.0/8"} {
+		if _, err := ParseSubnets(strings.NewReader(bad)); err == nil {
+			t.Errorf("Expected error for %q", bad)
+		}
+	}
+}
+
+func TestAutoPathSubnets(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "subnets")
+	if err := os.WriteFile(path, []byte("10.240.0.0/16 example.org example.com\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	ap := newTestAutoPath()
+	// The static search path would not expand b.example.net.
+	ap.search = []string{"example.net.", ""}
+	ap.subnets = &subnetFile{path: path}
+	if err := ap.subnets.load(); err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+
+	// test.ResponseWriter's remote address is 10.240.0.1.
+	for _, tc := range autopathTestCases {
+		m := tc.Msg()
+		rec := dnstest.NewRecorder(&test.ResponseWriter{})
+		if _, err := ap.ServeDNS(context.TODO(), rec, m); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if err := test.Section(tc, test.Answer, rec.Msg.Answer); err != nil {
+			t.Error(err)
+		}
+	}
+
+	// Other clients use the static search path.
+	m := new(dns.Msg)
+	m.SetQuestion("b.example.org.", dns.TypeA)
+	rec := dnstest.NewRecorder(&test.ResponseWriter{RemoteIP: "192.0.2.1"})
+	ap.ServeDNS(context.TODO(), rec, m)
+	if rec.Msg.Rcode != dns.RcodeNameError {
+		t.Errorf("Expected NXDOMAIN without autopath, got %s", dns.RcodeToString[rec.Msg.Rcode])
+	}
+}
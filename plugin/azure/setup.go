@@ -25,6 +25,16 @@ func setup(c *caddy.Controller) error {
 	if err != nil {
 		return plugin.Error("azure", err)
 	}
+	if dnsserver.DryRun(c) {
+		// Don't contact Azure when only validating.
+		h := &Azure{Fall: fall}
+		dnsserver.GetConfig(c).AddPlugin(func(next plugin.Handler) plugin.Handler {
+			h.Next = next
+			return h
+		})
+		return nil
+	}
+
 	ctx, cancel := context.WithCancel(context.Background())
 
 	publicDNSClient := publicAzureDNS.NewRecordSetsClient(env.Values[auth.SubscriptionID])
@@ -0,0 +1,155 @@
+// Package blocklist implements a plugin that blocks the names in (large) domain lists.
+package blocklist
+
+import (
+	"context"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/coredns/coredns/plugin"
+	"github.com/coredns/coredns/plugin/metadata"
+	"github.com/coredns/coredns/plugin/metrics"
+	"github.com/coredns/coredns/request"
+
+	"github.com/miekg/dns"
+)
+
+// Blocklist is a plugin that blocks the names in domain lists.
+type Blocklist struct {
+	Next  plugin.Handler
+	Zones []string
+
+	lists  []*list // allowlists first, then the other lists in the order they are configured
+	ttl    uint32
+	reload time.Duration
+
+	stop chan struct{}
+	wg   sync.WaitGroup
+}
+
+// match returns the first list that has name, or nil if there is none or if name is allowed.
+func (b *Blocklist) match(name string) *list {
+	for _, l := range b.lists {
+		if l.match(name) {
+			if l.action == actionAllow {
+				return nil
+			}
+			return l
+		}
+	}
+	return nil
+}
+
+// ServeDNS implements the plugin.Handler interface.
+func (b *Blocklist) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (int, error) {
+	state := request.Request{W: w, Req: r}
+	if plugin.Zones(b.Zones).Matches(state.Name()) == "" {
+		return plugin.NextOrFailure(b.Name(), b.Next, ctx, w, r)
+	}
+
+	l := b.match(state.Name())
+	if l == nil {
+		return plugin.NextOrFailure(b.Name(), b.Next, ctx, w, r)
+	}
+	blockedCount.WithLabelValues(metrics.WithServer(ctx), l.name).Inc()
+
+	m := new(dns.Msg)
+	m.SetReply(r)
+	switch l.action {
+	case actionNXDomain:
+		m.Rcode = dns.RcodeNameError
+	case actionRefused:
+		m.Rcode = dns.RcodeRefused
+	case actionSinkhole:
+		switch state.QType() {
+		case dns.TypeA:
+			m.Answer = answers(state.Name(), dns.TypeA, b.ttl, l.sinkhole4)
+		case dns.TypeAAAA:
+			m.Answer = answers(state.Name(), dns.TypeAAAA, b.ttl, l.sinkhole6)
+		}
+	}
+	w.WriteMsg(m)
+	return m.Rcode, nil
+}
+
+// Metadata implements the metadata.Provider interface.
+func (b *Blocklist) Metadata(ctx context.Context, state request.Request) context.Context {
+	if plugin.Zones(b.Zones).Matches(state.Name()) == "" {
+		return ctx
+	}
+	metadata.SetValueFunc(ctx, "blocklist/list", func() string {
+		if l := b.match(state.Name()); l != nil {
+			return l.name
+		}
+		return ""
+	})
+	return ctx
+}
+
+// Name implements the plugin.Handler interface.
+func (b *Blocklist) Name() string { return "blocklist" }
+
+// answers returns the A or AAAA records of ips for name.
+func answers(name string, qtype uint16, ttl uint32, ips []net.IP) []dns.RR {
+	rrs := make([]dns.RR, len(ips))
+	for i, ip := range ips {
+		hdr := dns.RR_Header{Name: name, Rrtype: qtype, Class: dns.ClassINET, Ttl: ttl}
+		if qtype == dns.TypeA {
+			rrs[i] = &dns.A{Hdr: hdr, A: ip}
+			continue
+		}
+		rrs[i] = &dns.AAAA{Hdr: hdr, AAAA: ip}
+	}
+	return rrs
+}
+
+// load (re)loads all lists that changed.
+func (b *Blocklist) load() {
+	for _, l := range b.lists {
+		ok, err := l.load()
+		if err != nil {
+			log.Warningf("Failed to load list %q from %s: %s", l.name, l.source, err)
+			continue
+		}
+		if ok {
+			log.Infof("Loaded list %q from %s with %d names", l.name, l.source, l.Len())
+			listEntries.WithLabelValues(l.name).Set(float64(l.Len()))
+		}
+	}
+}
+
+// OnStartup loads the lists and starts reloading them periodically.
+func (b *Blocklist) OnStartup() error {
+	b.load()
+	if b.reload == 0 {
+		return nil
+	}
+
+	b.stop = make(chan struct{})
+	b.wg.Add(1)
+	go func() {
+		defer b.wg.Done()
+		tick := time.NewTicker(b.reload)
+		defer tick.Stop()
+		for {
+			select {
+			case <-b.stop:
+				return
+			case <-tick.C:
+				b.load()
+			}
+		}
+	}()
+	return nil
+}
+
+// OnShutdown stops reloading the lists.
+func (b *Blocklist) OnShutdown() error {
+	if b.stop != nil {
+		close(b.stop)
+		b.wg.Wait()
+		b.stop = nil
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+package blocklist
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/coredns/coredns/plugin/metadata"
+	"github.com/coredns/coredns/plugin/pkg/dnstest"
+	"github.com/coredns/coredns/plugin/test"
+	"github.com/coredns/coredns/request"
+
+	"github.com/miekg/dns"
+)
+
+func newTestBlocklist() *Blocklist {
+	return &Blocklist{
+		Next:  test.NextHandler(dns.RcodeSuccess, nil),
+		Zones: []string{"."},
+		ttl:   defaultTTL,
+		lists: []*list{
+			{name: "allow", action: actionAllow, trie: newTrie([]string{"good.ads.example.org."})},
+			{name: "ads", action: actionNXDomain, trie: newTrie([]string{"ads.example.org."})},
+			{name: "malware", action: actionRefused, trie: newTrie([]string{"malware.example.net."})},
+			{name: "tracking", action: actionSinkhole, trie: newTrie([]string{"tracker.example.com."}),
+				sinkhole4: []net.IP{net.ParseIP("192.0.2.1")}, sinkhole6: []net.IP{net.ParseIP("2001:db8::1")}},
+		},
+	}
+}
+
+func TestBlocklist(t *testing.T) {
+	b := newTestBlocklist()
+
+	tests := []struct {
+		qname   string
+		qtype   uint16
+		blocked bool
+		rcode   int
+		answer  []dns.RR
+	}{
+		{"ads.example.org.", dns.TypeA, true, dns.RcodeNameError, nil},
+		{"www.ads.example.org.", dns.TypeAAAA, true, dns.RcodeNameError, nil},
+		{"good.ads.example.org.", dns.TypeA, false, 0, nil},
+		{"malware.example.net.", dns.TypeA, true, dns.RcodeRefused, nil},
+		{"tracker.example.com.", dns.TypeA, true, dns.RcodeSuccess, []dns.RR{test.A("tracker.example.com. 3600 IN A 192.0.2.1")}},
+		{"tracker.example.com.", dns.TypeAAAA, true, dns.RcodeSuccess, []dns.RR{test.AAAA("tracker.example.com. 3600 IN AAAA 2001:db8::1")}},
+		{"tracker.example.com.", dns.TypeMX, true, dns.RcodeSuccess, nil},
+		{"example.org.", dns.TypeA, false, 0, nil},
+	}
+
+	for i, tc := range tests {
+		m := new(dns.Msg)
+		m.SetQuestion(tc.qname, tc.qtype)
+		rec := dnstest.NewRecorder(&test.ResponseWriter{})
+		if _, err := b.ServeDNS(context.Background(), rec, m); err != nil {
+			t.Fatalf("Test %d: expected no error, got %s", i, err)
+		}
+		if !tc.blocked {
+			if rec.Msg != nil {
+				t.Errorf("Test %d: expected %s not to be blocked", i, tc.qname)
+			}
+			continue
+		}
+		if rec.Msg == nil {
+			t.Fatalf("Test %d: expected %s to be blocked", i, tc.qname)
+		}
+		if rec.Msg.Rcode != tc.rcode {
+			t.Errorf("Test %d: expected rcode %d, got %d", i, tc.rcode, rec.Msg.Rcode)
+		}
+		if err := test.Section(test.Case{Answer: tc.answer}, test.Answer, rec.Msg.Answer); err != nil {
+			t.Errorf("Test %d: %s", i, err)
+		}
+	}
+}
+
+func TestBlocklistMetadata(t *testing.T) {
+	b := newTestBlocklist()
+
+	for _, tc := range []struct {
+		qname string
+		list  string
+	}{
+		{"www.ads.example.org.", "ads"},
+		{"good.ads.example.org.", ""},
+		{"example.org.", ""},
+	} {
+		m := new(dns.Msg)
+		m.SetQuestion(tc.qname, dns.TypeA)
+		state := request.Request{W: &test.ResponseWriter{}, Req: m}
+
+		ctx := metadata.ContextWithMetadata(context.Background())
+		ctx = b.Metadata(ctx, state)
+		f := metadata.ValueFunc(ctx, "blocklist/list")
+		if f == nil {
+			t.Fatalf("Expected metadata for %s", tc.qname)
+		}
+		if got := f(); got != tc.list {
+			t.Errorf("Expected list %q for %s, got %q", tc.list, tc.qname, got)
+		}
+	}
+}
@@ -0,0 +1,236 @@
+package blocklist
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+type action int
+
+// The actions for names in a list.
+const (
+	actionNXDomain action = iota
+	actionRefused
+	actionSinkhole
+	actionAllow
+)
+
+func (a action) String() string {
+	switch a {
+	case actionNXDomain:
+		return "nxdomain"
+	case actionRefused:
+		return "refused"
+	case actionSinkhole:
+		return "sinkhole"
+	}
+	return "allow"
+}
+
+// list is a domain list loaded from a file or an HTTP(S) URL.
+type list struct {
+	name   string
+	source string
+	action action
+
+	sinkhole4 []net.IP
+	sinkhole6 []net.IP
+
+	mu   sync.RWMutex
+	trie *trie
+
+	// The following fields are only read and modified by a single goroutine.
+	mtime        time.Time
+	size         int64
+	etag         string
+	lastModified string
+}
+
+func (l *list) isURL() bool {
+	return strings.HasPrefix(l.source, "http://") || strings.HasPrefix(l.source, "https://")
+}
+
+// match returns true if name is in the list.
+func (l *list) match(name string) bool {
+	l.mu.RLock()
+	t := l.trie
+	l.mu.RUnlock()
+	return t != nil && t.match(name)
+}
+
+// Len returns the number of names in the list.
+func (l *list) Len() int {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	if l.trie == nil {
+		return 0
+	}
+	return l.trie.Len()
+}
+
+var client = &http.Client{Timeout: 30 * time.Second}
+
+// load (re)loads the list if its source changed. It returns true if the list was loaded. When the
+// source can't be read, the list keeps its current names.
+func (l *list) load() (bool, error) {
+	var (
+		r   io.ReadCloser
+		err error
+	)
+	if l.isURL() {
+		r, err = l.fetch()
+	} else {
+		r, err = l.open()
+	}
+	if err != nil || r == nil {
+		return false, err
+	}
+	defer r.Close()
+
+	names, err := parseList(r)
+	if err != nil {
+		return false, err
+	}
+	t := newTrie(names)
+
+	l.mu.Lock()
+	l.trie = t
+	l.mu.Unlock()
+	return true, nil
+}
+
+// open opens the file of the list, it returns nil if the file didn't change.
+func (l *list) open() (io.ReadCloser, error) {
+	file, err := os.Open(l.source)
+	if err != nil {
+		return nil, err
+	}
+	stat, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	if l.mtime.Equal(stat.ModTime()) && l.size == stat.Size() {
+		file.Close()
+		return nil, nil
+	}
+	l.mtime, l.size = stat.ModTime(), stat.Size()
+	return file, nil
+}
+
+// fetch retrieves the list from its URL, it returns nil if the list didn't change.
+func (l *list) fetch() (io.ReadCloser, error) {
+	req, err := http.NewRequest(http.MethodGet, l.source, nil)
+	if err != nil {
+		return nil, err
+	}
+	if l.etag != "" {
+		req.Header.Set("If-None-Match", l.etag)
+	}
+	if l.lastModified != "" {
+		req.Header.Set("If-Modified-Since", l.lastModified)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	switch resp.StatusCode {
+	case http.StatusOK:
+		l.etag, l.lastModified = resp.Header.Get("ETag"), resp.Header.Get("Last-Modified")
+		return resp.Body, nil
+	case http.StatusNotModified:
+		resp.Body.Close()
+		return nil, nil
+	}
+	resp.Body.Close()
+	return nil, fmt.Errorf("unexpected HTTP status: %s", resp.Status)
+}
+
+// parseList returns the names in a domain list. This can be a list with one domain per line, a hosts
+// file, or an AdGuard (Adblock) list of which only the basic domain rules are used.
+func parseList(r io.Reader) ([]string, error) {
+	var names []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		names = append(names, parseLine(scanner.Text())...)
+	}
+	return names, scanner.Err()
+}
+
+// parseLine returns the names in a single line of a domain list.
+func parseLine(line string) []string {
+	line = strings.TrimSpace(line)
+	if line == "" || line[0] == '!' || line[0] == '[' {
+		// Empty, Adblock comment or header.
+		return nil
+	}
+	if strings.Contains(line, "##") || strings.Contains(line, "#@#") || strings.Contains(line, "#?#") {
+		// Adblock element hiding rule.
+		return nil
+	}
+	if i := strings.IndexByte(line, '#'); i >= 0 {
+		line = line[:i]
+	}
+
+	fields := strings.Fields(line)
+	switch {
+	case len(fields) == 0:
+		return nil
+	case len(fields) > 1:
+		// hosts file, all but the address are names.
+		if net.ParseIP(fields[0]) == nil {
+			return nil
+		}
+		fields = fields[1:]
+	}
+
+	names := make([]string, 0, len(fields))
+	for _, f := range fields {
+		if strings.HasPrefix(f, "@@") {
+			// Adblock exception, use an allowlist for these.
+			continue
+		}
+		f = strings.TrimPrefix(f, "||")
+		if i := strings.IndexByte(f, '$'); i >= 0 {
+			// Adblock modifiers.
+			f = f[:i]
+		}
+		f = strings.TrimSuffix(f, "^")
+		f = strings.TrimPrefix(f, "*.")
+		f = strings.TrimPrefix(f, ".")
+		if f == "" || strings.ContainsAny(f, "/*^|:") {
+			// Regular expressions, URL paths and such.
+			continue
+		}
+		name := strings.ToLower(dns.Fqdn(f))
+		if _, ok := dns.IsDomainName(name); !ok || local[name] {
+			continue
+		}
+		names = append(names, name)
+	}
+	return names
+}
+
+// local are names commonly found in hosts files that should never be blocked.
+var local = map[string]bool{
+	"localhost.":             true,
+	"localhost.localdomain.": true,
+	"local.":                 true,
+	"broadcasthost.":         true,
+	"ip6-localhost.":         true,
+	"ip6-loopback.":          true,
+	"ip6-localnet.":          true,
+	"ip6-mcastprefix.":       true,
+	"ip6-allnodes.":          true,
+	"ip6-allrouters.":        true,
+	"ip6-allhosts.":          true,
+}
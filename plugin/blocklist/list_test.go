@@ -0,0 +1,100 @@
+package blocklist
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseLine(t *testing.T) {
+	tests := []struct {
+		line     string
+		expected []string
+	}{
+		{"example.org", []string{"example.org."}},
+		{"  Example.ORG.  ", []string{"example.org."}},
+		{"0.0.0.0 ads.example.org tracker.example.org # ads", []string{"ads.example.org.", "tracker.example.org."}},
+		{"127.0.0.1 localhost", []string{}},
+		{":: ads.example.org", []string{"ads.example.org."}},
+		{"||ads.example.org^", []string{"ads.example.org."}},
+		{"||ads.example.org^$important", []string{"ads.example.org."}},
+		{"*.ads.example.org", []string{"ads.example.org."}},
+		{"@@||good.example.org^", []string{}},
+		{"||example.org/ads/*", []string{}},
+		{"/ads[0-9]+/", []string{}},
+		{"example.org##.banner", nil},
+		{"! comment", nil},
+		{"# comment", nil},
+		{"[Adblock Plus 2.0]", nil},
+		{"", nil},
+		{"not a hosts line", nil},
+	}
+	for _, tc := range tests {
+		got := parseLine(tc.line)
+		if len(got) == 0 && len(tc.expected) == 0 {
+			continue
+		}
+		if !reflect.DeepEqual(got, tc.expected) {
+			t.Errorf("Expected %v for %q, got %v", tc.expected, tc.line, got)
+		}
+	}
+}
+
+func TestListLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ads.txt")
+	if err := os.WriteFile(path, []byte("ads.example.org\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	l := &list{name: "ads", source: path}
+	if ok, err := l.load(); !ok || err != nil {
+		t.Fatalf("Expected list to load, got %t, %v", ok, err)
+	}
+	if !l.match("x.ads.example.org.") {
+		t.Errorf("Expected x.ads.example.org. to match")
+	}
+	if ok, err := l.load(); ok || err != nil {
+		t.Errorf("Expected unchanged list not to load, got %t, %v", ok, err)
+	}
+
+	// A list that can't be read keeps its names.
+	os.Remove(path)
+	if _, err := l.load(); err == nil {
+		t.Errorf("Expected an error for a missing file")
+	}
+	if !l.match("ads.example.org.") {
+		t.Errorf("Expected ads.example.org. to still match")
+	}
+}
+
+func TestListFetch(t *testing.T) {
+	requests := 0
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		w.Write([]byte(strings.Join([]string{"! title", "||ads.example.org^", "0.0.0.0 tracker.example.net"}, "\n")))
+	}))
+	defer s.Close()
+
+	l := &list{name: "remote", source: s.URL + "/list.txt"}
+	if ok, err := l.load(); !ok || err != nil {
+		t.Fatalf("Expected list to load, got %t, %v", ok, err)
+	}
+	if l.Len() != 2 {
+		t.Errorf("Expected 2 names, got %d", l.Len())
+	}
+	if ok, err := l.load(); ok || err != nil {
+		t.Errorf("Expected unchanged list not to load, got %t, %v", ok, err)
+	}
+	if requests != 2 {
+		t.Errorf("Expected 2 requests, got %d", requests)
+	}
+}
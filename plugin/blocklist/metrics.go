@@ -0,0 +1,25 @@
+package blocklist
+
+import (
+	"github.com/coredns/coredns/plugin"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	// blockedCount is the number of requests blocked, per list.
+	blockedCount = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: plugin.Namespace,
+		Subsystem: "blocklist",
+		Name:      "blocked_requests_total",
+		Help:      "Counter of requests blocked by a list.",
+	}, []string{"server", "list"})
+	// listEntries is the number of names in each list.
+	listEntries = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: plugin.Namespace,
+		Subsystem: "blocklist",
+		Name:      "entries",
+		Help:      "The number of names in each list.",
+	}, []string{"list"})
+)
@@ -0,0 +1,156 @@
+package blocklist
+
+import (
+	"net"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/coredns/caddy"
+	"github.com/coredns/coredns/core/dnsserver"
+	"github.com/coredns/coredns/plugin"
+	clog "github.com/coredns/coredns/plugin/pkg/log"
+)
+
+var log = clog.NewWithPlugin("blocklist")
+
+func init() { plugin.Register("blocklist", setup) }
+
+const (
+	defaultReload = time.Hour
+	defaultTTL    = 3600
+)
+
+func setup(c *caddy.Controller) error {
+	b, err := blocklistParse(c)
+	if err != nil {
+		return plugin.Error("blocklist", err)
+	}
+
+	c.OnStartup(b.OnStartup)
+	c.OnShutdown(b.OnShutdown)
+
+	dnsserver.GetConfig(c).AddPlugin(func(next plugin.Handler) plugin.Handler {
+		b.Next = next
+		return b
+	})
+
+	return nil
+}
+
+func blocklistParse(c *caddy.Controller) (*Blocklist, error) {
+	config := dnsserver.GetConfig(c)
+	b := &Blocklist{ttl: defaultTTL, reload: defaultReload}
+
+	var allow, block []*list
+	names := map[string]bool{}
+
+	i := 0
+	for c.Next() {
+		if i > 0 {
+			return nil, plugin.ErrOnce
+		}
+		i++
+
+		b.Zones = plugin.OriginsFromArgsOrServerBlock(c.RemainingArgs(), c.ServerBlockKeys)
+
+		for c.NextBlock() {
+			switch c.Val() {
+			case "list":
+				// list NAME SOURCE [nxdomain|refused|sinkhole ADDRESS...]
+				args := c.RemainingArgs()
+				if len(args) < 2 {
+					return nil, c.ArgErr()
+				}
+				l := &list{name: args[0], source: args[1]}
+				if names[l.name] {
+					return nil, c.Errf("duplicate list '%s'", l.name)
+				}
+				names[l.name] = true
+
+				if len(args) > 2 {
+					switch args[2] {
+					case "nxdomain":
+						l.action = actionNXDomain
+					case "refused":
+						l.action = actionRefused
+					case "sinkhole":
+						l.action = actionSinkhole
+						if len(args) == 3 {
+							return nil, c.Errf("sinkhole needs at least one address")
+						}
+						for _, a := range args[3:] {
+							ip := net.ParseIP(a)
+							if ip == nil {
+								return nil, c.Errf("invalid sinkhole address '%s'", a)
+							}
+							if ip.To4() != nil {
+								l.sinkhole4 = append(l.sinkhole4, ip)
+							} else {
+								l.sinkhole6 = append(l.sinkhole6, ip)
+							}
+						}
+					default:
+						return nil, c.Errf("unknown action '%s'", args[2])
+					}
+					if l.action != actionSinkhole && len(args) > 3 {
+						return nil, c.ArgErr()
+					}
+				}
+				block = append(block, l)
+
+			case "allow":
+				// allow SOURCE...
+				args := c.RemainingArgs()
+				if len(args) == 0 {
+					return nil, c.ArgErr()
+				}
+				for _, source := range args {
+					allow = append(allow, &list{name: source, source: source, action: actionAllow})
+				}
+
+			case "reload":
+				args := c.RemainingArgs()
+				if len(args) != 1 {
+					return nil, c.ArgErr()
+				}
+				d, err := time.ParseDuration(args[0])
+				if err != nil {
+					return nil, c.Errf("invalid duration for reload '%s'", args[0])
+				}
+				if d < 0 {
+					return nil, c.Errf("invalid negative duration for reload '%s'", args[0])
+				}
+				b.reload = d
+
+			case "ttl":
+				args := c.RemainingArgs()
+				if len(args) != 1 {
+					return nil, c.ArgErr()
+				}
+				ttl, err := strconv.Atoi(args[0])
+				if err != nil {
+					return nil, c.Errf("ttl needs a number of seconds")
+				}
+				if ttl <= 0 || ttl > 65535 {
+					return nil, c.Errf("ttl provided is invalid")
+				}
+				b.ttl = uint32(ttl)
+
+			default:
+				return nil, c.Errf("unknown property '%s'", c.Val())
+			}
+		}
+	}
+
+	if len(block) == 0 {
+		return nil, c.Errf("no lists")
+	}
+	b.lists = append(allow, block...)
+	for _, l := range b.lists {
+		if !l.isURL() && !filepath.IsAbs(l.source) && config.Root != "" {
+			l.source = filepath.Join(config.Root, l.source)
+		}
+	}
+	return b, nil
+}
@@ -0,0 +1,86 @@
+package blocklist
+
+import (
+	"testing"
+	"time"
+
+	"github.com/coredns/caddy"
+)
+
+func TestSetup(t *testing.T) {
+	tests := []struct {
+		input     string
+		shouldErr bool
+		lists     int
+		reload    time.Duration
+	}{
+		{`blocklist {
+			list ads /etc/coredns/ads.txt
+		}`, false, 1, defaultReload},
+		{`blocklist example.org {
+			list ads https://lists.example.net/ads.txt refused
+			list malware /etc/coredns/malware.txt sinkhole 192.0.2.1 2001:db8::1
+			allow /etc/coredns/allow.txt /etc/coredns/allow2.txt
+			reload 10m
+			ttl 60
+		}`, false, 4, 10 * time.Minute},
+		// fails
+		{`blocklist`, true, 0, 0},
+		{`blocklist {
+			list ads
+		}`, true, 0, 0},
+		{`blocklist {
+			list ads /etc/coredns/ads.txt block
+		}`, true, 0, 0},
+		{`blocklist {
+			list ads /etc/coredns/ads.txt sinkhole
+		}`, true, 0, 0},
+		{`blocklist {
+			list ads /etc/coredns/ads.txt sinkhole example.org
+		}`, true, 0, 0},
+		{`blocklist {
+			list ads /etc/coredns/ads.txt nxdomain 192.0.2.1
+		}`, true, 0, 0},
+		{`blocklist {
+			list ads /etc/coredns/ads.txt
+			list ads /etc/coredns/more-ads.txt
+		}`, true, 0, 0},
+		{`blocklist {
+			allow /etc/coredns/allow.txt
+		}`, true, 0, 0},
+		{`blocklist {
+			list ads /etc/coredns/ads.txt
+			reload -1m
+		}`, true, 0, 0},
+		{`blocklist {
+			list ads /etc/coredns/ads.txt
+			ttl 0
+		}`, true, 0, 0},
+		{`blocklist {
+			list ads /etc/coredns/ads.txt
+		}
+		blocklist {
+			list ads /etc/coredns/ads.txt
+		}`, true, 0, 0},
+	}
+
+	for i, test := range tests {
+		c := caddy.NewTestController("dns", test.input)
+		b, err := blocklistParse(c)
+		if test.shouldErr && err == nil {
+			t.Errorf("Test %d: expected error but found none for input %s", i, test.input)
+		}
+		if !test.shouldErr && err != nil {
+			t.Errorf("Test %d: expected no error but found one for input %s, got: %v", i, test.input, err)
+		}
+		if test.shouldErr || err != nil {
+			continue
+		}
+		if len(b.lists) != test.lists {
+			t.Errorf("Test %d: expected %d lists, got %d", i, test.lists, len(b.lists))
+		}
+		if b.reload != test.reload {
+			t.Errorf("Test %d: expected reload %s, got %s", i, test.reload, b.reload)
+		}
+	}
+}
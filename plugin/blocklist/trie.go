@@ -0,0 +1,93 @@
+package blocklist
+
+import (
+	"sort"
+	"strings"
+)
+
+// trie is a suffix trie of domain names, with a node per label. A name in the trie matches itself and
+// all names below it. The trie is built once from all names in a list and not modified after that;
+// the children of a node are kept in a sorted slice, which is a lot smaller than a map.
+type trie struct {
+	root node
+	len  int // number of names, not counting the ones below another name
+}
+
+type node struct {
+	label    string
+	children []*node // sorted by label
+	end      bool    // a name ends here
+}
+
+// sep separates the reversed labels of a key, it sorts before any character that can be in a label,
+// so that keys sort in the same order as their labels do.
+const sep = "\x00"
+
+// newTrie returns a trie holding names, which must be lowercase and fully qualified.
+func newTrie(names []string) *trie {
+	keys := make([]string, len(names))
+	for i, name := range names {
+		keys[i] = key(name)
+	}
+	sort.Strings(keys)
+
+	t := &trie{}
+Keys:
+	for _, k := range keys {
+		n := &t.root
+		for _, label := range strings.Split(k, sep) {
+			if n.end {
+				// A parent is already in the trie.
+				continue Keys
+			}
+			// Because the keys are sorted, the child for label, if it exists, is the last one.
+			if l := len(n.children); l > 0 && n.children[l-1].label == label {
+				n = n.children[l-1]
+				continue
+			}
+			child := &node{label: label}
+			n.children = append(n.children, child)
+			n = child
+		}
+		if !n.end {
+			n.end = true
+			n.children = nil
+			t.len++
+		}
+	}
+	return t
+}
+
+// key returns the labels of name in reverse order, separated by sep.
+func key(name string) string {
+	labels := strings.Split(strings.TrimSuffix(name, "."), ".")
+	for i, j := 0, len(labels)-1; i < j; i, j = i+1, j-1 {
+		labels[i], labels[j] = labels[j], labels[i]
+	}
+	return strings.Join(labels, sep)
+}
+
+// match returns true if name, which must be lowercase and fully qualified, or one of its parents is
+// in the trie.
+func (t *trie) match(name string) bool {
+	n := &t.root
+	end := len(name) - 1 // skip the final dot
+	for end > 0 {
+		start := strings.LastIndexByte(name[:end], '.') + 1
+		label := name[start:end]
+
+		i := sort.Search(len(n.children), func(i int) bool { return n.children[i].label >= label })
+		if i == len(n.children) || n.children[i].label != label {
+			return false
+		}
+		n = n.children[i]
+		if n.end {
+			return true
+		}
+		end = start - 1
+	}
+	return false
+}
+
+// Len returns the number of names in t.
+func (t *trie) Len() int { return t.len }
@@ -0,0 +1,50 @@
+package blocklist
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestTrie(t *testing.T) {
+	tr := newTrie([]string{"example.org.", "www.example.org.", "ads.example.net.", "example-x.net.", "tracker."})
+	if tr.Len() != 4 {
+		t.Errorf("Expected 4 names, got %d", tr.Len())
+	}
+
+	tests := []struct {
+		name  string
+		match bool
+	}{
+		{"example.org.", true},
+		{"www.example.org.", true},
+		{"a.b.example.org.", true},
+		{"org.", false},
+		{"myexample.org.", false},
+		{"ads.example.net.", true},
+		{"x.ads.example.net.", true},
+		{"example.net.", false},
+		{"example-x.net.", true},
+		{"example.tracker.", true},
+		{"tracker.example.org.", true},
+		{"tracker.example.com.", false},
+		{".", false},
+	}
+	for _, tc := range tests {
+		if got := tr.match(tc.name); got != tc.match {
+			t.Errorf("Expected match(%s) to be %t, got %t", tc.name, tc.match, got)
+		}
+	}
+}
+
+func BenchmarkTrieMatch(b *testing.B) {
+	names := make([]string, 1000000)
+	for i := range names {
+		names[i] = fmt.Sprintf("host%d.domain%d.example.", i, i%1000)
+	}
+	tr := newTrie(names)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		tr.match("www.host500.domain500.example.")
+	}
+}
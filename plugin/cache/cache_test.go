@@ -13,6 +13,8 @@ import (
 	"github.com/coredns/coredns/request"
 
 	"github.com/miekg/dns"
+	ot "github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/mocktracer"
 )
 
 type cacheTestCase struct {
@@ -254,6 +256,33 @@ func TestCacheZeroTTL(t *testing.T) {
 	}
 }
 
+func TestCacheSpan(t *testing.T) {
+	c := New()
+	c.Next = ttlBackend(60)
+
+	tracer := mocktracer.New()
+	req := new(dns.Msg)
+	req.SetQuestion("example.org.", dns.TypeA)
+	for _, expected := range []string{"miss", "hit"} {
+		tracer.Reset()
+		span := tracer.StartSpan("cache")
+		c.ServeDNS(ot.ContextWithSpan(context.TODO(), span), &test.ResponseWriter{}, req)
+		span.Finish()
+
+		// On a miss the next plugin has a span too.
+		spans := tracer.FinishedSpans()
+		if spans[0].OperationName != "lookup" {
+			t.Fatalf("Expected the first span to be the lookup span, got %v", spans)
+		}
+		if spans[0].ParentID != span.(*mocktracer.MockSpan).SpanContext.SpanID {
+			t.Errorf("Expected the lookup span to be a child of the cache span")
+		}
+		if x := spans[0].Tag("coredns.io/cache"); x != expected {
+			t.Errorf("Expected lookup span to be tagged %q, got %v", expected, x)
+		}
+	}
+}
+
 func TestServeFromStaleCache(t *testing.T) {
 	c := New()
 	c.Next = ttlBackend(60)
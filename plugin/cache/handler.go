@@ -10,6 +10,7 @@ import (
 	"github.com/coredns/coredns/request"
 
 	"github.com/miekg/dns"
+	ot "github.com/opentracing/opentracing-go"
 )
 
 // ServeDNS implements the plugin.Handler interface.
@@ -34,23 +35,29 @@ func (c *Cache) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg)
 	// value to be smaller still to prevent UDP fragmentation?
 
 	ttl := 0
+	span := startSpan(ctx, "lookup")
 	i := c.getIgnoreTTL(now, state, server)
 	if i != nil {
 		ttl = i.ttl(now)
 	}
 	if i == nil {
+		finishSpan(span, "miss")
 		crr := &ResponseWriter{ResponseWriter: w, Cache: c, state: state, server: server, do: do}
 		return c.doRefresh(ctx, state, crr)
 	}
 	if ttl < 0 {
+		finishSpan(span, "stale")
 		servedStale.WithLabelValues(server, c.zonesMetricLabel).Inc()
 		// Adjust the time to get a 0 TTL in the reply built from a stale item.
 		now = now.Add(time.Duration(ttl) * time.Second)
 		cw := newPrefetchResponseWriter(server, state, c)
 		go c.doPrefetch(ctx, state, cw, i, now)
-	} else if c.shouldPrefetch(i, now) {
-		cw := newPrefetchResponseWriter(server, state, c)
-		go c.doPrefetch(ctx, state, cw, i, now)
+	} else {
+		finishSpan(span, "hit")
+		if c.shouldPrefetch(i, now) {
+			cw := newPrefetchResponseWriter(server, state, c)
+			go c.doPrefetch(ctx, state, cw, i, now)
+		}
 	}
 	resp := i.toMsg(r, now, do)
 	w.WriteMsg(resp)
@@ -86,6 +93,23 @@ func (c *Cache) shouldPrefetch(i *item, now time.Time) bool {
 	return i.Freq.Hits() >= c.prefetch && i.ttl(now) <= threshold
 }
 
+// startSpan starts a child span of the span of this plugin, if the query is being traced.
+func startSpan(ctx context.Context, name string) ot.Span {
+	if span := ot.SpanFromContext(ctx); span != nil {
+		return span.Tracer().StartSpan(name, ot.ChildOf(span.Context()))
+	}
+	return nil
+}
+
+// finishSpan records the cache result (hit, miss or stale) on span and finishes it.
+func finishSpan(span ot.Span, result string) {
+	if span == nil {
+		return
+	}
+	span.SetTag("coredns.io/cache", result)
+	span.Finish()
+}
+
 // Name implements the Handler interface.
 func (c *Cache) Name() string { return "cache" }
 
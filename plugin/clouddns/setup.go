@@ -78,6 +78,16 @@ func setup(c *caddy.Controller) error {
 			}
 		}
 
+		if dnsserver.DryRun(c) {
+			// Don't contact Google Cloud when only validating.
+			h := &CloudDNS{Fall: fall}
+			dnsserver.GetConfig(c).AddPlugin(func(next plugin.Handler) plugin.Handler {
+				h.Next = next
+				return h
+			})
+			continue
+		}
+
 		ctx, cancel := context.WithCancel(context.Background())
 		client, err := f(ctx, opt)
 		if err != nil {
@@ -0,0 +1,142 @@
+package dns64
+
+import (
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+const (
+	// ipv4OnlyArpa is the name that is used to discover the NAT64 prefix, see RFC 7050.
+	ipv4OnlyArpa = "ipv4only.arpa."
+
+	// minRediscovery is the minimum time between two discoveries, in case the TTL of the answer is low.
+	minRediscovery = time.Minute
+	// retryDiscovery is the time after which a failed discovery is retried.
+	retryDiscovery = 30 * time.Second
+)
+
+// wellKnownIPv4 are the addresses of ipv4only.arpa.
+var wellKnownIPv4 = []net.IP{net.IPv4(192, 0, 0, 170), net.IPv4(192, 0, 0, 171)}
+
+// prefixRule is the prefix for clients in some networks. It is either static or discovered.
+type prefixRule struct {
+	networks []*net.IPNet // all clients if empty
+	servers  []string     // servers to discover the prefix from, if not static
+
+	mu     sync.RWMutex
+	prefix *net.IPNet // nil until discovered
+}
+
+// matches returns true if the rule applies to clients with address ip.
+func (r *prefixRule) matches(ip net.IP) bool {
+	if len(r.networks) == 0 {
+		return true
+	}
+	for _, n := range r.networks {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *prefixRule) get() *net.IPNet {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.prefix
+}
+
+func (r *prefixRule) set(prefix *net.IPNet) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.prefix = prefix
+}
+
+// run discovers the prefix, and discovers it again when the TTL of the answer expires, until stop is
+// closed. When a discovery fails the previous prefix is kept.
+func (r *prefixRule) run(stop <-chan struct{}) {
+	for {
+		wait := retryDiscovery
+		prefix, ttl, err := discover(r.servers)
+		if err != nil {
+			log.Warningf("Failed to discover NAT64 prefix, retrying in %s: %s", wait, err)
+		} else {
+			if old := r.get(); old == nil || old.String() != prefix.String() {
+				log.Infof("Discovered NAT64 prefix %s", prefix)
+				r.set(prefix)
+			}
+			wait = time.Duration(ttl) * time.Second
+			if wait < minRediscovery {
+				wait = minRediscovery
+			}
+		}
+
+		select {
+		case <-stop:
+			return
+		case <-time.After(wait):
+		}
+	}
+}
+
+// discover resolves the AAAA records of ipv4only.arpa with servers and returns the NAT64 prefix that
+// is used in them, and the TTL of the record.
+func discover(servers []string) (*net.IPNet, uint32, error) {
+	m := new(dns.Msg)
+	m.SetQuestion(ipv4OnlyArpa, dns.TypeAAAA)
+
+	c := new(dns.Client)
+	err := fmt.Errorf("no servers")
+	for _, s := range servers {
+		var r *dns.Msg
+		r, _, err = c.Exchange(m, s)
+		if err != nil {
+			continue
+		}
+		for _, rr := range r.Answer {
+			if aaaa, ok := rr.(*dns.AAAA); ok {
+				if prefix := extractPrefix(aaaa.AAAA); prefix != nil {
+					return prefix, aaaa.Hdr.Ttl, nil
+				}
+			}
+		}
+		err = fmt.Errorf("no NAT64 prefix in the answer from %s", s)
+	}
+	return nil, 0, err
+}
+
+// extractPrefix returns the prefix with which one of the well-known IPv4 addresses of ipv4only.arpa
+// is embedded in ip, or nil if there is none (RFC 7050 section 3).
+func extractPrefix(ip net.IP) *net.IPNet {
+	if ip.To4() != nil {
+		return nil
+	}
+	for _, n := range []int{96, 64, 56, 48, 40, 32} {
+		v4 := from6(ip, n)
+		for _, wk := range wellKnownIPv4 {
+			if v4.Equal(wk) {
+				mask := net.CIDRMask(n, 128)
+				return &net.IPNet{IP: ip.Mask(mask), Mask: mask}
+			}
+		}
+	}
+	return nil
+}
+
+// from6 returns the IPv4 address embedded in ip with a prefix of n bits, it is the reverse of to6.
+func from6(ip net.IP, n int) net.IP {
+	v4 := make(net.IP, net.IPv4len)
+	for i, j := n/8, 0; j < net.IPv4len; i++ {
+		if i == 8 {
+			// Bits 64 to 71 are reserved.
+			continue
+		}
+		v4[j] = ip[i]
+		j++
+	}
+	return v4
+}
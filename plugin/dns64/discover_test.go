@@ -0,0 +1,115 @@
+package dns64
+
+import (
+	"net"
+	"testing"
+
+	"github.com/coredns/coredns/plugin/pkg/dnstest"
+	"github.com/coredns/coredns/plugin/test"
+
+	"github.com/miekg/dns"
+)
+
+func TestExtractPrefix(t *testing.T) {
+	tests := []struct {
+		addr   string
+		prefix string
+	}{
+		{"64:ff9b::192.0.0.170", "64:ff9b::/96"},
+		{"64:ff9b::192.0.0.171", "64:ff9b::/96"},
+		{"2001:db8:1:2:c0:0:aa00::", "2001:db8:1:2::/64"},
+		{"2001:db8:c000:aa::", "2001:db8::/32"},
+		{"64:ff9b::192.0.2.1", ""},
+		{"192.0.0.170", ""},
+	}
+	for _, tc := range tests {
+		p := extractPrefix(net.ParseIP(tc.addr))
+		got := ""
+		if p != nil {
+			got = p.String()
+		}
+		if got != tc.prefix {
+			t.Errorf("Expected prefix %q for %s, got %q", tc.prefix, tc.addr, got)
+		}
+	}
+
+	// Extracting is the reverse of synthesizing.
+	for _, n := range []string{"32", "40", "48", "56", "64", "96"} {
+		_, prefix, _ := net.ParseCIDR("2001:db8:aaaa:bbbb:cccc:dddd::/" + n)
+		v6, _ := to6(prefix, wellKnownIPv4[0])
+		if p := extractPrefix(v6); p == nil || p.String() != prefix.String() {
+			t.Errorf("Expected prefix %s from %s, got %v", prefix, v6, p)
+		}
+	}
+}
+
+func TestDiscover(t *testing.T) {
+	s := dnstest.NewServer(func(w dns.ResponseWriter, r *dns.Msg) {
+		m := new(dns.Msg)
+		m.SetReply(r)
+		if r.Question[0].Name == ipv4OnlyArpa && r.Question[0].Qtype == dns.TypeAAAA {
+			m.Answer = []dns.RR{
+				test.AAAA("ipv4only.arpa. 300 IN AAAA 2001:db8:64::192.0.0.170"),
+				test.AAAA("ipv4only.arpa. 300 IN AAAA 2001:db8:64::192.0.0.171"),
+			}
+		}
+		w.WriteMsg(m)
+	})
+	defer s.Close()
+
+	prefix, ttl, err := discover([]string{s.Addr})
+	if err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+	if prefix.String() != "2001:db8:64::/96" {
+		t.Errorf("Expected prefix 2001:db8:64::/96, got %s", prefix)
+	}
+	if ttl != 300 {
+		t.Errorf("Expected TTL 300, got %d", ttl)
+	}
+
+	empty := dnstest.NewServer(func(w dns.ResponseWriter, r *dns.Msg) {
+		m := new(dns.Msg)
+		m.SetRcode(r, dns.RcodeNameError)
+		w.WriteMsg(m)
+	})
+	defer empty.Close()
+	if _, _, err := discover([]string{empty.Addr}); err == nil {
+		t.Errorf("Expected an error without a NAT64 prefix")
+	}
+}
+
+func TestPrefixFor(t *testing.T) {
+	_, site1, _ := net.ParseCIDR("2001:db8:1::/48")
+	_, site2, _ := net.ParseCIDR("2001:db8:2::/48")
+	_, pfx1, _ := net.ParseCIDR("64:1::/96")
+	_, pfx2, _ := net.ParseCIDR("64:2::/96")
+	_, def, _ := net.ParseCIDR("64:ff9b::/96")
+
+	d := DNS64{
+		Prefix: def,
+		rules: []*prefixRule{
+			{networks: []*net.IPNet{site1}, prefix: pfx1},
+			{networks: []*net.IPNet{site2}}, // not discovered yet
+			{networks: []*net.IPNet{site2}, prefix: pfx2},
+		},
+	}
+	tests := []struct {
+		ip     string
+		prefix *net.IPNet
+	}{
+		{"2001:db8:1::1", pfx1},
+		{"2001:db8:2::1", pfx2},
+		{"2001:db8:3::1", def},
+	}
+	for _, tc := range tests {
+		if got := d.prefixFor(net.ParseIP(tc.ip)); got.String() != tc.prefix.String() {
+			t.Errorf("Expected prefix %s for %s, got %s", tc.prefix, tc.ip, got)
+		}
+	}
+
+	d.Prefix = nil
+	if got := d.prefixFor(net.ParseIP("2001:db8:3::1")); got != nil {
+		t.Errorf("Expected no prefix, got %s", got)
+	}
+}
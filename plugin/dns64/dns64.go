@@ -7,6 +7,7 @@ import (
 	"context"
 	"errors"
 	"net"
+	"sync"
 	"time"
 
 	"github.com/coredns/coredns/plugin"
@@ -26,16 +27,27 @@ type UpstreamInt interface {
 // DNS64 performs DNS64.
 type DNS64 struct {
 	Next         plugin.Handler
-	Prefix       *net.IPNet
-	TranslateAll bool // Not comply with 5.1.1
+	Prefix       *net.IPNet // prefix for the clients no rule applies to, may be nil
+	TranslateAll bool       // Not comply with 5.1.1
 	AllowIPv4    bool
 	Upstream     UpstreamInt
+
+	rules   []*prefixRule // checked in order, before Prefix
+	exclude []*net.IPNet  // A records (IPv4) that are not synthesized and AAAA records (IPv6) that are ignored
+
+	stop chan struct{}
+	wg   sync.WaitGroup
 }
 
 // ServeDNS implements the plugin.Handler interface.
 func (d *DNS64) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (int, error) {
+	state := request.Request{W: w, Req: r}
 	// Don't proxy if we don't need to.
-	if !d.requestShouldIntercept(&request.Request{W: w, Req: r}) {
+	if !d.requestShouldIntercept(&state) {
+		return d.Next.ServeDNS(ctx, w, r)
+	}
+	prefix := d.prefixFor(net.ParseIP(state.IP()))
+	if prefix == nil {
 		return d.Next.ServeDNS(ctx, w, r)
 	}
 
@@ -53,7 +65,7 @@ func (d *DNS64) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg)
 	}
 
 	// otherwise do the actual DNS64 request and response synthesis
-	msg, err := d.DoDNS64(ctx, w, r, nw.Msg)
+	msg, err := d.doDNS64(ctx, w, r, nw.Msg, prefix)
 	if err != nil {
 		// err means we weren't able to even issue the A request
 		// to CoreDNS upstream
@@ -68,6 +80,69 @@ func (d *DNS64) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg)
 // Name implements the Handler interface.
 func (d *DNS64) Name() string { return "dns64" }
 
+// prefixFor returns the prefix to use for a client with address ip: the prefix of the first rule that
+// applies to the client and has a prefix, or d.Prefix.
+func (d *DNS64) prefixFor(ip net.IP) *net.IPNet {
+	for _, r := range d.rules {
+		if !r.matches(ip) {
+			continue
+		}
+		if p := r.get(); p != nil {
+			return p
+		}
+	}
+	return d.Prefix
+}
+
+// excluded returns true if ip is in the exclusion list. The addresses of A records (v4 is true) are
+// only matched against IPv4 networks and those of AAAA records against IPv6 networks, net.IPNet's
+// Contains can't be used as it would match any IPv4 address with ::ffff:0:0/96.
+func (d *DNS64) excluded(ip net.IP, v4 bool) bool {
+	if v4 {
+		ip = ip.To4()
+	} else {
+		ip = ip.To16()
+	}
+	if ip == nil {
+		return false
+	}
+	for _, n := range d.exclude {
+		if len(n.IP) != len(ip) || len(n.Mask) != len(ip) {
+			continue
+		}
+		if ip.Mask(n.Mask).Equal(n.IP) {
+			return true
+		}
+	}
+	return false
+}
+
+// OnStartup starts the discovery of the prefixes.
+func (d *DNS64) OnStartup() error {
+	d.stop = make(chan struct{})
+	for _, r := range d.rules {
+		if len(r.servers) == 0 {
+			continue
+		}
+		d.wg.Add(1)
+		go func(r *prefixRule) {
+			defer d.wg.Done()
+			r.run(d.stop)
+		}(r)
+	}
+	return nil
+}
+
+// OnShutdown stops the discovery of the prefixes.
+func (d *DNS64) OnShutdown() error {
+	if d.stop != nil {
+		close(d.stop)
+		d.wg.Wait()
+		d.stop = nil
+	}
+	return nil
+}
+
 // requestShouldIntercept returns true if the request represents one that is eligible
 // for DNS64 rewriting:
 // 1. The request came in over IPv6 or the 'allow_ipv4' option is set
@@ -105,9 +180,9 @@ func (d *DNS64) responseShouldDNS64(origResponse *dns.Msg) bool {
 		return true
 	}
 
-	// if response includes AAAA record, no need to rewrite
+	// if response includes AAAA record, no need to rewrite, unless it is excluded (5.1.4)
 	for _, rr := range origResponse.Answer {
-		if rr.Header().Rrtype == dns.TypeAAAA {
+		if rr.Header().Rrtype == dns.TypeAAAA && !d.excluded(rr.(*dns.AAAA).AAAA, false) {
 			return false
 		}
 	}
@@ -117,17 +192,25 @@ func (d *DNS64) responseShouldDNS64(origResponse *dns.Msg) bool {
 // DoDNS64 takes an (empty) response to an AAAA question, issues the A request,
 // and synthesizes the answer. Returns the response message, or error on internal failure.
 func (d *DNS64) DoDNS64(ctx context.Context, w dns.ResponseWriter, r *dns.Msg, origResponse *dns.Msg) (*dns.Msg, error) {
+	return d.doDNS64(ctx, w, r, origResponse, d.Prefix)
+}
+
+func (d *DNS64) doDNS64(ctx context.Context, w dns.ResponseWriter, r *dns.Msg, origResponse *dns.Msg, prefix *net.IPNet) (*dns.Msg, error) {
 	req := request.Request{W: w, Req: r} // req is unused
 	resp, err := d.Upstream.Lookup(ctx, req, req.Name(), dns.TypeA)
 	if err != nil {
 		return nil, err
 	}
-	out := d.Synthesize(r, origResponse, resp)
+	out := d.synthesize(r, origResponse, resp, prefix)
 	return out, nil
 }
 
 // Synthesize merges the AAAA response and the records from the A response
 func (d *DNS64) Synthesize(origReq, origResponse, resp *dns.Msg) *dns.Msg {
+	return d.synthesize(origReq, origResponse, resp, d.Prefix)
+}
+
+func (d *DNS64) synthesize(origReq, origResponse, resp *dns.Msg, prefix *net.IPNet) *dns.Msg {
 	ret := dns.Msg{}
 	ret.SetReply(origReq)
 
@@ -156,8 +239,12 @@ func (d *DNS64) Synthesize(origReq, origResponse, resp *dns.Msg) *dns.Msg {
 			ret.Answer = append(ret.Answer, rr)
 			continue
 		}
+		// IPv4 addresses in the exclusion list are never synthesized
+		if d.excluded(rr.(*dns.A).A, true) {
+			continue
+		}
 
-		aaaa, _ := to6(d.Prefix, rr.(*dns.A).A)
+		aaaa, _ := to6(prefix, rr.(*dns.A).A)
 
 		// ttl is min of SOA TTL and A TTL
 		ttl := SOATtl
@@ -555,3 +555,35 @@ func (fu *fakeUpstream) Lookup(_ context.Context, _ request.Request, name string
 
 	return fu.resp, nil
 }
+
+func TestExclude(t *testing.T) {
+	_, mapped, _ := net.ParseCIDR("::ffff:0:0/96")
+	_, private, _ := net.ParseCIDR("10.0.0.0/8")
+	_, pref, _ := net.ParseCIDR("64:ff9b::/96")
+	d := DNS64{Prefix: pref, exclude: []*net.IPNet{mapped, private}}
+
+	// An AAAA record in an excluded network is ignored.
+	resp := &dns.Msg{Answer: []dns.RR{test.AAAA("example.com. IN AAAA ::ffff:192.0.2.1")}}
+	if !d.responseShouldDNS64(resp) {
+		t.Errorf("Expected DNS64 for an excluded AAAA record")
+	}
+	resp.Answer = append(resp.Answer, test.AAAA("example.com. IN AAAA 2001:db8::1"))
+	if d.responseShouldDNS64(resp) {
+		t.Errorf("Expected no DNS64 with a usable AAAA record")
+	}
+
+	// An A record in an excluded network is not synthesized.
+	req := new(dns.Msg)
+	req.SetQuestion("example.com.", dns.TypeAAAA)
+	aResp := &dns.Msg{Answer: []dns.RR{
+		test.A("example.com. 60 IN A 10.0.0.1"),
+		test.A("example.com. 60 IN A 192.0.2.1"),
+	}}
+	ret := d.Synthesize(req, new(dns.Msg), aResp)
+	if len(ret.Answer) != 1 {
+		t.Fatalf("Expected 1 answer, got %d", len(ret.Answer))
+	}
+	if got := ret.Answer[0].(*dns.AAAA).AAAA.String(); got != "64:ff9b::c000:201" {
+		t.Errorf("Expected 64:ff9b::c000:201, got %s", got)
+	}
+}
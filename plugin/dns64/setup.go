@@ -6,8 +6,12 @@ import (
 	"github.com/coredns/caddy"
 	"github.com/coredns/coredns/core/dnsserver"
 	"github.com/coredns/coredns/plugin"
+	"github.com/coredns/coredns/plugin/pkg/cidr"
 	clog "github.com/coredns/coredns/plugin/pkg/log"
+	"github.com/coredns/coredns/plugin/pkg/parse"
 	"github.com/coredns/coredns/plugin/pkg/upstream"
+
+	"github.com/miekg/dns"
 )
 
 const pluginName = "dns64"
@@ -22,6 +26,9 @@ func setup(c *caddy.Controller) error {
 		return plugin.Error(pluginName, err)
 	}
 
+	c.OnStartup(dns64.OnStartup)
+	c.OnShutdown(dns64.OnShutdown)
+
 	dnsserver.GetConfig(c).AddPlugin(func(next plugin.Handler) plugin.Handler {
 		dns64.Next = next
 		return dns64
@@ -32,10 +39,14 @@ func setup(c *caddy.Controller) error {
 
 func dns64Parse(c *caddy.Controller) (*DNS64, error) {
 	_, defaultPref, _ := net.ParseCIDR("64:ff9b::/96")
+	_, mapped, _ := net.ParseCIDR("::ffff:0:0/96")
 	dns64 := &DNS64{
 		Upstream: upstream.New(),
 		Prefix:   defaultPref,
+		exclude:  []*net.IPNet{mapped},
 	}
+	// Set when the prefix for all clients is configured (true) or discovered (false).
+	var defaultSet, defaultDiscovered bool
 
 	for c.Next() {
 		args := c.RemainingArgs()
@@ -46,6 +57,7 @@ func dns64Parse(c *caddy.Controller) (*DNS64, error) {
 				return nil, err
 			}
 			dns64.Prefix = pref
+			defaultSet = true
 			continue
 		}
 		if len(args) > 0 {
@@ -55,15 +67,60 @@ func dns64Parse(c *caddy.Controller) (*DNS64, error) {
 		for c.NextBlock() {
 			switch c.Val() {
 			case "prefix":
-				if !c.NextArg() {
+				// prefix PREFIX [for NETWORK...]
+				args, networks, err := parseFor(c)
+				if err != nil {
+					return nil, err
+				}
+				if len(args) != 1 {
 					return nil, c.ArgErr()
 				}
-				pref, err := parsePrefix(c, c.Val())
+				pref, err := parsePrefix(c, args[0])
 
 				if err != nil {
 					return nil, err
 				}
-				dns64.Prefix = pref
+				if networks == nil {
+					dns64.Prefix = pref
+					defaultSet = true
+					continue
+				}
+				dns64.rules = append(dns64.rules, &prefixRule{networks: networks, prefix: pref})
+			case "discover":
+				// discover [SERVER...] [for NETWORK...]
+				args, networks, err := parseFor(c)
+				if err != nil {
+					return nil, err
+				}
+				var servers []string
+				if len(args) == 0 {
+					cfg, err := dns.ClientConfigFromFile("/etc/resolv.conf")
+					if err != nil {
+						return nil, c.Errf("no servers to discover the prefix from: %s", err)
+					}
+					for _, s := range cfg.Servers {
+						servers = append(servers, net.JoinHostPort(s, cfg.Port))
+					}
+				} else {
+					servers, err = parse.HostPortOrFile(args...)
+					if err != nil {
+						return nil, err
+					}
+				}
+				if networks == nil {
+					defaultDiscovered = true
+				}
+				dns64.rules = append(dns64.rules, &prefixRule{networks: networks, servers: servers})
+			case "exclude":
+				args := c.RemainingArgs()
+				if len(args) == 0 {
+					return nil, c.ArgErr()
+				}
+				networks, err := cidr.ParseNetworks(args)
+				if err != nil {
+					return nil, c.Err(err.Error())
+				}
+				dns64.exclude = append(dns64.exclude, networks...)
 			case "translate_all":
 				dns64.TranslateAll = true
 			case "allow_ipv4":
@@ -73,9 +130,33 @@ func dns64Parse(c *caddy.Controller) (*DNS64, error) {
 			}
 		}
 	}
+	if defaultDiscovered && !defaultSet {
+		// Don't use the well known prefix while the prefix hasn't been discovered.
+		dns64.Prefix = nil
+	}
 	return dns64, nil
 }
 
+// parseFor parses the remaining arguments of the current line as ARGS... [for NETWORK...]. The
+// returned networks are nil if there is no "for".
+func parseFor(c *caddy.Controller) ([]string, []*net.IPNet, error) {
+	args := c.RemainingArgs()
+	i := 0
+	for ; i < len(args) && args[i] != "for"; i++ {
+	}
+	if i == len(args) {
+		return args, nil, nil
+	}
+	if i == len(args)-1 {
+		return nil, nil, c.Errf("'for' needs at least one network")
+	}
+	networks, err := cidr.ParseNetworks(args[i+1:])
+	if err != nil {
+		return nil, nil, c.Err(err.Error())
+	}
+	return args[:i], networks, nil
+}
+
 func parsePrefix(c *caddy.Controller, addr string) (*net.IPNet, error) {
 	_, pref, err := net.ParseCIDR(addr)
 	if err != nil {
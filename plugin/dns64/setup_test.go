@@ -151,3 +151,72 @@ func TestSetupDns64(t *testing.T) {
 		}
 	}
 }
+
+func TestSetupDns64Rules(t *testing.T) {
+	tests := []struct {
+		input       string
+		shouldErr   bool
+		wantPrefix  string
+		wantRules   int
+		wantExclude int
+	}{
+		{`dns64 {
+			prefix 64:1::/96 for 2001:db8:1::/48 10.0.0.0/8
+		}`, false, "64:ff9b::/96", 1, 1},
+		{`dns64 {
+			prefix 64:1::/96 for 2001:db8:1::1
+		}`, false, "64:ff9b::/96", 1, 1},
+		{`dns64 {
+			discover 10.0.0.1
+		}`, false, "<nil>", 1, 1},
+		{`dns64 {
+			prefix 64:2::/96
+			discover 10.0.0.1 10.0.0.2:5353
+		}`, false, "64:2::/96", 1, 1},
+		{`dns64 {
+			discover 10.0.0.1 for 2001:db8:1::/48
+		}`, false, "64:ff9b::/96", 1, 1},
+		{`dns64 {
+			exclude 2001:db8::/32 fe80::/10
+		}`, false, "64:ff9b::/96", 0, 3},
+		// fails
+		{`dns64 {
+			prefix 64:1::/96 for
+		}`, true, "", 0, 0},
+		{`dns64 {
+			prefix 64:1::/96 for 2001:db8::/300
+		}`, true, "", 0, 0},
+		{`dns64 {
+			prefix for 2001:db8::/32
+		}`, true, "", 0, 0},
+		{`dns64 {
+			discover 10.0.0.1 for
+		}`, true, "", 0, 0},
+		{`dns64 {
+			exclude
+		}`, true, "", 0, 0},
+		{`dns64 {
+			exclude foo
+		}`, true, "", 0, 0},
+	}
+
+	for i, test := range tests {
+		c := caddy.NewTestController("dns", test.input)
+		d, err := dns64Parse(c)
+		if (err != nil) != test.shouldErr {
+			t.Errorf("Test %d expected %v error, got %v for %s", i, test.shouldErr, err, test.input)
+		}
+		if err != nil {
+			continue
+		}
+		if d.Prefix.String() != test.wantPrefix {
+			t.Errorf("Test %d expected prefix %s, got %s", i, test.wantPrefix, d.Prefix.String())
+		}
+		if len(d.rules) != test.wantRules {
+			t.Errorf("Test %d expected %d rules, got %d", i, test.wantRules, len(d.rules))
+		}
+		if len(d.exclude) != test.wantExclude {
+			t.Errorf("Test %d expected %d excluded networks, got %d", i, test.wantExclude, len(d.exclude))
+		}
+	}
+}
@@ -2,10 +2,14 @@ package dnstap
 
 import (
 	"context"
+	"net"
+	"strings"
 	"time"
 
 	"github.com/coredns/coredns/plugin"
 	"github.com/coredns/coredns/plugin/dnstap/msg"
+	"github.com/coredns/coredns/plugin/pkg/replacer"
+	"github.com/coredns/coredns/request"
 
 	tap "github.com/dnstap/golang-dnstap"
 	"github.com/miekg/dns"
@@ -16,17 +20,96 @@ type Dnstap struct {
 	Next plugin.Handler
 	io   tapper
 
+	repl replacer.Replacer
+
 	// IncludeRawMessage will include the raw DNS message into the dnstap messages if true.
 	IncludeRawMessage bool
+	// Identity and Version are set in every dnstap message.
+	Identity []byte
+	Version  []byte
+	// ExtraFormat is rendered with the replacer and set as the Extra field of every dnstap message.
+	ExtraFormat string
+	// extraMsg is true if ExtraFormat has placeholders that are replaced from the DNS message.
+	extraMsg bool
+}
+
+// messagePlaceholders are the placeholders of the replacer that need the DNS message.
+var messagePlaceholders = []string{"{type}", "{name}", "{class}", "{size}", "{>id}", "{>opcode}", "{>do}", "{>bufsize}"}
+
+// needsMessage returns true if format has placeholders that are replaced from the DNS message.
+func needsMessage(format string) bool {
+	format = strings.ToLower(format)
+	for _, p := range messagePlaceholders {
+		if strings.Contains(format, p) {
+			return true
+		}
+	}
+	return false
 }
 
-// TapMessage sends the message m to the dnstap interface.
+// TapMessage sends the message m to the dnstap interface. Placeholders in ExtraFormat are replaced
+// using the addresses and the DNS message in m, metadata placeholders are left empty. The DNS
+// message is only unpacked if ExtraFormat has placeholders that need it.
 func (h Dnstap) TapMessage(m *tap.Message) {
+	if h.ExtraFormat == "" {
+		t := tap.Dnstap_MESSAGE
+		h.io.Dnstap(&tap.Dnstap{Type: &t, Identity: h.Identity, Version: h.Version, Message: m})
+		return
+	}
+	h.TapMessageWithMetadata(context.Background(), m, messageState(m, h.extraMsg))
+}
+
+// TapMessageWithMetadata sends the message m to the dnstap interface, with the Extra field set
+// from ExtraFormat. Placeholders in ExtraFormat are replaced using state and the metadata in ctx.
+func (h Dnstap) TapMessageWithMetadata(ctx context.Context, m *tap.Message, state request.Request) {
 	t := tap.Dnstap_MESSAGE
-	h.io.Dnstap(tap.Dnstap{Type: &t, Message: m})
+	var extra []byte
+	if h.ExtraFormat != "" {
+		extra = []byte(h.repl.Replace(ctx, state, nil, h.ExtraFormat))
+	}
+	h.io.Dnstap(&tap.Dnstap{Type: &t, Identity: h.Identity, Version: h.Version, Extra: extra, Message: m})
 }
 
-func (h Dnstap) tapQuery(w dns.ResponseWriter, query *dns.Msg, queryTime time.Time) {
+// messageState returns a request made from the dnstap message m, for messages that are sent without
+// one. If unpack is true, the DNS message is the query or the response in m, if any, otherwise it is
+// empty.
+func messageState(m *tap.Message, unpack bool) request.Request {
+	req := new(dns.Msg)
+	switch {
+	case !unpack:
+	case m.QueryMessage != nil:
+		req.Unpack(m.QueryMessage)
+	case m.ResponseMessage != nil:
+		req.Unpack(m.ResponseMessage)
+	}
+	return request.Request{W: messageWriter{m}, Req: req}
+}
+
+// messageWriter is a dns.ResponseWriter that only returns the addresses of a dnstap message.
+type messageWriter struct{ m *tap.Message }
+
+func (w messageWriter) RemoteAddr() net.Addr { return w.addr(w.m.QueryAddress, w.m.QueryPort) }
+func (w messageWriter) LocalAddr() net.Addr  { return w.addr(w.m.ResponseAddress, w.m.ResponsePort) }
+
+func (w messageWriter) addr(ip []byte, port *uint32) net.Addr {
+	p := 0
+	if port != nil {
+		p = int(*port)
+	}
+	if w.m.SocketProtocol != nil && *w.m.SocketProtocol != tap.SocketProtocol_UDP {
+		return &net.TCPAddr{IP: ip, Port: p}
+	}
+	return &net.UDPAddr{IP: ip, Port: p}
+}
+
+func (w messageWriter) WriteMsg(*dns.Msg) error     { return nil }
+func (w messageWriter) Write(b []byte) (int, error) { return len(b), nil }
+func (w messageWriter) Close() error                { return nil }
+func (w messageWriter) TsigStatus() error           { return nil }
+func (w messageWriter) TsigTimersOnly(bool)         {}
+func (w messageWriter) Hijack()                     {}
+
+func (h Dnstap) tapQuery(ctx context.Context, w dns.ResponseWriter, query *dns.Msg, queryTime time.Time) {
 	q := new(tap.Message)
 	msg.SetQueryTime(q, queryTime)
 	msg.SetQueryAddress(q, w.RemoteAddr())
@@ -36,7 +119,7 @@ func (h Dnstap) tapQuery(w dns.ResponseWriter, query *dns.Msg, queryTime time.Ti
 		q.QueryMessage = buf
 	}
 	msg.SetType(q, tap.Message_CLIENT_QUERY)
-	h.TapMessage(q)
+	h.TapMessageWithMetadata(ctx, q, request.Request{W: w, Req: query})
 }
 
 // ServeDNS logs the client query and response to dnstap and passes the dnstap Context.
@@ -44,14 +127,17 @@ func (h Dnstap) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg)
 	rw := &ResponseWriter{
 		ResponseWriter: w,
 		Dnstap:         h,
+		ctx:            ctx,
 		query:          r,
 		queryTime:      time.Now(),
+		respType:       tap.Message_CLIENT_RESPONSE,
 	}
 
 	// The query tap message should be sent before sending the query to the
 	// forwarder. Otherwise, the tap messages will come out out of order.
-	h.tapQuery(w, r, rw.queryTime)
+	h.tapQuery(ctx, w, r, rw.queryTime)
 
+	ctx = context.WithValue(ctx, Key{}, h)
 	return plugin.NextOrFailure(h.Name(), h.Next, ctx, rw, r)
 }
 
@@ -6,7 +6,10 @@ import (
 	"testing"
 
 	"github.com/coredns/coredns/plugin/dnstap/msg"
+	"github.com/coredns/coredns/plugin/metadata"
+	"github.com/coredns/coredns/plugin/pkg/replacer"
 	test "github.com/coredns/coredns/plugin/test"
+	"github.com/coredns/coredns/request"
 
 	tap "github.com/dnstap/golang-dnstap"
 	"github.com/miekg/dns"
@@ -34,7 +37,7 @@ type writer struct {
 	queue []*tap.Message
 }
 
-func (w *writer) Dnstap(e tap.Dnstap) {
+func (w *writer) Dnstap(e *tap.Dnstap) {
 	if len(w.queue) == 0 {
 		w.t.Error("Message not expected")
 	}
@@ -42,6 +45,9 @@ func (w *writer) Dnstap(e tap.Dnstap) {
 	ex := w.queue[0]
 	got := e.Message
 
+	if ex.Type != nil && *ex.Type != *got.Type {
+		w.t.Errorf("Expected message type %s, got %s", ex.Type, got.Type)
+	}
 	if string(ex.QueryAddress) != string(got.QueryAddress) {
 		w.t.Errorf("Expected source address %s, got %s", ex.QueryAddress, got.QueryAddress)
 	}
@@ -83,3 +89,102 @@ func testMessage() *tap.Message {
 		QueryPort:      &port,
 	}
 }
+
+func TestDnstapAuth(t *testing.T) {
+	q := test.Case{Qname: "example.org", Qtype: dns.TypeA}.Msg()
+	r := test.Case{
+		Qname: "example.org.", Qtype: dns.TypeA,
+		Answer: []dns.RR{
+			test.A("example.org. 3600	IN	A 10.0.0.1"),
+		},
+	}.Msg()
+
+	w := writer{t: t}
+	for _, typ := range []tap.Message_Type{tap.Message_CLIENT_QUERY, tap.Message_AUTH_QUERY, tap.Message_CLIENT_RESPONSE, tap.Message_AUTH_RESPONSE} {
+		m := testMessage()
+		if typ == tap.Message_AUTH_QUERY || typ == tap.Message_AUTH_RESPONSE {
+			m.ResponseAddress = net.ParseIP("127.0.0.1")
+		}
+		msg.SetType(m, typ)
+		w.queue = append(w.queue, m)
+	}
+
+	h := Dnstap{
+		Next: test.HandlerFunc(func(ctx context.Context, w dns.ResponseWriter, q *dns.Msg) (int, error) {
+			tapPlugin, ok := FromContext(ctx)
+			if !ok {
+				t.Fatal("Expected dnstap plugin in context")
+			}
+			return 0, tapPlugin.TapAuth(ctx, w, q).WriteMsg(r)
+		}),
+		io: &w,
+	}
+	if _, err := h.ServeDNS(context.TODO(), &test.ResponseWriter{}, q); err != nil {
+		t.Fatal(err)
+	}
+	if len(w.queue) != 0 {
+		t.Errorf("Expected all messages to be sent, %d left", len(w.queue))
+	}
+}
+
+type capture struct{ msgs []*tap.Dnstap }
+
+func (c *capture) Dnstap(e *tap.Dnstap) { c.msgs = append(c.msgs, e) }
+
+func TestTapMessageWithMetadata(t *testing.T) {
+	c := &capture{}
+	h := Dnstap{
+		io:          c,
+		repl:        replacer.New(),
+		Identity:    []byte("coredns-1"),
+		Version:     []byte("CoreDNS-1.9.3"),
+		ExtraFormat: "ns={/test/namespace} qname={name} remote={remote}",
+		extraMsg:    true,
+	}
+
+	ctx := metadata.ContextWithMetadata(context.TODO())
+	metadata.SetValueFunc(ctx, "test/namespace", func() string { return "default" })
+
+	q := test.Case{Qname: "example.org.", Qtype: dns.TypeA}.Msg()
+	state := request.Request{W: &test.ResponseWriter{}, Req: q}
+
+	// Without a request, the placeholders are replaced from the message.
+	m := testMessage()
+	m.QueryMessage, _ = q.Pack()
+	h.TapMessage(m)
+	h.TapMessageWithMetadata(ctx, testMessage(), state)
+
+	if len(c.msgs) != 2 {
+		t.Fatalf("Expected 2 messages, got %d", len(c.msgs))
+	}
+	for _, m := range c.msgs {
+		if string(m.Identity) != "coredns-1" {
+			t.Errorf("Expected identity %q, got %q", "coredns-1", m.Identity)
+		}
+		if string(m.Version) != "CoreDNS-1.9.3" {
+			t.Errorf("Expected version %q, got %q", "CoreDNS-1.9.3", m.Version)
+		}
+	}
+	if x := string(c.msgs[0].Extra); x != "ns=- qname=example.org. remote=10.240.0.1" {
+		t.Errorf("Expected extra %q, got %q", "ns=- qname=example.org. remote=10.240.0.1", x)
+	}
+	if x := string(c.msgs[1].Extra); x != "ns=default qname=example.org. remote=10.240.0.1" {
+		t.Errorf("Expected extra %q, got %q", "ns=default qname=example.org. remote=10.240.0.1", x)
+	}
+}
+
+func TestNeedsMessage(t *testing.T) {
+	tests := map[string]bool{
+		"":                            false,
+		"remote={remote}:{port}":      false,
+		"ns={/test/namespace}":        false,
+		"qname={name} type={type}":    true,
+		"id={>ID}":                    true,
+		"size={size} remote={remote}": true,
+	}
+	for format, want := range tests {
+		if got := needsMessage(format); got != want {
+			t.Errorf("Expected needsMessage(%q) to be %t, got %t", format, want, got)
+		}
+	}
+}
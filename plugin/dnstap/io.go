@@ -1,6 +1,7 @@
 package dnstap
 
 import (
+	"crypto/tls"
 	"net"
 	"sync/atomic"
 	"time"
@@ -14,24 +15,32 @@ const (
 
 	tcpTimeout   = 4 * time.Second
 	flushTimeout = 1 * time.Second
+
+	// Reconnection attempts are spaced out exponentially between these durations.
+	minBackoff = 1 * time.Second
+	maxBackoff = 1 * time.Minute
 )
 
 // tapper interface is used in testing to mock the Dnstap method.
 type tapper interface {
-	Dnstap(tap.Dnstap)
+	Dnstap(*tap.Dnstap)
 }
 
 // dio implements the Tapper interface.
 type dio struct {
 	endpoint     string
 	proto        string
+	tlsConfig    *tls.Config // when not nil, TLS is used on top of TCP
 	conn         net.Conn
 	enc          *encoder
-	queue        chan tap.Dnstap
+	queue        chan *tap.Dnstap
 	dropped      uint32
 	quit         chan struct{}
 	flushTimeout time.Duration
 	tcpTimeout   time.Duration
+
+	backoff  time.Duration // wait this long after the next failed connection attempt
+	nextDial time.Time     // don't try to connect before this time
 }
 
 // newIO returns a new and initialized pointer to a dio.
@@ -39,10 +48,11 @@ func newIO(proto, endpoint string) *dio {
 	return &dio{
 		endpoint:     endpoint,
 		proto:        proto,
-		queue:        make(chan tap.Dnstap, queueSize),
+		queue:        make(chan *tap.Dnstap, queueSize),
 		quit:         make(chan struct{}),
 		flushTimeout: flushTimeout,
 		tcpTimeout:   tcpTimeout,
+		backoff:      minBackoff,
 	}
 }
 
@@ -56,23 +66,69 @@ func (d *dio) dial() error {
 		tcpConn.SetNoDelay(false)
 	}
 
+	if d.tlsConfig != nil {
+		tlsConn := tls.Client(conn, d.tlsConfig)
+		tlsConn.SetDeadline(time.Now().Add(d.tcpTimeout))
+		if err := tlsConn.Handshake(); err != nil {
+			conn.Close()
+			return err
+		}
+		tlsConn.SetDeadline(time.Time{})
+		conn = tlsConn
+	}
+
 	d.enc, err = newEncoder(conn, d.tcpTimeout)
-	return err
+	if err != nil {
+		conn.Close()
+		d.enc = nil
+		return err
+	}
+	d.conn = conn
+	return nil
+}
+
+// reconnect drops the current connection, if any, and dials the endpoint again. When this fails
+// the next attempt is delayed, doubling the delay for each failure up to maxBackoff.
+func (d *dio) reconnect() {
+	if d.conn != nil {
+		d.conn.Close()
+		d.conn, d.enc = nil, nil
+	}
+	now := time.Now()
+	if now.Before(d.nextDial) {
+		return
+	}
+	if err := d.dial(); err != nil {
+		log.Warningf("Failed to connect to dnstap endpoint %s, retrying in %s: %s", d.endpoint, d.backoff, err)
+		d.nextDial = now.Add(d.backoff)
+		d.backoff *= 2
+		if d.backoff > maxBackoff {
+			d.backoff = maxBackoff
+		}
+		return
+	}
+	d.nextDial = time.Time{}
+	d.backoff = minBackoff
 }
 
 // Connect connects to the dnstap endpoint.
 func (d *dio) connect() error {
 	err := d.dial()
+	if err != nil {
+		d.nextDial = time.Now().Add(d.backoff)
+		d.backoff *= 2
+	}
 	go d.serve()
 	return err
 }
 
 // Dnstap enqueues the payload for log.
-func (d *dio) Dnstap(payload tap.Dnstap) {
+func (d *dio) Dnstap(payload *tap.Dnstap) {
 	select {
 	case d.queue <- payload:
 	default:
 		atomic.AddUint32(&d.dropped, 1)
+		DroppedCount.WithLabelValues("queue_full").Inc()
 	}
 }
 
@@ -80,14 +136,12 @@ func (d *dio) Dnstap(payload tap.Dnstap) {
 func (d *dio) close() { close(d.quit) }
 
 func (d *dio) write(payload *tap.Dnstap) error {
-	if d.enc == nil {
-		atomic.AddUint32(&d.dropped, 1)
-		return nil
-	}
 	if err := d.enc.writeMsg(payload); err != nil {
 		atomic.AddUint32(&d.dropped, 1)
+		DroppedCount.WithLabelValues("write_error").Inc()
 		return err
 	}
+	SentCount.Inc()
 	return nil
 }
 
@@ -96,6 +150,12 @@ func (d *dio) serve() {
 	defer timeout.Stop()
 	for {
 		timeout.Reset(d.flushTimeout)
+
+		// While we are not connected, messages stay in the queue until it is full.
+		queue := d.queue
+		if d.enc == nil {
+			queue = nil
+		}
 		select {
 		case <-d.quit:
 			if d.enc == nil {
@@ -104,18 +164,18 @@ func (d *dio) serve() {
 			d.enc.flush()
 			d.enc.close()
 			return
-		case payload := <-d.queue:
-			if err := d.write(&payload); err != nil {
-				d.dial()
+		case payload := <-queue:
+			if err := d.write(payload); err != nil {
+				d.reconnect()
 			}
 		case <-timeout.C:
 			if dropped := atomic.SwapUint32(&d.dropped, 0); dropped > 0 {
 				log.Warningf("Dropped dnstap messages: %d", dropped)
 			}
 			if d.enc == nil {
-				d.dial()
-			} else {
-				d.enc.flush()
+				d.reconnect()
+			} else if err := d.enc.flush(); err != nil {
+				d.reconnect()
 			}
 		}
 	}
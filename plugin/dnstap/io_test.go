@@ -14,20 +14,24 @@ import (
 
 var (
 	msgType = tap.Dnstap_MESSAGE
-	tmsg    = tap.Dnstap{Type: &msgType}
+	tmsg    = &tap.Dnstap{Type: &msgType}
 )
 
+// accept runs in its own goroutine, so it reports errors with t.Errorf and returns.
 func accept(t *testing.T, l net.Listener, count int) {
 	server, err := l.Accept()
 	if err != nil {
-		t.Fatalf("Server accepted: %s", err)
+		t.Errorf("Server accepted: %s", err)
+		return
 	}
 	dec, err := fs.NewDecoder(server, &fs.DecoderOptions{
 		ContentType:   []byte("protobuf:dnstap.Dnstap"),
 		Bidirectional: true,
 	})
 	if err != nil {
-		t.Fatalf("Server decoder: %s", err)
+		t.Errorf("Server decoder: %s", err)
+		server.Close()
+		return
 	}
 
 	for i := 0; i < count; i++ {
@@ -99,7 +103,7 @@ func TestRace(t *testing.T) {
 	for i := 0; i < count; i++ {
 		go func() {
 			tmsg := tap.Dnstap_MESSAGE
-			dio.Dnstap(tap.Dnstap{Type: &tmsg})
+			dio.Dnstap(&tap.Dnstap{Type: &tmsg})
 			wg.Done()
 		}()
 	}
@@ -153,3 +157,51 @@ func TestReconnect(t *testing.T) {
 	}
 	wg.Wait()
 }
+
+func TestBackoff(t *testing.T) {
+	l, err := reuseport.Listen("tcp", ":0")
+	if err != nil {
+		t.Fatalf("Cannot start listener: %s", err)
+	}
+	addr := l.Addr().String()
+	l.Close()
+
+	dio := newIO("tcp", addr)
+	dio.tcpTimeout = 10 * time.Millisecond
+
+	dio.reconnect()
+	if dio.backoff != 2*minBackoff {
+		t.Errorf("Expected backoff to be %s, got %s", 2*minBackoff, dio.backoff)
+	}
+	next := dio.nextDial
+	// Within the backoff period no connection is attempted.
+	dio.reconnect()
+	if dio.nextDial != next {
+		t.Errorf("Expected no connection attempt before %s", next)
+	}
+
+	for i := 0; i < 10; i++ {
+		dio.nextDial = time.Time{}
+		dio.reconnect()
+	}
+	if dio.backoff != maxBackoff {
+		t.Errorf("Expected backoff to be capped at %s, got %s", maxBackoff, dio.backoff)
+	}
+
+	l, err = reuseport.Listen("tcp", addr)
+	if err != nil {
+		t.Fatalf("Cannot start listener: %s", err)
+	}
+	defer l.Close()
+	go accept(t, l, 0)
+	dio.tcpTimeout = time.Second
+	dio.nextDial = time.Time{}
+	dio.reconnect()
+	if dio.enc == nil {
+		t.Fatal("Expected to be connected")
+	}
+	if dio.backoff != minBackoff {
+		t.Errorf("Expected backoff to be reset to %s, got %s", minBackoff, dio.backoff)
+	}
+	dio.conn.Close()
+}
@@ -0,0 +1,26 @@
+package dnstap
+
+import (
+	"github.com/coredns/coredns/plugin"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Variables declared for monitoring.
+var (
+	// SentCount is the number of dnstap messages written to the endpoint.
+	SentCount = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: plugin.Namespace,
+		Subsystem: "dnstap",
+		Name:      "sent_total",
+		Help:      "Counter of dnstap messages sent to the endpoint.",
+	})
+	// DroppedCount is the number of dnstap messages that could not be sent.
+	DroppedCount = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: plugin.Namespace,
+		Subsystem: "dnstap",
+		Name:      "dropped_total",
+		Help:      "Counter of dnstap messages that were dropped per reason.",
+	}, []string{"reason"})
+)
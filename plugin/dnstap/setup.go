@@ -1,13 +1,21 @@
 package dnstap
 
 import (
+	"net"
+	"os"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/coredns/caddy"
 	"github.com/coredns/coredns/core/dnsserver"
 	"github.com/coredns/coredns/plugin"
 	clog "github.com/coredns/coredns/plugin/pkg/log"
 	"github.com/coredns/coredns/plugin/pkg/parse"
+	"github.com/coredns/coredns/plugin/pkg/replacer"
+	pkgtls "github.com/coredns/coredns/plugin/pkg/tls"
+
+	tap "github.com/dnstap/golang-dnstap"
 )
 
 var log = clog.NewWithPlugin("dnstap")
@@ -17,31 +25,117 @@ func init() { plugin.Register("dnstap", setup) }
 func parseConfig(c *caddy.Controller) (Dnstap, error) {
 	c.Next() // directive name
 	d := Dnstap{}
-	endpoint := ""
-
-	if !c.Args(&endpoint) {
+	args := c.RemainingArgs()
+	if len(args) == 0 || len(args) > 2 {
 		return d, c.ArgErr()
 	}
+	endpoint := args[0]
 
-	if strings.HasPrefix(endpoint, "tcp://") {
+	var dio *dio
+	switch {
+	case strings.HasPrefix(endpoint, "tcp://"):
 		// remote IP endpoint
 		servers, err := parse.HostPortOrFile(endpoint[6:])
 		if err != nil {
 			return d, c.ArgErr()
 		}
-		dio := newIO("tcp", servers[0])
-		d = Dnstap{io: dio}
-	} else {
+		dio = newIO("tcp", servers[0])
+	case strings.HasPrefix(endpoint, "tls://"):
+		// remote endpoint, the host name is also used to verify the certificate
+		if _, _, err := net.SplitHostPort(endpoint[6:]); err != nil {
+			return d, c.ArgErr()
+		}
+		dio = newIO("tcp", endpoint[6:])
+		var err error
+		if dio.tlsConfig, err = pkgtls.NewTLSConfigFromArgs(); err != nil {
+			return d, err
+		}
+	default:
 		endpoint = strings.TrimPrefix(endpoint, "unix://")
-		dio := newIO("unix", endpoint)
-		d = Dnstap{io: dio}
+		dio = newIO("unix", endpoint)
 	}
+	d = Dnstap{io: dio, repl: replacer.New()}
+	d.Identity = []byte(hostname())
+	d.Version = []byte(caddy.AppName + "-" + caddy.AppVersion)
 
-	d.IncludeRawMessage = c.NextArg() && c.Val() == "full"
+	d.IncludeRawMessage = len(args) == 2 && args[1] == "full"
+
+	serverName := ""
+	for c.NextBlock() {
+		switch c.Val() {
+		case "tls":
+			if dio.proto != "tcp" {
+				return d, c.Err("tls can only be used with a tcp:// or tls:// endpoint")
+			}
+			args := c.RemainingArgs()
+			if len(args) > 3 {
+				return d, c.ArgErr()
+			}
+			tlsConfig, err := pkgtls.NewTLSConfigFromArgs(args...)
+			if err != nil {
+				return d, err
+			}
+			dio.tlsConfig = tlsConfig
+		case "tls_servername":
+			if !c.NextArg() {
+				return d, c.ArgErr()
+			}
+			serverName = c.Val()
+		case "queue":
+			if !c.NextArg() {
+				return d, c.ArgErr()
+			}
+			size, err := strconv.Atoi(c.Val())
+			if err != nil || size <= 0 {
+				return d, c.Errf("invalid queue size %q", c.Val())
+			}
+			dio.queue = make(chan *tap.Dnstap, size)
+		case "identity":
+			if !c.NextArg() {
+				return d, c.ArgErr()
+			}
+			d.Identity = []byte(c.Val())
+		case "version":
+			if !c.NextArg() {
+				return d, c.ArgErr()
+			}
+			d.Version = []byte(c.Val())
+		case "extra":
+			if !c.NextArg() {
+				return d, c.ArgErr()
+			}
+			d.ExtraFormat = c.Val()
+			d.extraMsg = needsMessage(d.ExtraFormat)
+		case "flush":
+			if !c.NextArg() {
+				return d, c.ArgErr()
+			}
+			dur, err := time.ParseDuration(c.Val())
+			if err != nil || dur <= 0 {
+				return d, c.Errf("invalid flush interval %q", c.Val())
+			}
+			dio.flushTimeout = dur
+		default:
+			return d, c.Errf("unknown property '%s'", c.Val())
+		}
+	}
+
+	if dio.tlsConfig != nil {
+		if serverName == "" {
+			serverName, _, _ = net.SplitHostPort(dio.endpoint)
+		}
+		dio.tlsConfig.ServerName = serverName
+	}
 
 	return d, nil
 }
 
+// hostname returns the host name as reported by the kernel, or the empty string if that fails.
+func hostname() string {
+	h, _ := os.Hostname()
+	return h
+}
+
 func setup(c *caddy.Controller) error {
 	dnstap, err := parseConfig(c)
 	if err != nil {
@@ -2,6 +2,7 @@ package dnstap
 
 import (
 	"testing"
+	"time"
 
 	"github.com/coredns/caddy"
 )
@@ -43,3 +44,105 @@ func TestConfig(t *testing.T) {
 		}
 	}
 }
+
+func TestConfigBlock(t *testing.T) {
+	tests := []struct {
+		in         string
+		tls        bool
+		serverName string
+		queue      int
+		flush      time.Duration
+		fail       bool
+	}{
+		{"dnstap tls://127.0.0.1:6000", true, "127.0.0.1", queueSize, flushTimeout, false},
+		{`dnstap tls://collector.example.org:6000 full {
+			queue 100
+			flush 100ms
+		}`, true, "collector.example.org", 100, 100 * time.Millisecond, false},
+		{`dnstap tcp://10.0.0.1:6000 {
+			tls
+			tls_servername dnstap.example.org
+		}`, true, "dnstap.example.org", queueSize, flushTimeout, false},
+		{`dnstap tcp://10.0.0.1:6000 {
+			queue 5000
+		}`, false, "", 5000, flushTimeout, false},
+		{`dnstap /tmp/dnstap.sock {
+			tls
+		}`, false, "", 0, 0, true},
+		{`dnstap tcp://10.0.0.1:6000 {
+			queue -1
+		}`, false, "", 0, 0, true},
+		{`dnstap tcp://10.0.0.1:6000 {
+			flush never
+		}`, false, "", 0, 0, true},
+		{`dnstap tcp://10.0.0.1:6000 {
+			nonsense
+		}`, false, "", 0, 0, true},
+	}
+	for i, tc := range tests {
+		c := caddy.NewTestController("dns", tc.in)
+		tap, err := parseConfig(c)
+		if tc.fail {
+			if err == nil {
+				t.Errorf("Test %d: expected test to fail: %s", i, tc.in)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("Test %d: expected no error, got %s", i, err)
+		}
+		dio := tap.io.(*dio)
+		if (dio.tlsConfig != nil) != tc.tls {
+			t.Errorf("Test %d: expected TLS %t, got %t", i, tc.tls, dio.tlsConfig != nil)
+		}
+		if tc.tls && dio.tlsConfig.ServerName != tc.serverName {
+			t.Errorf("Test %d: expected server name %s, got %s", i, tc.serverName, dio.tlsConfig.ServerName)
+		}
+		if x := cap(dio.queue); x != tc.queue {
+			t.Errorf("Test %d: expected queue size %d, got %d", i, tc.queue, x)
+		}
+		if dio.flushTimeout != tc.flush {
+			t.Errorf("Test %d: expected flush interval %s, got %s", i, tc.flush, dio.flushTimeout)
+		}
+	}
+}
+
+func TestConfigIdentity(t *testing.T) {
+	c := caddy.NewTestController("dns", "dnstap /tmp/dnstap.sock")
+	d, err := parseConfig(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if x := string(d.Identity); x != hostname() {
+		t.Errorf("Expected default identity %q, got %q", hostname(), x)
+	}
+	if x := string(d.Version); x != caddy.AppName+"-"+caddy.AppVersion {
+		t.Errorf("Expected default version %q, got %q", caddy.AppName+"-"+caddy.AppVersion, x)
+	}
+
+	c = caddy.NewTestController("dns", `dnstap /tmp/dnstap.sock {
+		identity NAME
+		version VER
+		extra "ns={/kubernetes/client-namespace}"
+	}`)
+	d, err = parseConfig(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if x := string(d.Identity); x != "NAME" {
+		t.Errorf("Expected identity %q, got %q", "NAME", x)
+	}
+	if x := string(d.Version); x != "VER" {
+		t.Errorf("Expected version %q, got %q", "VER", x)
+	}
+	if x := d.ExtraFormat; x != "ns={/kubernetes/client-namespace}" {
+		t.Errorf("Expected extra %q, got %q", "ns={/kubernetes/client-namespace}", x)
+	}
+
+	for _, in := range []string{"dnstap /tmp/dnstap.sock {\nidentity\n}", "dnstap /tmp/dnstap.sock {\nextra\n}"} {
+		c := caddy.NewTestController("dns", in)
+		if _, err := parseConfig(c); err == nil {
+			t.Errorf("Expected error for %q", in)
+		}
+	}
+}
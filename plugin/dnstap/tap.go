@@ -0,0 +1,80 @@
+package dnstap
+
+import (
+	"context"
+	"time"
+
+	"github.com/coredns/coredns/plugin/dnstap/msg"
+	"github.com/coredns/coredns/request"
+
+	tap "github.com/dnstap/golang-dnstap"
+	"github.com/miekg/dns"
+)
+
+// Key is the context key under which the dnstap plugin stores itself for the plugins that
+// come after it in the chain.
+type Key struct{}
+
+// FromContext returns the dnstap plugin that handles the current query. This allows plugins, and
+// packages without a setup function such as plugin/pkg/upstream, to send dnstap messages without
+// looking up the handler.
+func FromContext(ctx context.Context) (Dnstap, bool) {
+	h, ok := ctx.Value(Key{}).(Dnstap)
+	return h, ok
+}
+
+// TapAuth sends an AUTH_QUERY message for query and returns a ResponseWriter that sends an
+// AUTH_RESPONSE message for each response written to it. Plugins that answer from authoritative
+// data, such as *file* and *kubernetes*, wrap their ResponseWriter with it.
+func (h Dnstap) TapAuth(ctx context.Context, w dns.ResponseWriter, query *dns.Msg) dns.ResponseWriter {
+	now := time.Now()
+	q := new(tap.Message)
+	msg.SetQueryTime(q, now)
+	msg.SetQueryAddress(q, w.RemoteAddr())
+	msg.SetResponseAddress(q, w.LocalAddr())
+
+	if h.IncludeRawMessage {
+		buf, _ := query.Pack()
+		q.QueryMessage = buf
+	}
+	msg.SetType(q, tap.Message_AUTH_QUERY)
+	h.TapMessageWithMetadata(ctx, q, request.Request{W: w, Req: query})
+
+	return &ResponseWriter{
+		ResponseWriter: w,
+		Dnstap:         h,
+		ctx:            ctx,
+		query:          query,
+		queryTime:      now,
+		respType:       tap.Message_AUTH_RESPONSE,
+	}
+}
+
+// TapResolver sends a RESOLVER_QUERY message for query and, when reply is not nil, a
+// RESOLVER_RESPONSE message for reply. These are used for the lookups CoreDNS does on its own
+// behalf while resolving a client's query, e.g. to resolve a CNAME target.
+func (h Dnstap) TapResolver(ctx context.Context, state request.Request, query, reply *dns.Msg, start time.Time) {
+	q := new(tap.Message)
+	msg.SetQueryTime(q, start)
+	msg.SetQueryAddress(q, state.W.RemoteAddr())
+	if h.IncludeRawMessage {
+		buf, _ := query.Pack()
+		q.QueryMessage = buf
+	}
+	msg.SetType(q, tap.Message_RESOLVER_QUERY)
+	h.TapMessageWithMetadata(ctx, q, state)
+
+	if reply == nil {
+		return
+	}
+	r := new(tap.Message)
+	msg.SetQueryTime(r, start)
+	msg.SetResponseTime(r, time.Now())
+	msg.SetQueryAddress(r, state.W.RemoteAddr())
+	if h.IncludeRawMessage {
+		buf, _ := reply.Pack()
+		r.ResponseMessage = buf
+	}
+	msg.SetType(r, tap.Message_RESOLVER_RESPONSE)
+	h.TapMessageWithMetadata(ctx, r, state)
+}
@@ -1,18 +1,22 @@
 package dnstap
 
 import (
+	"context"
 	"time"
 
 	"github.com/coredns/coredns/plugin/dnstap/msg"
+	"github.com/coredns/coredns/request"
 
 	tap "github.com/dnstap/golang-dnstap"
 	"github.com/miekg/dns"
 )
 
-// ResponseWriter captures the client response and logs the query to dnstap.
+// ResponseWriter captures the client (or authoritative) response and logs it to dnstap.
 type ResponseWriter struct {
 	queryTime time.Time
+	ctx       context.Context
 	query     *dns.Msg
+	respType  tap.Message_Type
 	dns.ResponseWriter
 	Dnstap
 }
@@ -28,13 +32,16 @@ func (w *ResponseWriter) WriteMsg(resp *dns.Msg) error {
 	msg.SetQueryTime(r, w.queryTime)
 	msg.SetResponseTime(r, time.Now())
 	msg.SetQueryAddress(r, w.RemoteAddr())
+	if w.respType == tap.Message_AUTH_RESPONSE {
+		msg.SetResponseAddress(r, w.LocalAddr())
+	}
 
 	if w.IncludeRawMessage {
 		buf, _ := resp.Pack()
 		r.ResponseMessage = buf
 	}
 
-	msg.SetType(r, tap.Message_CLIENT_RESPONSE)
-	w.TapMessage(r)
+	msg.SetType(r, w.respType)
+	w.TapMessageWithMetadata(w.ctx, r, request.Request{W: w.ResponseWriter, Req: w.query})
 	return nil
 }
@@ -0,0 +1,109 @@
+package etcd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/coredns/coredns/plugin"
+	"github.com/coredns/coredns/plugin/autopath"
+	"github.com/coredns/coredns/request"
+
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	etcdcv3 "go.etcd.io/etcd/client/v3"
+)
+
+// autoPathRefresh is how often the search paths are read from etcd.
+const autoPathRefresh = 30 * time.Second
+
+// searchPath is the value of a key under the autopath path in etcd.
+type searchPath struct {
+	Subnets []string `json:"subnets"`
+	Search  []string `json:"search"`
+}
+
+// autoPathKey returns the path under which the search paths are stored.
+func (e *Etcd) autoPathKey() string {
+	return "/" + e.PathPrefix + "/_autopath/"
+}
+
+// AutoPath implements the AutoPathFunc call from the autopath plugin.
+// It returns the search path of the client's subnet, as stored in etcd, or nil if there is none.
+func (e *Etcd) AutoPath(state request.Request) []string {
+	if plugin.Zones(e.Zones).Matches(state.Name()) == "" {
+		return nil
+	}
+	ip := net.ParseIP(state.IP())
+	if ip == nil {
+		return nil
+	}
+	e.autoPathMu.RLock()
+	s := e.autoPathSubnets
+	e.autoPathMu.RUnlock()
+	return s.Search(ip)
+}
+
+// startAutoPath reads the search paths from etcd now and then every autoPathRefresh, in the
+// background. If they can't be read, the previous search paths are kept.
+func (e *Etcd) startAutoPath() error {
+	e.autoPathStop = make(chan struct{})
+	go func() {
+		tick := time.NewTicker(autoPathRefresh)
+		defer tick.Stop()
+		for {
+			e.updateSubnets()
+			select {
+			case <-tick.C:
+			case <-e.autoPathStop:
+				return
+			}
+		}
+	}()
+	return nil
+}
+
+func (e *Etcd) stopAutoPath() error {
+	if e.autoPathStop != nil {
+		close(e.autoPathStop)
+	}
+	return nil
+}
+
+func (e *Etcd) updateSubnets() {
+	s, err := e.readSubnets()
+	if err != nil {
+		log.Warningf("Failed to read search paths from %s: %s", e.autoPathKey(), err)
+		return
+	}
+	e.autoPathMu.Lock()
+	e.autoPathSubnets = s
+	e.autoPathMu.Unlock()
+}
+
+func (e *Etcd) readSubnets() (*autopath.Subnets, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), etcdTimeout)
+	defer cancel()
+	r, err := e.Client.Get(ctx, e.autoPathKey(), etcdcv3.WithPrefix())
+	if err != nil {
+		return nil, err
+	}
+	return parseSearchPaths(r.Kvs)
+}
+
+func parseSearchPaths(kvs []*mvccpb.KeyValue) (*autopath.Subnets, error) {
+	s := &autopath.Subnets{}
+	for _, kv := range kvs {
+		sp := new(searchPath)
+		if err := json.Unmarshal(kv.Value, sp); err != nil {
+			return nil, fmt.Errorf("%s: %s", kv.Key, err)
+		}
+		for _, n := range sp.Subnets {
+			if err := s.Add(n, sp.Search); err != nil {
+				return nil, fmt.Errorf("%s: %s", kv.Key, err)
+			}
+		}
+	}
+	return s, nil
+}
@@ -0,0 +1,39 @@
+package etcd
+
+import (
+	"net"
+	"reflect"
+	"testing"
+
+	"go.etcd.io/etcd/api/v3/mvccpb"
+)
+
+func TestParseSearchPaths(t *testing.T) {
+	kvs := []*mvccpb.KeyValue{
+		{Key: []byte("/skydns/_autopath/corp"), Value: []byte(`{"subnets":["10.0.0.0/8"],"search":["corp.example.org","example.org"]}`)},
+		{Key: []byte("/skydns/_autopath/vm"), Value: []byte(`{"subnets":["10.1.0.0/16","2001:db8::/32"],"search":["vm.example.org"]}`)},
+	}
+	s, err := parseSearchPaths(kvs)
+	if err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+	tests := []struct {
+		ip     string
+		search []string
+	}{
+		{"10.0.0.1", []string{"corp.example.org.", "example.org.", ""}},
+		{"10.1.0.1", []string{"vm.example.org.", ""}},
+		{"2001:db8::1", []string{"vm.example.org.", ""}},
+		{"192.0.2.1", nil},
+	}
+	for _, tc := range tests {
+		if got := s.Search(net.ParseIP(tc.ip)); !reflect.DeepEqual(got, tc.search) {
+			t.Errorf("Expected search path %v for %s, got %v", tc.search, tc.ip, got)
+		}
+	}
+
+	bad := []*mvccpb.KeyValue{{Key: []byte("/skydns/_autopath/bad"), Value: []byte(`{"subnets":["10.0.0.0/33"],"search":["example.org"]}`)}}
+	if _, err := parseSearchPaths(bad); err == nil {
+		t.Errorf("Expected error for an invalid subnet")
+	}
+}
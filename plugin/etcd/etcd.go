@@ -7,9 +7,11 @@ import (
 	"errors"
 	"fmt"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/coredns/coredns/plugin"
+	"github.com/coredns/coredns/plugin/autopath"
 	"github.com/coredns/coredns/plugin/etcd/msg"
 	"github.com/coredns/coredns/plugin/pkg/fall"
 	"github.com/coredns/coredns/plugin/pkg/upstream"
@@ -38,6 +40,11 @@ type Etcd struct {
 	Client     *etcdcv3.Client
 
 	endpoints []string // Stored here as well, to aid in testing.
+
+	// Search paths for the autopath plugin, read from etcd every autoPathRefresh.
+	autoPathMu      sync.RWMutex
+	autoPathSubnets *autopath.Subnets
+	autoPathStop    chan struct{}
 }
 
 // Services implements the ServiceBackend interface.
@@ -6,12 +6,15 @@ import (
 	"github.com/coredns/caddy"
 	"github.com/coredns/coredns/core/dnsserver"
 	"github.com/coredns/coredns/plugin"
+	clog "github.com/coredns/coredns/plugin/pkg/log"
 	mwtls "github.com/coredns/coredns/plugin/pkg/tls"
 	"github.com/coredns/coredns/plugin/pkg/upstream"
 
 	etcdcv3 "go.etcd.io/etcd/client/v3"
 )
 
+var log = clog.NewWithPlugin("etcd")
+
 func init() { plugin.Register("etcd", setup) }
 
 func setup(c *caddy.Controller) error {
@@ -20,6 +23,9 @@ func setup(c *caddy.Controller) error {
 		return plugin.Error("etcd", err)
 	}
 
+	c.OnStartup(e.startAutoPath)
+	c.OnShutdown(e.stopAutoPath)
+
 	dnsserver.GetConfig(c).AddPlugin(func(next plugin.Handler) plugin.Handler {
 		e.Next = next
 		return e
@@ -85,12 +91,16 @@ func etcdParse(c *caddy.Controller) (*Etcd, error) {
 				}
 			}
 		}
+		etc.endpoints = endpoints
+		if dnsserver.DryRun(c) {
+			// Don't connect to etcd when only validating.
+			return &etc, nil
+		}
 		client, err := newEtcdClient(endpoints, tlsConfig, username, password)
 		if err != nil {
 			return &Etcd{}, err
 		}
 		etc.Client = client
-		etc.endpoints = endpoints
 
 		return &etc, nil
 	}
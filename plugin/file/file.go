@@ -7,6 +7,7 @@ import (
 	"io"
 
 	"github.com/coredns/coredns/plugin"
+	"github.com/coredns/coredns/plugin/dnstap"
 	clog "github.com/coredns/coredns/plugin/pkg/log"
 	"github.com/coredns/coredns/plugin/transfer"
 	"github.com/coredns/coredns/request"
@@ -60,22 +61,19 @@ func (f File) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (i
 			m.Authoritative = true
 			w.WriteMsg(m)
 
-			log.Infof("Notify from %s for %s: checking transfer", state.IP(), zone)
-			ok, err := z.shouldTransfer()
-			if ok {
-				z.TransferIn()
-			} else {
-				log.Infof("Notify from %s for %s: no SOA serial increase seen", state.IP(), zone)
-			}
-			if err != nil {
-				log.Warningf("Notify from %s for %s: failed primary check: %s", state.IP(), zone, err)
-			}
+			log.Infof("Notify from %s for %s", state.IP(), zone)
+			z.Notify()
 			return dns.RcodeSuccess, nil
 		}
 		log.Infof("Dropping notify from %s for %s", state.IP(), zone)
 		return dns.RcodeSuccess, nil
 	}
 
+	if r.Opcode == dns.OpcodeUpdate {
+		w.WriteMsg(z.DynamicUpdate(state, f.transfer))
+		return dns.RcodeSuccess, nil
+	}
+
 	z.RLock()
 	exp := z.Expired
 	z.RUnlock()
@@ -84,6 +82,10 @@ func (f File) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (i
 		return dns.RcodeServerFailure, nil
 	}
 
+	if tapPlugin, ok := dnstap.FromContext(ctx); ok {
+		w = tapPlugin.TapAuth(ctx, w, r)
+	}
+
 	answer, ns, extra, result := z.Lookup(ctx, state, qname)
 
 	m := new(dns.Msg)
@@ -127,12 +129,15 @@ func (s *serialErr) Error() string {
 	return fmt.Sprintf("%s for origin %s in file %s, with %d SOA serial", s.err, s.origin, s.zone, s.serial)
 }
 
-// Parse parses the zone in filename and returns a new Zone or an error.
+// Parse parses the zone in filename and returns a new Zone or an error. The zone is in the
+// structured format if filename has a .json, .yaml or .yml extension, see NewZoneParser.
 // If serial >= 0 it will reload the zone, if the SOA hasn't changed
 // it returns an error indicating nothing was read.
 func Parse(f io.Reader, origin, fileName string, serial int64) (*Zone, error) {
-	zp := dns.NewZoneParser(f, dns.Fqdn(origin), fileName)
-	zp.SetIncludeAllowed(true)
+	zp, err := NewZoneParser(f, origin, fileName)
+	if err != nil {
+		return nil, err
+	}
 	z := NewZone(origin, fileName)
 	seenSOA := false
 	for rr, ok := zp.Next(); ok; rr, ok = zp.Next() {
@@ -0,0 +1,158 @@
+package file
+
+import (
+	"os"
+	"strings"
+
+	"github.com/miekg/dns"
+)
+
+// JournalSuffix is appended to the name of a zone file to get the name of its journal. The journal
+// holds the dynamic updates of the zone, the zone file itself is never written.
+const JournalSuffix = ".jnl"
+
+// The journal holds one entry per update, in RFC 1035 format: the old SOA, the deleted records, the
+// new SOA, the added records and the new SOA again. This is a difference sequence of an IXFR (RFC
+// 1995), closed with the new SOA so an entry that was only partly written can be recognized.
+
+// journal returns the path of the journal of z.
+func (z *Zone) journal() string { return z.File() + JournalSuffix }
+
+// appendJournal appends the entry rrs to the journal at path and syncs it to disk. If that fails,
+// the journal is truncated to what it was, so it never ends with a partial entry.
+func appendJournal(path string, rrs []dns.RR) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	stat, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+
+	var b strings.Builder
+	for _, rr := range rrs {
+		b.WriteString(rr.String())
+		b.WriteByte('\n')
+	}
+	if _, err = f.WriteString(b.String()); err == nil {
+		err = f.Sync()
+	}
+	if err != nil {
+		f.Truncate(stat.Size())
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// ReplayJournal applies the entries of the journal of z that follow on from its serial. Entries for
+// other serials are skipped: these were superseded by a zone file with a higher serial. z must not
+// be serving queries yet.
+func (z *Zone) ReplayJournal() {
+	path := z.journal()
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return
+	}
+	if err != nil {
+		log.Errorf("Failed to open the journal of `%s': %v", z.origin, err)
+		return
+	}
+	defer f.Close()
+
+	var (
+		entry   []dns.RR
+		soas    int
+		applied int
+	)
+	zp := dns.NewZoneParser(f, z.origin, path)
+	for rr, ok := zp.Next(); ok; rr, ok = zp.Next() {
+		entry = append(entry, rr)
+		if _, ok := rr.(*dns.SOA); !ok {
+			continue
+		}
+		if soas++; soas < 3 {
+			continue
+		}
+		if z.applyEntry(entry) {
+			applied++
+		}
+		entry, soas = nil, 0
+	}
+	if err := zp.Err(); err != nil {
+		log.Errorf("Failed to read the journal of `%s': %v", z.origin, err)
+	}
+	if applied > 0 {
+		log.Infof("Applied %d updates from the journal of `%s', serial is now %d", applied, z.origin, z.Apex.SOA.Serial)
+	}
+}
+
+// applyEntry applies the journal entry rrs to z if it follows on from the serial of z.
+func (z *Zone) applyEntry(rrs []dns.RR) bool {
+	old, ok := rrs[0].(*dns.SOA)
+	if !ok || z.Apex.SOA == nil || old.Serial != z.Apex.SOA.Serial {
+		return false
+	}
+	deleting := true
+	for _, rr := range rrs[1 : len(rrs)-1] {
+		if _, ok := rr.(*dns.SOA); ok {
+			deleting = false
+			continue
+		}
+		if deleting {
+			z.deleteRR(rr)
+			continue
+		}
+		z.Insert(rr)
+	}
+	z.Insert(rrs[len(rrs)-1])
+	return true
+}
+
+// journalEntry returns the journal entry for changing the records of names in z into those in z1.
+// The caller must hold the read lock.
+func (z *Zone) journalEntry(z1 *Zone, names []string) []dns.RR {
+	var deleted, added []dns.RR
+	for _, name := range names {
+		old, cur := z.records(name), z1.records(name)
+		deleted = append(deleted, missing(old, cur)...)
+		added = append(added, missing(cur, old)...)
+	}
+
+	rrs := []dns.RR{z.Apex.SOA}
+	rrs = append(rrs, deleted...)
+	rrs = append(rrs, z1.Apex.SOA)
+	rrs = append(rrs, added...)
+	return append(rrs, z1.Apex.SOA)
+}
+
+// records returns the records of name in z, including those in the apex but without the SOA.
+func (z *Zone) records(name string) []dns.RR {
+	var rrs []dns.RR
+	if name == z.origin {
+		rrs = append(rrs, z.Apex.SIGSOA...)
+		rrs = append(rrs, z.Apex.NS...)
+		rrs = append(rrs, z.Apex.SIGNS...)
+	}
+	if e, ok := z.Tree.Search(name); ok {
+		rrs = append(rrs, e.All()...)
+	}
+	return rrs
+}
+
+// missing returns the records in a that are not in b, including differences in TTL.
+func missing(a, b []dns.RR) []dns.RR {
+	in := make(map[string]struct{}, len(b))
+	for _, rr := range b {
+		in[rr.String()] = struct{}{}
+	}
+	var rrs []dns.RR
+	for _, rr := range a {
+		if _, ok := in[rr.String()]; !ok {
+			rrs = append(rrs, rr)
+		}
+	}
+	return rrs
+}
@@ -0,0 +1,40 @@
+package file
+
+import (
+	"github.com/coredns/coredns/plugin"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// These metrics are about zones that are transferred from a primary, i.e. by the secondary plugin.
+var (
+	// zoneSerial is the SOA serial of a transferred zone.
+	zoneSerial = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: plugin.Namespace,
+		Subsystem: "secondary",
+		Name:      "zone_serial",
+		Help:      "SOA serial of the transferred zone.",
+	}, []string{"zone"})
+	// lastTransfer is the time of the last successful transfer of a zone.
+	lastTransfer = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: plugin.Namespace,
+		Subsystem: "secondary",
+		Name:      "last_transfer_timestamp_seconds",
+		Help:      "Unix time of the last successful transfer of the zone.",
+	}, []string{"zone"})
+	// transferFailures counts the failed transfers and SOA checks, per primary.
+	transferFailures = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: plugin.Namespace,
+		Subsystem: "secondary",
+		Name:      "transfer_failures_total",
+		Help:      "Counter of failed transfers and SOA checks of the zone, per primary.",
+	}, []string{"zone", "primary"})
+	// zoneExpired is 1 if the zone expired.
+	zoneExpired = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: plugin.Namespace,
+		Subsystem: "secondary",
+		Name:      "zone_expired",
+		Help:      "Whether the zone expired (1) because it couldn't be refreshed, or not (0).",
+	}, []string{"zone"})
+)
@@ -19,13 +19,13 @@ func (z *Zone) isNotify(state request.Request) bool {
 		return false
 	}
 	// If remote IP matches we accept.
-	remote := state.IP()
+	remote := net.ParseIP(state.IP())
 	for _, f := range z.TransferFrom {
 		from, _, err := net.SplitHostPort(f)
 		if err != nil {
 			continue
 		}
-		if from == remote {
+		if net.ParseIP(from).Equal(remote) {
 			return true
 		}
 	}
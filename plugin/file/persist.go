@@ -0,0 +1,134 @@
+package file
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/coredns/coredns/plugin/file/tree"
+
+	"github.com/miekg/dns"
+)
+
+var zeroTime time.Time
+
+// Save writes the zone to z.Persist in RFC 1035 format, or in the structured format if the file has
+// its extension. The file is replaced atomically, so it always
+// holds a complete zone. Its modification time is the time the zone was last refreshed.
+func (z *Zone) Save() error {
+	z.RLock()
+	refreshed := z.refreshedAt
+	z.RUnlock()
+	return z.saveTo(z.Persist, refreshed)
+}
+
+// saveTo atomically replaces the file path with the zone in RFC 1035 format, or in the structured
+// format if path has its extension. If mtime is not zero it
+// is set as the modification time of the file.
+func (z *Zone) saveTo(path string, mtime time.Time) error {
+	mode := os.FileMode(0644)
+	if stat, err := os.Stat(path); err == nil {
+		mode = stat.Mode().Perm()
+	}
+
+	// A hidden file, so the auto plugin doesn't pick it up as a zone.
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) // fails once renamed
+
+	if err := z.write(tmp, path); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), mode); err != nil {
+		return err
+	}
+	if !mtime.IsZero() {
+		if err := os.Chtimes(tmp.Name(), mtime, mtime); err != nil {
+			return err
+		}
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// write writes the zone to f, in the format of the zone file path.
+func (z *Zone) write(f *os.File, path string) error {
+	z.RLock()
+	defer z.RUnlock()
+
+	if z.Apex.SOA == nil {
+		return fmt.Errorf("zone %s has no SOA record", z.origin)
+	}
+
+	rrs := []dns.RR{z.Apex.SOA}
+	rrs = append(rrs, z.Apex.SIGSOA...)
+	rrs = append(rrs, z.Apex.NS...)
+	rrs = append(rrs, z.Apex.SIGNS...)
+	z.Walk(func(e *tree.Elem, _ map[uint16][]dns.RR) error {
+		rrs = append(rrs, e.All()...)
+		return nil
+	})
+
+	w := bufio.NewWriter(f)
+	if Structured(path) {
+		if err := writeStructured(w, z.origin, rrs, strings.ToLower(filepath.Ext(path)) == ".json"); err != nil {
+			return err
+		}
+		return w.Flush()
+	}
+
+	fmt.Fprintf(w, "$ORIGIN %s\n", z.origin)
+	for _, rr := range rrs {
+		fmt.Fprintln(w, rr.String())
+	}
+	return w.Flush()
+}
+
+// Load reads the zone that was saved to z.Persist and sets it live, the zone counts as refreshed at
+// the time the file was last written. It returns false if there is no saved zone.
+func (z *Zone) Load() (bool, error) {
+	f, err := os.Open(z.Persist)
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+
+	stat, err := f.Stat()
+	if err != nil {
+		return false, err
+	}
+	z1, err := Parse(f, z.origin, z.Persist, -1)
+	if err != nil {
+		return false, err
+	}
+
+	z.Lock()
+	z.Tree = z1.Tree
+	z.Apex = z1.Apex
+	z.refreshedAt = stat.ModTime()
+	z.Unlock()
+
+	zoneSerial.WithLabelValues(z.origin).Set(float64(z1.Apex.SOA.Serial))
+	lastTransfer.WithLabelValues(z.origin).Set(float64(stat.ModTime().Unix()))
+	return true, nil
+}
+
+// touch records in the saved zone that it was refreshed at t.
+func (z *Zone) touch(t time.Time) error {
+	return os.Chtimes(z.Persist, t, t)
+}
@@ -0,0 +1,89 @@
+package file
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/coredns/coredns/plugin/test"
+
+	"github.com/miekg/dns"
+)
+
+func TestSaveLoad(t *testing.T) {
+	dir := t.TempDir()
+	z := NewZone(testZone, "stdin")
+	for _, rr := range []dns.RR{
+		test.SOA(fmt.Sprintf("%s IN SOA bla. bla. 250 7200 3600 1209600 3600", testZone)),
+		test.NS(fmt.Sprintf("%s IN NS ns1.%s", testZone, testZone)),
+		test.A(fmt.Sprintf("ns1.%s IN A 127.0.0.1", testZone)),
+		test.A(fmt.Sprintf("a.%s IN A 127.0.0.2", testZone)),
+		test.AAAA(fmt.Sprintf("a.%s IN AAAA ::1", testZone)),
+		test.TXT(fmt.Sprintf(`b.%s IN TXT "hello world"`, testZone)),
+	} {
+		z.Insert(rr)
+	}
+	z.Persist = filepath.Join(dir, "secondary.miek.nl.zone")
+	refreshed := time.Now().Add(-time.Hour).Truncate(time.Second)
+	z.refreshedAt = refreshed
+
+	if err := z.Save(); err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+	if files, _ := os.ReadDir(dir); len(files) != 1 {
+		t.Errorf("Expected only the zone file in %s, got %d files", dir, len(files))
+	}
+
+	z1 := NewZone(testZone, "stdin")
+	z1.Persist = z.Persist
+	ok, err := z1.Load()
+	if err != nil || !ok {
+		t.Fatalf("Expected the zone to load, got %t, %v", ok, err)
+	}
+	if z1.Apex.SOA.Serial != 250 || len(z1.Apex.NS) != 1 {
+		t.Errorf("Expected the apex to load, got %v", z1.Apex)
+	}
+	if !z1.refreshedAt.Equal(refreshed) {
+		t.Errorf("Expected the zone to be refreshed at %s, got %s", refreshed, z1.refreshedAt)
+	}
+	for name, qtype := range map[string]uint16{"a.": dns.TypeAAAA, "b.": dns.TypeTXT, "ns1.": dns.TypeA} {
+		e, ok := z1.Tree.Search(name + testZone)
+		if !ok || len(e.Type(qtype)) != 1 {
+			t.Errorf("Expected a %s record for %s", dns.TypeToString[qtype], name+testZone)
+		}
+	}
+
+	// A refresh moves the refresh time of the saved zone.
+	z1.refreshed()
+	if stat, err := os.Stat(z.Persist); err != nil || time.Since(stat.ModTime()) > time.Minute {
+		t.Errorf("Expected the saved zone to be refreshed now, got %v", stat.ModTime())
+	}
+
+	z2 := NewZone(testZone, "stdin")
+	z2.Persist = filepath.Join(dir, "missing.zone")
+	if ok, err := z2.Load(); ok || err != nil {
+		t.Errorf("Expected no zone and no error, got %t, %v", ok, err)
+	}
+}
+
+func TestUpdateExpireSaved(t *testing.T) {
+	z := NewZone(testZone, "stdin")
+	z.TransferFrom = []string{"127.0.0.1:0"}
+	z.Apex.SOA = test.SOA(fmt.Sprintf("%s IN SOA bla. bla. 250 7200 3600 600 0", testZone))
+	// Saved long ago, past the expire time.
+	z.refreshedAt = time.Now().Add(-time.Hour)
+	go z.Update()
+
+	for i := 0; i < 20; i++ {
+		time.Sleep(50 * time.Millisecond)
+		z.RLock()
+		exp := z.Expired
+		z.RUnlock()
+		if exp {
+			return
+		}
+	}
+	t.Fatal("Expected the zone to expire")
+}
@@ -0,0 +1,78 @@
+package file
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+const (
+	backoffMin = time.Second
+	backoffMax = 10 * time.Minute
+)
+
+var errBackoff = errors.New("all primaries are backing off")
+
+// primaries keeps track of the primaries that failed, so they are not tried again until their
+// backoff has passed. The zero value is ready to use.
+type primaries struct {
+	mu    sync.Mutex
+	state map[string]*backoff
+}
+
+type backoff struct {
+	failures int
+	until    time.Time
+}
+
+// order returns the addresses in addrs that are not backing off, in the configured order. If an error
+// is returned, they are all backing off.
+func (p *primaries) order(addrs []string) ([]string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := time.Now()
+	ok := make([]string, 0, len(addrs))
+	for _, a := range addrs {
+		if b, found := p.state[a]; found && now.Before(b.until) {
+			continue
+		}
+		ok = append(ok, a)
+	}
+	if len(ok) == 0 && len(addrs) > 0 {
+		return nil, errBackoff
+	}
+	return ok, nil
+}
+
+// failed records a failure of the primary addr and returns for how long it will be skipped.
+func (p *primaries) failed(addr string) time.Duration {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.state == nil {
+		p.state = make(map[string]*backoff)
+	}
+	b, ok := p.state[addr]
+	if !ok {
+		b = &backoff{}
+		p.state[addr] = b
+	}
+	b.failures++
+	d := backoffMin
+	for i := 1; i < b.failures && d < backoffMax; i++ {
+		d *= 2
+	}
+	if d > backoffMax {
+		d = backoffMax
+	}
+	b.until = time.Now().Add(d)
+	return d
+}
+
+// ok records a success of the primary addr, it will not be skipped anymore.
+func (p *primaries) ok(addr string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.state, addr)
+}
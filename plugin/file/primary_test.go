@@ -0,0 +1,47 @@
+package file
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPrimariesBackoff(t *testing.T) {
+	p := primaries{}
+	addrs := []string{"10.0.0.1:53", "10.0.0.2:53"}
+
+	if got, _ := p.order(addrs); len(got) != 2 {
+		t.Fatalf("Expected 2 primaries, got %v", got)
+	}
+
+	if d := p.failed(addrs[0]); d != backoffMin {
+		t.Errorf("Expected backoff %s, got %s", backoffMin, d)
+	}
+	if d := p.failed(addrs[0]); d != 2*backoffMin {
+		t.Errorf("Expected backoff %s, got %s", 2*backoffMin, d)
+	}
+	for i := 0; i < 20; i++ {
+		p.failed(addrs[0])
+	}
+	if d := p.failed(addrs[0]); d != backoffMax {
+		t.Errorf("Expected backoff %s, got %s", backoffMax, d)
+	}
+	if got, _ := p.order(addrs); len(got) != 1 || got[0] != addrs[1] {
+		t.Errorf("Expected only %s, got %v", addrs[1], got)
+	}
+
+	p.failed(addrs[1])
+	if _, err := p.order(addrs); err != errBackoff {
+		t.Errorf("Expected %v, got %v", errBackoff, err)
+	}
+
+	p.ok(addrs[0])
+	if got, _ := p.order(addrs); len(got) != 1 || got[0] != addrs[0] {
+		t.Errorf("Expected only %s, got %v", addrs[0], got)
+	}
+
+	// The backoff passes.
+	p.state[addrs[1]].until = time.Now().Add(-time.Second)
+	if got, _ := p.order(addrs); len(got) != 2 {
+		t.Errorf("Expected 2 primaries, got %v", got)
+	}
+}
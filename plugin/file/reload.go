@@ -26,21 +26,27 @@ func (z *Zone) Reload(t *transfer.Transfer) error {
 					continue
 				}
 
+				// Hold off dynamic updates, so one can't be applied between reading the serial and
+				// replacing the zone, and then be undone by it.
+				z.updateMu.Lock()
 				serial := z.SOASerialIfDefined()
 				zone, err := Parse(reader, z.origin, zFile, serial)
 				reader.Close()
 				if err != nil {
+					z.updateMu.Unlock()
 					if _, ok := err.(*serialErr); !ok {
 						log.Errorf("Parsing zone %q: %v", z.origin, err)
 					}
 					continue
 				}
+				zone.ReplayJournal()
 
 				// copy elements we need
 				z.Lock()
 				z.Apex = zone.Apex
 				z.Tree = zone.Tree
 				z.Unlock()
+				z.updateMu.Unlock()
 
 				log.Infof("Successfully reloaded zone %q in %q with %d SOA serial", z.origin, zFile, z.Apex.SOA.Serial)
 				if t != nil {
@@ -70,6 +70,42 @@ func TestZoneReload(t *testing.T) {
 	}
 }
 
+func TestZoneReloadWaitsForUpdate(t *testing.T) {
+	fileName, rm, err := test.TempFile(".", reloadZoneTest)
+	if err != nil {
+		t.Fatalf("Failed to create zone: %s", err)
+	}
+	defer rm()
+	reader, err := os.Open(fileName)
+	if err != nil {
+		t.Fatalf("Failed to open zone: %s", err)
+	}
+	z, err := Parse(reader, "miek.nl", fileName, 0)
+	reader.Close()
+	if err != nil {
+		t.Fatalf("Failed to parse zone: %s", err)
+	}
+	if err := os.WriteFile(fileName, []byte(reloadZone2Test), 0644); err != nil {
+		t.Fatalf("Failed to write new zone data: %s", err)
+	}
+
+	// While an update is being applied, the zone is not reloaded.
+	z.updateMu.Lock()
+	z.ReloadInterval = 10 * time.Millisecond
+	z.Reload(nil)
+	defer z.OnShutdown()
+	time.Sleep(30 * time.Millisecond)
+	if serial := z.SOASerialIfDefined(); serial != 1460175181 {
+		t.Errorf("Expected the zone not to be reloaded during an update, got serial %d", serial)
+	}
+	z.updateMu.Unlock()
+
+	time.Sleep(30 * time.Millisecond)
+	if serial := z.SOASerialIfDefined(); serial != 1460175182 {
+		t.Errorf("Expected the zone to be reloaded after the update, got serial %d", serial)
+	}
+}
+
 func TestZoneReloadSOAChange(t *testing.T) {
 	_, err := Parse(strings.NewReader(reloadZoneTest), "miek.nl.", "stdin", 1460175181)
 	if err == nil {
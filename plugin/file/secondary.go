@@ -1,45 +1,72 @@
 package file
 
 import (
+	"errors"
+	"fmt"
 	"math/rand"
+	"strings"
 	"time"
 
 	"github.com/miekg/dns"
 )
 
-// TransferIn retrieves the zone from the masters, parses it and sets it live.
+// TransferIn retrieves the zone from the primaries, parses it and sets it live. The primaries are
+// tried in the configured order, skipping the ones that failed recently. If z.IXFR is set and the
+// zone was transferred before, an incremental transfer is tried first.
 func (z *Zone) TransferIn() error {
 	if len(z.TransferFrom) == 0 {
 		return nil
 	}
+	if z.IXFR {
+		z.RLock()
+		soa := z.Apex.SOA
+		z.RUnlock()
+		if soa != nil {
+			err := z.transferInIncremental(soa)
+			if err == nil {
+				return nil
+			}
+			log.Warningf("Failed incremental transfer of `%s', falling back to AXFR: %v", z.origin, err)
+		}
+	}
+
+	primaries, err := z.primaries.order(z.TransferFrom)
+	if err != nil {
+		return err
+	}
+
 	m := new(dns.Msg)
 	m.SetAxfr(z.origin)
 
-	z1 := z.CopyWithoutApex()
 	var (
 		Err error
 		tr  string
+		z1  *Zone
 	)
 
 Transfer:
-	for _, tr = range z.TransferFrom {
+	for _, tr = range primaries {
+		z1 = z.CopyWithoutApex()
 		t := new(dns.Transfer)
 		c, err := t.In(m, tr)
 		if err != nil {
 			log.Errorf("Failed to setup transfer `%s' with `%q': %v", z.origin, tr, err)
 			Err = err
+			z.failed(tr)
 			continue Transfer
 		}
 		for env := range c {
 			if env.Error != nil {
 				log.Errorf("Failed to transfer `%s' from %q: %v", z.origin, tr, env.Error)
 				Err = env.Error
+				z.failed(tr)
 				continue Transfer
 			}
 			for _, rr := range env.RR {
 				if err := z1.Insert(rr); err != nil {
 					log.Errorf("Failed to parse transfer `%s' from: %q: %v", z.origin, tr, err)
 					Err = err
+					z.failed(tr)
 					continue Transfer
 				}
 			}
@@ -54,45 +81,283 @@ Transfer:
 	z.Lock()
 	z.Tree = z1.Tree
 	z.Apex = z1.Apex
-	z.Expired = false
 	z.Unlock()
+	z.transferred(tr)
 	log.Infof("Transferred: %s from %s", z.origin, tr)
 	return nil
 }
 
+var errNoIXFR = errors.New("no incremental transfer available")
+
+// transferInIncremental retrieves the changes to the zone since soa with IXFR (RFC 1995), applies
+// them to a copy of the zone and sets that live.
+func (z *Zone) transferInIncremental(soa *dns.SOA) error {
+	primaries, err := z.primaries.order(z.TransferFrom)
+	if err != nil {
+		return err
+	}
+
+	m := new(dns.Msg)
+	m.SetIxfr(z.origin, soa.Serial, soa.Ns, soa.Mbox)
+
+	var Err error
+	for _, tr := range primaries {
+		t := new(dns.Transfer)
+		c, err := t.In(m, tr)
+		if err != nil {
+			Err = err
+			z.failed(tr)
+			continue
+		}
+		var rrs []dns.RR
+		for env := range c {
+			if env.Error != nil {
+				err = env.Error
+				continue
+			}
+			rrs = append(rrs, env.RR...)
+		}
+		if err != nil {
+			Err = err
+			z.failed(tr)
+			continue
+		}
+
+		z1, err := z.applyIXFR(rrs)
+		if err != nil {
+			// Not a failure of the primary, it may just not have the differences.
+			Err = err
+			continue
+		}
+		if z1 == nil {
+			// Nothing changed.
+			z.transferred(tr)
+			return nil
+		}
+
+		z.Lock()
+		z.Tree = z1.Tree
+		z.Apex = z1.Apex
+		z.Unlock()
+		z.transferred(tr)
+		log.Infof("Transferred: %s from %s (incremental)", z.origin, tr)
+		return nil
+	}
+	return Err
+}
+
+// transferred records a successful transfer of the zone from the primary tr.
+func (z *Zone) transferred(tr string) {
+	z.primaries.ok(tr)
+
+	z.Lock()
+	z.Expired = false
+	z.refreshedAt = time.Now()
+	soa := z.Apex.SOA
+	z.Unlock()
+
+	zoneExpired.WithLabelValues(z.origin).Set(0)
+	lastTransfer.WithLabelValues(z.origin).SetToCurrentTime()
+	if soa != nil {
+		zoneSerial.WithLabelValues(z.origin).Set(float64(soa.Serial))
+	}
+
+	if z.Persist != "" {
+		if err := z.Save(); err != nil {
+			log.Errorf("Failed to save `%s' to %s: %v", z.origin, z.Persist, err)
+		}
+	}
+}
+
+// failed records a failure of the primary tr.
+func (z *Zone) failed(tr string) {
+	d := z.primaries.failed(tr)
+	transferFailures.WithLabelValues(z.origin, tr).Inc()
+	log.Warningf("Primary %s of `%s' failed, skipping it for %s", tr, z.origin, d)
+}
+
+// expire marks the zone as expired, it will be answered with SERVFAIL until it is refreshed.
+func (z *Zone) expire() {
+	z.Lock()
+	exp := z.Expired
+	z.Expired = true
+	z.Unlock()
+	if exp {
+		return
+	}
+	zoneExpired.WithLabelValues(z.origin).Set(1)
+	log.Errorf("Zone `%s' expired, no primary could be reached", z.origin)
+}
+
+// refreshed marks the zone as not expired, after a check found it up to date.
+func (z *Zone) refreshed() {
+	now := time.Now()
+	z.Lock()
+	exp := z.Expired
+	z.Expired = false
+	z.refreshedAt = now
+	z.Unlock()
+	if exp {
+		zoneExpired.WithLabelValues(z.origin).Set(0)
+		log.Infof("Zone `%s' is up to date again", z.origin)
+	}
+
+	if z.Persist != "" {
+		if err := z.touch(now); err != nil {
+			log.Warningf("Failed to record refresh of `%s' in %s: %v", z.origin, z.Persist, err)
+		}
+	}
+}
+
+// applyIXFR returns a copy of z with the IXFR response rrs applied to it, or nil if z is up to date.
+// A response can also hold the entire zone, like an AXFR.
+func (z *Zone) applyIXFR(rrs []dns.RR) (*Zone, error) {
+	if len(rrs) == 0 {
+		return nil, errNoIXFR
+	}
+	soa, ok := rrs[0].(*dns.SOA)
+	if !ok {
+		return nil, errNoIXFR
+	}
+
+	z.RLock()
+	defer z.RUnlock()
+
+	if len(rrs) == 1 {
+		// Either we're up to date, or the primary wants us to do an AXFR.
+		if !less(z.Apex.SOA.Serial, soa.Serial) {
+			return nil, nil
+		}
+		return nil, errNoIXFR
+	}
+
+	z1 := z.CopyWithoutApex()
+	if _, ok := rrs[1].(*dns.SOA); !ok {
+		for _, rr := range rrs[:len(rrs)-1] {
+			if err := z1.Insert(rr); err != nil {
+				return nil, err
+			}
+		}
+		return z1, nil
+	}
+
+	z1 = z.clone()
+
+	// Each difference sequence is the old SOA, the deleted records, the new SOA and the added records.
+	deleting := false
+	for _, rr := range rrs[1 : len(rrs)-1] {
+		if _, ok := rr.(*dns.SOA); ok {
+			deleting = !deleting
+			continue
+		}
+		if deleting {
+			z1.deleteRR(rr)
+			continue
+		}
+		if err := z1.Insert(rr); err != nil {
+			return nil, err
+		}
+	}
+	z1.Insert(soa)
+	return z1, nil
+}
+
+// deleteRR deletes the single record rr from z.
+func (z *Zone) deleteRR(rr dns.RR) {
+	rr.Header().Name = strings.ToLower(rr.Header().Name)
+	if rr.Header().Name == z.origin {
+		switch rr.Header().Rrtype {
+		case dns.TypeSOA:
+			return
+		case dns.TypeNS:
+			z.Apex.NS = deleteFrom(z.Apex.NS, rr)
+			return
+		case dns.TypeRRSIG:
+			switch rr.(*dns.RRSIG).TypeCovered {
+			case dns.TypeSOA:
+				z.Apex.SIGSOA = deleteFrom(z.Apex.SIGSOA, rr)
+				return
+			case dns.TypeNS:
+				z.Apex.SIGNS = deleteFrom(z.Apex.SIGNS, rr)
+				return
+			}
+		}
+	}
+
+	e, ok := z.Tree.Search(rr.Header().Name)
+	if !ok {
+		return
+	}
+	rest := deleteFrom(e.Type(rr.Header().Rrtype), rr)
+	// Tree.Delete removes the entire RRset, put back what's left.
+	z.Tree.Delete(rr)
+	for _, r := range rest {
+		z.Tree.Insert(r)
+	}
+}
+
+// deleteFrom returns a copy of rrs without rr.
+func deleteFrom(rrs []dns.RR, rr dns.RR) []dns.RR {
+	rest := make([]dns.RR, 0, len(rrs))
+	for _, r := range rrs {
+		if !dns.IsDuplicate(r, rr) {
+			rest = append(rest, r)
+		}
+	}
+	return rest
+}
+
 // shouldTransfer checks the primaries of zone, retrieves the SOA record, checks the current serial
 // and the remote serial and will return true if the remote one is higher than the locally configured one.
+// An error is returned if none of the primaries returned a SOA record.
 func (z *Zone) shouldTransfer() (bool, error) {
+	primaries, err := z.primaries.order(z.TransferFrom)
+	if err != nil {
+		return false, err
+	}
+
 	c := new(dns.Client)
 	c.Net = "tcp" // do this query over TCP to minimize spoofing
 	m := new(dns.Msg)
 	m.SetQuestion(z.origin, dns.TypeSOA)
 
-	var Err error
+	Err := errors.New("no primaries")
 	serial := -1
 
 Transfer:
-	for _, tr := range z.TransferFrom {
-		Err = nil
+	for _, tr := range primaries {
 		ret, _, err := c.Exchange(m, tr)
-		if err != nil || ret.Rcode != dns.RcodeSuccess {
+		if err != nil {
 			Err = err
+			z.failed(tr)
+			continue
+		}
+		if ret.Rcode != dns.RcodeSuccess {
+			Err = fmt.Errorf("rcode %s from %s", dns.RcodeToString[ret.Rcode], tr)
+			z.failed(tr)
 			continue
 		}
 		for _, a := range ret.Answer {
 			if a.Header().Rrtype == dns.TypeSOA {
 				serial = int(a.(*dns.SOA).Serial)
+				z.primaries.ok(tr)
 				break Transfer
 			}
 		}
+		Err = fmt.Errorf("no SOA record from %s", tr)
+		z.failed(tr)
 	}
 	if serial == -1 {
 		return false, Err
 	}
-	if z.Apex.SOA == nil {
-		return true, Err
+
+	z.RLock()
+	soa := z.Apex.SOA
+	z.RUnlock()
+	if soa == nil {
+		return true, nil
 	}
-	return less(z.Apex.SOA.Serial, uint32(serial)), Err
+	return less(soa.Serial, uint32(serial)), nil
 }
 
 // less returns true of a is smaller than b when taking RFC 1982 serial arithmetic into account.
@@ -103,91 +368,136 @@ func less(a, b uint32) bool {
 	return (a - b) > MaxSerialIncrement
 }
 
-// Update updates the secondary zone according to its SOA. It will run for the life time of the server
+// Notify makes Update check the primaries for a new serial now, as a NOTIFY was received. It doesn't
+// block; if a check is already pending, it does nothing.
+func (z *Zone) Notify() {
+	select {
+	case z.notify <- struct{}{}:
+	default:
+	}
+}
+
+// TransferInRetry transfers the zone from its primaries, retrying with a backoff until a transfer
+// succeeds. It returns false if the zone was shut down before that.
+func (z *Zone) TransferInRetry() bool {
+	dur := time.Millisecond * 250
+	step := time.Duration(2)
+	max := time.Second * 10
+	for {
+		err := z.TransferIn()
+		if err == nil {
+			return true
+		}
+		log.Warningf("All '%s' primaries failed to transfer, retrying in %s: %s", z.origin, dur.String(), err)
+		select {
+		case <-time.After(dur):
+		case <-z.shutdown:
+			return false
+		}
+		dur = step * dur
+		if dur > max {
+			dur = max
+		}
+	}
+}
+
+// Update updates the secondary zone according to its SOA. It will run until the zone is shut down
 // and uses the SOA parameters. Every refresh it will check for a new SOA number. If that fails (for all
-// server) it will retry every retry interval. If the zone failed to transfer before the expire, the zone
-// will be marked expired.
+// server) it will retry every retry interval. If the zone could not be refreshed before the expire,
+// the zone will be marked expired. A NOTIFY triggers a check right away.
 func (z *Zone) Update() error {
 	// If we don't have a SOA, we don't have a zone, wait for it to appear.
-	for z.Apex.SOA == nil {
-		time.Sleep(1 * time.Second)
+	for z.soa() == nil {
+		select {
+		case <-time.After(1 * time.Second):
+		case <-z.shutdown:
+			return nil
+		}
 	}
 	retryActive := false
 
 Restart:
-	refresh := time.Second * time.Duration(z.Apex.SOA.Refresh)
-	retry := time.Second * time.Duration(z.Apex.SOA.Retry)
-	expire := time.Second * time.Duration(z.Apex.SOA.Expire)
+	soa := z.soa()
+	refresh := time.Second * time.Duration(soa.Refresh)
+	retry := time.Second * time.Duration(soa.Retry)
+	expire := time.Second * time.Duration(soa.Expire)
+
+	// Refresh and expire count from the last refresh, which may be before the start of the server
+	// if the zone was loaded from disk.
+	z.RLock()
+	last := z.refreshedAt
+	z.RUnlock()
+	if last.IsZero() {
+		last = time.Now()
+	}
 
-	refreshTicker := time.NewTicker(refresh)
+	refreshTimer := time.NewTimer(time.Until(last.Add(refresh)))
 	retryTicker := time.NewTicker(retry)
-	expireTicker := time.NewTicker(expire)
+	expireAt := last.Add(expire)
+	expireTimer := time.NewTimer(time.Until(expireAt))
 
 	for {
+		var check bool
 		select {
-		case <-expireTicker.C:
-			if !retryActive {
-				break
-			}
-			z.Expired = true
+		case <-z.shutdown:
+			refreshTimer.Stop()
+			retryTicker.Stop()
+			expireTimer.Stop()
+			return nil
+
+		case <-expireTimer.C:
+			z.expire()
+
+		case <-z.notify:
+			log.Infof("Notify for %s: checking transfer", z.origin)
+			check = true
 
 		case <-retryTicker.C:
 			if !retryActive {
 				break
 			}
-
 			time.Sleep(jitter(2000)) // 2s randomize
+			check = true
 
-			ok, err := z.shouldTransfer()
-			if err != nil {
-				log.Warningf("Failed retry check %s", err)
-				continue
-			}
-
-			if ok {
-				if err := z.TransferIn(); err != nil {
-					// transfer failed, leave retryActive true
-					break
-				}
-			}
-
-			// no errors, stop timers and restart
-			retryActive = false
-			refreshTicker.Stop()
-			retryTicker.Stop()
-			expireTicker.Stop()
-			goto Restart
-
-		case <-refreshTicker.C:
-
+		case <-refreshTimer.C:
 			time.Sleep(jitter(5000)) // 5s randomize
+			check = true
+		}
+		if !check {
+			continue
+		}
 
-			ok, err := z.shouldTransfer()
-			if err != nil {
-				log.Warningf("Failed refresh check %s", err)
-				retryActive = true
-				continue
-			}
-
-			if ok {
-				if err := z.TransferIn(); err != nil {
-					// transfer failed
-					retryActive = true
-					break
-				}
+		ok, err := z.shouldTransfer()
+		if err == nil && ok {
+			err = z.TransferIn()
+		}
+		if err != nil {
+			log.Warningf("Failed refresh of %s: %s", z.origin, err)
+			retryActive = true
+			// The timer may have fired while we were waiting for the jitter.
+			if time.Now().After(expireAt) {
+				z.expire()
 			}
-
-			// no errors, stop timers and restart
-			retryActive = false
-			refreshTicker.Stop()
-			retryTicker.Stop()
-			expireTicker.Stop()
-			goto Restart
-
+			continue
 		}
+		z.refreshed()
+
+		// no errors, stop timers and restart
+		retryActive = false
+		refreshTimer.Stop()
+		retryTicker.Stop()
+		expireTimer.Stop()
+		goto Restart
 	}
 }
 
+// soa returns the SOA record of the zone, or nil if there is none.
+func (z *Zone) soa() *dns.SOA {
+	z.RLock()
+	defer z.RUnlock()
+	return z.Apex.SOA
+}
+
 // jitter returns a random duration between [0,n) * time.Millisecond
 func jitter(n int) time.Duration {
 	r := rand.Intn(n)
@@ -3,6 +3,7 @@ package file
 import (
 	"fmt"
 	"testing"
+	"time"
 
 	"github.com/coredns/coredns/plugin/pkg/dnstest"
 	"github.com/coredns/coredns/plugin/test"
@@ -144,3 +145,184 @@ func newRequest(zone string, qtype uint16) request.Request {
 	m.SetEdns0(4097, true)
 	return request.Request{W: &test.ResponseWriter{}, Req: m}
 }
+
+func TestApplyIXFR(t *testing.T) {
+	z := NewZone(testZone, "stdin")
+	for _, rr := range []dns.RR{
+		test.SOA(fmt.Sprintf("%s IN SOA bla. bla. 1 0 0 0 0", testZone)),
+		test.NS(fmt.Sprintf("%s IN NS ns1.%s", testZone, testZone)),
+		test.A(fmt.Sprintf("a.%s IN A 127.0.0.1", testZone)),
+		test.A(fmt.Sprintf("a.%s IN A 127.0.0.2", testZone)),
+		test.A(fmt.Sprintf("b.%s IN A 127.0.0.3", testZone)),
+	} {
+		z.Insert(rr)
+	}
+
+	// Up to date.
+	z1, err := z.applyIXFR([]dns.RR{test.SOA(fmt.Sprintf("%s IN SOA bla. bla. 1 0 0 0 0", testZone))})
+	if err != nil || z1 != nil {
+		t.Fatalf("Expected no changes, got %v, %v", z1, err)
+	}
+	// Newer serial, but no differences: the primary wants an AXFR.
+	if _, err := z.applyIXFR([]dns.RR{test.SOA(fmt.Sprintf("%s IN SOA bla. bla. 2 0 0 0 0", testZone))}); err != errNoIXFR {
+		t.Fatalf("Expected %v, got %v", errNoIXFR, err)
+	}
+
+	z1, err = z.applyIXFR([]dns.RR{
+		test.SOA(fmt.Sprintf("%s IN SOA bla. bla. 3 0 0 0 0", testZone)),
+		test.SOA(fmt.Sprintf("%s IN SOA bla. bla. 1 0 0 0 0", testZone)),
+		test.A(fmt.Sprintf("a.%s IN A 127.0.0.2", testZone)),
+		test.SOA(fmt.Sprintf("%s IN SOA bla. bla. 2 0 0 0 0", testZone)),
+		test.NS(fmt.Sprintf("%s IN NS ns2.%s", testZone, testZone)),
+		test.SOA(fmt.Sprintf("%s IN SOA bla. bla. 2 0 0 0 0", testZone)),
+		test.A(fmt.Sprintf("b.%s IN A 127.0.0.3", testZone)),
+		test.SOA(fmt.Sprintf("%s IN SOA bla. bla. 3 0 0 0 0", testZone)),
+		test.A(fmt.Sprintf("c.%s IN A 127.0.0.4", testZone)),
+		test.SOA(fmt.Sprintf("%s IN SOA bla. bla. 3 0 0 0 0", testZone)),
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if z1.Apex.SOA.Serial != 3 {
+		t.Errorf("Expected serial 3, got %d", z1.Apex.SOA.Serial)
+	}
+	if len(z1.Apex.NS) != 2 {
+		t.Errorf("Expected 2 NS records, got %d", len(z1.Apex.NS))
+	}
+	if e, ok := z1.Tree.Search("a." + testZone); !ok || len(e.Type(dns.TypeA)) != 1 {
+		t.Errorf("Expected a single A record for a.%s", testZone)
+	}
+	if _, ok := z1.Tree.Search("b." + testZone); ok {
+		t.Errorf("Expected b.%s to be deleted", testZone)
+	}
+	if _, ok := z1.Tree.Search("c." + testZone); !ok {
+		t.Errorf("Expected c.%s to be added", testZone)
+	}
+	// The original zone is left alone.
+	if e, ok := z.Tree.Search("a." + testZone); !ok || len(e.Type(dns.TypeA)) != 2 {
+		t.Errorf("Expected the original zone to be unchanged")
+	}
+}
+
+func TestShouldTransferRefused(t *testing.T) {
+	s := dnstest.NewServer(func(w dns.ResponseWriter, r *dns.Msg) {
+		m := new(dns.Msg)
+		m.SetRcode(r, dns.RcodeRefused)
+		w.WriteMsg(m)
+	})
+	defer s.Close()
+
+	z := NewZone(testZone, "stdin")
+	z.TransferFrom = []string{s.Addr}
+	z.Apex.SOA = test.SOA(fmt.Sprintf("%s IN SOA bla. bla. 250 0 0 0 0", testZone))
+
+	// A primary that refuses must not count as a successful refresh.
+	if _, err := z.shouldTransfer(); err == nil {
+		t.Fatal("Expected an error from a refusing primary")
+	}
+	// It is skipped now.
+	if _, err := z.shouldTransfer(); err != errBackoff {
+		t.Fatalf("Expected %v, got %v", errBackoff, err)
+	}
+}
+
+func TestTransferInFailover(t *testing.T) {
+	soa := soa{250}
+	s := dnstest.NewServer(soa.Handler)
+	defer s.Close()
+
+	dead := dnstest.NewServer(soa.Handler)
+	deadAddr := dead.Addr
+	dead.Close()
+
+	z := NewZone(testZone, "stdin")
+	z.TransferFrom = []string{deadAddr, s.Addr}
+	if err := z.TransferIn(); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if z.Apex.SOA == nil || z.Apex.SOA.Serial != 250 {
+		t.Fatalf("Expected the zone with serial 250, got %v", z.Apex.SOA)
+	}
+	primaries, _ := z.primaries.order(z.TransferFrom)
+	if len(primaries) != 1 || primaries[0] != s.Addr {
+		t.Errorf("Expected only %s to be tried next, got %v", s.Addr, primaries)
+	}
+}
+
+func TestUpdateExpire(t *testing.T) {
+	refuse := false
+	s := dnstest.NewServer(func(w dns.ResponseWriter, r *dns.Msg) {
+		m := new(dns.Msg)
+		m.SetReply(r)
+		if refuse {
+			m.Rcode = dns.RcodeRefused
+		} else {
+			m.Answer = []dns.RR{test.SOA(fmt.Sprintf("%s IN SOA bla. bla. 250 1 1 2 0", testZone))}
+		}
+		w.WriteMsg(m)
+	})
+	defer s.Close()
+
+	z := NewZone(testZone, "stdin")
+	z.TransferFrom = []string{s.Addr}
+	z.Apex.SOA = test.SOA(fmt.Sprintf("%s IN SOA bla. bla. 250 1 1 2 0", testZone))
+	refuse = true
+	go z.Update()
+
+	// The refresh check is delayed by up to 5s of jitter.
+	for i := 0; i < 100; i++ {
+		time.Sleep(100 * time.Millisecond)
+		z.RLock()
+		exp := z.Expired
+		z.RUnlock()
+		if exp {
+			return
+		}
+	}
+	t.Fatal("Expected the zone to expire")
+}
+
+func TestNotify(t *testing.T) {
+	z := NewZone(testZone, "stdin")
+	// Notify never blocks.
+	z.Notify()
+	z.Notify()
+	select {
+	case <-z.notify:
+	default:
+		t.Fatal("Expected a pending notify")
+	}
+}
+
+func TestShutdown(t *testing.T) {
+	soa := soa{250}
+	dead := dnstest.NewServer(soa.Handler)
+	deadAddr := dead.Addr
+	dead.Close()
+
+	z := NewZone(testZone, "stdin")
+	z.TransferFrom = []string{deadAddr}
+	retry := make(chan bool)
+	go func() { retry <- z.TransferInRetry() }()
+	update := make(chan struct{})
+	go func() {
+		z.Update()
+		close(update)
+	}()
+
+	z.OnShutdown()
+	z.OnShutdown() // may be called more than once
+	select {
+	case ok := <-retry:
+		if ok {
+			t.Error("Expected the transfer to fail")
+		}
+	case <-time.After(5 * time.Second):
+		t.Error("Expected TransferInRetry to return on shutdown")
+	}
+	select {
+	case <-update:
+	case <-time.After(5 * time.Second):
+		t.Error("Expected Update to return on shutdown")
+	}
+}
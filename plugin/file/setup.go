@@ -2,6 +2,7 @@ package file
 
 import (
 	"errors"
+	"net"
 	"os"
 	"path/filepath"
 	"time"
@@ -9,6 +10,7 @@ import (
 	"github.com/coredns/caddy"
 	"github.com/coredns/coredns/core/dnsserver"
 	"github.com/coredns/coredns/plugin"
+	"github.com/coredns/coredns/plugin/pkg/cidr"
 	"github.com/coredns/coredns/plugin/pkg/upstream"
 	"github.com/coredns/coredns/plugin/transfer"
 )
@@ -75,6 +77,7 @@ func fileParse(c *caddy.Controller) (Zones, error) {
 
 	var openErr error
 	reload := 1 * time.Minute
+	var allowUpdate []*net.IPNet
 
 	for c.Next() {
 		// file db.file [zones...]
@@ -101,6 +104,7 @@ func fileParse(c *caddy.Controller) (Zones, error) {
 				if err != nil {
 					return Zones{}, err
 				}
+				zone.ReplayJournal()
 				z[origins[i]] = zone
 			}
 			names = append(names, origins[i])
@@ -121,6 +125,16 @@ func fileParse(c *caddy.Controller) (Zones, error) {
 			case "upstream":
 				// remove soon
 				c.RemainingArgs()
+			case "update":
+				t := c.RemainingArgs()
+				if len(t) == 0 {
+					return Zones{}, c.ArgErr()
+				}
+				networks, err := cidr.ParseNetworks(t)
+				if err != nil {
+					return Zones{}, c.Err(err.Error())
+				}
+				allowUpdate = networks
 
 			default:
 				return Zones{}, c.Errf("unknown property '%s'", c.Val())
@@ -130,6 +144,7 @@ func fileParse(c *caddy.Controller) (Zones, error) {
 		for i := range origins {
 			z[origins[i]].ReloadInterval = reload
 			z[origins[i]].Upstream = upstream.New()
+			z[origins[i]].AllowUpdate = allowUpdate
 		}
 	}
 
@@ -122,3 +122,50 @@ func TestParseReload(t *testing.T) {
 		}
 	}
 }
+
+func TestParseUpdate(t *testing.T) {
+	name, rm, err := test.TempFile(".", dbMiekNL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rm()
+
+	tests := []struct {
+		input     string
+		shouldErr bool
+		networks  []string
+	}{
+		{`file ` + name + ` example.org.`, false, nil},
+		{`file ` + name + ` example.org. {
+			update 10.0.0.0/8 ::1
+			}`, false, []string{"10.0.0.0/8", "::1/128"}},
+		{`file ` + name + ` example.org. {
+			update
+			}`, true, nil},
+		{`file ` + name + ` example.org. {
+			update 10.0.0.0/33
+			}`, true, nil},
+	}
+
+	for i, tc := range tests {
+		c := caddy.NewTestController("dns", tc.input)
+		z, err := fileParse(c)
+		if err == nil && tc.shouldErr {
+			t.Fatalf("Test %d expected errors, but got no error", i)
+		} else if err != nil && !tc.shouldErr {
+			t.Fatalf("Test %d expected no errors, but got '%v'", i, err)
+		}
+		if tc.shouldErr {
+			continue
+		}
+		networks := z.Z["example.org."].AllowUpdate
+		if len(networks) != len(tc.networks) {
+			t.Fatalf("Test %d expected %v, got %v", i, tc.networks, networks)
+		}
+		for j, n := range networks {
+			if n.String() != tc.networks[j] {
+				t.Errorf("Test %d expected network %s, got %s", i, tc.networks[j], n)
+			}
+		}
+	}
+}
@@ -5,5 +5,6 @@ func (z *Zone) OnShutdown() error {
 	if 0 < z.ReloadInterval {
 		z.reloadShutdown <- true
 	}
+	z.shutdownOnce.Do(func() { close(z.shutdown) })
 	return nil
 }
@@ -0,0 +1,294 @@
+package file
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/miekg/dns"
+	"gopkg.in/yaml.v2"
+)
+
+// ZoneParser returns the records of a zone file one by one, see dns.ZoneParser.
+type ZoneParser interface {
+	Next() (dns.RR, bool)
+	Err() error
+}
+
+// NewZoneParser returns a parser for the zone in f. Zone files with a .json, .yaml or .yml extension
+// are in the structured format, which is parsed completely before returning, all other files are in
+// the RFC 1035 format and may include other files.
+func NewZoneParser(f io.Reader, origin, fileName string) (ZoneParser, error) {
+	if !Structured(fileName) {
+		zp := dns.NewZoneParser(f, dns.Fqdn(origin), fileName)
+		zp.SetIncludeAllowed(true)
+		return zp, nil
+	}
+	rrs, err := parseStructured(f, dns.Fqdn(origin), fileName)
+	if err != nil {
+		return nil, err
+	}
+	return &rrParser{rrs: rrs}, nil
+}
+
+// Structured returns true if fileName has the extension of a zone file in the structured format.
+func Structured(fileName string) bool {
+	switch strings.ToLower(filepath.Ext(fileName)) {
+	case ".json", ".yaml", ".yml":
+		return true
+	}
+	return false
+}
+
+// rrParser is a ZoneParser for records that have been parsed already.
+type rrParser struct {
+	rrs []dns.RR
+}
+
+func (p *rrParser) Next() (dns.RR, bool) {
+	if len(p.rrs) == 0 {
+		return nil, false
+	}
+	rr := p.rrs[0]
+	p.rrs = p.rrs[1:]
+	return rr, true
+}
+
+func (p *rrParser) Err() error { return nil }
+
+// structuredZone is a zone in the structured format, see the README for its schema.
+type structuredZone struct {
+	Origin  string             `json:"origin,omitempty" yaml:"origin,omitempty"`
+	TTL     *uint32            `json:"ttl,omitempty" yaml:"ttl,omitempty"`
+	Records []structuredRecord `json:"records" yaml:"records"`
+}
+
+type structuredRecord struct {
+	Name  string  `json:"name" yaml:"name"`
+	TTL   *uint32 `json:"ttl,omitempty" yaml:"ttl,omitempty"`
+	Class string  `json:"class,omitempty" yaml:"class,omitempty"`
+	Type  string  `json:"type" yaml:"type"`
+	Data  rdata   `json:"data" yaml:"data"`
+}
+
+// rdata is the data of one or more records, in the presentation format. It is either a string or a
+// list of strings. Any other value is an error, which is reported with the path of the record.
+type rdata struct {
+	values []string
+	err    error
+}
+
+var errRdata = errors.New("must be a string or a list of strings")
+
+func (d *rdata) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err == nil {
+		d.values = []string{s}
+		return nil
+	}
+	if err := json.Unmarshal(b, &d.values); err != nil {
+		d.err = errRdata
+	}
+	return nil
+}
+
+func (d *rdata) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var s string
+	if err := unmarshal(&s); err == nil {
+		d.values = []string{s}
+		return nil
+	}
+	if err := unmarshal(&d.values); err != nil {
+		d.err = errRdata
+	}
+	return nil
+}
+
+func (d rdata) MarshalJSON() ([]byte, error) {
+	if len(d.values) == 1 {
+		return json.Marshal(d.values[0])
+	}
+	return json.Marshal(d.values)
+}
+
+func (d rdata) MarshalYAML() (interface{}, error) {
+	if len(d.values) == 1 {
+		return d.values[0], nil
+	}
+	return d.values, nil
+}
+
+// parseStructured parses the zone in the structured format in f, and returns its records. Errors
+// cite the path of the entry that is wrong, e.g. records[2].data[1].
+func parseStructured(f io.Reader, origin, fileName string) ([]dns.RR, error) {
+	b, err := io.ReadAll(f)
+	if err != nil {
+		return nil, err
+	}
+
+	sz := structuredZone{}
+	if strings.ToLower(filepath.Ext(fileName)) == ".json" {
+		dec := json.NewDecoder(strings.NewReader(string(b)))
+		dec.DisallowUnknownFields()
+		err = dec.Decode(&sz)
+	} else {
+		err = yaml.UnmarshalStrict(b, &sz)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", fileName, err)
+	}
+
+	if sz.Origin != "" && !strings.EqualFold(dns.Fqdn(sz.Origin), origin) {
+		return nil, fmt.Errorf("%s: origin: %q is not the origin of the zone %q", fileName, sz.Origin, origin)
+	}
+	ttl := uint32(3600)
+	if sz.TTL != nil {
+		ttl = *sz.TTL
+	}
+
+	var rrs []dns.RR
+	for i, r := range sz.Records {
+		path := "records[" + strconv.Itoa(i) + "]"
+		records, err := r.parse(origin, ttl)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s%v", fileName, path, err)
+		}
+		rrs = append(rrs, records...)
+	}
+	return rrs, nil
+}
+
+// parse returns the records of r, errors start with the path of the entry in r that is wrong.
+func (r structuredRecord) parse(origin string, ttl uint32) ([]dns.RR, error) {
+	if r.Name == "" {
+		return nil, fmt.Errorf(".name: missing")
+	}
+	if r.TTL != nil {
+		ttl = *r.TTL
+	}
+	class := uint16(dns.ClassINET)
+	if r.Class != "" {
+		c, ok := dns.StringToClass[strings.ToUpper(r.Class)]
+		if !ok {
+			return nil, fmt.Errorf(".class: unknown class %q", r.Class)
+		}
+		class = c
+	}
+	if r.Type == "" {
+		return nil, fmt.Errorf(".type: missing")
+	}
+	typ, ok := dns.StringToType[strings.ToUpper(r.Type)]
+	if !ok {
+		return nil, fmt.Errorf(".type: unknown type %q", r.Type)
+	}
+	if r.Data.err != nil {
+		return nil, fmt.Errorf(".data: %v", r.Data.err)
+	}
+	if len(r.Data.values) == 0 {
+		return nil, fmt.Errorf(".data: missing")
+	}
+
+	if strings.ContainsAny(r.Name, " \t\r\n;()") {
+		return nil, fmt.Errorf(".name: invalid name %q", r.Name)
+	}
+
+	rrs := make([]dns.RR, len(r.Data.values))
+	for i, data := range r.Data.values {
+		if typ == dns.TypeTXT || typ == dns.TypeSPF {
+			data = quote(data)
+		}
+		line := fmt.Sprintf("%s %d %s %s %s", r.Name, ttl, dns.ClassToString[class], dns.TypeToString[typ], data)
+		rr, err := parseRR(line, origin)
+		if err != nil {
+			return nil, fmt.Errorf(".data[%d]: %v", i, err)
+		}
+		if !dns.IsSubDomain(origin, rr.Header().Name) {
+			return nil, fmt.Errorf(".name: %q is not in the zone %q", r.Name, origin)
+		}
+		rrs[i] = rr
+	}
+	return rrs, nil
+}
+
+// parseRR parses line, which must hold a single record, relative names are relative to origin.
+func parseRR(line, origin string) (dns.RR, error) {
+	zp := dns.NewZoneParser(strings.NewReader(line), origin, "")
+	rr, ok := zp.Next()
+	if err := zp.Err(); err != nil {
+		// The line and column are those of the line made from the entry, leave them out.
+		msg := strings.TrimPrefix(err.Error(), "dns: ")
+		if i := strings.LastIndex(msg, " at line: "); i > 0 {
+			msg = msg[:i]
+		}
+		return nil, errors.New(msg)
+	}
+	if !ok {
+		return nil, fmt.Errorf("no record")
+	}
+	if _, ok := zp.Next(); ok {
+		return nil, fmt.Errorf("more than one record")
+	}
+	return rr, nil
+}
+
+// writeStructured writes rrs to w in the structured format, as JSON if asJSON is true and as YAML
+// otherwise. Records of the same RRset that follow each other are written as one entry.
+func writeStructured(w io.Writer, origin string, rrs []dns.RR, asJSON bool) error {
+	sz := structuredZone{Origin: origin}
+	for _, rr := range rrs {
+		hdr := rr.Header()
+		data := strings.TrimPrefix(rr.String(), hdr.String())
+		if n := len(sz.Records); n > 0 {
+			last := &sz.Records[n-1]
+			if last.Name == hdr.Name && *last.TTL == hdr.Ttl && last.Type == dns.TypeToString[hdr.Rrtype] && last.Class == "" {
+				last.Data.values = append(last.Data.values, data)
+				continue
+			}
+		}
+		ttl := hdr.Ttl
+		r := structuredRecord{Name: hdr.Name, TTL: &ttl, Type: dns.TypeToString[hdr.Rrtype], Data: rdata{values: []string{data}}}
+		if hdr.Class != dns.ClassINET {
+			r.Class = dns.ClassToString[hdr.Class]
+		}
+		sz.Records = append(sz.Records, r)
+	}
+
+	if asJSON {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(sz)
+	}
+	b, err := yaml.Marshal(sz)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(b)
+	return err
+}
+
+// quote quotes a TXT string, unless it is quoted already. This allows a single string to be given
+// without the quotes and escapes of the presentation format.
+func quote(s string) string {
+	if strings.HasPrefix(s, `"`) {
+		return s
+	}
+	b := strings.Builder{}
+	b.WriteByte('"')
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '"' || c == '\\':
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		case c < ' ' || c == 0x7f:
+			fmt.Fprintf(&b, "\\%03d", c)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
@@ -0,0 +1,274 @@
+package file
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/coredns/coredns/plugin/file/tree"
+	"github.com/coredns/coredns/plugin/pkg/dnstest"
+	"github.com/coredns/coredns/plugin/test"
+	"github.com/coredns/coredns/plugin/transfer"
+
+	"github.com/miekg/dns"
+)
+
+func TestParseStructured(t *testing.T) {
+	expected := records(t, mustParse(t, structuredZone1035, "db.example.org"))
+
+	for _, fileName := range []string{"example.org.yaml", "example.org.yml", "example.org.json", "EXAMPLE.ORG.JSON"} {
+		content := structuredZoneYAML
+		if strings.HasSuffix(strings.ToLower(fileName), ".json") {
+			content = structuredZoneJSON
+		}
+		got := records(t, mustParse(t, content, fileName))
+
+		if len(got) != len(expected) {
+			t.Fatalf("%s: expected %d records, got %d: %v", fileName, len(expected), len(got), got)
+		}
+		for i := range got {
+			if got[i] != expected[i] {
+				t.Errorf("%s: expected record %q, got %q", fileName, expected[i], got[i])
+			}
+		}
+	}
+}
+
+func TestParseStructuredErrors(t *testing.T) {
+	tests := []struct {
+		fileName string
+		content  string
+		expected string
+	}{
+		{"z.yaml", "records:\n- {name: '@', type: A, data: 192.0.2.1}\n", "has no SOA record"},
+		{"z.yaml", "origin: example.net\nrecords: []\n", `z.yaml: origin: "example.net" is not the origin of the zone "example.org."`},
+		{"z.yaml", "records:\n- {name: '@', type: A}\n", "z.yaml: records[0].data: missing"},
+		{"z.yaml", "records:\n- {type: A, data: 192.0.2.1}\n", "z.yaml: records[0].name: missing"},
+		{"z.yaml", "records:\n- {name: 'a b', type: A, data: 192.0.2.1}\n", "z.yaml: records[0].name: invalid name"},
+		{"z.yaml", "records:\n- {name: www, data: 192.0.2.1}\n", "z.yaml: records[0].type: missing"},
+		{"z.yaml", "records:\n- {name: www, type: FOO, data: 192.0.2.1}\n", `z.yaml: records[0].type: unknown type "FOO"`},
+		{"z.yaml", "records:\n- {name: www, class: XX, type: A, data: 192.0.2.1}\n", `z.yaml: records[0].class: unknown class "XX"`},
+		{"z.yaml", "records:\n- {name: www, type: A, data: 192.0.2.1}\n- {name: www, type: A, data: [192.0.2.2, 192.0.2.300]}\n", `z.yaml: records[1].data[1]: bad A A: "192.0.2.300"`},
+		{"z.yaml", "records:\n- {name: www.example.net., type: A, data: 192.0.2.1}\n", `z.yaml: records[0].name: "www.example.net." is not in the zone "example.org."`},
+		{"z.yaml", "records:\n- {name: www, type: A, data: \"192.0.2.1\\nwww A 192.0.2.2\"}\n", "z.yaml: records[0].data[0]: more than one record"},
+		{"z.yaml", "records:\n- {name: www, type: A, data: {ip: 192.0.2.1}}\n", "z.yaml: records[0].data: must be a string or a list of strings"},
+		{"z.json", `{"records": [{"name": "www", "type": "A", "data": 1}]}`, "z.json: records[0].data: must be a string or a list of strings"},
+		{"z.yaml", "records:\n- {name: www, type: A, data: 192.0.2.1, weight: 1}\n", "field weight not found"},
+		{"z.json", `{"records": [{"name": "www", "type": "A", "data": "192.0.2.1", "weight": 1}]}`, `json: unknown field "weight"`},
+		{"z.json", `{"records": [{"name": "www", "type": "MX", "data": ["10 mx1", "mx2"]}]}`, `z.json: records[0].data[1]: bad MX Pref: "mx2"`},
+	}
+
+	for i, tc := range tests {
+		_, err := Parse(strings.NewReader(tc.content), "example.org.", tc.fileName, 0)
+		if err == nil {
+			t.Errorf("Test %d: expected error %q, got none", i, tc.expected)
+			continue
+		}
+		if !strings.Contains(err.Error(), tc.expected) {
+			t.Errorf("Test %d: expected error %q, got %q", i, tc.expected, err)
+		}
+	}
+}
+
+func TestQuote(t *testing.T) {
+	tests := []struct {
+		in       string
+		expected string
+	}{
+		{`v=spf1 -all`, `"v=spf1 -all"`},
+		{`"already" "quoted"`, `"already" "quoted"`},
+		{`say "hi"; \o/`, `"say \"hi\"; \\o/"`},
+		{"tab\there", `"tab\009here"`},
+	}
+	for i, tc := range tests {
+		if got := quote(tc.in); got != tc.expected {
+			t.Errorf("Test %d: expected %s, got %s", i, tc.expected, got)
+		}
+	}
+}
+
+func TestLookupStructured(t *testing.T) {
+	zone := mustParse(t, structuredZoneYAML, "example.org.yaml")
+	fm := File{Next: test.ErrorHandler(), Zones: Zones{Z: map[string]*Zone{"example.org.": zone}, Names: []string{"example.org."}}}
+	ctx := context.TODO()
+
+	tests := []test.Case{
+		{
+			Qname: "a.wild.example.org.", Qtype: dns.TypeTXT,
+			Answer: []dns.RR{test.TXT(`a.wild.example.org. 300 IN TXT "wild \"card\""`)},
+			Ns:     structuredAuth,
+		},
+		{
+			Qname: "example.org.", Qtype: dns.TypeMX,
+			Answer: []dns.RR{
+				test.MX("example.org. 3600 IN MX 10 mx1.example.org."),
+				test.MX("example.org. 3600 IN MX 20 mx2.example.org."),
+			},
+			Ns: structuredAuth,
+		},
+	}
+	for _, tc := range tests {
+		m := tc.Msg()
+		rec := dnstest.NewRecorder(&test.ResponseWriter{})
+		if _, err := fm.ServeDNS(ctx, rec, m); err != nil {
+			t.Errorf("Expected no error, got %v", err)
+			return
+		}
+		if err := test.SortAndCheck(rec.Msg, tc); err != nil {
+			t.Error(err)
+		}
+	}
+}
+
+func TestZoneReloadStructured(t *testing.T) {
+	fileName := filepath.Join(t.TempDir(), "example.org.yaml")
+	if err := os.WriteFile(fileName, []byte(structuredZoneYAML), 0644); err != nil {
+		t.Fatal(err)
+	}
+	reader, err := os.Open(fileName)
+	if err != nil {
+		t.Fatalf("Failed to open zone: %s", err)
+	}
+	z, err := Parse(reader, "example.org.", fileName, 0)
+	reader.Close()
+	if err != nil {
+		t.Fatalf("Failed to parse zone: %s", err)
+	}
+
+	z.ReloadInterval = 10 * time.Millisecond
+	z.Reload(&transfer.Transfer{})
+	defer close(z.reloadShutdown)
+
+	updated := strings.Replace(structuredZoneYAML, "2021010101", "2021010102", 1)
+	updated = strings.Replace(updated, "[192.0.2.1, 192.0.2.2]", "192.0.2.3", 1)
+	if err := os.WriteFile(fileName, []byte(updated), 0644); err != nil {
+		t.Fatalf("Failed to write new zone data: %s", err)
+	}
+	// Could still be racy, but we need to wait a bit for the event to be seen
+	time.Sleep(50 * time.Millisecond)
+
+	if serial := z.SOASerialIfDefined(); serial != 2021010102 {
+		t.Fatalf("Expected serial %d, got %d", 2021010102, serial)
+	}
+	z.RLock()
+	e, _ := z.Tree.Search("www.example.org.")
+	z.RUnlock()
+	if a := e.Type(dns.TypeA); len(a) != 1 || a[0].(*dns.A).A.String() != "192.0.2.3" {
+		t.Errorf("Expected the reloaded A record, got %v", a)
+	}
+}
+
+func TestSaveStructured(t *testing.T) {
+	z := mustParse(t, structuredZoneYAML, "example.org.yaml")
+	expected := records(t, z)
+
+	for _, name := range []string{"example.org.yaml", "example.org.json", "db.example.org"} {
+		path := filepath.Join(t.TempDir(), name)
+		if err := z.saveTo(path, zeroTime); err != nil {
+			t.Fatalf("%s: failed to save zone: %s", name, err)
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := records(t, mustParse(t, string(content), name))
+		if strings.Join(got, "\n") != strings.Join(expected, "\n") {
+			t.Errorf("%s: expected records\n%s\ngot\n%s", name, strings.Join(expected, "\n"), strings.Join(got, "\n"))
+		}
+	}
+}
+
+func mustParse(t *testing.T, content, fileName string) *Zone {
+	t.Helper()
+	z, err := Parse(strings.NewReader(content), "example.org.", fileName, 0)
+	if err != nil {
+		t.Fatalf("Failed to parse %s: %s", fileName, err)
+	}
+	return z
+}
+
+// records returns the records of z in presentation format, sorted.
+func records(t *testing.T, z *Zone) []string {
+	t.Helper()
+	rrs := []string{z.Apex.SOA.String()}
+	for _, rr := range z.Apex.NS {
+		rrs = append(rrs, rr.String())
+	}
+	z.Walk(func(e *tree.Elem, _ map[uint16][]dns.RR) error {
+		for _, rr := range e.All() {
+			rrs = append(rrs, rr.String())
+		}
+		return nil
+	})
+	sort.Strings(rrs)
+	return rrs
+}
+
+var structuredAuth = []dns.RR{
+	test.NS("example.org. 3600 IN NS ns1.example.org."),
+	test.NS("example.org. 3600 IN NS ns2.example.org."),
+}
+
+const structuredZone1035 = `$TTL 3600
+@           IN SOA   ns1 hostmaster 2021010101 7200 3600 1209600 3600
+@           IN NS    ns1
+@           IN NS    ns2
+@           IN MX    10 mx1
+@           IN MX    20 mx2
+ns1         IN A     192.0.2.53
+ns2         IN AAAA  2001:db8::53
+www         IN A     192.0.2.1
+www         IN A     192.0.2.2
+web     300 IN CNAME www
+_sip._udp   IN SRV   10 60 5060 sip.example.org.
+*.wild  300 IN TXT   "wild \"card\""
+@           IN TXT   "v=spf1 mx -all"
+@           IN CAA   0 issue "letsencrypt.org"
+`
+
+const structuredZoneYAML = `origin: example.org.
+ttl: 3600
+records:
+  - name: "@"
+    type: SOA
+    data: ns1 hostmaster 2021010101 7200 3600 1209600 3600
+  - name: "@"
+    type: NS
+    data: [ns1, ns2]
+  - name: "@"
+    type: MX
+    data:
+      - 10 mx1
+      - 20 mx2
+  - {name: ns1, type: A, data: 192.0.2.53}
+  - {name: ns2, type: AAAA, data: "2001:db8::53"}
+  - name: www
+    type: A
+    data: [192.0.2.1, 192.0.2.2]
+  - {name: web, ttl: 300, type: CNAME, data: www}
+  - {name: _sip._udp, type: SRV, data: 10 60 5060 sip.example.org.}
+  - {name: "*.wild", ttl: 300, class: IN, type: TXT, data: 'wild "card"'}
+  - {name: example.org., type: txt, data: v=spf1 mx -all}
+  - {name: "@", type: CAA, data: 0 issue "letsencrypt.org"}
+`
+
+const structuredZoneJSON = `{
+  "origin": "example.org.",
+  "records": [
+    {"name": "@", "type": "SOA", "data": "ns1 hostmaster 2021010101 7200 3600 1209600 3600"},
+    {"name": "@", "type": "NS", "data": ["ns1", "ns2"]},
+    {"name": "@", "type": "MX", "data": ["10 mx1", "20 mx2"]},
+    {"name": "ns1", "type": "A", "data": "192.0.2.53"},
+    {"name": "ns2", "type": "AAAA", "data": "2001:db8::53"},
+    {"name": "www", "type": "A", "data": ["192.0.2.1", "192.0.2.2"]},
+    {"name": "web", "ttl": 300, "type": "CNAME", "data": "www"},
+    {"name": "_sip._udp", "type": "SRV", "data": "10 60 5060 sip.example.org."},
+    {"name": "*.wild", "ttl": 300, "class": "IN", "type": "TXT", "data": "wild \"card\""},
+    {"name": "example.org.", "type": "TXT", "data": "v=spf1 mx -all"},
+    {"name": "@", "type": "CAA", "data": "0 issue \"letsencrypt.org\""}
+  ]
+}`
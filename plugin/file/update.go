@@ -0,0 +1,454 @@
+package file
+
+import (
+	"net"
+	"strings"
+
+	"github.com/coredns/coredns/plugin/file/tree"
+	"github.com/coredns/coredns/plugin/transfer"
+	"github.com/coredns/coredns/request"
+
+	"github.com/miekg/dns"
+)
+
+// DynamicUpdate applies the dynamic update (RFC 2136) in state to the zone and returns the reply. If
+// the zone changed, its serial is increased, the change is written to the journal of the zone and the
+// secondaries are notified through t, which may be nil.
+func (z *Zone) DynamicUpdate(state request.Request, t *transfer.Transfer) *dns.Msg {
+	m := new(dns.Msg)
+	m.SetReply(state.Req)
+
+	rcode, changed := z.update(state)
+	m.Rcode = rcode
+	if rcode != dns.RcodeSuccess {
+		log.Infof("Update from %s for %s: %s", state.IP(), z.origin, dns.RcodeToString[rcode])
+		return m
+	}
+	if !changed {
+		return m
+	}
+
+	serial := z.SOASerialIfDefined()
+	log.Infof("Update from %s for %s: applied, serial is now %d", state.IP(), z.origin, serial)
+	go func() {
+		if err := t.Notify(z.origin); err != nil {
+			log.Warningf("Failed sending notifies: %s", err)
+		}
+	}()
+	return m
+}
+
+// allowUpdate returns true if ip may send dynamic updates.
+func (z *Zone) allowUpdate(ip net.IP) bool {
+	for _, n := range z.AllowUpdate {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// update checks and applies the update in state, it returns the rcode of the reply and whether the
+// zone changed.
+func (z *Zone) update(state request.Request) (int, bool) {
+	if !z.allowUpdate(net.ParseIP(state.IP())) {
+		return dns.RcodeRefused, false
+	}
+
+	r := state.Req
+	// Zone section (RFC 2136 section 3.1).
+	if len(r.Question) != 1 || r.Question[0].Qtype != dns.TypeSOA || r.Question[0].Qclass != dns.ClassINET {
+		return dns.RcodeFormatError, false
+	}
+	if strings.ToLower(dns.Fqdn(r.Question[0].Name)) != z.origin {
+		return dns.RcodeNotAuth, false
+	}
+
+	// Updates are applied one at a time, to a copy of the apex and the names they change. Only
+	// updates and reloads change the zone, so it doesn't change while the lock is held.
+	z.updateMu.Lock()
+	defer z.updateMu.Unlock()
+
+	names := updateNames(r.Ns)
+	z.RLock()
+	rcode := dns.RcodeServerFailure
+	if z.Apex.SOA != nil {
+		// Prerequisite section (RFC 2136 section 3.2).
+		rcode = z.checkPrerequisites(r.Answer)
+	}
+	z1 := z.partial(names)
+	z.RUnlock()
+	if rcode != dns.RcodeSuccess {
+		return rcode, false
+	}
+
+	// Update section prescan (RFC 2136 section 3.4.1).
+	for _, rr := range r.Ns {
+		if rcode := z.prescan(rr); rcode != dns.RcodeSuccess {
+			return rcode, false
+		}
+	}
+
+	changed := false
+	serialSet := false
+	for _, rr := range r.Ns {
+		rr = dns.Copy(rr)
+		rr.Header().Name = strings.ToLower(rr.Header().Name)
+		if soa, ok := rr.(*dns.SOA); ok && rr.Header().Class == dns.ClassINET {
+			if !less(z1.Apex.SOA.Serial, soa.Serial) {
+				continue
+			}
+			serialSet = true
+		}
+		if z1.applyUpdate(rr) {
+			changed = true
+		}
+	}
+	if !changed {
+		return dns.RcodeSuccess, false
+	}
+
+	if !serialSet {
+		soa := dns.Copy(z1.Apex.SOA).(*dns.SOA)
+		soa.Serial++
+		z1.Apex.SOA = soa
+	}
+
+	// The change is only made live once it is in the journal.
+	z.RLock()
+	entry := z.journalEntry(z1, names)
+	z.RUnlock()
+	if err := appendJournal(z.journal(), entry); err != nil {
+		log.Errorf("Failed to write the journal of %s: %v", z.origin, err)
+		return dns.RcodeServerFailure, false
+	}
+
+	z.Lock()
+	z.replace(z1, names)
+	z.Unlock()
+	return dns.RcodeSuccess, true
+}
+
+// checkPrerequisites checks the prerequisites in rrs against z (RFC 2136 section 3.2).
+func (z *Zone) checkPrerequisites(rrs []dns.RR) int {
+	// RRsets that must exist with these exact records, value dependent.
+	type key struct {
+		name  string
+		rtype uint16
+	}
+	exact := map[key][]dns.RR{}
+
+	for _, rr := range rrs {
+		hdr := rr.Header()
+		name := strings.ToLower(hdr.Name)
+		if hdr.Ttl != 0 {
+			return dns.RcodeFormatError
+		}
+		if !dns.IsSubDomain(z.origin, name) {
+			return dns.RcodeNotZone
+		}
+		empty := isEmpty(rr)
+
+		switch hdr.Class {
+		case dns.ClassANY:
+			if !empty {
+				return dns.RcodeFormatError
+			}
+			if hdr.Rrtype == dns.TypeANY {
+				if !z.nameInUse(name) {
+					return dns.RcodeNameError
+				}
+				continue
+			}
+			if len(z.rrset(name, hdr.Rrtype)) == 0 {
+				return dns.RcodeNXRrset
+			}
+		case dns.ClassNONE:
+			if !empty {
+				return dns.RcodeFormatError
+			}
+			if hdr.Rrtype == dns.TypeANY {
+				if z.nameInUse(name) {
+					return dns.RcodeYXDomain
+				}
+				continue
+			}
+			if len(z.rrset(name, hdr.Rrtype)) > 0 {
+				return dns.RcodeYXRrset
+			}
+		case dns.ClassINET:
+			k := key{name, hdr.Rrtype}
+			exact[k] = append(exact[k], rr)
+		default:
+			return dns.RcodeFormatError
+		}
+	}
+
+	for k, want := range exact {
+		if !sameRRset(z.rrset(k.name, k.rtype), want) {
+			return dns.RcodeNXRrset
+		}
+	}
+	return dns.RcodeSuccess
+}
+
+// prescan checks a record from the update section (RFC 2136 section 3.4.1).
+func (z *Zone) prescan(rr dns.RR) int {
+	hdr := rr.Header()
+	if !dns.IsSubDomain(z.origin, strings.ToLower(hdr.Name)) {
+		return dns.RcodeNotZone
+	}
+	switch hdr.Rrtype {
+	case dns.TypeAXFR, dns.TypeIXFR, dns.TypeMAILA, dns.TypeMAILB, dns.TypeOPT, dns.TypeTSIG:
+		return dns.RcodeFormatError
+	case dns.TypeNSEC3, dns.TypeNSEC3PARAM:
+		// Insert doesn't accept these.
+		return dns.RcodeRefused
+	}
+
+	switch hdr.Class {
+	case dns.ClassINET:
+		if hdr.Rrtype == dns.TypeANY {
+			return dns.RcodeFormatError
+		}
+	case dns.ClassANY:
+		if hdr.Ttl != 0 || !isEmpty(rr) {
+			return dns.RcodeFormatError
+		}
+	case dns.ClassNONE:
+		if hdr.Ttl != 0 || hdr.Rrtype == dns.TypeANY {
+			return dns.RcodeFormatError
+		}
+	default:
+		return dns.RcodeFormatError
+	}
+	return dns.RcodeSuccess
+}
+
+// applyUpdate applies a record from the update section to z (RFC 2136 section 3.4.2) and returns
+// true if the zone changed.
+func (z *Zone) applyUpdate(rr dns.RR) bool {
+	hdr := rr.Header()
+	name := hdr.Name
+	apex := name == z.origin
+
+	switch hdr.Class {
+	case dns.ClassINET:
+		if hdr.Rrtype == dns.TypeSOA {
+			if !apex {
+				return false
+			}
+			z.Apex.SOA = rr.(*dns.SOA)
+			return true
+		}
+		// A CNAME can't exist next to other data.
+		if e, ok := z.Tree.Search(name); ok {
+			cname := len(e.Type(dns.TypeCNAME)) > 0
+			if hdr.Rrtype == dns.TypeCNAME && !cname && !e.Empty() {
+				return false
+			}
+			if hdr.Rrtype != dns.TypeCNAME && cname && !isDNSSEC(hdr.Rrtype) {
+				return false
+			}
+		}
+		// A record that already exists has its TTL updated.
+		for _, r := range z.rrset(name, hdr.Rrtype) {
+			if dns.IsDuplicate(r, rr) {
+				if r.Header().Ttl == hdr.Ttl {
+					return false
+				}
+				z.deleteRR(dns.Copy(r))
+				break
+			}
+		}
+		z.Insert(rr)
+		return true
+
+	case dns.ClassANY:
+		if hdr.Rrtype == dns.TypeANY {
+			e, ok := z.Tree.Search(name)
+			if !ok || e.Empty() {
+				return false
+			}
+			// At the apex the SOA and NS records are kept, these are in z.Apex.
+			for _, t := range e.Types() {
+				z.Tree.Delete(&dns.ANY{Hdr: dns.RR_Header{Name: name, Rrtype: t}})
+			}
+			return true
+		}
+		if apex && (hdr.Rrtype == dns.TypeSOA || hdr.Rrtype == dns.TypeNS) {
+			return false
+		}
+		if len(z.rrset(name, hdr.Rrtype)) == 0 {
+			return false
+		}
+		z.Tree.Delete(&dns.ANY{Hdr: dns.RR_Header{Name: name, Rrtype: hdr.Rrtype}})
+		return true
+
+	case dns.ClassNONE:
+		if hdr.Rrtype == dns.TypeSOA {
+			return false
+		}
+		hdr.Class = dns.ClassINET
+		found := false
+		for _, r := range z.rrset(name, hdr.Rrtype) {
+			if dns.IsDuplicate(r, rr) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+		// The last NS record of the zone can't be deleted.
+		if apex && hdr.Rrtype == dns.TypeNS && len(z.Apex.NS) == 1 {
+			return false
+		}
+		z.deleteRR(rr)
+		return true
+	}
+	return false
+}
+
+// rrset returns the records with name and type rtype.
+func (z *Zone) rrset(name string, rtype uint16) []dns.RR {
+	if name == z.origin {
+		switch rtype {
+		case dns.TypeSOA:
+			if z.Apex.SOA == nil {
+				return nil
+			}
+			return []dns.RR{z.Apex.SOA}
+		case dns.TypeNS:
+			return z.Apex.NS
+		}
+	}
+	e, ok := z.Tree.Search(name)
+	if !ok {
+		return nil
+	}
+	rrs := e.Type(rtype)
+	if name == z.origin && rtype == dns.TypeRRSIG {
+		rrs = append(append(append([]dns.RR(nil), rrs...), z.Apex.SIGSOA...), z.Apex.SIGNS...)
+	}
+	return rrs
+}
+
+// nameInUse returns true if name has any records.
+func (z *Zone) nameInUse(name string) bool {
+	if name == z.origin {
+		return true
+	}
+	e, ok := z.Tree.Search(name)
+	return ok && !e.Empty()
+}
+
+// updateNames returns the names that the records in rrs change.
+func updateNames(rrs []dns.RR) []string {
+	seen := map[string]struct{}{}
+	names := []string{}
+	for _, rr := range rrs {
+		name := strings.ToLower(rr.Header().Name)
+		if _, ok := seen[name]; !ok {
+			seen[name] = struct{}{}
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// partial returns a copy of the apex of z and the records of names, that can be changed without
+// changing z. The caller must hold the read lock.
+func (z *Zone) partial(names []string) *Zone {
+	z1 := z.CopyWithoutApex()
+	z1.Apex = z.copyApex()
+	for _, name := range names {
+		if e, ok := z.Tree.Search(name); ok {
+			for _, rr := range e.All() {
+				z1.Tree.Insert(rr)
+			}
+		}
+	}
+	return z1
+}
+
+// replace replaces the apex of z and the records of names with those in z1. The caller must hold the lock.
+func (z *Zone) replace(z1 *Zone, names []string) {
+	z.Apex = z1.Apex
+	for _, name := range names {
+		if e, ok := z.Tree.Search(name); ok {
+			for _, t := range e.Types() {
+				z.Tree.Delete(&dns.ANY{Hdr: dns.RR_Header{Name: name, Rrtype: t}})
+			}
+		}
+		if e, ok := z1.Tree.Search(name); ok {
+			for _, rr := range e.All() {
+				z.Tree.Insert(rr)
+			}
+		}
+	}
+}
+
+// copyApex returns a copy of the apex of z. The caller must hold the read lock.
+func (z *Zone) copyApex() Apex {
+	return Apex{
+		SOA:    z.Apex.SOA,
+		NS:     append([]dns.RR(nil), z.Apex.NS...),
+		SIGSOA: append([]dns.RR(nil), z.Apex.SIGSOA...),
+		SIGNS:  append([]dns.RR(nil), z.Apex.SIGNS...),
+	}
+}
+
+// clone returns a copy of z that can be changed without changing z. The caller must hold the read lock.
+func (z *Zone) clone() *Zone {
+	z1 := z.CopyWithoutApex()
+	z1.Apex = z.copyApex()
+	z.Tree.Walk(func(e *tree.Elem, _ map[uint16][]dns.RR) error {
+		for _, rr := range e.All() {
+			z1.Tree.Insert(rr)
+		}
+		return nil
+	})
+	return z1
+}
+
+// sameRRset returns true if a and b hold the same records, ignoring their TTLs.
+func sameRRset(a, b []dns.RR) bool {
+	contains := func(rrs []dns.RR, rr dns.RR) bool {
+		for _, r := range rrs {
+			if dns.IsDuplicate(r, rr) {
+				return true
+			}
+		}
+		return false
+	}
+	for _, rr := range b {
+		if !contains(a, rr) {
+			return false
+		}
+	}
+	for _, rr := range a {
+		if !contains(b, rr) {
+			return false
+		}
+	}
+	return true
+}
+
+// isEmpty returns true if rr has no rdata, as used in prerequisites and deletes. Such records are
+// unpacked from the wire with a zero rdata length, or created as ANY records.
+func isEmpty(rr dns.RR) bool {
+	switch rr.(type) {
+	case *dns.ANY, *dns.RR_Header:
+		return true
+	}
+	return rr.Header().Rdlength == 0
+}
+
+func isDNSSEC(t uint16) bool {
+	switch t {
+	case dns.TypeRRSIG, dns.TypeNSEC, dns.TypeDNSKEY, dns.TypeDS:
+		return true
+	}
+	return false
+}
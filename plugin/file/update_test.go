@@ -0,0 +1,304 @@
+package file
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/coredns/coredns/plugin/pkg/cidr"
+	"github.com/coredns/coredns/plugin/pkg/dnstest"
+	"github.com/coredns/coredns/plugin/test"
+
+	"github.com/miekg/dns"
+)
+
+const dbUpdate = `$ORIGIN example.org.
+@       3600 IN SOA  ns.example.org. admin.example.org. 10 7200 3600 1209600 3600
+        3600 IN NS   ns.example.org.
+ns      3600 IN A    127.0.0.1
+a       3600 IN A    127.0.0.2
+a       3600 IN A    127.0.0.3
+www     3600 IN CNAME a.example.org.
+`
+
+func newUpdateZone(t *testing.T) (File, string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "db.example.org")
+	if err := os.WriteFile(path, []byte(dbUpdate), 0640); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	z, err := Parse(f, "example.org.", path, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	z.AllowUpdate, _ = cidr.ParseNetworks([]string{"10.240.0.0/16"})
+	return File{Zones: Zones{Z: map[string]*Zone{"example.org.": z}, Names: []string{"example.org."}}}, path
+}
+
+// sendUpdate sends the update m through the wire format, like a client would, and returns the rcode.
+func sendUpdate(t *testing.T, f File, m *dns.Msg, remote string) int {
+	t.Helper()
+	buf, err := m.Pack()
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := new(dns.Msg)
+	if err := r.Unpack(buf); err != nil {
+		t.Fatal(err)
+	}
+	rec := dnstest.NewRecorder(&test.ResponseWriter{RemoteIP: remote})
+	if _, err := f.ServeDNS(context.TODO(), rec, r); err != nil {
+		t.Fatal(err)
+	}
+	return rec.Msg.Rcode
+}
+
+func newUpdate(zone string) *dns.Msg {
+	m := new(dns.Msg)
+	m.SetUpdate(zone)
+	return m
+}
+
+func TestDynamicUpdate(t *testing.T) {
+	f, path := newUpdateZone(t)
+	z := f.Zones.Z["example.org."]
+
+	tests := []struct {
+		name    string
+		update  func(m *dns.Msg)
+		zone    string
+		remote  string
+		rcode   int
+		serial  uint32
+		present []string
+		absent  []string
+	}{
+		{
+			name:   "not allowed",
+			update: func(m *dns.Msg) { m.Insert([]dns.RR{test.A("b.example.org. 300 IN A 127.0.0.4")}) },
+			remote: "192.0.2.1", rcode: dns.RcodeRefused, serial: 10,
+			absent: []string{"b.example.org. 300 IN A 127.0.0.4"},
+		},
+		{
+			name:   "add",
+			update: func(m *dns.Msg) { m.Insert([]dns.RR{test.A("b.example.org. 300 IN A 127.0.0.4")}) },
+			rcode:  dns.RcodeSuccess, serial: 11,
+			present: []string{"b.example.org. 300 IN A 127.0.0.4"},
+		},
+		{
+			name:   "add existing record",
+			update: func(m *dns.Msg) { m.Insert([]dns.RR{test.A("b.example.org. 300 IN A 127.0.0.4")}) },
+			rcode:  dns.RcodeSuccess, serial: 11,
+		},
+		{
+			name:   "change TTL",
+			update: func(m *dns.Msg) { m.Insert([]dns.RR{test.A("b.example.org. 600 IN A 127.0.0.4")}) },
+			rcode:  dns.RcodeSuccess, serial: 12,
+			present: []string{"b.example.org. 600 IN A 127.0.0.4"},
+		},
+		{
+			name: "name not used",
+			update: func(m *dns.Msg) {
+				m.NameNotUsed([]dns.RR{test.A("a.example.org. 0 IN A 127.0.0.1")})
+				m.Insert([]dns.RR{test.A("a.example.org. 300 IN A 127.0.0.9")})
+			},
+			rcode: dns.RcodeYXDomain, serial: 12,
+			absent: []string{"a.example.org. 300 IN A 127.0.0.9"},
+		},
+		{
+			name: "rrset used",
+			update: func(m *dns.Msg) {
+				m.RRsetUsed([]dns.RR{test.AAAA("a.example.org. 0 IN AAAA ::1")})
+			},
+			rcode: dns.RcodeNXRrset, serial: 12,
+		},
+		{
+			name: "rrset value",
+			update: func(m *dns.Msg) {
+				m.Used([]dns.RR{test.A("a.example.org. 0 IN A 127.0.0.2")})
+				m.Insert([]dns.RR{test.A("c.example.org. 300 IN A 127.0.0.5")})
+			},
+			rcode: dns.RcodeNXRrset, serial: 12,
+			absent: []string{"c.example.org. 300 IN A 127.0.0.5"},
+		},
+		{
+			name: "rrset value matches",
+			update: func(m *dns.Msg) {
+				m.Used([]dns.RR{test.A("a.example.org. 0 IN A 127.0.0.2"), test.A("a.example.org. 0 IN A 127.0.0.3")})
+				m.NameUsed([]dns.RR{test.A("ns.example.org. 0 IN A 127.0.0.1")})
+				m.RRsetNotUsed([]dns.RR{test.AAAA("a.example.org. 0 IN AAAA ::1")})
+				m.Insert([]dns.RR{test.A("c.example.org. 300 IN A 127.0.0.5")})
+			},
+			rcode: dns.RcodeSuccess, serial: 13,
+			present: []string{"c.example.org. 300 IN A 127.0.0.5"},
+		},
+		{
+			name:   "delete record",
+			update: func(m *dns.Msg) { m.Remove([]dns.RR{test.A("a.example.org. 3600 IN A 127.0.0.2")}) },
+			rcode:  dns.RcodeSuccess, serial: 14,
+			present: []string{"a.example.org. 3600 IN A 127.0.0.3"},
+			absent:  []string{"a.example.org. 3600 IN A 127.0.0.2"},
+		},
+		{
+			name:   "delete rrset",
+			update: func(m *dns.Msg) { m.RemoveRRset([]dns.RR{test.A("b.example.org. 0 IN A 127.0.0.4")}) },
+			rcode:  dns.RcodeSuccess, serial: 15,
+			absent: []string{"b.example.org. 600 IN A 127.0.0.4"},
+		},
+		{
+			name:   "delete name",
+			update: func(m *dns.Msg) { m.RemoveName([]dns.RR{test.A("c.example.org. 0 IN A 127.0.0.5")}) },
+			rcode:  dns.RcodeSuccess, serial: 16,
+			absent: []string{"c.example.org. 300 IN A 127.0.0.5"},
+		},
+		{
+			name: "keep apex",
+			update: func(m *dns.Msg) {
+				m.Remove([]dns.RR{test.NS("example.org. 3600 IN NS ns.example.org.")})
+				m.RemoveRRset([]dns.RR{test.SOA("example.org. 0 IN SOA ns.example.org. admin.example.org. 10 7200 3600 1209600 3600")})
+			},
+			rcode: dns.RcodeSuccess, serial: 16,
+			present: []string{"example.org. 3600 IN NS ns.example.org."},
+		},
+		{
+			name:   "cname conflict",
+			update: func(m *dns.Msg) { m.Insert([]dns.RR{test.A("www.example.org. 300 IN A 127.0.0.6")}) },
+			rcode:  dns.RcodeSuccess, serial: 16,
+			absent: []string{"www.example.org. 300 IN A 127.0.0.6"},
+		},
+		{
+			name:   "not in zone",
+			update: func(m *dns.Msg) { m.Insert([]dns.RR{test.A("b.example.net. 300 IN A 127.0.0.4")}) },
+			rcode:  dns.RcodeNotZone, serial: 16,
+		},
+		{
+			name:   "not the zone",
+			update: func(m *dns.Msg) { m.Insert([]dns.RR{test.A("b.sub.example.org. 300 IN A 127.0.0.4")}) },
+			zone:   "sub.example.org.", rcode: dns.RcodeNotAuth, serial: 16,
+		},
+		{
+			name: "set serial",
+			update: func(m *dns.Msg) {
+				m.Insert([]dns.RR{test.SOA("example.org. 3600 IN SOA ns.example.org. admin.example.org. 100 7200 3600 1209600 3600")})
+			},
+			rcode: dns.RcodeSuccess, serial: 100,
+		},
+	}
+
+	for _, tc := range tests {
+		zone := tc.zone
+		if zone == "" {
+			zone = "example.org."
+		}
+		remote := tc.remote
+		if remote == "" {
+			remote = "10.240.0.1"
+		}
+		m := newUpdate(zone)
+		tc.update(m)
+		if rcode := sendUpdate(t, f, m, remote); rcode != tc.rcode {
+			t.Errorf("Test %q: expected rcode %s, got %s", tc.name, dns.RcodeToString[tc.rcode], dns.RcodeToString[rcode])
+		}
+		if serial := z.Apex.SOA.Serial; serial != tc.serial {
+			t.Errorf("Test %q: expected serial %d, got %d", tc.name, tc.serial, serial)
+		}
+		for _, s := range tc.present {
+			if !hasRR(z, s) {
+				t.Errorf("Test %q: expected %s in the zone", tc.name, s)
+			}
+		}
+		for _, s := range tc.absent {
+			if hasRR(z, s) {
+				t.Errorf("Test %q: expected no %s in the zone", tc.name, s)
+			}
+		}
+	}
+
+	// The zone file is left alone, the changes are in the journal.
+	buf, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(buf) != dbUpdate {
+		t.Errorf("Expected %s not to be changed, got:\n%s", path, buf)
+	}
+
+	// Replaying the journal on the zone file gives the updated zone.
+	z1, err := Parse(strings.NewReader(dbUpdate), "example.org.", path, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	z1.ReplayJournal()
+	if z1.Apex.SOA.Serial != 100 {
+		t.Errorf("Expected serial 100 after replaying the journal, got %d", z1.Apex.SOA.Serial)
+	}
+	for _, s := range []string{"a.example.org. 3600 IN A 127.0.0.3", "example.org. 3600 IN NS ns.example.org."} {
+		if !hasRR(z1, s) {
+			t.Errorf("Expected %s after replaying the journal", s)
+		}
+	}
+	for _, s := range []string{"a.example.org. 3600 IN A 127.0.0.2", "b.example.org. 600 IN A 127.0.0.4", "c.example.org. 300 IN A 127.0.0.5"} {
+		if hasRR(z1, s) {
+			t.Errorf("Expected no %s after replaying the journal", s)
+		}
+	}
+}
+
+func TestDynamicUpdateJournal(t *testing.T) {
+	f, path := newUpdateZone(t)
+	z := f.Zones.Z["example.org."]
+
+	m := newUpdate("example.org.")
+	m.Insert([]dns.RR{test.A("b.example.org. 300 IN A 127.0.0.4")})
+	if rcode := sendUpdate(t, f, m, "10.240.0.1"); rcode != dns.RcodeSuccess {
+		t.Fatalf("Expected rcode %s, got %s", dns.RcodeToString[dns.RcodeSuccess], dns.RcodeToString[rcode])
+	}
+
+	// The zone file is edited, with a higher serial: the journal no longer applies.
+	edited := strings.Replace(dbUpdate, " 10 7200", " 20 7200", 1)
+	z1, err := Parse(strings.NewReader(edited), "example.org.", path, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	z1.ReplayJournal()
+	if z1.Apex.SOA.Serial != 20 || hasRR(z1, "b.example.org. 300 IN A 127.0.0.4") {
+		t.Errorf("Expected the journal to be skipped for a newer zone file, got serial %d", z1.Apex.SOA.Serial)
+	}
+
+	// When the journal can't be written, the update fails and the zone is not changed.
+	os.Remove(path + JournalSuffix)
+	if err := os.Mkdir(path+JournalSuffix, 0755); err != nil {
+		t.Fatal(err)
+	}
+	m = newUpdate("example.org.")
+	m.Insert([]dns.RR{test.A("c.example.org. 300 IN A 127.0.0.5")})
+	if rcode := sendUpdate(t, f, m, "10.240.0.1"); rcode != dns.RcodeServerFailure {
+		t.Errorf("Expected rcode %s, got %s", dns.RcodeToString[dns.RcodeServerFailure], dns.RcodeToString[rcode])
+	}
+	if z.Apex.SOA.Serial != 11 || hasRR(z, "c.example.org. 300 IN A 127.0.0.5") {
+		t.Errorf("Expected the zone not to change, got serial %d", z.Apex.SOA.Serial)
+	}
+}
+
+// hasRR returns true if the record s, including its TTL, is in z.
+func hasRR(z *Zone, s string) bool {
+	rr, err := dns.NewRR(s)
+	if err != nil {
+		return false
+	}
+	z.RLock()
+	defer z.RUnlock()
+	for _, r := range z.rrset(rr.Header().Name, rr.Header().Rrtype) {
+		if dns.IsDuplicate(r, rr) && r.Header().Ttl == rr.Header().Ttl {
+			return true
+		}
+	}
+	return false
+}
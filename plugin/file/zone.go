@@ -2,6 +2,7 @@ package file
 
 import (
 	"fmt"
+	"net"
 	"path/filepath"
 	"strings"
 	"sync"
@@ -26,9 +27,21 @@ type Zone struct {
 
 	StartupOnce  sync.Once
 	TransferFrom []string
+	IXFR         bool // request incremental transfers once the zone has a SOA
+
+	Persist string // file to save the zone to after a transfer, if not empty
+
+	AllowUpdate []*net.IPNet // networks that may send dynamic updates, none if empty
+	updateMu    sync.Mutex   // serializes dynamic updates and reloads
+
+	primaries   primaries     // primaries from TransferFrom that failed
+	notify      chan struct{} // a NOTIFY was received
+	refreshedAt time.Time     // last successful transfer or check of the serial
 
 	ReloadInterval time.Duration
 	reloadShutdown chan bool
+	shutdown       chan struct{} // closed when the zone is shut down, stops TransferInRetry and Update
+	shutdownOnce   sync.Once
 
 	Upstream *upstream.Upstream // Upstream for looking up external names during the resolution process.
 }
@@ -49,6 +62,8 @@ func NewZone(name, file string) *Zone {
 		file:           filepath.Clean(file),
 		Tree:           &tree.Tree{},
 		reloadShutdown: make(chan bool),
+		shutdown:       make(chan struct{}),
+		notify:         make(chan struct{}, 1),
 	}
 }
 
@@ -56,6 +71,8 @@ func NewZone(name, file string) *Zone {
 func (z *Zone) Copy() *Zone {
 	z1 := NewZone(z.origin, z.file)
 	z1.TransferFrom = z.TransferFrom
+	z1.IXFR = z.IXFR
+	z1.AllowUpdate = z.AllowUpdate
 	z1.Expired = z.Expired
 
 	z1.Apex = z.Apex
@@ -66,6 +83,8 @@ func (z *Zone) Copy() *Zone {
 func (z *Zone) CopyWithoutApex() *Zone {
 	z1 := NewZone(z.origin, z.file)
 	z1.TransferFrom = z.TransferFrom
+	z1.IXFR = z.IXFR
+	z1.AllowUpdate = z.AllowUpdate
 	z1.Expired = z.Expired
 
 	return z1
@@ -1,6 +1,7 @@
 package forward
 
 import (
+	"context"
 	"net"
 	"strconv"
 	"time"
@@ -13,7 +14,7 @@ import (
 )
 
 // toDnstap will send the forward and received message to the dnstap plugin.
-func toDnstap(f *Forward, host string, state request.Request, opts options, reply *dns.Msg, start time.Time) {
+func toDnstap(ctx context.Context, f *Forward, host string, state request.Request, opts options, reply *dns.Msg, start time.Time) {
 	// Query
 	q := new(tap.Message)
 	msg.SetQueryTime(q, start)
@@ -44,7 +45,7 @@ func toDnstap(f *Forward, host string, state request.Request, opts options, repl
 		q.QueryMessage = buf
 	}
 	msg.SetType(q, tap.Message_FORWARDER_QUERY)
-	f.tapPlugin.TapMessage(q)
+	f.tapPlugin.TapMessageWithMetadata(ctx, q, state)
 
 	// Response
 	if reply != nil {
@@ -58,6 +59,6 @@ func toDnstap(f *Forward, host string, state request.Request, opts options, repl
 		msg.SetResponseAddress(r, ta)
 		msg.SetResponseTime(r, time.Now())
 		msg.SetType(r, tap.Message_FORWARDER_RESPONSE)
-		f.tapPlugin.TapMessage(r)
+		f.tapPlugin.TapMessageWithMetadata(ctx, r, state)
 	}
 }
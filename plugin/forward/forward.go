@@ -16,6 +16,7 @@ import (
 	"github.com/coredns/coredns/plugin/dnstap"
 	"github.com/coredns/coredns/plugin/metadata"
 	clog "github.com/coredns/coredns/plugin/pkg/log"
+	"github.com/coredns/coredns/plugin/pkg/rcode"
 	"github.com/coredns/coredns/request"
 
 	"github.com/miekg/dns"
@@ -120,7 +121,8 @@ func (f *Forward) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg
 		}
 
 		if span != nil {
-			child = span.Tracer().StartSpan("connect", ot.ChildOf(span.Context()))
+			child = span.Tracer().StartSpan("connect", ot.ChildOf(span.Context()),
+				ot.Tag{Key: string(otext.SpanKind), Value: string(otext.SpanKindRPCClientEnum)})
 			otext.PeerAddress.Set(child, proxy.addr)
 			ctx = ot.ContextWithSpan(ctx, child)
 		}
@@ -148,11 +150,16 @@ func (f *Forward) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg
 		}
 
 		if child != nil {
+			if err != nil {
+				otext.Error.Set(child, true)
+			} else {
+				child.SetTag("coredns.io/rcode", rcode.ToString(ret.Rcode))
+			}
 			child.Finish()
 		}
 
 		if f.tapPlugin != nil {
-			toDnstap(f, proxy.addr, state, opts, ret, start)
+			toDnstap(ctx, f, proxy.addr, state, opts, ret, start)
 		}
 
 		upstreamErr = err
@@ -0,0 +1,129 @@
+// Package geodns implements a plugin that answers with the records of the region a client is in.
+package geodns
+
+import (
+	"context"
+
+	"github.com/coredns/coredns/plugin"
+	"github.com/coredns/coredns/plugin/metrics"
+	"github.com/coredns/coredns/request"
+
+	"github.com/miekg/dns"
+)
+
+// GeoDNS answers queries for names that have records per region, with the records of the region of
+// the client, as found by the geoip plugin.
+type GeoDNS struct {
+	Next  plugin.Handler
+	Zones []string
+
+	regions  []*region // in the order of the Corefile
+	defaults []string  // regions to use when the client is in none
+	names    map[string]struct{}
+}
+
+// ServeDNS implements the plugin.Handler interface.
+func (g *GeoDNS) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (int, error) {
+	state := request.Request{W: w, Req: r}
+	qname := state.Name()
+
+	if plugin.Zones(g.Zones).Matches(qname) == "" {
+		return plugin.NextOrFailure(g.Name(), g.Next, ctx, w, r)
+	}
+	if _, ok := g.names[qname]; !ok {
+		return plugin.NextOrFailure(g.Name(), g.Next, ctx, w, r)
+	}
+
+	c := newClient(ctx)
+	reg, records := g.lookup(c, qname)
+	answer := filter(records, state.QType())
+	if len(answer) == 0 {
+		// The region has no records of this type, others may.
+		return plugin.NextOrFailure(g.Name(), g.Next, ctx, w, r)
+	}
+
+	m := new(dns.Msg)
+	m.SetReply(r)
+	m.Authoritative = true
+	m.Answer = answer
+	ecs(r, m, c.subnet)
+
+	answersCount.WithLabelValues(metrics.WithServer(ctx), reg.name).Inc()
+
+	w.WriteMsg(m)
+	return dns.RcodeSuccess, nil
+}
+
+// Name implements the Handler interface.
+func (g *GeoDNS) Name() string { return "geodns" }
+
+// lookup returns the first region that has records for qname, out of the region of c, its fallback
+// regions and the default regions.
+func (g *GeoDNS) lookup(c client, qname string) (*region, []dns.RR) {
+	var names []string
+	if reg := match(g.regions, c); reg != nil {
+		names = append(names, reg.name)
+		names = append(names, reg.fallback...)
+	}
+	names = append(names, g.defaults...)
+
+	for _, name := range names {
+		reg := g.region(name)
+		if records, ok := reg.records[qname]; ok {
+			return reg, records
+		}
+	}
+	return nil, nil
+}
+
+func (g *GeoDNS) region(name string) *region {
+	for _, r := range g.regions {
+		if r.name == name {
+			return r
+		}
+	}
+	return nil
+}
+
+// filter returns the records of type qtype, or the CNAME if there is one.
+func filter(records []dns.RR, qtype uint16) []dns.RR {
+	var out []dns.RR
+	for _, rr := range records {
+		switch rr.Header().Rrtype {
+		case qtype:
+			out = append(out, dns.Copy(rr))
+		case dns.TypeCNAME:
+			return []dns.RR{dns.Copy(rr)}
+		}
+	}
+	return out
+}
+
+// ecs copies the EDNS Client Subnet option of r to m. If the answer is for that subnet, because the
+// location is that of its address, the scope is set to the source prefix length, otherwise it is 0
+// as the answer does not depend on the subnet.
+func ecs(r, m *dns.Msg, fromSubnet bool) {
+	o := r.IsEdns0()
+	if o == nil {
+		return
+	}
+	for _, opt := range o.Option {
+		subnet, ok := opt.(*dns.EDNS0_SUBNET)
+		if !ok {
+			continue
+		}
+		var scope uint8
+		if fromSubnet {
+			scope = subnet.SourceNetmask
+		}
+		m.SetEdns0(o.UDPSize(), o.Do())
+		m.IsEdns0().Option = append(m.IsEdns0().Option, &dns.EDNS0_SUBNET{
+			Code:          dns.EDNS0SUBNET,
+			Family:        subnet.Family,
+			SourceNetmask: subnet.SourceNetmask,
+			SourceScope:   scope,
+			Address:       subnet.Address,
+		})
+		return
+	}
+}
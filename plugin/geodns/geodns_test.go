@@ -0,0 +1,137 @@
+package geodns
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/coredns/caddy"
+	"github.com/coredns/coredns/plugin/metadata"
+	"github.com/coredns/coredns/plugin/pkg/dnstest"
+	"github.com/coredns/coredns/plugin/test"
+
+	"github.com/miekg/dns"
+)
+
+func TestGeoDNS(t *testing.T) {
+	c := caddy.NewTestController("dns", `geodns example.org {
+		region eu continent EU
+		region us country US
+		region apac location 1.35 103.82
+		region global
+		record eu www 60 IN A 192.0.2.1
+		record eu www 60 IN A 192.0.2.2
+		record us www 60 IN A 198.51.100.1
+		record us www 60 IN AAAA 2001:db8::1
+		record global www 60 IN A 203.0.113.1
+		record global alias 60 IN CNAME www.example.org.
+		fallback apac global
+		default global
+	}`)
+	g, err := parse(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	g.Next = test.NextHandler(dns.RcodeRefused, nil)
+
+	tests := []struct {
+		labels map[string]string
+		qname  string
+		qtype  uint16
+		rcode  int
+		answer []string
+	}{
+		{map[string]string{"geoip/continent/code": "EU"}, "www.example.org.", dns.TypeA, dns.RcodeSuccess, []string{"192.0.2.1", "192.0.2.2"}},
+		{map[string]string{"geoip/continent/code": "NA", "geoip/country/code": "US"}, "www.example.org.", dns.TypeAAAA, dns.RcodeSuccess, []string{"2001:db8::1"}},
+		// Nearest is apac, which has no records, so its fallback is used.
+		{map[string]string{"geoip/latitude": "35.68", "geoip/longitude": "139.69"}, "www.example.org.", dns.TypeA, dns.RcodeSuccess, []string{"203.0.113.1"}},
+		// No metadata at all, so the default.
+		{nil, "WWW.example.org.", dns.TypeA, dns.RcodeSuccess, []string{"203.0.113.1"}},
+		// Region has the name, but not the type.
+		{map[string]string{"geoip/continent/code": "EU"}, "www.example.org.", dns.TypeAAAA, dns.RcodeRefused, nil},
+		// The CNAME is returned for any type.
+		{nil, "alias.example.org.", dns.TypeA, dns.RcodeSuccess, []string{"www.example.org."}},
+		// Not a name with regions.
+		{nil, "mail.example.org.", dns.TypeA, dns.RcodeRefused, nil},
+		{nil, "www.example.net.", dns.TypeA, dns.RcodeRefused, nil},
+	}
+
+	for i, tc := range tests {
+		ctx := metadata.ContextWithMetadata(context.Background())
+		for label, value := range tc.labels {
+			value := value
+			metadata.SetValueFunc(ctx, label, func() string { return value })
+		}
+
+		m := new(dns.Msg)
+		m.SetQuestion(tc.qname, tc.qtype)
+		rec := dnstest.NewRecorder(&test.ResponseWriter{})
+		rcode, _ := g.ServeDNS(ctx, rec, m)
+		if rcode != tc.rcode {
+			t.Errorf("Test %d: expected rcode %s, got %s", i, dns.RcodeToString[tc.rcode], dns.RcodeToString[rcode])
+			continue
+		}
+		if tc.rcode != dns.RcodeSuccess {
+			continue
+		}
+		if !rec.Msg.Authoritative {
+			t.Errorf("Test %d: expected an authoritative answer", i)
+		}
+		if len(rec.Msg.Answer) != len(tc.answer) {
+			t.Errorf("Test %d: expected %d records, got %d", i, len(tc.answer), len(rec.Msg.Answer))
+			continue
+		}
+		for j, rr := range rec.Msg.Answer {
+			var got string
+			switch rr := rr.(type) {
+			case *dns.A:
+				got = rr.A.String()
+			case *dns.AAAA:
+				got = rr.AAAA.String()
+			case *dns.CNAME:
+				got = rr.Target
+			}
+			if got != tc.answer[j] {
+				t.Errorf("Test %d: expected %s, got %s", i, tc.answer[j], got)
+			}
+		}
+	}
+}
+
+func TestGeoDNSSubnet(t *testing.T) {
+	c := caddy.NewTestController("dns", `geodns example.org {
+		region global
+		record global www 60 IN A 203.0.113.1
+		default global
+	}`)
+	g, err := parse(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m := new(dns.Msg)
+	m.SetQuestion("www.example.org.", dns.TypeA)
+	m.SetEdns0(4096, false)
+	m.IsEdns0().Option = append(m.IsEdns0().Option, &dns.EDNS0_SUBNET{
+		Code: dns.EDNS0SUBNET, Family: 1, SourceNetmask: 24, Address: net.ParseIP("192.0.2.0").To4(),
+	})
+
+	// The scope is only the source prefix length if geoip looked up the address in the option.
+	for fromSubnet, scope := range map[string]uint8{"true": 24, "false": 0, "": 0} {
+		ctx := metadata.ContextWithMetadata(context.Background())
+		if fromSubnet != "" {
+			metadata.SetValueFunc(ctx, "geoip/edns-subnet", func() string { return fromSubnet })
+		}
+		rec := dnstest.NewRecorder(&test.ResponseWriter{})
+		g.ServeDNS(ctx, rec, m)
+
+		o := rec.Msg.IsEdns0()
+		if o == nil || len(o.Option) != 1 {
+			t.Fatalf("Expected an EDNS Client Subnet option in the reply, got %v", o)
+		}
+		subnet := o.Option[0].(*dns.EDNS0_SUBNET)
+		if subnet.SourceScope != scope || !subnet.Address.Equal(net.ParseIP("192.0.2.0")) {
+			t.Errorf("Expected scope %d for 192.0.2.0 with geoip/edns-subnet %q, got %d for %s", scope, fromSubnet, subnet.SourceScope, subnet.Address)
+		}
+	}
+}
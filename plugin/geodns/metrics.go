@@ -0,0 +1,16 @@
+package geodns
+
+import (
+	"github.com/coredns/coredns/plugin"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// answersCount is the number of answers per region.
+var answersCount = promauto.NewCounterVec(prometheus.CounterOpts{
+	Namespace: plugin.Namespace,
+	Subsystem: "geodns",
+	Name:      "answers_total",
+	Help:      "Counter of answers per region.",
+}, []string{"server", "region"})
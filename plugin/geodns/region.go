@@ -0,0 +1,139 @@
+package geodns
+
+import (
+	"context"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/coredns/coredns/plugin/metadata"
+
+	"github.com/miekg/dns"
+)
+
+// region is a set of records, for the clients that match it.
+type region struct {
+	name string
+
+	continents   []string
+	countries    []string
+	subdivisions []string // country and subdivision code, e.g. US-CA
+	asns         []string
+	location     *location
+
+	// fallback are the regions to use, in this order, when this region has no records for a name.
+	fallback []string
+
+	// records holds the records by lowercased owner name.
+	records map[string][]dns.RR
+}
+
+type location struct {
+	latitude, longitude float64
+}
+
+// client is where a query comes from, according to the geoip metadata.
+type client struct {
+	continent    string
+	country      string
+	subdivisions []string
+	asn          string
+	location     *location
+
+	// subnet is true if the location is that of the address in the EDNS Client Subnet option.
+	subnet bool
+}
+
+// newClient reads the location of the client from the metadata set by the geoip plugin.
+func newClient(ctx context.Context) client {
+	value := func(label string) string {
+		if f := metadata.ValueFunc(ctx, label); f != nil {
+			return f()
+		}
+		return ""
+	}
+
+	c := client{
+		continent: value("geoip/continent/code"),
+		country:   value("geoip/country/code"),
+		asn:       value("geoip/asn/number"),
+		subnet:    value("geoip/edns-subnet") == "true",
+	}
+	if s := value("geoip/subdivisions/code"); s != "" {
+		for _, sub := range strings.Split(s, ",") {
+			c.subdivisions = append(c.subdivisions, c.country+"-"+sub)
+		}
+	}
+
+	lat, err1 := strconv.ParseFloat(value("geoip/latitude"), 64)
+	lon, err2 := strconv.ParseFloat(value("geoip/longitude"), 64)
+	// A location of 0, 0 is what the database has when it doesn't know.
+	if err1 == nil && err2 == nil && (lat != 0 || lon != 0) {
+		c.location = &location{lat, lon}
+	}
+	return c
+}
+
+// match returns the region c is in. The most specific match wins: ASN, subdivision, country and then
+// continent. If none match, c is in the nearest region with a location. It returns nil if c is in no
+// region.
+func match(regions []*region, c client) *region {
+	kinds := []struct {
+		value  []string
+		values func(r *region) []string
+	}{
+		{[]string{c.asn}, func(r *region) []string { return r.asns }},
+		{c.subdivisions, func(r *region) []string { return r.subdivisions }},
+		{[]string{c.country}, func(r *region) []string { return r.countries }},
+		{[]string{c.continent}, func(r *region) []string { return r.continents }},
+	}
+	for _, kind := range kinds {
+		for _, r := range regions {
+			if in(kind.value, kind.values(r)) {
+				return r
+			}
+		}
+	}
+
+	if c.location == nil {
+		return nil
+	}
+	var nearest *region
+	min := math.Inf(1)
+	for _, r := range regions {
+		if r.location == nil {
+			continue
+		}
+		if d := distance(*c.location, *r.location); d < min {
+			nearest, min = r, d
+		}
+	}
+	return nearest
+}
+
+// in returns true if any of the non-empty values is in set.
+func in(values, set []string) bool {
+	for _, v := range values {
+		if v == "" {
+			continue
+		}
+		for _, s := range set {
+			if strings.EqualFold(v, s) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+const earthRadius = 6371 // km
+
+// distance returns the great-circle distance between a and b in kilometers, using the haversine formula.
+func distance(a, b location) float64 {
+	rad := func(deg float64) float64 { return deg * math.Pi / 180 }
+
+	dlat := rad(b.latitude - a.latitude)
+	dlon := rad(b.longitude - a.longitude)
+	h := math.Sin(dlat/2)*math.Sin(dlat/2) + math.Cos(rad(a.latitude))*math.Cos(rad(b.latitude))*math.Sin(dlon/2)*math.Sin(dlon/2)
+	return 2 * earthRadius * math.Asin(math.Sqrt(h))
+}
@@ -0,0 +1,53 @@
+package geodns
+
+import (
+	"math"
+	"testing"
+)
+
+func TestDistance(t *testing.T) {
+	london := location{51.5074, -0.1278}
+	paris := location{48.8566, 2.3522}
+	if d := distance(london, paris); math.Abs(d-344) > 2 {
+		t.Errorf("Expected about 344km between London and Paris, got %.0f", d)
+	}
+	if d := distance(paris, paris); d != 0 {
+		t.Errorf("Expected no distance, got %f", d)
+	}
+}
+
+func TestMatch(t *testing.T) {
+	regions := []*region{
+		{name: "eu", continents: []string{"EU"}},
+		{name: "de", countries: []string{"DE"}},
+		{name: "bavaria", subdivisions: []string{"DE-BY"}},
+		{name: "isp", asns: []string{"64512"}},
+		{name: "singapore", location: &location{1.35, 103.82}},
+		{name: "virginia", location: &location{38.9, -77.4}},
+	}
+
+	tests := []struct {
+		client client
+		region string
+	}{
+		{client{continent: "EU", country: "FR"}, "eu"},
+		{client{continent: "EU", country: "DE"}, "de"},
+		{client{continent: "EU", country: "DE", subdivisions: []string{"DE-BY"}}, "bavaria"},
+		{client{continent: "EU", country: "DE", subdivisions: []string{"DE-BY"}, asn: "64512"}, "isp"},
+		{client{continent: "AS", country: "JP", location: &location{35.68, 139.69}}, "singapore"},
+		{client{continent: "NA", country: "CA", location: &location{45.5, -73.6}}, "virginia"},
+		{client{continent: "NA", country: "CA"}, ""},
+		{client{}, ""},
+	}
+
+	for i, tc := range tests {
+		r := match(regions, tc.client)
+		name := ""
+		if r != nil {
+			name = r.name
+		}
+		if name != tc.region {
+			t.Errorf("Test %d: expected region %q, got %q", i, tc.region, name)
+		}
+	}
+}
@@ -0,0 +1,156 @@
+package geodns
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/coredns/caddy"
+	"github.com/coredns/coredns/core/dnsserver"
+	"github.com/coredns/coredns/plugin"
+
+	"github.com/miekg/dns"
+)
+
+func init() { plugin.Register("geodns", setup) }
+
+func setup(c *caddy.Controller) error {
+	g, err := parse(c)
+	if err != nil {
+		return plugin.Error("geodns", err)
+	}
+
+	dnsserver.GetConfig(c).AddPlugin(func(next plugin.Handler) plugin.Handler {
+		g.Next = next
+		return g
+	})
+
+	return nil
+}
+
+func parse(c *caddy.Controller) (*GeoDNS, error) {
+	g := &GeoDNS{names: map[string]struct{}{}}
+	i := 0
+	for c.Next() {
+		if i > 0 {
+			return nil, plugin.ErrOnce
+		}
+		i++
+
+		g.Zones = plugin.OriginsFromArgsOrServerBlock(c.RemainingArgs(), c.ServerBlockKeys)
+
+		var fallbacks [][]string
+		for c.NextBlock() {
+			switch c.Val() {
+			case "region":
+				args := c.RemainingArgs()
+				if len(args) == 0 || len(args) == 2 {
+					return nil, c.ArgErr()
+				}
+				r := g.region(args[0])
+				if r == nil {
+					r = &region{name: args[0], records: map[string][]dns.RR{}}
+					g.regions = append(g.regions, r)
+				}
+				if len(args) == 1 {
+					// A region that is only used as a fallback or default.
+					continue
+				}
+				values := args[2:]
+				switch args[1] {
+				case "continent":
+					r.continents = append(r.continents, values...)
+				case "country":
+					r.countries = append(r.countries, values...)
+				case "subdivision":
+					for _, v := range values {
+						if !strings.Contains(v, "-") {
+							return nil, c.Errf("subdivision '%s' must be a country and a subdivision code, like US-CA", v)
+						}
+					}
+					r.subdivisions = append(r.subdivisions, values...)
+				case "asn":
+					for _, v := range values {
+						if _, err := strconv.ParseUint(strings.TrimPrefix(strings.ToUpper(v), "AS"), 10, 32); err != nil {
+							return nil, c.Errf("invalid ASN '%s'", v)
+						}
+						r.asns = append(r.asns, strings.TrimPrefix(strings.ToUpper(v), "AS"))
+					}
+				case "location":
+					if len(values) != 2 {
+						return nil, c.ArgErr()
+					}
+					lat, err := strconv.ParseFloat(values[0], 64)
+					if err != nil || lat < -90 || lat > 90 {
+						return nil, c.Errf("invalid latitude '%s'", values[0])
+					}
+					lon, err := strconv.ParseFloat(values[1], 64)
+					if err != nil || lon < -180 || lon > 180 {
+						return nil, c.Errf("invalid longitude '%s'", values[1])
+					}
+					r.location = &location{lat, lon}
+				default:
+					return nil, c.Errf("unknown region match '%s'", args[1])
+				}
+			case "record":
+				args := c.RemainingArgs()
+				if len(args) < 2 {
+					return nil, c.ArgErr()
+				}
+				r := g.region(args[0])
+				if r == nil {
+					return nil, c.Errf("unknown region '%s'", args[0])
+				}
+				zp := dns.NewZoneParser(strings.NewReader(strings.Join(args[1:], " ")), g.Zones[0], "")
+				rr, ok := zp.Next()
+				if err := zp.Err(); err != nil {
+					return nil, c.Errf("invalid record: %s", err)
+				}
+				if !ok {
+					return nil, c.ArgErr()
+				}
+				name := strings.ToLower(rr.Header().Name)
+				if plugin.Zones(g.Zones).Matches(name) == "" {
+					return nil, c.Errf("record '%s' is not in the zones of this plugin", rr.Header().Name)
+				}
+				r.records[name] = append(r.records[name], rr)
+				g.names[name] = struct{}{}
+			case "fallback":
+				args := c.RemainingArgs()
+				if len(args) < 2 {
+					return nil, c.ArgErr()
+				}
+				fallbacks = append(fallbacks, args)
+			case "default":
+				args := c.RemainingArgs()
+				if len(args) == 0 {
+					return nil, c.ArgErr()
+				}
+				g.defaults = append(g.defaults, args...)
+			default:
+				return nil, c.Errf("unknown property '%s'", c.Val())
+			}
+		}
+
+		for _, args := range fallbacks {
+			r := g.region(args[0])
+			if r == nil {
+				return nil, c.Errf("unknown region '%s'", args[0])
+			}
+			for _, name := range args[1:] {
+				if g.region(name) == nil {
+					return nil, c.Errf("unknown region '%s'", name)
+				}
+			}
+			r.fallback = append(r.fallback, args[1:]...)
+		}
+		for _, name := range g.defaults {
+			if g.region(name) == nil {
+				return nil, c.Errf("unknown region '%s'", name)
+			}
+		}
+	}
+	if len(g.names) == 0 {
+		return nil, c.Err("no records")
+	}
+	return g, nil
+}
@@ -0,0 +1,94 @@
+package geodns
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/coredns/caddy"
+)
+
+func TestSetup(t *testing.T) {
+	tests := []struct {
+		input      string
+		shouldErr  bool
+		errContent string
+	}{
+		{`geodns example.org {
+			region eu continent EU
+			region us-west subdivision US-CA US-OR
+			region as64512 asn AS64512 64513
+			region apac location 1.35 103.82
+			region global
+			record eu www 60 IN A 192.0.2.1
+			record global www.example.org. 60 IN A 192.0.2.10
+			fallback eu global
+			default global
+		}`, false, ""},
+
+		{`geodns example.org`, true, "no records"},
+		{`geodns example.org {
+			region eu continent
+		}`, true, "Wrong argument count"},
+		{`geodns example.org {
+			region eu planet Earth
+		}`, true, "unknown region match"},
+		{`geodns example.org {
+			region us subdivision CA
+		}`, true, "must be a country and a subdivision code"},
+		{`geodns example.org {
+			region x asn ASx
+		}`, true, "invalid ASN"},
+		{`geodns example.org {
+			region x location 91 0
+		}`, true, "invalid latitude"},
+		{`geodns example.org {
+			region x location 0 181
+		}`, true, "invalid longitude"},
+		{`geodns example.org {
+			record eu www 60 IN A 192.0.2.1
+		}`, true, "unknown region"},
+		{`geodns example.org {
+			region eu continent EU
+			record eu www 60 IN A 192.0.2.300
+		}`, true, "invalid record"},
+		{`geodns example.org {
+			region eu continent EU
+			record eu www.example.net. 60 IN A 192.0.2.1
+		}`, true, "not in the zones"},
+		{`geodns example.org {
+			region eu continent EU
+			record eu www 60 IN A 192.0.2.1
+			fallback eu us
+		}`, true, "unknown region 'us'"},
+		{`geodns example.org {
+			region eu continent EU
+			record eu www 60 IN A 192.0.2.1
+			default us
+		}`, true, "unknown region 'us'"},
+		{`geodns example.org {
+			bogus
+		}`, true, "unknown property"},
+		{`geodns example.org {
+			region eu continent EU
+			record eu www 60 IN A 192.0.2.1
+		}
+		geodns example.org`, true, "this plugin"},
+	}
+
+	for i, test := range tests {
+		c := caddy.NewTestController("dns", test.input)
+		_, err := parse(c)
+
+		if test.shouldErr && err == nil {
+			t.Errorf("Test %d: Expected error but found none for input %s", i, test.input)
+		}
+		if err != nil {
+			if !test.shouldErr {
+				t.Errorf("Test %d: Expected no error but found one for input %s. Error was: %v", i, test.input, err)
+			}
+			if !strings.Contains(err.Error(), test.errContent) {
+				t.Errorf("Test %d: Expected error to contain %q, got %q", i, test.errContent, err)
+			}
+		}
+	}
+}
@@ -0,0 +1,30 @@
+package geoip
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/coredns/coredns/plugin/metadata"
+
+	"github.com/oschwald/geoip2-golang"
+)
+
+func (g GeoIP) setAnonymousIPMetadata(ctx context.Context, data *geoip2.AnonymousIP) {
+	labels := []struct {
+		name  string
+		value bool
+	}{
+		{"is_anonymous", data.IsAnonymous},
+		{"is_anonymous_vpn", data.IsAnonymousVPN},
+		{"is_hosting_provider", data.IsHostingProvider},
+		{"is_public_proxy", data.IsPublicProxy},
+		{"is_residential_proxy", data.IsResidentialProxy},
+		{"is_tor_exit_node", data.IsTorExitNode},
+	}
+	for _, label := range labels {
+		value := strconv.FormatBool(label.value)
+		metadata.SetValueFunc(ctx, pluginName+"/anonymous/"+label.name, func() string {
+			return value
+		})
+	}
+}
@@ -0,0 +1,47 @@
+package geoip
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/coredns/coredns/plugin/metadata"
+
+	"github.com/oschwald/geoip2-golang"
+)
+
+func (g GeoIP) setASNMetadata(ctx context.Context, data *geoip2.ASN) {
+	number := strconv.FormatUint(uint64(data.AutonomousSystemNumber), 10)
+	metadata.SetValueFunc(ctx, pluginName+"/asn/number", func() string {
+		return number
+	})
+	organization := data.AutonomousSystemOrganization
+	metadata.SetValueFunc(ctx, pluginName+"/asn/organization", func() string {
+		return organization
+	})
+}
+
+// setISPMetadata sets the ISP labels, and the ASN ones as the ISP schema is a superset of the ASN
+// schema.
+func (g GeoIP) setISPMetadata(ctx context.Context, data *geoip2.ISP) {
+	g.setASNMetadata(ctx, &geoip2.ASN{
+		AutonomousSystemNumber:       data.AutonomousSystemNumber,
+		AutonomousSystemOrganization: data.AutonomousSystemOrganization,
+	})
+
+	name := data.ISP
+	metadata.SetValueFunc(ctx, pluginName+"/isp/name", func() string {
+		return name
+	})
+	organization := data.Organization
+	metadata.SetValueFunc(ctx, pluginName+"/isp/organization", func() string {
+		return organization
+	})
+	mcc := data.MobileCountryCode
+	metadata.SetValueFunc(ctx, pluginName+"/isp/mobile_country_code", func() string {
+		return mcc
+	})
+	mnc := data.MobileNetworkCode
+	metadata.SetValueFunc(ctx, pluginName+"/isp/mobile_network_code", func() string {
+		return mnc
+	})
+}
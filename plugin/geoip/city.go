@@ -3,6 +3,7 @@ package geoip
 import (
 	"context"
 	"strconv"
+	"strings"
 
 	"github.com/coredns/coredns/plugin/metadata"
 
@@ -34,6 +35,14 @@ func (g GeoIP) setCityMetadata(ctx context.Context, data *geoip2.City) {
 	metadata.SetValueFunc(ctx, pluginName+"/country/is_in_european_union", func() string {
 		return isInEurope
 	})
+	subdivisionCodes := make([]string, len(data.Subdivisions))
+	for i, sub := range data.Subdivisions {
+		subdivisionCodes[i] = sub.IsoCode
+	}
+	subdivisions := strings.Join(subdivisionCodes, ",")
+	metadata.SetValueFunc(ctx, pluginName+"/subdivisions/code", func() string {
+		return subdivisions
+	})
 	continentCode := data.Continent.Code
 	metadata.SetValueFunc(ctx, pluginName+"/continent/code", func() string {
 		return continentCode
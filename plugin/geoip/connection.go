@@ -0,0 +1,16 @@
+package geoip
+
+import (
+	"context"
+
+	"github.com/coredns/coredns/plugin/metadata"
+
+	"github.com/oschwald/geoip2-golang"
+)
+
+func (g GeoIP) setConnectionTypeMetadata(ctx context.Context, data *geoip2.ConnectionType) {
+	connectionType := data.ConnectionType
+	metadata.SetValueFunc(ctx, pluginName+"/connection/type", func() string {
+		return connectionType
+	})
+}
@@ -5,9 +5,14 @@ import (
 	"context"
 	"fmt"
 	"net"
+	"os"
 	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
 
 	"github.com/coredns/coredns/plugin"
+	"github.com/coredns/coredns/plugin/metadata"
 	clog "github.com/coredns/coredns/plugin/pkg/log"
 	"github.com/coredns/coredns/request"
 
@@ -21,52 +26,186 @@ var log = clog.NewWithPlugin(pluginName)
 // geoIP2 database, and which data can be later consumed by other middlewares.
 type GeoIP struct {
 	Next plugin.Handler
-	db   db
+	dbs  []*db
+
+	// edns0 makes the plugin look up the address in the EDNS Client Subnet option of a query, if it
+	// has one, instead of the source address.
+	edns0 bool
+	// reload is the interval at which the database files are checked for changes.
+	reload time.Duration
+	stop   chan struct{}
 }
 
 type db struct {
+	path string
+
+	mu sync.RWMutex
 	*geoip2.Reader
 	// provides defines the schemas that can be obtained by querying this database, by using
 	// bitwise operations.
 	provides int
+
+	// mtime and size are only read and modified by a single goroutine
+	mtime time.Time
+	size  int64
 }
 
 const (
 	city = 1 << iota
+	asn
+	isp
+	connectionType
+	anonymousIP
 )
 
 var probingIP = net.ParseIP("127.0.0.1")
 
-func newGeoIP(dbPath string) (*GeoIP, error) {
-	reader, err := geoip2.Open(dbPath)
+// schemas are the schemas a database may provide, with the lookup that works if it does.
+var schemas = []struct {
+	provides int
+	name     string
+	validate func(*geoip2.Reader) error
+}{
+	{name: "city", provides: city, validate: func(r *geoip2.Reader) error { _, err := r.City(probingIP); return err }},
+	{name: "asn", provides: asn, validate: func(r *geoip2.Reader) error { _, err := r.ASN(probingIP); return err }},
+	{name: "isp", provides: isp, validate: func(r *geoip2.Reader) error { _, err := r.ISP(probingIP); return err }},
+	{name: "connection type", provides: connectionType, validate: func(r *geoip2.Reader) error { _, err := r.ConnectionType(probingIP); return err }},
+	{name: "anonymous ip", provides: anonymousIP, validate: func(r *geoip2.Reader) error { _, err := r.AnonymousIP(probingIP); return err }},
+}
+
+func newGeoIP(dbPaths ...string) (*GeoIP, error) {
+	g := &GeoIP{}
+	provided := map[int]string{}
+	for _, path := range dbPaths {
+		db, err := openDB(path)
+		if err != nil {
+			return nil, err
+		}
+		for _, schema := range schemas {
+			if db.provides&schema.provides == 0 {
+				continue
+			}
+			if other, ok := provided[schema.provides]; ok {
+				return nil, fmt.Errorf("databases %q and %q both provide schema %q", filepath.Base(other), filepath.Base(path), schema.name)
+			}
+			provided[schema.provides] = path
+		}
+		g.dbs = append(g.dbs, db)
+	}
+	return g, nil
+}
+
+func openDB(path string) (*db, error) {
+	reader, provides, err := open(path)
 	if err != nil {
-		return nil, fmt.Errorf("failed to open database file: %v", err)
+		return nil, err
 	}
-	db := db{Reader: reader}
-	schemas := []struct {
-		provides int
-		name     string
-		validate func() error
-	}{
-		{name: "city", provides: city, validate: func() error { _, err := reader.City(probingIP); return err }},
+	db := &db{path: path, Reader: reader, provides: provides}
+	if stat, err := os.Stat(path); err == nil {
+		db.mtime = stat.ModTime()
+		db.size = stat.Size()
 	}
+	return db, nil
+}
+
+// open opens the database in path, and figures out the schemas it provides.
+func open(path string) (*geoip2.Reader, int, error) {
+	reader, err := geoip2.Open(path)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to open database file: %v", err)
+	}
+	provides := 0
 	// Query the database to figure out the database type.
 	for _, schema := range schemas {
-		if err := schema.validate(); err != nil {
+		if err := schema.validate(reader); err != nil {
 			// If we get an InvalidMethodError then we know this database does not provide that schema.
 			if _, ok := err.(geoip2.InvalidMethodError); !ok {
-				return nil, fmt.Errorf("unexpected failure looking up database %q schema %q: %v", filepath.Base(dbPath), schema.name, err)
+				reader.Close()
+				return nil, 0, fmt.Errorf("unexpected failure looking up database %q schema %q: %v", filepath.Base(path), schema.name, err)
 			}
 		} else {
-			db.provides |= schema.provides
+			provides |= schema.provides
 		}
 	}
 
-	if db.provides&city == 0 {
-		return nil, fmt.Errorf("database does not provide city schema")
+	if provides == 0 {
+		reader.Close()
+		return nil, 0, fmt.Errorf("database does not provide any supported schema")
+	}
+	return reader, provides, nil
+}
+
+// reload reopens the database if its size or modification time changed. The new database must
+// provide the same schemas.
+func (db *db) reload() error {
+	stat, err := os.Stat(db.path)
+	if err != nil {
+		return err
+	}
+	if db.mtime.Equal(stat.ModTime()) && db.size == stat.Size() {
+		return nil
+	}
+
+	reader, provides, err := open(db.path)
+	if err != nil {
+		return err
+	}
+	if provides != db.provides {
+		reader.Close()
+		return fmt.Errorf("database %q no longer provides the same schemas", filepath.Base(db.path))
+	}
+
+	db.mu.Lock()
+	old := db.Reader
+	db.Reader = reader
+	db.mu.Unlock()
+	old.Close()
+
+	db.mtime = stat.ModTime()
+	db.size = stat.Size()
+	log.Infof("Reloaded database %q", filepath.Base(db.path))
+	return nil
+}
+
+// OnStartup starts checking the databases for changes every g.reload.
+func (g *GeoIP) OnStartup() error {
+	g.stop = make(chan struct{})
+	if g.reload == 0 {
+		return nil
 	}
+	go func() {
+		ticker := time.NewTicker(g.reload)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-g.stop:
+				return
+			case <-ticker.C:
+				for _, db := range g.dbs {
+					if err := db.reload(); err != nil {
+						log.Warningf("Failed to reload database: %s", err)
+					}
+				}
+			}
+		}
+	}()
+	return nil
+}
 
-	return &GeoIP{db: db}, nil
+// OnShutdown stops checking the databases and closes them.
+func (g *GeoIP) OnShutdown() error {
+	if g.stop != nil {
+		close(g.stop)
+	}
+	for _, db := range g.dbs {
+		db.mu.Lock()
+		if db.Reader != nil {
+			db.Close()
+			db.Reader = nil
+		}
+		db.mu.Unlock()
+	}
+	return nil
 }
 
 // ServeDNS implements the plugin.Handler interface.
@@ -77,18 +216,80 @@ func (g GeoIP) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (
 // Metadata implements the metadata.Provider Interface in the metadata plugin, and is used to store
 // the data associated with the source IP of every request.
 func (g GeoIP) Metadata(ctx context.Context, state request.Request) context.Context {
-	srcIP := net.ParseIP(state.IP())
+	srcIP, subnet := g.lookupIP(state)
+	fromSubnet := strconv.FormatBool(subnet)
+	metadata.SetValueFunc(ctx, pluginName+"/edns-subnet", func() string {
+		return fromSubnet
+	})
 
-	switch {
-	case g.db.provides&city == city:
-		data, err := g.db.City(srcIP)
+	for _, db := range g.dbs {
+		db.mu.RLock()
+		g.setMetadata(ctx, db, srcIP)
+		db.mu.RUnlock()
+	}
+	return ctx
+}
+
+func (g GeoIP) setMetadata(ctx context.Context, db *db, ip net.IP) {
+	if db.Reader == nil {
+		// Closed, queries may still be in flight while the server shuts down.
+		return
+	}
+	if db.provides&city == city {
+		data, err := db.City(ip)
 		if err != nil {
 			log.Debugf("Setting up metadata failed due to database lookup error: %v", err)
-			return ctx
+			return
 		}
 		g.setCityMetadata(ctx, data)
 	}
-	return ctx
+	switch {
+	case db.provides&isp == isp:
+		data, err := db.ISP(ip)
+		if err != nil {
+			log.Debugf("Setting up metadata failed due to database lookup error: %v", err)
+			return
+		}
+		g.setISPMetadata(ctx, data)
+	case db.provides&asn == asn:
+		data, err := db.ASN(ip)
+		if err != nil {
+			log.Debugf("Setting up metadata failed due to database lookup error: %v", err)
+			return
+		}
+		g.setASNMetadata(ctx, data)
+	}
+	if db.provides&connectionType == connectionType {
+		data, err := db.ConnectionType(ip)
+		if err != nil {
+			log.Debugf("Setting up metadata failed due to database lookup error: %v", err)
+			return
+		}
+		g.setConnectionTypeMetadata(ctx, data)
+	}
+	if db.provides&anonymousIP == anonymousIP {
+		data, err := db.AnonymousIP(ip)
+		if err != nil {
+			log.Debugf("Setting up metadata failed due to database lookup error: %v", err)
+			return
+		}
+		g.setAnonymousIPMetadata(ctx, data)
+	}
+}
+
+// lookupIP returns the address to look up: the address in the EDNS Client Subnet option if g.edns0
+// is set and the query has one, the source address otherwise. The bool is true for the former.
+func (g GeoIP) lookupIP(state request.Request) (net.IP, bool) {
+	if g.edns0 {
+		if o := state.Req.IsEdns0(); o != nil {
+			for _, opt := range o.Option {
+				if subnet, ok := opt.(*dns.EDNS0_SUBNET); ok && subnet.Address != nil && !subnet.Address.IsUnspecified() {
+					return subnet.Address, true
+				}
+			}
+		}
+	}
+	return net.ParseIP(state.IP()), false
 }
 
 // Name implements the Handler interface.
@@ -3,11 +3,18 @@ package geoip
 import (
 	"context"
 	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
 	"testing"
+	"time"
 
 	"github.com/coredns/coredns/plugin/metadata"
 	"github.com/coredns/coredns/plugin/test"
 	"github.com/coredns/coredns/request"
+
+	"github.com/miekg/dns"
 )
 
 func TestMetadata(t *testing.T) {
@@ -24,6 +31,8 @@ func TestMetadata(t *testing.T) {
 		// is_in_european_union is set to true only to work around bool zero value, and test is really being set.
 		{cityDBPath, "geoip/country/is_in_european_union", "true"},
 
+		{cityDBPath, "geoip/subdivisions/code", "ENG,CAM"},
+
 		{cityDBPath, "geoip/continent/code", "EU"},
 		{cityDBPath, "geoip/continent/name", "Europe"},
 
@@ -31,6 +40,25 @@ func TestMetadata(t *testing.T) {
 		{cityDBPath, "geoip/longitude", "0.1315"},
 		{cityDBPath, "geoip/timezone", "Europe/London"},
 		{cityDBPath, "geoip/postalcode", "CB4"},
+
+		{asnDBPath, "geoip/asn/number", "20712"},
+		{asnDBPath, "geoip/asn/organization", "Andrews & Arnold Ltd"},
+
+		{ispDBPath, "geoip/asn/number", "20712"},
+		{ispDBPath, "geoip/asn/organization", "Andrews & Arnold Ltd"},
+		{ispDBPath, "geoip/isp/name", "Andrews & Arnold Ltd"},
+		{ispDBPath, "geoip/isp/organization", "STONEHOUSE office network"},
+		{ispDBPath, "geoip/isp/mobile_country_code", "234"},
+		{ispDBPath, "geoip/isp/mobile_network_code", "15"},
+
+		{connectionTypeDBPath, "geoip/connection/type", "Corporate"},
+
+		{anonymousIPDBPath, "geoip/anonymous/is_anonymous", "true"},
+		{anonymousIPDBPath, "geoip/anonymous/is_anonymous_vpn", "true"},
+		{anonymousIPDBPath, "geoip/anonymous/is_hosting_provider", "false"},
+		{anonymousIPDBPath, "geoip/anonymous/is_public_proxy", "false"},
+		{anonymousIPDBPath, "geoip/anonymous/is_residential_proxy", "false"},
+		{anonymousIPDBPath, "geoip/anonymous/is_tor_exit_node", "true"},
 	}
 
 	for i, _test := range tests {
@@ -59,3 +87,144 @@ func TestMetadata(t *testing.T) {
 		}
 	}
 }
+
+func TestMetadataMultipleDatabases(t *testing.T) {
+	geoIP, err := newGeoIP(cityDBPath, ispDBPath, connectionTypeDBPath, anonymousIPDBPath)
+	if err != nil {
+		t.Fatalf("Unable to create geoIP plugin: %v", err)
+	}
+	state := request.Request{W: &test.ResponseWriter{RemoteIP: "81.2.69.142"}, Req: new(dns.Msg)}
+	ctx := metadata.ContextWithMetadata(context.Background())
+	geoIP.Metadata(ctx, state)
+
+	expected := map[string]string{
+		"geoip/city/name":                  "Cambridge",
+		"geoip/asn/number":                 "20712",
+		"geoip/isp/name":                   "Andrews & Arnold Ltd",
+		"geoip/connection/type":            "Corporate",
+		"geoip/anonymous/is_tor_exit_node": "true",
+	}
+	for label, value := range expected {
+		fn := metadata.ValueFunc(ctx, label)
+		if fn == nil {
+			t.Errorf("Label %q not set in metadata plugin context", label)
+			continue
+		}
+		if fn() != value {
+			t.Errorf("Expected value for label %q should be %q, got %q instead", label, value, fn())
+		}
+	}
+}
+
+func TestMetadataEDNSSubnet(t *testing.T) {
+	tests := []struct {
+		edns0    bool
+		subnet   string
+		expected string
+	}{
+		{true, "81.2.69.142", "20712"},
+		// Addresses that aren't in the database get zero values.
+		{true, "", "0"},
+		{false, "81.2.69.142", "0"},
+	}
+
+	for i, tc := range tests {
+		geoIP, err := newGeoIP(asnDBPath)
+		if err != nil {
+			t.Fatalf("Test %d: unable to create geoIP plugin: %v", i, err)
+		}
+		geoIP.edns0 = tc.edns0
+
+		m := new(dns.Msg)
+		m.SetQuestion("example.org.", dns.TypeA)
+		if tc.subnet != "" {
+			m.SetEdns0(4096, false)
+			m.IsEdns0().Option = append(m.IsEdns0().Option, &dns.EDNS0_SUBNET{
+				Code: dns.EDNS0SUBNET, Family: 1, SourceNetmask: 32, Address: net.ParseIP(tc.subnet),
+			})
+		}
+		// The source address isn't in the database.
+		state := request.Request{W: &test.ResponseWriter{RemoteIP: "10.240.0.1"}, Req: m}
+		ctx := metadata.ContextWithMetadata(context.Background())
+		geoIP.Metadata(ctx, state)
+
+		fn := metadata.ValueFunc(ctx, "geoip/asn/number")
+		if fn == nil {
+			t.Errorf("Test %d: label not set in metadata plugin context", i)
+			continue
+		}
+		if fn() != tc.expected {
+			t.Errorf("Test %d: expected ASN %q, got %q", i, tc.expected, fn())
+		}
+
+		fromSubnet := strconv.FormatBool(tc.edns0 && tc.subnet != "")
+		if fn := metadata.ValueFunc(ctx, "geoip/edns-subnet"); fn == nil || fn() != fromSubnet {
+			t.Errorf("Test %d: expected geoip/edns-subnet to be %q", i, fromSubnet)
+		}
+	}
+}
+
+func TestReload(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "GeoIP2.mmdb")
+	copyFile(t, asnDBPath, path)
+
+	geoIP, err := newGeoIP(path)
+	if err != nil {
+		t.Fatalf("Unable to create geoIP plugin: %v", err)
+	}
+	defer geoIP.OnShutdown()
+
+	// Replace the database atomically, with one that provides a different schema.
+	copyFile(t, ispDBPath, path+".tmp")
+	if err := os.Rename(path+".tmp", path); err != nil {
+		t.Fatal(err)
+	}
+	if err := geoIP.dbs[0].reload(); err == nil {
+		t.Errorf("Expected an error reloading a database with different schemas")
+	}
+
+	// And now with the same schema, but an updated modification time.
+	copyFile(t, asnDBPath, path+".tmp")
+	later := time.Now().Add(time.Minute)
+	if err := os.Chtimes(path+".tmp", later, later); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(path+".tmp", path); err != nil {
+		t.Fatal(err)
+	}
+	old := geoIP.dbs[0].Reader
+	if err := geoIP.dbs[0].reload(); err != nil {
+		t.Fatalf("Expected no error reloading the database, got: %v", err)
+	}
+	if geoIP.dbs[0].Reader == old {
+		t.Errorf("Expected the database to be reloaded")
+	}
+
+	// Nothing changed since.
+	current := geoIP.dbs[0].Reader
+	if err := geoIP.dbs[0].reload(); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if geoIP.dbs[0].Reader != current {
+		t.Errorf("Expected the database not to be reloaded")
+	}
+
+	state := request.Request{W: &test.ResponseWriter{RemoteIP: "81.2.69.142"}, Req: new(dns.Msg)}
+	ctx := metadata.ContextWithMetadata(context.Background())
+	geoIP.Metadata(ctx, state)
+	if fn := metadata.ValueFunc(ctx, "geoip/asn/number"); fn == nil || fn() != "20712" {
+		t.Errorf("Expected the reloaded database to be used for lookups")
+	}
+}
+
+func copyFile(t *testing.T, src, dst string) {
+	t.Helper()
+	data, err := os.ReadFile(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(dst, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+}
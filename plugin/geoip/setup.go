@@ -1,6 +1,8 @@
 package geoip
 
 import (
+	"time"
+
 	"github.com/coredns/caddy"
 	"github.com/coredns/coredns/core/dnsserver"
 	"github.com/coredns/coredns/plugin"
@@ -8,6 +10,8 @@ import (
 
 const pluginName = "geoip"
 
+const defaultReload = 30 * time.Second
+
 func init() { plugin.Register(pluginName, setup) }
 
 func setup(c *caddy.Controller) error {
@@ -16,6 +20,9 @@ func setup(c *caddy.Controller) error {
 		return plugin.Error(pluginName, err)
 	}
 
+	c.OnStartup(geoip.OnStartup)
+	c.OnShutdown(geoip.OnShutdown)
+
 	dnsserver.GetConfig(c).AddPlugin(func(next plugin.Handler) plugin.Handler {
 		geoip.Next = next
 		return geoip
@@ -25,29 +32,55 @@ func setup(c *caddy.Controller) error {
 }
 
 func geoipParse(c *caddy.Controller) (*GeoIP, error) {
-	var dbPath string
+	var (
+		dbPaths []string
+		edns0   bool
+		reload  = defaultReload
+	)
 
+	i := 0
 	for c.Next() {
-		if !c.NextArg() {
-			return nil, c.ArgErr()
+		if i > 0 {
+			return nil, plugin.ErrOnce
 		}
-		if dbPath != "" {
-			return nil, c.Errf("configuring multiple databases is not supported")
-		}
-		dbPath = c.Val()
-		// There shouldn't be any more arguments.
-		if len(c.RemainingArgs()) != 0 {
+		i++
+
+		dbPaths = c.RemainingArgs()
+		if len(dbPaths) == 0 {
 			return nil, c.ArgErr()
 		}
-		// The plugin should not have any config block.
-		if c.NextBlock() {
-			return nil, c.Err("unexpected config block")
+
+		for c.NextBlock() {
+			switch c.Val() {
+			case "edns-subnet":
+				if c.NextArg() {
+					return nil, c.ArgErr()
+				}
+				edns0 = true
+			case "reload":
+				remaining := c.RemainingArgs()
+				if len(remaining) != 1 {
+					return nil, c.Errf("reload needs a duration (zero seconds to disable)")
+				}
+				d, err := time.ParseDuration(remaining[0])
+				if err != nil {
+					return nil, c.Errf("invalid duration for reload '%s'", remaining[0])
+				}
+				if d < 0 {
+					return nil, c.Errf("invalid negative duration for reload '%s'", remaining[0])
+				}
+				reload = d
+			default:
+				return nil, c.Errf("unknown property '%s'", c.Val())
+			}
 		}
 	}
 
-	geoIP, err := newGeoIP(dbPath)
+	geoIP, err := newGeoIP(dbPaths...)
 	if err != nil {
 		return geoIP, c.Err(err.Error())
 	}
+	geoIP.edns0 = edns0
+	geoIP.reload = reload
 	return geoIP, nil
 }
@@ -12,9 +12,13 @@ import (
 )
 
 var (
-	fixturesDir   = "./testdata"
-	cityDBPath    = filepath.Join(fixturesDir, "GeoLite2-City.mmdb")
-	unknownDBPath = filepath.Join(fixturesDir, "GeoLite2-UnknownDbType.mmdb")
+	fixturesDir          = "./testdata"
+	cityDBPath           = filepath.Join(fixturesDir, "GeoLite2-City.mmdb")
+	asnDBPath            = filepath.Join(fixturesDir, "GeoLite2-ASN.mmdb")
+	ispDBPath            = filepath.Join(fixturesDir, "GeoIP2-ISP.mmdb")
+	connectionTypeDBPath = filepath.Join(fixturesDir, "GeoIP2-Connection-Type.mmdb")
+	anonymousIPDBPath    = filepath.Join(fixturesDir, "GeoIP2-Anonymous-IP.mmdb")
+	unknownDBPath        = filepath.Join(fixturesDir, "GeoLite2-UnknownDbType.mmdb")
 )
 
 func TestProbingIP(t *testing.T) {
@@ -52,14 +56,24 @@ func TestGeoIPParse(t *testing.T) {
 	}{
 		// Valid
 		{false, fmt.Sprintf("%s %s\n", pluginName, cityDBPath), "", city},
+		{false, fmt.Sprintf("%s %s\n", pluginName, asnDBPath), "", asn},
+		{false, fmt.Sprintf("%s %s\n", pluginName, ispDBPath), "", isp | asn},
+		{false, fmt.Sprintf("%s %s\n", pluginName, connectionTypeDBPath), "", connectionType},
+		{false, fmt.Sprintf("%s %s\n", pluginName, anonymousIPDBPath), "", anonymousIP},
+		{false, fmt.Sprintf("%s %s %s %s\n", pluginName, cityDBPath, connectionTypeDBPath, anonymousIPDBPath), "", city | connectionType | anonymousIP},
+		{false, fmt.Sprintf("%s %s {\n\tedns-subnet\n\treload 1m\n}\n", pluginName, cityDBPath), "", city},
 
 		// Invalid
 		{true, pluginName, "Wrong argument count", 0},
-		{true, fmt.Sprintf("%s %s {\n\tlanguages en fr es zh-CN\n}\n", pluginName, cityDBPath), "unexpected config block", 0},
-		{true, fmt.Sprintf("%s %s\n%s %s\n", pluginName, cityDBPath, pluginName, cityDBPath), "configuring multiple databases is not supported", 0},
-		{true, fmt.Sprintf("%s 1 2 3", pluginName), "Wrong argument count", 0},
+		{true, fmt.Sprintf("%s %s {\n\tlanguages en fr es zh-CN\n}\n", pluginName, cityDBPath), "unknown property 'languages'", 0},
+		{true, fmt.Sprintf("%s %s\n%s %s\n", pluginName, cityDBPath, pluginName, cityDBPath), "this plugin can only be used once per Server Block", 0},
+		{true, fmt.Sprintf("%s 1 2 3", pluginName), "failed to open database file: open 1: no such file or directory", 0},
 		{true, fmt.Sprintf("%s { }", pluginName), "Error during parsing", 0},
-		{true, fmt.Sprintf("%s /dbpath { city }", pluginName), "unexpected config block", 0},
+		{true, fmt.Sprintf("%s /dbpath { city }", pluginName), "unknown property 'city'", 0},
+		{true, fmt.Sprintf("%s %s {\n\tedns-subnet yes\n}\n", pluginName, cityDBPath), "Wrong argument count", 0},
+		{true, fmt.Sprintf("%s %s {\n\treload\n}\n", pluginName, cityDBPath), "reload needs a duration", 0},
+		{true, fmt.Sprintf("%s %s {\n\treload -1s\n}\n", pluginName, cityDBPath), "invalid negative duration for reload", 0},
+		{true, fmt.Sprintf("%s %s %s\n", pluginName, asnDBPath, ispDBPath), "both provide schema \"asn\"", 0},
 		{true, fmt.Sprintf("%s /invalidPath\n", pluginName), "failed to open database file: open /invalidPath: no such file or directory", 0},
 		{true, fmt.Sprintf("%s %s\n", pluginName, unknownDBPath), "reader does not support the \"UnknownDbType\" database type", 0},
 	}
@@ -83,12 +97,16 @@ func TestGeoIPParse(t *testing.T) {
 			continue
 		}
 
-		if geoIP.db.Reader == nil {
-			t.Errorf("Test %d: after parsing database reader should be initialized", i)
+		provides := 0
+		for _, db := range geoIP.dbs {
+			if db.Reader == nil {
+				t.Errorf("Test %d: after parsing database reader should be initialized", i)
+			}
+			provides |= db.provides
 		}
 
-		if geoIP.db.provides&test.expectedDBType == 0 {
-			t.Errorf("Test %d: expected db type %d not found, database file provides %d", i, test.expectedDBType, geoIP.db.provides)
+		if provides != test.expectedDBType {
+			t.Errorf("Test %d: expected db type %d not found, database files provide %d", i, test.expectedDBType, provides)
 		}
 	}
 
@@ -85,6 +85,11 @@ func parseStanza(c *caddy.Controller) (*GRPC, error) {
 		g.tlsConfig.ServerName = g.tlsServerName
 	}
 	for _, host := range toHosts {
+		if dnsserver.DryRun(c) {
+			// Don't dial the upstreams when only validating.
+			g.proxies = append(g.proxies, &Proxy{addr: host})
+			continue
+		}
 		pr, err := newProxy(host, g.tlsConfig)
 		if err != nil {
 			return nil, err
@@ -36,6 +36,10 @@ func (h Hosts) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (
 		}
 	}
 
+	if h.options.blocklist != "" && state.QType() != dns.TypePTR && h.blocked(qname) {
+		return h.block(w, r, state)
+	}
+
 	switch state.QType() {
 	case dns.TypePTR:
 		names := h.LookupStaticAddr(dnsutil.ExtractAddressFromReverse(qname))
@@ -72,6 +76,28 @@ func (h Hosts) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (
 	return dns.RcodeSuccess, nil
 }
 
+// block answers a query for a blocked name, with NXDOMAIN or with the sinkhole addresses.
+func (h Hosts) block(w dns.ResponseWriter, r *dns.Msg, state request.Request) (int, error) {
+	m := new(dns.Msg)
+	m.SetReply(r)
+	m.Authoritative = true
+
+	if h.options.blocklist == blockNXDomain {
+		m.Rcode = dns.RcodeNameError
+		w.WriteMsg(m)
+		return dns.RcodeNameError, nil
+	}
+
+	switch state.QType() {
+	case dns.TypeA:
+		m.Answer = a(state.Name(), h.options.ttl, h.options.sinkhole4)
+	case dns.TypeAAAA:
+		m.Answer = aaaa(state.Name(), h.options.ttl, h.options.sinkhole6)
+	}
+	w.WriteMsg(m)
+	return dns.RcodeSuccess, nil
+}
+
 func (h Hosts) otherRecordsExist(qname string) bool {
 	if len(h.LookupStaticHostV4(qname)) > 0 {
 		return true
@@ -2,6 +2,7 @@ package hosts
 
 import (
 	"context"
+	"net"
 	"strings"
 	"testing"
 
@@ -118,3 +119,61 @@ const hostsExample = `
 reload 5s
 timeout 3600
 `
+
+func TestLookupBlocklist(t *testing.T) {
+	const blocked = "0.0.0.0 ads.example.org\n10.0.0.1 www.example.org\n"
+	tests := []struct {
+		mode     string
+		qname    string
+		qtype    uint16
+		rcode    int
+		expected []string
+	}{
+		{blockNXDomain, "ads.example.org.", dns.TypeA, dns.RcodeNameError, nil},
+		{blockNXDomain, "www.example.org.", dns.TypeA, dns.RcodeSuccess, []string{"10.0.0.1"}},
+		{blockSinkhole, "ads.example.org.", dns.TypeA, dns.RcodeSuccess, []string{"192.0.2.53"}},
+		{blockSinkhole, "ads.example.org.", dns.TypeAAAA, dns.RcodeSuccess, []string{"2001:db8::53"}},
+		{blockSinkhole, "ads.example.org.", dns.TypeMX, dns.RcodeSuccess, nil},
+	}
+
+	for i, tc := range tests {
+		h := Hosts{
+			Next: test.NextHandler(dns.RcodeNameError, nil),
+			Hostsfile: &Hostsfile{
+				Origins: []string{"."},
+				hmap:    newMap(),
+				inline:  newMap(),
+				options: newOptions(),
+			},
+		}
+		h.options.blocklist = tc.mode
+		h.options.sinkhole4 = []net.IP{net.ParseIP("192.0.2.53")}
+		h.options.sinkhole6 = []net.IP{net.ParseIP("2001:db8::53")}
+		h.hmap = h.parse(strings.NewReader(blocked))
+
+		m := new(dns.Msg)
+		m.SetQuestion(tc.qname, tc.qtype)
+		rec := dnstest.NewRecorder(&test.ResponseWriter{})
+		if _, err := h.ServeDNS(context.Background(), rec, m); err != nil {
+			t.Fatalf("Test %d: expected no error, got %v", i, err)
+		}
+		if rec.Msg.Rcode != tc.rcode {
+			t.Errorf("Test %d: expected rcode %d, got %d", i, tc.rcode, rec.Msg.Rcode)
+		}
+		if len(rec.Msg.Answer) != len(tc.expected) {
+			t.Fatalf("Test %d: expected %d answers, got %d", i, len(tc.expected), len(rec.Msg.Answer))
+		}
+		for j, rr := range rec.Msg.Answer {
+			var ip net.IP
+			switch rr := rr.(type) {
+			case *dns.A:
+				ip = rr.A
+			case *dns.AAAA:
+				ip = rr.AAAA
+			}
+			if ip.String() != tc.expected[j] {
+				t.Errorf("Test %d: expected %s, got %s", i, tc.expected[j], ip)
+			}
+		}
+	}
+}
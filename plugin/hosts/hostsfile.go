@@ -12,6 +12,7 @@ import (
 	"io"
 	"net"
 	"os"
+	"path/filepath"
 	"strings"
 	"sync"
 	"time"
@@ -39,8 +40,21 @@ type options struct {
 
 	// The time between two reload of the configuration
 	reload time.Duration
+
+	// What to do with names that are mapped to 0.0.0.0 or ::, either blockNXDomain or
+	// blockSinkhole. If empty these are treated like any other entry.
+	blocklist string
+
+	// The addresses returned for blocked names in sinkhole mode.
+	sinkhole4 []net.IP
+	sinkhole6 []net.IP
 }
 
+const (
+	blockNXDomain = "nxdomain"
+	blockSinkhole = "sinkhole"
+)
+
 func newOptions() *options {
 	return &options{
 		autoReverse: true,
@@ -59,13 +73,17 @@ type Map struct {
 	// including IPv6 address without zone identifier.
 	// We don't support old-classful IP address notation.
 	addr map[string][]string
+
+	// Names that are blocked, only used in blocklist mode.
+	blocked map[string]struct{}
 }
 
 func newMap() *Map {
 	return &Map{
-		name4: make(map[string][]net.IP),
-		name6: make(map[string][]net.IP),
-		addr:  make(map[string][]string),
+		name4:   make(map[string][]net.IP),
+		name6:   make(map[string][]net.IP),
+		addr:    make(map[string][]string),
+		blocked: make(map[string]struct{}),
 	}
 }
 
@@ -81,7 +99,68 @@ func (h *Map) Len() int {
 	for _, a := range h.addr {
 		l += len(a)
 	}
-	return l
+	return l + len(h.blocked)
+}
+
+// has returns true if name has any entry in h.
+func (h *Map) has(name string) bool {
+	if _, ok := h.name4[name]; ok {
+		return true
+	}
+	if _, ok := h.name6[name]; ok {
+		return true
+	}
+	_, ok := h.blocked[name]
+	return ok
+}
+
+// merge merges maps into a single map. The first map that has an entry for a name, defines all
+// the entries of that name; the same holds for the reverse entries of an address. Blocked names
+// are the exception, these are blocked if any map blocks them.
+func merge(maps []*Map) *Map {
+	switch len(maps) {
+	case 0:
+		return newMap()
+	case 1:
+		return maps[0]
+	}
+
+	m := newMap()
+	for _, hm := range maps {
+		for name, ips := range hm.name4 {
+			if !m.has(name) {
+				m.name4[name] = ips
+				if ips6, ok := hm.name6[name]; ok {
+					m.name6[name] = ips6
+				}
+			}
+		}
+		for name, ips := range hm.name6 {
+			if !m.has(name) {
+				m.name6[name] = ips
+			}
+		}
+		// A blocked name stays blocked, whichever file lists it first.
+		for name := range hm.blocked {
+			m.blocked[name] = struct{}{}
+		}
+		for addr, names := range hm.addr {
+			if _, ok := m.addr[addr]; !ok {
+				m.addr[addr] = names
+			}
+		}
+	}
+	return m
+}
+
+// hostsFile is a single hosts file that is reloaded when it changes.
+type hostsFile struct {
+	path string
+	hmap *Map
+
+	// mtime and size are only read and modified by a single goroutine
+	mtime time.Time
+	size  int64
 }
 
 // Hostsfile contains known host entries.
@@ -97,52 +176,123 @@ type Hostsfile struct {
 	// inline saves the hosts file that is inlined in a Corefile.
 	inline *Map
 
-	// path to the hosts file
-	path string
+	// paths of the hosts files, these may be glob patterns. When a name is in more than one file,
+	// the first file (in this order, glob matches are sorted) wins.
+	paths []string
 
-	// mtime and size are only read and modified by a single goroutine
-	mtime time.Time
-	size  int64
+	// files holds the hosts files that were read, by path. It is only read and modified by a
+	// single goroutine.
+	files map[string]*hostsFile
 
 	options *options
 }
 
-// readHosts determines if the cached data needs to be updated based on the size and modification time of the hostsfile.
+// readHosts rereads the hosts files that have changed, based on their size and modification time,
+// and drops the ones that no longer exist or match.
 func (h *Hostsfile) readHosts() {
-	file, err := os.Open(h.path)
+	if h.files == nil {
+		h.files = make(map[string]*hostsFile)
+	}
+
+	changed := false
+	paths := h.expand()
+	seen := make(map[string]bool, len(paths))
+	maps := make([]*Map, 0, len(paths))
+	for _, path := range paths {
+		seen[path] = true
+		f, ok := h.files[path]
+		if !ok {
+			f = &hostsFile{path: path}
+			h.files[path] = f
+		}
+		if h.readFile(f) {
+			changed = true
+		}
+		if f.hmap != nil {
+			maps = append(maps, f.hmap)
+		}
+	}
+	for path := range h.files {
+		if !seen[path] {
+			delete(h.files, path)
+			hostsEntries.DeleteLabelValues(path)
+			changed = true
+		}
+	}
+	if !changed {
+		return
+	}
+
+	hmap := merge(maps)
+
+	h.Lock()
+	h.hmap = hmap
+	h.Unlock()
+
+	if h.inline.Len() > 0 {
+		hostsEntries.WithLabelValues("inline").Set(float64(h.inline.Len()))
+	}
+}
+
+// expand returns the paths of the hosts files, with glob patterns expanded.
+func (h *Hostsfile) expand() []string {
+	paths := make([]string, 0, len(h.paths))
+	seen := make(map[string]bool, len(h.paths))
+	for _, p := range h.paths {
+		matches := []string{p}
+		if isGlob(p) {
+			// Glob returns its matches sorted, the only possible error is a bad pattern, which
+			// is checked on setup.
+			matches, _ = filepath.Glob(p)
+		}
+		for _, m := range matches {
+			if seen[m] {
+				continue
+			}
+			if isGlob(p) {
+				if s, err := os.Stat(m); err != nil || s.IsDir() {
+					continue
+				}
+			}
+			seen[m] = true
+			paths = append(paths, m)
+		}
+	}
+	return paths
+}
+
+// readFile rereads f if it changed and returns true if it did.
+func (h *Hostsfile) readFile(f *hostsFile) bool {
+	file, err := os.Open(f.path)
 	if err != nil {
 		// We already log a warning if the file doesn't exist or can't be opened on setup. No need to return the error here.
-		return
+		return false
 	}
 	defer file.Close()
 
 	stat, err := file.Stat()
 	if err != nil {
-		return
+		return false
 	}
-	h.RLock()
-	size := h.size
-	h.RUnlock()
-
-	if h.mtime.Equal(stat.ModTime()) && size == stat.Size() {
-		return
+	if f.mtime.Equal(stat.ModTime()) && f.size == stat.Size() {
+		return false
 	}
 
-	newMap := h.parse(file)
-	log.Debugf("Parsed hosts file into %d entries", newMap.Len())
+	f.hmap = h.parse(file)
+	log.Debugf("Parsed hosts file %s into %d entries", f.path, f.hmap.Len())
 
-	h.Lock()
-
-	h.hmap = newMap
 	// Update the data cache.
-	h.mtime = stat.ModTime()
-	h.size = stat.Size()
+	f.mtime = stat.ModTime()
+	f.size = stat.Size()
 
-	hostsEntries.WithLabelValues().Set(float64(h.inline.Len() + h.hmap.Len()))
+	hostsEntries.WithLabelValues(f.path).Set(float64(f.hmap.Len()))
 	hostsReloadTime.Set(float64(stat.ModTime().UnixNano()) / 1e9)
-	h.Unlock()
+	return true
 }
 
+// isGlob returns true if path contains any of the glob meta characters.
+func isGlob(path string) bool { return strings.ContainsAny(path, "*?[") }
+
 func (h *Hostsfile) initInline(inline []string) {
 	if len(inline) == 0 {
 		return
@@ -171,6 +321,8 @@ func (h *Hostsfile) parse(r io.Reader) *Map {
 			continue
 		}
 
+		blocked := h.options.blocklist != "" && addr.IsUnspecified()
+
 		family := 0
 		if addr.To4() != nil {
 			family = 1
@@ -184,6 +336,10 @@ func (h *Hostsfile) parse(r io.Reader) *Map {
 				// name is not in Origins
 				continue
 			}
+			if blocked {
+				hmap.blocked[name] = struct{}{}
+				continue
+			}
 			switch family {
 			case 1:
 				hmap.name4[name] = append(hmap.name4[name], addr)
@@ -236,6 +392,19 @@ func (h *Hostsfile) LookupStaticHostV6(host string) []net.IP {
 	return append(ip1, ip2...)
 }
 
+// blocked returns true if host is blocked.
+func (h *Hostsfile) blocked(host string) bool {
+	host = strings.ToLower(host)
+
+	h.RLock()
+	defer h.RUnlock()
+	if _, ok := h.hmap.blocked[host]; ok {
+		return true
+	}
+	_, ok := h.inline.blocked[host]
+	return ok
+}
+
 // LookupStaticAddr looks up the hosts for the given address from the hosts file.
 func (h *Hostsfile) LookupStaticAddr(addr string) []string {
 	addr = parseIP(addr).String()
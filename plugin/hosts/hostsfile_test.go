@@ -6,6 +6,8 @@ package hosts
 
 import (
 	"net"
+	"os"
+	"path/filepath"
 	"reflect"
 	"strings"
 	"testing"
@@ -211,7 +213,7 @@ func testStaticAddr(t *testing.T, ent staticIPEntry, h *Hostsfile) {
 		ent.out[i] = plugin.Name(ent.out[i]).Normalize()
 	}
 	if !reflect.DeepEqual(hosts, ent.out) {
-		t.Errorf("%s, lookupStaticAddr(%s) = %v; want %v", h.path, ent.in, hosts, h)
+		t.Errorf("lookupStaticAddr(%s) = %v; want %v", ent.in, hosts, h)
 	}
 }
 
@@ -239,3 +241,97 @@ func TestHostCacheModification(t *testing.T) {
 	}
 	testStaticAddr(t, entip, h)
 }
+
+func TestHostsFiles(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("main", "10.0.0.1 main.example.org shared.example.org\n")
+	write("b.hosts", "10.0.0.3 b.example.org shared.example.org\n")
+	write("a.hosts", "10.0.0.2 a.example.org shared.example.org\nfd00::2 a.example.org\n")
+
+	h := &Hostsfile{
+		Origins: []string{"."},
+		hmap:    newMap(),
+		inline:  newMap(),
+		paths:   []string{filepath.Join(dir, "main"), filepath.Join(dir, "*.hosts")},
+		options: newOptions(),
+	}
+	h.readHosts()
+
+	for _, ent := range []staticHostEntry{
+		{"main.example.org.", []string{"10.0.0.1"}, nil},
+		{"a.example.org.", []string{"10.0.0.2"}, []string{"fd00::2"}},
+		{"b.example.org.", []string{"10.0.0.3"}, nil},
+		// The first file wins.
+		{"shared.example.org.", []string{"10.0.0.1"}, nil},
+	} {
+		testStaticHost(t, ent, h)
+	}
+	testStaticAddr(t, staticIPEntry{"10.0.0.3", []string{"b.example.org.", "shared.example.org."}}, h)
+
+	// A file that no longer matches is dropped, the others are kept.
+	if err := os.Remove(filepath.Join(dir, "b.hosts")); err != nil {
+		t.Fatal(err)
+	}
+	h.readHosts()
+	if len(h.files) != 2 {
+		t.Errorf("Expected 2 files, got %d", len(h.files))
+	}
+	testStaticHost(t, staticHostEntry{"b.example.org.", nil, nil}, h)
+	testStaticHost(t, staticHostEntry{"a.example.org.", []string{"10.0.0.2"}, []string{"fd00::2"}}, h)
+}
+
+func TestHostsBlocklist(t *testing.T) {
+	h := &Hostsfile{
+		Origins: []string{"."},
+		hmap:    newMap(),
+		inline:  newMap(),
+		options: newOptions(),
+	}
+	h.options.blocklist = blockNXDomain
+	h.hmap = h.parse(strings.NewReader("0.0.0.0 ads.example.org\n:: tracker.example.org\n10.0.0.1 www.example.org\n"))
+
+	for _, name := range []string{"ads.example.org.", "ADS.example.org.", "tracker.example.org."} {
+		if !h.blocked(name) {
+			t.Errorf("Expected %s to be blocked", name)
+		}
+	}
+	if h.blocked("www.example.org.") {
+		t.Errorf("Expected www.example.org. not to be blocked")
+	}
+	if names := h.LookupStaticAddr("0.0.0.0"); len(names) != 0 {
+		t.Errorf("Expected no reverse entries for blocked names, got %v", names)
+	}
+}
+
+func TestHostsBlocklistPrecedence(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("main", "10.0.0.1 www.example.org\n10.0.0.2 both.example.org\n0.0.0.0 both.example.org\n")
+	write("block.hosts", "0.0.0.0 www.example.org\n")
+
+	h := &Hostsfile{
+		Origins: []string{"."},
+		hmap:    newMap(),
+		inline:  newMap(),
+		paths:   []string{filepath.Join(dir, "main"), filepath.Join(dir, "*.hosts")},
+		options: newOptions(),
+	}
+	h.options.blocklist = blockNXDomain
+	h.readHosts()
+
+	// Blocking wins from an address in the same file, and from an address in an earlier file.
+	for _, name := range []string{"both.example.org.", "www.example.org."} {
+		if !h.blocked(name) {
+			t.Errorf("Expected %s to be blocked", name)
+		}
+	}
+}
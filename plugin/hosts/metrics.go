@@ -8,13 +8,13 @@ import (
 )
 
 var (
-	// hostsEntries is the combined number of entries in hosts and Corefile.
+	// hostsEntries is the number of entries per hosts file, entries from the Corefile have file="inline".
 	hostsEntries = promauto.NewGaugeVec(prometheus.GaugeOpts{
 		Namespace: plugin.Namespace,
 		Subsystem: "hosts",
 		Name:      "entries",
-		Help:      "The combined number of entries in hosts and Corefile.",
-	}, []string{})
+		Help:      "The number of entries in each hosts file and the Corefile.",
+	}, []string{"file"})
 	// hostsReloadTime is the timestamp of the last reload of hosts file.
 	hostsReloadTime = promauto.NewGauge(prometheus.GaugeOpts{
 		Namespace: plugin.Namespace,
@@ -1,6 +1,7 @@
 package hosts
 
 import (
+	"net"
 	"os"
 	"path/filepath"
 	"strconv"
@@ -56,6 +57,12 @@ func setup(c *caddy.Controller) error {
 		return nil
 	})
 
+	c.OnRestart(func() error {
+		// Files may no longer be used after the restart, each instance sets its own again.
+		hostsEntries.Reset()
+		return nil
+	})
+
 	dnsserver.GetConfig(c).AddPlugin(func(next plugin.Handler) plugin.Handler {
 		h.Next = next
 		return h
@@ -69,7 +76,7 @@ func hostsParse(c *caddy.Controller) (Hosts, error) {
 
 	h := Hosts{
 		Hostsfile: &Hostsfile{
-			path:    "/etc/hosts",
+			paths:   []string{"/etc/hosts"},
 			hmap:    newMap(),
 			inline:  newMap(),
 			options: newOptions(),
@@ -87,23 +94,12 @@ func hostsParse(c *caddy.Controller) (Hosts, error) {
 		args := c.RemainingArgs()
 
 		if len(args) >= 1 {
-			h.path = args[0]
-			args = args[1:]
-
-			if !filepath.IsAbs(h.path) && config.Root != "" {
-				h.path = filepath.Join(config.Root, h.path)
-			}
-			s, err := os.Stat(h.path)
+			path, err := hostsPath(c, config.Root, args[0])
 			if err != nil {
-				if os.IsNotExist(err) {
-					log.Warningf("File does not exist: %s", h.path)
-				} else {
-					return h, c.Errf("unable to access hosts file '%s': %v", h.path, err)
-				}
-			}
-			if s != nil && s.IsDir() {
-				log.Warningf("Hosts file %q is a directory", h.path)
+				return h, err
 			}
+			h.paths[0] = path
+			args = args[1:]
 		}
 
 		h.Origins = plugin.OriginsFromArgsOrServerBlock(args, c.ServerBlockKeys)
@@ -112,6 +108,47 @@ func hostsParse(c *caddy.Controller) (Hosts, error) {
 			switch c.Val() {
 			case "fallthrough":
 				h.Fall.SetZonesFromArgs(c.RemainingArgs())
+			case "files":
+				remaining := c.RemainingArgs()
+				if len(remaining) == 0 {
+					return h, c.ArgErr()
+				}
+				for _, p := range remaining {
+					path, err := hostsPath(c, config.Root, p)
+					if err != nil {
+						return h, err
+					}
+					h.paths = append(h.paths, path)
+				}
+			case "blocklist":
+				remaining := c.RemainingArgs()
+				if len(remaining) == 0 {
+					return h, c.ArgErr()
+				}
+				switch remaining[0] {
+				case blockNXDomain:
+					if len(remaining) != 1 {
+						return h, c.ArgErr()
+					}
+				case blockSinkhole:
+					if len(remaining) == 1 {
+						return h, c.Errf("blocklist sinkhole needs at least one address")
+					}
+					for _, a := range remaining[1:] {
+						ip := net.ParseIP(a)
+						if ip == nil {
+							return h, c.Errf("invalid sinkhole address '%s'", a)
+						}
+						if ip.To4() != nil {
+							h.options.sinkhole4 = append(h.options.sinkhole4, ip)
+						} else {
+							h.options.sinkhole6 = append(h.options.sinkhole6, ip)
+						}
+					}
+				default:
+					return h, c.Errf("unknown blocklist mode '%s', expected %s or %s", remaining[0], blockNXDomain, blockSinkhole)
+				}
+				h.options.blocklist = remaining[0]
 			case "no_reverse":
 				h.options.autoReverse = false
 			case "ttl":
@@ -155,3 +192,34 @@ func hostsParse(c *caddy.Controller) (Hosts, error) {
 
 	return h, nil
 }
+
+// hostsPath returns path relative to root, if it isn't absolute, and warns if it doesn't exist. Path
+// may be a glob pattern, which is only checked for validity.
+func hostsPath(c *caddy.Controller, root, path string) (string, error) {
+	if !filepath.IsAbs(path) && root != "" {
+		path = filepath.Join(root, path)
+	}
+	if isGlob(path) {
+		matches, err := filepath.Glob(path)
+		if err != nil {
+			return path, c.Errf("invalid hosts file pattern '%s': %v", path, err)
+		}
+		if len(matches) == 0 {
+			log.Warningf("No files match: %s", path)
+		}
+		return path, nil
+	}
+
+	s, err := os.Stat(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			log.Warningf("File does not exist: %s", path)
+		} else {
+			return path, c.Errf("unable to access hosts file '%s': %v", path, err)
+		}
+	}
+	if s != nil && s.IsDir() {
+		log.Warningf("Hosts file %q is a directory", path)
+	}
+	return path, nil
+}
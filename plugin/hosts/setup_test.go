@@ -1,6 +1,7 @@
 package hosts
 
 import (
+	"reflect"
 	"testing"
 
 	"github.com/coredns/caddy"
@@ -76,8 +77,8 @@ func TestHostsParse(t *testing.T) {
 		} else if err != nil && !test.shouldErr {
 			t.Fatalf("Test %d expected no errors, but got '%v'", i, err)
 		} else if !test.shouldErr {
-			if h.path != test.expectedPath {
-				t.Fatalf("Test %d expected %v, got %v", i, test.expectedPath, h.path)
+			if h.paths[0] != test.expectedPath {
+				t.Fatalf("Test %d expected %v, got %v", i, test.expectedPath, h.paths[0])
 			}
 		} else {
 			if !h.Fall.Equal(test.expectedFallthrough) {
@@ -167,3 +168,62 @@ func TestHostsInlineParse(t *testing.T) {
 		}
 	}
 }
+
+func TestHostsParseOptions(t *testing.T) {
+	tests := []struct {
+		input         string
+		shouldErr     bool
+		expectedPaths []string
+		blocklist     string
+	}{
+		{`hosts /etc/hosts {
+			files /tmp/a.hosts /etc/hosts.d/*.hosts
+		}`, false, []string{"/etc/hosts", "/tmp/a.hosts", "/etc/hosts.d/*.hosts"}, ""},
+		{`hosts /etc/hosts.d/*.hosts`, false, []string{"/etc/hosts.d/*.hosts"}, ""},
+		{`hosts {
+			blocklist nxdomain
+		}`, false, []string{"/etc/hosts"}, blockNXDomain},
+		{`hosts {
+			blocklist sinkhole 192.0.2.53 2001:db8::53
+		}`, false, []string{"/etc/hosts"}, blockSinkhole},
+		// fails
+		{`hosts {
+			files
+		}`, true, nil, ""},
+		{`hosts /etc/hosts.d/[.hosts`, true, nil, ""},
+		{`hosts {
+			blocklist
+		}`, true, nil, ""},
+		{`hosts {
+			blocklist nxdomain 192.0.2.53
+		}`, true, nil, ""},
+		{`hosts {
+			blocklist sinkhole
+		}`, true, nil, ""},
+		{`hosts {
+			blocklist sinkhole example.org
+		}`, true, nil, ""},
+		{`hosts {
+			blocklist refuse
+		}`, true, nil, ""},
+	}
+
+	for i, test := range tests {
+		c := caddy.NewTestController("dns", test.input)
+		h, err := hostsParse(c)
+		if err == nil && test.shouldErr {
+			t.Fatalf("Test %d expected errors, but got no error", i)
+		} else if err != nil && !test.shouldErr {
+			t.Fatalf("Test %d expected no errors, but got '%v'", i, err)
+		}
+		if test.shouldErr {
+			continue
+		}
+		if !reflect.DeepEqual(h.paths, test.expectedPaths) {
+			t.Errorf("Test %d expected paths %v, got %v", i, test.expectedPaths, h.paths)
+		}
+		if h.options.blocklist != test.blocklist {
+			t.Errorf("Test %d expected blocklist %q, got %q", i, test.blocklist, h.options.blocklist)
+		}
+	}
+}
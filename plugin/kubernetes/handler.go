@@ -4,6 +4,7 @@ import (
 	"context"
 
 	"github.com/coredns/coredns/plugin"
+	"github.com/coredns/coredns/plugin/dnstap"
 	"github.com/coredns/coredns/request"
 
 	"github.com/miekg/dns"
@@ -62,10 +63,16 @@ func (k Kubernetes) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.M
 		_, _, err = plugin.A(ctx, &k, zone, fake, nil, plugin.Options{})
 	}
 
+	if k.IsNameError(err) && k.Fall.Through(state.Name()) {
+		return plugin.NextOrFailure(k.Name(), k.Next, ctx, w, r)
+	}
+
+	if tapPlugin, ok := dnstap.FromContext(ctx); ok {
+		w = tapPlugin.TapAuth(ctx, w, r)
+		state.W = w
+	}
+
 	if k.IsNameError(err) {
-		if k.Fall.Through(state.Name()) {
-			return plugin.NextOrFailure(k.Name(), k.Next, ctx, w, r)
-		}
 		if !k.APIConn.HasSynced() {
 			// If we haven't synchronized with the kubernetes cluster, return server failure
 			return plugin.BackendError(ctx, &k, zone, dns.RcodeServerFailure, state, nil /* err */, plugin.Options{})
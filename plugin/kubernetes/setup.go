@@ -39,15 +39,18 @@ func setup(c *caddy.Controller) error {
 		return plugin.Error(pluginName, err)
 	}
 
-	onStart, onShut, err := k.InitKubeCache(context.Background())
-	if err != nil {
-		return plugin.Error(pluginName, err)
-	}
-	if onStart != nil {
-		c.OnStartup(onStart)
-	}
-	if onShut != nil {
-		c.OnShutdown(onShut)
+	// Don't load the client config or contact the API server when only validating.
+	if !dnsserver.DryRun(c) {
+		onStart, onShut, err := k.InitKubeCache(context.Background())
+		if err != nil {
+			return plugin.Error(pluginName, err)
+		}
+		if onStart != nil {
+			c.OnStartup(onStart)
+		}
+		if onShut != nil {
+			c.OnShutdown(onShut)
+		}
 	}
 
 	dnsserver.GetConfig(c).AddPlugin(func(next plugin.Handler) plugin.Handler {
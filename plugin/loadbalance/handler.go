@@ -22,3 +22,24 @@ func (rr RoundRobin) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.
 
 // Name implements the Handler interface.
 func (rr RoundRobin) Name() string { return "loadbalance" }
+
+// LoadBalance is a plugin to rewrite responses for "load balancing", with weights, health checks
+// and a limit on the number of addresses. Without any of these it does what RoundRobin does.
+type LoadBalance struct {
+	Next plugin.Handler
+
+	policy     string
+	weights    *weightsFile // may be nil
+	txtWeights *txtCache    // may be nil
+	health     *health      // may be nil
+	maxAnswers int
+}
+
+// ServeDNS implements the plugin.Handler interface.
+func (lb *LoadBalance) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (int, error) {
+	lw := &ResponseWriter{ResponseWriter: w, ctx: ctx, lb: lb}
+	return plugin.NextOrFailure(lb.Name(), lb.Next, ctx, lw, r)
+}
+
+// Name implements the Handler interface.
+func (lb *LoadBalance) Name() string { return "loadbalance" }
@@ -0,0 +1,210 @@
+package loadbalance
+
+import (
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+const (
+	defaultHealthInterval = 10 * time.Second
+	defaultHealthTimeout  = 2 * time.Second
+
+	// targetTTL is the number of intervals an address is still probed after it was last in an answer.
+	targetTTL = 10
+	// maxHealthTargets is the maximum number of addresses that are probed, addresses seen after that
+	// are not probed and are up.
+	maxHealthTargets = 1024
+	// maxConcurrentProbes is the maximum number of probes that run at the same time.
+	maxConcurrentProbes = 16
+)
+
+// health probes the addresses of the names in names and in the weights file seen in answers, so the
+// ones that are down can be left out.
+type health struct {
+	proto    string // "tcp" or "http"
+	port     string
+	path     string // for http
+	interval time.Duration
+	timeout  time.Duration
+
+	names   map[string]struct{}
+	weights *weightsFile // may be nil
+
+	client *http.Client
+
+	sync.RWMutex
+	targets map[string]*target
+
+	stop chan struct{}
+}
+
+type target struct {
+	down int32 // 1 if the last probe failed
+	seen int64 // unix nano of when the address was last in an answer
+}
+
+func newHealth(proto, port, path string) *health {
+	return &health{
+		proto:    proto,
+		port:     port,
+		path:     path,
+		interval: defaultHealthInterval,
+		timeout:  defaultHealthTimeout,
+		names:    make(map[string]struct{}),
+		targets:  make(map[string]*target),
+	}
+}
+
+// probed returns true if the addresses of name are probed.
+func (h *health) probed(name string) bool {
+	if _, ok := h.names[name]; ok {
+		return true
+	}
+	return h.weights != nil && h.weights.has(name)
+}
+
+// alive returns true if addr is not known to be down, addr is probed from now on unless there are
+// already maxHealthTargets addresses probed.
+func (h *health) alive(addr string) bool {
+	now := time.Now().UnixNano()
+
+	h.RLock()
+	t, ok := h.targets[addr]
+	h.RUnlock()
+	if !ok {
+		h.Lock()
+		if t, ok = h.targets[addr]; !ok {
+			if len(h.targets) >= maxHealthTargets {
+				h.Unlock()
+				return true
+			}
+			t = &target{}
+			h.targets[addr] = t
+		}
+		h.Unlock()
+	}
+	atomic.StoreInt64(&t.seen, now)
+	return atomic.LoadInt32(&t.down) == 0
+}
+
+// filter removes the records of addresses that are down, unless all of them are. Records of names
+// that are not probed are kept.
+func (h *health) filter(records []dns.RR) []dns.RR {
+	alive := make([]dns.RR, 0, len(records))
+	for _, r := range records {
+		if !h.probed(strings.ToLower(r.Header().Name)) || h.alive(recordAddress(r)) {
+			alive = append(alive, r)
+		}
+	}
+	if len(alive) == 0 {
+		return records
+	}
+	return alive
+}
+
+// probe returns true if addr is up.
+func (h *health) probe(addr string) bool {
+	hostport := net.JoinHostPort(addr, h.port)
+	if h.proto == "tcp" {
+		conn, err := net.DialTimeout("tcp", hostport, h.timeout)
+		if err != nil {
+			return false
+		}
+		conn.Close()
+		return true
+	}
+
+	resp, err := h.client.Get("http://" + hostport + h.path)
+	if err != nil {
+		return false
+	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+	return resp.StatusCode >= 200 && resp.StatusCode < 400
+}
+
+// check probes all addresses and forgets about the ones that were not in an answer for a while.
+func (h *health) check() {
+	expired := time.Now().Add(-targetTTL * h.interval).UnixNano()
+
+	h.Lock()
+	targets := make(map[string]*target, len(h.targets))
+	for addr, t := range h.targets {
+		if atomic.LoadInt64(&t.seen) < expired {
+			delete(h.targets, addr)
+			continue
+		}
+		targets[addr] = t
+	}
+	h.Unlock()
+
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, maxConcurrentProbes)
+	for addr, t := range targets {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(addr string, t *target) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			var down int32
+			if !h.probe(addr) {
+				down = 1
+			}
+			if atomic.SwapInt32(&t.down, down) != down {
+				if down == 1 {
+					log.Warningf("Health check of %s failed, leaving it out of answers", addr)
+				} else {
+					log.Infof("Health check of %s succeeded, adding it back to answers", addr)
+				}
+			}
+		}(addr, t)
+	}
+	wg.Wait()
+}
+
+// OnStartup starts probing every h.interval.
+func (h *health) OnStartup() error {
+	h.client = &http.Client{
+		Timeout: h.timeout,
+		// A redirect is an answer, it's not followed.
+		CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+	}
+	h.stop = make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(h.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-h.stop:
+				return
+			case <-ticker.C:
+				h.check()
+			}
+		}
+	}()
+	return nil
+}
+
+// OnShutdown stops probing.
+func (h *health) OnShutdown() error {
+	close(h.stop)
+	return nil
+}
+
+// recordAddress returns the address of an A or AAAA record.
+func recordAddress(r dns.RR) string {
+	switch r := r.(type) {
+	case *dns.A:
+		return r.A.String()
+	case *dns.AAAA:
+		return r.AAAA.String()
+	}
+	return ""
+}
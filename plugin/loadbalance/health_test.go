@@ -0,0 +1,129 @@
+package loadbalance
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/coredns/coredns/plugin/test"
+
+	"github.com/miekg/dns"
+)
+
+func TestHealthProbe(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+	_, port, _ := net.SplitHostPort(ln.Addr().String())
+
+	h := newHealth("tcp", port, "")
+	h.OnStartup()
+	defer h.OnShutdown()
+	if !h.probe("127.0.0.1") {
+		t.Errorf("Expected TCP probe of listening port to succeed")
+	}
+	ln.Close()
+	if h.probe("127.0.0.1") {
+		t.Errorf("Expected TCP probe of closed port to fail")
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/ok":
+			w.WriteHeader(http.StatusOK)
+		case "/redirect":
+			http.Redirect(w, r, "/fail", http.StatusFound)
+		default:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer srv.Close()
+	_, port, _ = net.SplitHostPort(srv.Listener.Addr().String())
+
+	for path, up := range map[string]bool{"/ok": true, "/redirect": true, "/fail": false} {
+		h := newHealth("http", port, path)
+		h.OnStartup()
+		if got := h.probe("127.0.0.1"); got != up {
+			t.Errorf("Expected HTTP probe of %s to return %t, got %t", path, up, got)
+		}
+		h.OnShutdown()
+	}
+}
+
+func TestHealthFilter(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	_, port, _ := net.SplitHostPort(ln.Addr().String())
+
+	records := []dns.RR{
+		test.A("www.example.org. 300 IN A 127.0.0.1"),
+		test.A("www.example.org. 300 IN A 127.0.0.2"),
+	}
+
+	h := newHealth("tcp", port, "")
+	h.names["www.example.org."] = struct{}{}
+	h.OnStartup()
+	defer h.OnShutdown()
+
+	// Unknown addresses are up.
+	if got := h.filter(records); len(got) != 2 {
+		t.Fatalf("Expected 2 records before the first check, got %d", len(got))
+	}
+
+	// Only 127.0.0.1 listens.
+	h.check()
+	got := h.filter(records)
+	if len(got) != 1 || recordAddress(got[0]) != "127.0.0.1" {
+		t.Fatalf("Expected only 127.0.0.1 to be up, got %v", got)
+	}
+
+	// When all are down, none are removed.
+	ln.Close()
+	h.check()
+	if got := h.filter(records); len(got) != 2 {
+		t.Errorf("Expected 2 records when all are down, got %d", len(got))
+	}
+}
+
+func TestHealthNotProbed(t *testing.T) {
+	h := newHealth("tcp", "1", "")
+	h.names["www.example.org."] = struct{}{}
+
+	// The addresses of other names are not probed.
+	h.filter([]dns.RR{test.A("example.org. 300 IN A 127.0.0.1")})
+	if len(h.targets) != 0 {
+		t.Errorf("Expected no targets for a name that is not probed, got %d", len(h.targets))
+	}
+
+	// Names in the weights file are probed.
+	h.weights = &weightsFile{table: table{"example.org.": {"127.0.0.1": 10}}}
+	if !h.probed("example.org.") {
+		t.Errorf("Expected example.org. from the weights file to be probed")
+	}
+
+	// Addresses after the first maxHealthTargets are not probed and are up.
+	for i := 0; i < maxHealthTargets+10; i++ {
+		addr := net.IPv4(10, 0, byte(i>>8), byte(i)).String()
+		if !h.alive(addr) {
+			t.Fatalf("Expected %s to be up", addr)
+		}
+	}
+	if len(h.targets) != maxHealthTargets {
+		t.Errorf("Expected %d targets, got %d", maxHealthTargets, len(h.targets))
+	}
+}
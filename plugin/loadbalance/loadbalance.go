@@ -2,6 +2,9 @@
 package loadbalance
 
 import (
+	"context"
+	"strings"
+
 	"github.com/miekg/dns"
 )
 
@@ -26,10 +29,20 @@ func (r *RoundRobinResponseWriter) WriteMsg(res *dns.Msg) error {
 }
 
 func roundRobin(in []dns.RR) []dns.RR {
-	cname := []dns.RR{}
-	address := []dns.RR{}
-	mx := []dns.RR{}
-	rest := []dns.RR{}
+	cname, address, mx, rest := split(in)
+
+	roundRobinShuffle(address)
+	roundRobinShuffle(mx)
+
+	return join(cname, rest, address, mx)
+}
+
+// split splits in in CNAME, address (A and AAAA), MX and all other records.
+func split(in []dns.RR) (cname, address, mx, rest []dns.RR) {
+	cname = []dns.RR{}
+	address = []dns.RR{}
+	mx = []dns.RR{}
+	rest = []dns.RR{}
 	for _, r := range in {
 		switch r.Header().Rrtype {
 		case dns.TypeCNAME:
@@ -42,10 +55,11 @@ func roundRobin(in []dns.RR) []dns.RR {
 			rest = append(rest, r)
 		}
 	}
+	return cname, address, mx, rest
+}
 
-	roundRobinShuffle(address)
-	roundRobinShuffle(mx)
-
+// join puts the records back together, CNAMEs must come first.
+func join(cname, rest, address, mx []dns.RR) []dns.RR {
 	out := append(cname, rest...)
 	out = append(out, address...)
 	out = append(out, mx...)
@@ -78,3 +92,102 @@ func (r *RoundRobinResponseWriter) Write(buf []byte) (int, error) {
 	n, err := r.ResponseWriter.Write(buf)
 	return n, err
 }
+
+// ResponseWriter is a response writer that orders A, AAAA and MX records as set in a LoadBalance.
+type ResponseWriter struct {
+	dns.ResponseWriter
+	ctx context.Context
+	lb  *LoadBalance
+}
+
+// WriteMsg implements the dns.ResponseWriter interface.
+func (w *ResponseWriter) WriteMsg(res *dns.Msg) error {
+	if res.Rcode != dns.RcodeSuccess {
+		return w.ResponseWriter.WriteMsg(res)
+	}
+
+	if res.Question[0].Qtype == dns.TypeAXFR || res.Question[0].Qtype == dns.TypeIXFR {
+		return w.ResponseWriter.WriteMsg(res)
+	}
+
+	res.Answer = w.answer(res.Answer)
+	res.Ns = roundRobin(res.Ns)
+	res.Extra = roundRobin(res.Extra)
+
+	return w.ResponseWriter.WriteMsg(res)
+}
+
+// answer orders the records in the answer section. Addresses that are down are left out, the rest is
+// ordered by weight, or round robin, and the first lb.maxAnswers of each type are kept.
+func (w *ResponseWriter) answer(in []dns.RR) []dns.RR {
+	cname, address, mx, rest := split(in)
+
+	if w.lb.health != nil {
+		address = w.lb.health.filter(address)
+	}
+	if w.lb.policy == weightedPolicy {
+		weightedShuffle(address, w.weight(address))
+	} else {
+		roundRobinShuffle(address)
+	}
+	if w.lb.maxAnswers > 0 {
+		address = limit(address, w.lb.maxAnswers)
+	}
+	roundRobinShuffle(mx)
+
+	return join(cname, rest, address, mx)
+}
+
+// weight returns a function that returns the weight of an address record. Weights from the weights
+// file come first, then those from TXT records.
+func (w *ResponseWriter) weight(records []dns.RR) func(dns.RR) uint32 {
+	var txt map[string]map[string]uint32
+	if w.lb.txtWeights != nil {
+		txt = map[string]map[string]uint32{}
+		for _, r := range records {
+			name := r.Header().Name
+			if _, ok := txt[name]; !ok {
+				txt[name] = w.lb.txtWeights.weights(w.ctx, w.lb.Next, w.ResponseWriter, name)
+			}
+		}
+	}
+
+	return func(r dns.RR) uint32 {
+		name, addr := r.Header().Name, recordAddress(r)
+		if w.lb.weights != nil {
+			if weight, ok := w.lb.weights.weight(strings.ToLower(name), addr); ok {
+				return weight
+			}
+		}
+		if weight, ok := txt[name][addr]; ok {
+			return weight
+		}
+		return defaultWeight
+	}
+}
+
+// limit keeps the first max A and the first max AAAA records.
+func limit(records []dns.RR, max int) []dns.RR {
+	out := records[:0]
+	a, aaaa := 0, 0
+	for _, r := range records {
+		switch r.Header().Rrtype {
+		case dns.TypeA:
+			if a++; a > max {
+				continue
+			}
+		case dns.TypeAAAA:
+			if aaaa++; aaaa > max {
+				continue
+			}
+		}
+		out = append(out, r)
+	}
+	return out
+}
+
+// Write implements the dns.ResponseWriter interface.
+func (w *ResponseWriter) Write(buf []byte) (int, error) {
+	log.Warning("LoadBalance called with Write: not ordering records")
+	return w.ResponseWriter.Write(buf)
+}
@@ -202,3 +202,83 @@ func handler() plugin.Handler {
 		return dns.RcodeSuccess, nil
 	})
 }
+
+func TestLoadBalanceWeighted(t *testing.T) {
+	// next answers the question with the records below, and TXT queries with the weights.
+	lookups := 0
+	next := plugin.HandlerFunc(func(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (int, error) {
+		m := new(dns.Msg)
+		m.SetReply(r)
+		switch r.Question[0].Qtype {
+		case dns.TypeTXT:
+			lookups++
+			if r.Question[0].Name == "_weight.www.example.org." {
+				m.Answer = []dns.RR{test.TXT(`_weight.www.example.org. 300 IN TXT "10.0.0.1 0" "10.0.0.2 0" "10.0.0.3 1" "bogus"`)}
+			}
+		case dns.TypeA:
+			m.Answer = []dns.RR{
+				test.CNAME("example.org. 300 IN CNAME www.example.org."),
+				test.A("www.example.org. 300 IN A 10.0.0.1"),
+				test.A("www.example.org. 300 IN A 10.0.0.2"),
+				test.A("www.example.org. 300 IN A 10.0.0.3"),
+				test.A("www.example.org. 300 IN A 10.0.0.4"),
+			}
+		}
+		w.WriteMsg(m)
+		return dns.RcodeSuccess, nil
+	})
+
+	lb := &LoadBalance{Next: next, policy: weightedPolicy, txtWeights: newTxtCache([]string{"example.org."}), maxAnswers: 2}
+
+	for i := 0; i < 20; i++ {
+		rec := dnstest.NewRecorder(&test.ResponseWriter{})
+		req := new(dns.Msg)
+		req.SetQuestion("example.org.", dns.TypeA)
+		if _, err := lb.ServeDNS(context.TODO(), rec, req); err != nil {
+			t.Fatalf("Expected no error, got %s", err)
+		}
+
+		answer := rec.Msg.Answer
+		if len(answer) != 3 {
+			t.Fatalf("Expected 3 records in the answer, got %d", len(answer))
+		}
+		if answer[0].Header().Rrtype != dns.TypeCNAME {
+			t.Errorf("Expected the CNAME first, got %s", answer[0])
+		}
+		// 10.0.0.1 and 10.0.0.2 have a zero weight, so they come after 10.0.0.3 and 10.0.0.4.
+		for _, rr := range answer[1:] {
+			if a := recordAddress(rr); a != "10.0.0.3" && a != "10.0.0.4" {
+				t.Errorf("Expected only 10.0.0.3 and 10.0.0.4 in the answer, got %s", a)
+			}
+		}
+	}
+	if lookups != 1 {
+		t.Errorf("Expected the weights to be looked up once, got %d lookups", lookups)
+	}
+
+	// Names outside the zones of txt_weights are not looked up.
+	lb.txtWeights = newTxtCache([]string{"example.net."})
+	rec := dnstest.NewRecorder(&test.ResponseWriter{})
+	req := new(dns.Msg)
+	req.SetQuestion("example.org.", dns.TypeA)
+	if _, err := lb.ServeDNS(context.TODO(), rec, req); err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+	if lookups != 1 {
+		t.Errorf("Expected no lookups for names outside the zones, got %d lookups", lookups-1)
+	}
+}
+
+func TestLimit(t *testing.T) {
+	records := []dns.RR{
+		test.A("www.example.org. 300 IN A 10.0.0.1"),
+		test.AAAA("www.example.org. 300 IN AAAA ::1"),
+		test.A("www.example.org. 300 IN A 10.0.0.2"),
+		test.AAAA("www.example.org. 300 IN AAAA ::2"),
+		test.A("www.example.org. 300 IN A 10.0.0.3"),
+	}
+	got := limit(records, 1)
+	if len(got) != 2 || recordAddress(got[0]) != "10.0.0.1" || recordAddress(got[1]) != "::1" {
+		t.Errorf("Expected the first A and AAAA record, got %v", got)
+	}
+}
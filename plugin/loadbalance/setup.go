@@ -2,6 +2,10 @@ package loadbalance
 
 import (
 	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/coredns/caddy"
 	"github.com/coredns/coredns/core/dnsserver"
@@ -11,34 +15,185 @@ import (
 
 var log = clog.NewWithPlugin("loadbalance")
 
+const (
+	roundRobinPolicy = "round_robin"
+	weightedPolicy   = "weighted"
+
+	defaultWeightsReload = 30 * time.Second
+)
+
 func init() { plugin.Register("loadbalance", setup) }
 
 func setup(c *caddy.Controller) error {
-	err := parse(c)
+	lb, err := parse(c)
 	if err != nil {
 		return plugin.Error("loadbalance", err)
 	}
 
+	if lb.weights != nil {
+		c.OnStartup(lb.weights.OnStartup)
+		c.OnShutdown(lb.weights.OnShutdown)
+	}
+	if lb.health != nil {
+		c.OnStartup(lb.health.OnStartup)
+		c.OnShutdown(lb.health.OnShutdown)
+	}
+
+	if lb.policy == roundRobinPolicy && lb.health == nil && lb.maxAnswers == 0 {
+		dnsserver.GetConfig(c).AddPlugin(func(next plugin.Handler) plugin.Handler {
+			return RoundRobin{Next: next}
+		})
+		return nil
+	}
 	dnsserver.GetConfig(c).AddPlugin(func(next plugin.Handler) plugin.Handler {
-		return RoundRobin{Next: next}
+		lb.Next = next
+		return lb
 	})
-
 	return nil
 }
 
-func parse(c *caddy.Controller) error {
+func parse(c *caddy.Controller) (*LoadBalance, error) {
+	lb := &LoadBalance{policy: roundRobinPolicy}
+	i := 0
 	for c.Next() {
+		if i > 0 {
+			return nil, plugin.ErrOnce
+		}
+		i++
+
 		args := c.RemainingArgs()
 		switch len(args) {
 		case 0:
-			return nil
 		case 1:
-			if args[0] != "round_robin" {
-				return fmt.Errorf("unknown policy: %s", args[0])
+			if args[0] != roundRobinPolicy && args[0] != weightedPolicy {
+				return nil, fmt.Errorf("unknown policy: %s", args[0])
+			}
+			lb.policy = args[0]
+		default:
+			return nil, c.ArgErr()
+		}
 
+		var interval, timeout time.Duration
+		var healthNames []string
+		for c.NextBlock() {
+			switch c.Val() {
+			case "weights":
+				args := c.RemainingArgs()
+				if len(args) < 1 || len(args) > 2 {
+					return nil, c.ArgErr()
+				}
+				path := args[0]
+				if !filepath.IsAbs(path) && dnsserver.GetConfig(c).Root != "" {
+					path = filepath.Join(dnsserver.GetConfig(c).Root, path)
+				}
+				lb.weights = &weightsFile{path: path, reload: defaultWeightsReload}
+				if len(args) == 2 {
+					d, err := time.ParseDuration(args[1])
+					if err != nil || d < 0 {
+						return nil, c.Errf("invalid reload duration '%s'", args[1])
+					}
+					lb.weights.reload = d
+				}
+				if err := lb.weights.read(); err != nil {
+					return nil, c.Errf("unable to read weights: %s", err)
+				}
+			case "txt_weights":
+				zones := plugin.OriginsFromArgsOrServerBlock(c.RemainingArgs(), c.ServerBlockKeys)
+				lb.txtWeights = newTxtCache(zones)
+			case "health_check":
+				args := c.RemainingArgs()
+				if len(args) < 2 {
+					return nil, c.ArgErr()
+				}
+				if p, err := strconv.ParseUint(args[1], 10, 16); err != nil || p == 0 {
+					return nil, c.Errf("invalid port '%s'", args[1])
+				}
+				switch {
+				case args[0] == "tcp" && len(args) == 2:
+					lb.health = newHealth("tcp", args[1], "")
+				case args[0] == "http" && len(args) <= 3:
+					path := "/"
+					if len(args) == 3 {
+						path = args[2]
+					}
+					if !strings.HasPrefix(path, "/") {
+						return nil, c.Errf("invalid path '%s'", path)
+					}
+					lb.health = newHealth("http", args[1], path)
+				case args[0] == "tcp" || args[0] == "http":
+					return nil, c.ArgErr()
+				default:
+					return nil, c.Errf("unknown health check protocol '%s'", args[0])
+				}
+			case "health_names":
+				args := c.RemainingArgs()
+				if len(args) == 0 {
+					return nil, c.ArgErr()
+				}
+				for _, n := range args {
+					healthNames = append(healthNames, plugin.Name(n).Normalize())
+				}
+			case "health_interval":
+				d, err := duration(c)
+				if err != nil {
+					return nil, err
+				}
+				interval = d
+			case "health_timeout":
+				d, err := duration(c)
+				if err != nil {
+					return nil, err
+				}
+				timeout = d
+			case "max_answers":
+				args := c.RemainingArgs()
+				if len(args) != 1 {
+					return nil, c.ArgErr()
+				}
+				n, err := strconv.Atoi(args[0])
+				if err != nil || n <= 0 {
+					return nil, c.Errf("invalid number of answers '%s'", args[0])
+				}
+				lb.maxAnswers = n
+			default:
+				return nil, c.Errf("unknown property '%s'", c.Val())
 			}
-			return nil
 		}
+
+		if (lb.weights != nil || lb.txtWeights != nil) && lb.policy != weightedPolicy {
+			return nil, c.Errf("weights need the %s policy", weightedPolicy)
+		}
+		if (interval != 0 || timeout != 0 || len(healthNames) > 0) && lb.health == nil {
+			return nil, c.Err("health_names, health_interval and health_timeout need a health_check")
+		}
+		if lb.health == nil {
+			continue
+		}
+		if len(healthNames) == 0 && lb.weights == nil {
+			return nil, c.Err("health_check needs health_names or weights")
+		}
+		for _, n := range healthNames {
+			lb.health.names[n] = struct{}{}
+		}
+		lb.health.weights = lb.weights
+		if interval != 0 {
+			lb.health.interval = interval
+		}
+		if timeout != 0 {
+			lb.health.timeout = timeout
+		}
+	}
+	return lb, nil
+}
+
+func duration(c *caddy.Controller) (time.Duration, error) {
+	args := c.RemainingArgs()
+	if len(args) != 1 {
+		return 0, c.ArgErr()
+	}
+	d, err := time.ParseDuration(args[0])
+	if err != nil || d <= 0 {
+		return 0, c.Errf("invalid duration '%s'", args[0])
 	}
-	return c.ArgErr()
+	return d, nil
 }
@@ -1,6 +1,8 @@
 package loadbalance
 
 import (
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 
@@ -8,6 +10,11 @@ import (
 )
 
 func TestSetup(t *testing.T) {
+	weights := filepath.Join(t.TempDir(), "weights")
+	if err := os.WriteFile(weights, []byte("www.example.org. 10.0.0.1 10\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
 	tests := []struct {
 		input              string
 		shouldErr          bool
@@ -17,14 +24,75 @@ func TestSetup(t *testing.T) {
 		// positive
 		{`loadbalance`, false, "round_robin", ""},
 		{`loadbalance round_robin`, false, "round_robin", ""},
+		{`loadbalance weighted`, false, "weighted", ""},
+		{`loadbalance weighted {
+			weights ` + weights + ` 10s
+			txt_weights
+		}`, false, "weighted", ""},
+		{`loadbalance weighted {
+			txt_weights example.org example.net
+		}`, false, "weighted", ""},
+		{`loadbalance {
+			health_check tcp 80
+			health_names www.example.org example.org
+			health_interval 5s
+			health_timeout 1s
+			max_answers 2
+		}`, false, "round_robin", ""},
+		{`loadbalance weighted {
+			health_check http 8080 /healthz
+			weights ` + weights + `
+		}`, false, "weighted", ""},
 		// negative
 		{`loadbalance fleeb`, true, "", "unknown policy"},
 		{`loadbalance a b`, true, "", "argument count or unexpected line"},
+		{`loadbalance {
+			weights ` + weights + `
+		}`, true, "", "weights need the weighted policy"},
+		{`loadbalance weighted {
+			weights /does/not/exist
+		}`, true, "", "unable to read weights"},
+		{`loadbalance weighted {
+			weights ` + weights + ` -1s
+		}`, true, "", "invalid reload duration"},
+		{`loadbalance {
+			health_check udp 53
+		}`, true, "", "unknown health check protocol"},
+		{`loadbalance {
+			health_check tcp 0
+		}`, true, "", "invalid port"},
+		{`loadbalance {
+			health_check tcp 80 /path
+		}`, true, "", "argument count"},
+		{`loadbalance {
+			health_check http 80 path
+		}`, true, "", "invalid path"},
+		{`loadbalance {
+			health_interval 5s
+		}`, true, "", "need a health_check"},
+		{`loadbalance {
+			health_names www.example.org
+		}`, true, "", "need a health_check"},
+		{`loadbalance {
+			health_names
+			health_check tcp 80
+		}`, true, "", "argument count"},
+		{`loadbalance {
+			health_check tcp 80
+		}`, true, "", "needs health_names or weights"},
+		{`loadbalance {
+			max_answers 0
+		}`, true, "", "invalid number of answers"},
+		{`loadbalance {
+			bogus
+		}`, true, "", "unknown property"},
+		{`loadbalance
+		loadbalance`, true, "", "this plugin"},
 	}
 
 	for i, test := range tests {
 		c := caddy.NewTestController("dns", test.input)
-		err := parse(c)
+		lb, err := parse(c)
 
 		if test.shouldErr && err == nil {
 			t.Errorf("Test %d: Expected error but found %s for input %s", i, err, test.input)
@@ -38,6 +106,10 @@ func TestSetup(t *testing.T) {
 			if !strings.Contains(err.Error(), test.expectedErrContent) {
 				t.Errorf("Test %d: Expected error to contain: %v, found error: %v, input: %s", i, test.expectedErrContent, err, test.input)
 			}
+			continue
+		}
+		if lb.policy != test.expectedPolicy {
+			t.Errorf("Test %d: Expected policy %s, got %s", i, test.expectedPolicy, lb.policy)
 		}
 	}
 }
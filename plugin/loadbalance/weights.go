@@ -0,0 +1,280 @@
+package loadbalance
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"net"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/coredns/coredns/plugin"
+	"github.com/coredns/coredns/plugin/pkg/cache"
+	"github.com/coredns/coredns/plugin/pkg/nonwriter"
+
+	"github.com/miekg/dns"
+)
+
+// defaultWeight is the weight of an address that has none set.
+const defaultWeight = 1
+
+// weightPrefix is the label prepended to a name to find the TXT records with the weights of its addresses.
+const weightPrefix = "_weight."
+
+const (
+	// txtCacheSize is the number of names for which the weights from TXT records are cached.
+	txtCacheSize = 10000
+	// defaultTxtTTL is how long the weights of a name are cached when the lookup doesn't give a TTL.
+	defaultTxtTTL = 30 * time.Second
+)
+
+// table maps a name to the weights of its addresses.
+type table map[string]map[string]uint32
+
+// weightsFile holds the weights read from a file that is reloaded when it changes.
+type weightsFile struct {
+	path   string
+	reload time.Duration
+
+	sync.RWMutex
+	table table
+
+	// mtime and size are only read and modified by a single goroutine
+	mtime time.Time
+	size  int64
+
+	stop chan struct{}
+}
+
+// weight returns the weight of addr for name.
+func (f *weightsFile) weight(name, addr string) (uint32, bool) {
+	f.RLock()
+	defer f.RUnlock()
+	w, ok := f.table[name][addr]
+	return w, ok
+}
+
+// has returns true if the file has weights for name.
+func (f *weightsFile) has(name string) bool {
+	f.RLock()
+	defer f.RUnlock()
+	_, ok := f.table[name]
+	return ok
+}
+
+// read rereads the file if its size or modification time changed.
+func (f *weightsFile) read() error {
+	file, err := os.Open(f.path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	stat, err := file.Stat()
+	if err != nil {
+		return err
+	}
+	if f.mtime.Equal(stat.ModTime()) && f.size == stat.Size() {
+		return nil
+	}
+
+	t, err := parseWeights(file)
+	if err != nil {
+		return fmt.Errorf("%s: %s", f.path, err)
+	}
+
+	f.Lock()
+	f.table = t
+	f.Unlock()
+	f.mtime = stat.ModTime()
+	f.size = stat.Size()
+	return nil
+}
+
+// OnStartup starts rereading the file every f.reload.
+func (f *weightsFile) OnStartup() error {
+	f.stop = make(chan struct{})
+	if f.reload == 0 {
+		return nil
+	}
+	go func() {
+		ticker := time.NewTicker(f.reload)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-f.stop:
+				return
+			case <-ticker.C:
+				if err := f.read(); err != nil {
+					log.Warningf("Failed to reload weights: %s", err)
+				}
+			}
+		}
+	}()
+	return nil
+}
+
+// OnShutdown stops rereading the file.
+func (f *weightsFile) OnShutdown() error {
+	close(f.stop)
+	return nil
+}
+
+// parseWeights parses lines with a name, an address and its weight. Everything after a '#' is a comment.
+func parseWeights(r io.Reader) (table, error) {
+	t := table{}
+	scanner := bufio.NewScanner(r)
+	for i := 1; scanner.Scan(); i++ {
+		line := scanner.Text()
+		if j := strings.IndexByte(line, '#'); j >= 0 {
+			line = line[:j]
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) != 3 {
+			return nil, fmt.Errorf("line %d: expected a name, an address and a weight", i)
+		}
+		name := plugin.Name(fields[0]).Normalize()
+		addr, w, err := parseWeight(fields[1], fields[2])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %s", i, err)
+		}
+		if t[name] == nil {
+			t[name] = map[string]uint32{}
+		}
+		t[name][addr] = w
+	}
+	return t, scanner.Err()
+}
+
+// parseWeight parses an address and its weight, the address is returned in its canonical form.
+func parseWeight(addr, weight string) (string, uint32, error) {
+	ip := net.ParseIP(addr)
+	if ip == nil {
+		return "", 0, fmt.Errorf("invalid address %q", addr)
+	}
+	w, err := strconv.ParseUint(weight, 10, 32)
+	if err != nil {
+		return "", 0, fmt.Errorf("invalid weight %q", weight)
+	}
+	return ip.String(), uint32(w), nil
+}
+
+// txtCache caches the weights from TXT records per name. Only names in zones are looked up.
+type txtCache struct {
+	c     *cache.Cache
+	zones []string
+}
+
+type txtEntry struct {
+	name    string
+	weights map[string]uint32
+	expire  time.Time
+}
+
+func newTxtCache(zones []string) *txtCache {
+	return &txtCache{c: cache.New(txtCacheSize), zones: zones}
+}
+
+// weights returns the weights of the addresses of name, they are looked up with txtWeights when they
+// are not cached or have expired. Names that are not in c.zones have no weights.
+func (c *txtCache) weights(ctx context.Context, next plugin.Handler, w dns.ResponseWriter, name string) map[string]uint32 {
+	name = strings.ToLower(name)
+	if plugin.Zones(c.zones).Matches(name) == "" {
+		return nil
+	}
+	key := cache.Hash([]byte(name))
+	now := time.Now()
+	if el, ok := c.c.Get(key); ok {
+		if e := el.(*txtEntry); e.name == name && now.Before(e.expire) {
+			return e.weights
+		}
+	}
+
+	weights, ttl := txtWeights(ctx, next, w, name)
+	c.c.Add(key, &txtEntry{name: name, weights: weights, expire: now.Add(ttl)})
+	return weights
+}
+
+// txtWeights looks up the TXT records of _weight.name with the next plugin. Each string in them is
+// an address and its weight. The weights are returned with how long they may be cached: the lowest
+// TTL of the TXT records, or the negative TTL from the SOA record when there are none.
+func txtWeights(ctx context.Context, next plugin.Handler, w dns.ResponseWriter, name string) (map[string]uint32, time.Duration) {
+	req := new(dns.Msg)
+	req.SetQuestion(weightPrefix+name, dns.TypeTXT)
+	nw := nonwriter.New(w)
+	if _, err := plugin.NextOrFailure("loadbalance", next, ctx, nw, req); err != nil || nw.Msg == nil {
+		return nil, defaultTxtTTL
+	}
+
+	weights := map[string]uint32{}
+	ttl := uint32(math.MaxUint32)
+	for _, rr := range nw.Msg.Answer {
+		txt, ok := rr.(*dns.TXT)
+		if !ok {
+			continue
+		}
+		if txt.Hdr.Ttl < ttl {
+			ttl = txt.Hdr.Ttl
+		}
+		for _, s := range txt.Txt {
+			fields := strings.Fields(s)
+			if len(fields) != 2 {
+				continue
+			}
+			if addr, w, err := parseWeight(fields[0], fields[1]); err == nil {
+				weights[addr] = w
+			}
+		}
+	}
+	if ttl == math.MaxUint32 {
+		for _, rr := range nw.Msg.Ns {
+			if soa, ok := rr.(*dns.SOA); ok {
+				ttl = soa.Hdr.Ttl
+				if soa.Minttl < ttl {
+					ttl = soa.Minttl
+				}
+			}
+		}
+	}
+	if ttl == math.MaxUint32 {
+		return weights, defaultTxtTTL
+	}
+	return weights, time.Duration(ttl) * time.Second
+}
+
+// weightedShuffle orders records randomly, where the chance of a record to come first is its weight
+// divided by the total weight of records. Records with a zero weight come last.
+func weightedShuffle(records []dns.RR, weight func(dns.RR) uint32) {
+	keys := make([]float64, len(records))
+	for i, r := range records {
+		w := weight(r)
+		if w == 0 {
+			keys[i] = -1 - rand.Float64()
+			continue
+		}
+		keys[i] = math.Pow(rand.Float64(), 1/float64(w))
+	}
+	sort.Sort(byKey{records, keys})
+}
+
+type byKey struct {
+	records []dns.RR
+	keys    []float64
+}
+
+func (b byKey) Len() int           { return len(b.records) }
+func (b byKey) Less(i, j int) bool { return b.keys[i] > b.keys[j] }
+func (b byKey) Swap(i, j int) {
+	b.records[i], b.records[j] = b.records[j], b.records[i]
+	b.keys[i], b.keys[j] = b.keys[j], b.keys[i]
+}
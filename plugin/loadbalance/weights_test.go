@@ -0,0 +1,145 @@
+package loadbalance
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/coredns/coredns/plugin"
+	"github.com/coredns/coredns/plugin/test"
+
+	"github.com/miekg/dns"
+)
+
+func TestParseWeights(t *testing.T) {
+	tbl, err := parseWeights(strings.NewReader(`# name address weight
+www.example.org 10.0.0.1 100
+WWW.example.org. 10.0.0.2   0 # drained
+www.example.org. 2001:db8::0001 5
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := map[string]uint32{"10.0.0.1": 100, "10.0.0.2": 0, "2001:db8::1": 5}
+	if len(tbl) != 1 || len(tbl["www.example.org."]) != len(expect) {
+		t.Fatalf("Expected weights for www.example.org. only, got %v", tbl)
+	}
+	for addr, w := range expect {
+		if got, ok := tbl["www.example.org."][addr]; !ok || got != w {
+			t.Errorf("Expected weight %d for %s, got %d", w, addr, got)
+		}
+	}
+
+	for _, bad := range []string{
+		"www.example.org 10.0.0.1",
+		"www.example.org 10.0.0.300 1",
+		"www.example.org 10.0.0.1 -1",
+		"www.example.org 10.0.0.1 1 1",
+	} {
+		if _, err := parseWeights(strings.NewReader(bad)); err == nil {
+			t.Errorf("Expected error for %q", bad)
+		}
+	}
+}
+
+func TestWeightedShuffle(t *testing.T) {
+	weights := map[string]uint32{"10.0.0.1": 3, "10.0.0.2": 1, "10.0.0.3": 0}
+	weight := func(r dns.RR) uint32 { return weights[recordAddress(r)] }
+
+	first := map[string]int{}
+	const n = 10000
+	for i := 0; i < n; i++ {
+		records := []dns.RR{
+			test.A("www.example.org. 300 IN A 10.0.0.3"),
+			test.A("www.example.org. 300 IN A 10.0.0.2"),
+			test.A("www.example.org. 300 IN A 10.0.0.1"),
+		}
+		weightedShuffle(records, weight)
+		first[recordAddress(records[0])]++
+		if recordAddress(records[2]) != "10.0.0.3" {
+			t.Fatalf("Expected the record with a zero weight last, got %v", records)
+		}
+	}
+	// 10.0.0.1 should come first 3 out of 4 times.
+	if f := float64(first["10.0.0.1"]) / n; f < 0.7 || f > 0.8 {
+		t.Errorf("Expected 10.0.0.1 first about 75%% of the time, got %.2f", f)
+	}
+}
+
+func TestWeightsFileReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "weights")
+	if err := os.WriteFile(path, []byte("www.example.org. 10.0.0.1 10\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	f := &weightsFile{path: path}
+	if err := f.read(); err != nil {
+		t.Fatal(err)
+	}
+	if w, ok := f.weight("www.example.org.", "10.0.0.1"); !ok || w != 10 {
+		t.Errorf("Expected weight 10, got %d", w)
+	}
+
+	if err := os.WriteFile(path, []byte("www.example.org. 10.0.0.1 200\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	// Make sure the modification time differs.
+	later := time.Now().Add(time.Minute)
+	os.Chtimes(path, later, later)
+	if err := f.read(); err != nil {
+		t.Fatal(err)
+	}
+	if w, _ := f.weight("www.example.org.", "10.0.0.1"); w != 200 {
+		t.Errorf("Expected weight 200 after reload, got %d", w)
+	}
+
+	// A broken file keeps the weights there are.
+	if err := os.WriteFile(path, []byte("broken\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	os.Chtimes(path, later.Add(time.Minute), later.Add(time.Minute))
+	if err := f.read(); err == nil {
+		t.Error("Expected error for broken weights file")
+	}
+	if w, _ := f.weight("www.example.org.", "10.0.0.1"); w != 200 {
+		t.Errorf("Expected weight 200 to be kept, got %d", w)
+	}
+}
+
+func TestTxtWeightsTTL(t *testing.T) {
+	next := plugin.HandlerFunc(func(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (int, error) {
+		m := new(dns.Msg)
+		m.SetReply(r)
+		switch r.Question[0].Name {
+		case "_weight.www.example.org.":
+			m.Answer = []dns.RR{
+				test.TXT(`_weight.www.example.org. 300 IN TXT "10.0.0.1 10"`),
+				test.TXT(`_weight.www.example.org. 60 IN TXT "10.0.0.2 20"`),
+			}
+		case "_weight.example.org.":
+			m.Ns = []dns.RR{test.SOA("example.org. 3600 IN SOA ns.example.org. admin.example.org. 1 7200 3600 1209600 120")}
+		}
+		w.WriteMsg(m)
+		return dns.RcodeSuccess, nil
+	})
+
+	tests := []struct {
+		name    string
+		weights int
+		ttl     time.Duration
+	}{
+		{"www.example.org.", 2, 60 * time.Second},
+		{"example.org.", 0, 120 * time.Second},
+	}
+	for _, tc := range tests {
+		weights, ttl := txtWeights(context.TODO(), next, &test.ResponseWriter{}, tc.name)
+		if len(weights) != tc.weights {
+			t.Errorf("Expected %d weights for %s, got %v", tc.weights, tc.name, weights)
+		}
+		if ttl != tc.ttl {
+			t.Errorf("Expected a TTL of %s for %s, got %s", tc.ttl, tc.name, ttl)
+		}
+	}
+}
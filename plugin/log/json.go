@@ -0,0 +1,225 @@
+package log
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/coredns/coredns/plugin/metadata"
+	"github.com/coredns/coredns/plugin/pkg/dnstest"
+	"github.com/coredns/coredns/request"
+
+	"github.com/miekg/dns"
+)
+
+// Labels that only make sense in structured output. They can be used as JSON fields next to
+// all the labels the replacer understands.
+const (
+	timeLabel     = "{time}"
+	ednsLabel     = "{edns}"
+	ecsLabel      = "{ecs}"
+	metadataLabel = "{metadata}"
+	answerLabel   = "{answer}"
+)
+
+// jsonField is a single key in a JSON log record and the label whose value is stored under it.
+type jsonField struct {
+	Key   string
+	Label string
+}
+
+// DefaultJSONFields is the field set used when json is given without any fields.
+var DefaultJSONFields = []string{
+	timeLabel,
+	"{remote}", "{port}", "{>id}", "{type}", "{class}", "{name}", "{proto}", "{size}",
+	"{>do}", "{>bufsize}", "{>opcode}",
+	"{rcode}", "{>rflags}", "{rsize}", "{duration}",
+	ednsLabel, ecsLabel, metadataLabel,
+}
+
+// parseJSONField parses a field specification, either LABEL or KEY=LABEL. When no key is given
+// the key is derived from the label: "{name}" becomes "name", "{>id}" becomes "id" and a
+// metadata label "{/geoip/city/name}" becomes "geoip/city/name".
+func parseJSONField(s string) (jsonField, error) {
+	key, label := "", s
+	if i := strings.Index(s, "={"); i > 0 {
+		key, label = s[:i], s[i+1:]
+	}
+	if len(label) < 3 || label[0] != '{' || label[len(label)-1] != '}' {
+		return jsonField{}, fmt.Errorf("invalid json field %q, expected {LABEL} or KEY={LABEL}", s)
+	}
+	if key == "" {
+		key = strings.TrimLeft(label[1:len(label)-1], ">/")
+	}
+	if key == "" {
+		return jsonField{}, fmt.Errorf("invalid json field %q, empty key", s)
+	}
+	return jsonField{Key: key, Label: label}, nil
+}
+
+// appendJSON appends a JSON object holding all fields to b.
+func (l Logger) appendJSON(ctx context.Context, b []byte, state request.Request, rr *dnstest.Recorder, fields []jsonField) []byte {
+	b = append(b, '{')
+	for i, f := range fields {
+		if i > 0 {
+			b = append(b, ',')
+		}
+		b = appendJSONString(b, f.Key)
+		b = append(b, ':')
+		b = l.appendJSONValue(ctx, b, state, rr, f.Label)
+	}
+	return append(b, '}')
+}
+
+// appendJSONValue appends the typed value of label to b. Labels are interpreted the same way as
+// in the text format; numbers and booleans are stored as such, missing values become null.
+func (l Logger) appendJSONValue(ctx context.Context, b []byte, state request.Request, rr *dnstest.Recorder, label string) []byte {
+	switch label {
+	case timeLabel:
+		return appendJSONString(b, time.Now().UTC().Format(time.RFC3339Nano))
+	case "{remote}":
+		return appendJSONString(b, state.IP())
+	case "{local}":
+		return appendJSONString(b, state.LocalIP())
+	case "{port}":
+		p, _ := strconv.Atoi(state.Port())
+		return strconv.AppendInt(b, int64(p), 10)
+	case "{size}":
+		return strconv.AppendInt(b, int64(state.Req.Len()), 10)
+	case "{>id}":
+		return strconv.AppendInt(b, int64(state.Req.Id), 10)
+	case "{>opcode}":
+		return strconv.AppendInt(b, int64(state.Req.Opcode), 10)
+	case "{>do}":
+		return strconv.AppendBool(b, state.Do())
+	case "{>bufsize}":
+		return strconv.AppendInt(b, int64(state.Size()), 10)
+	case "{rcode}":
+		if rr == nil || rr.Msg == nil {
+			return append(b, "null"...)
+		}
+		return appendJSONString(b, l.repl.Replace(ctx, state, rr, label))
+	case "{rsize}":
+		if rr == nil {
+			return append(b, "null"...)
+		}
+		return strconv.AppendInt(b, int64(rr.Len), 10)
+	case "{duration}":
+		if rr == nil {
+			return append(b, "null"...)
+		}
+		return strconv.AppendFloat(b, time.Since(rr.Start).Seconds(), 'f', -1, 64)
+	case "{>rflags}":
+		if rr == nil || rr.Msg == nil {
+			return append(b, "null"...)
+		}
+		return appendJSONStrings(b, strings.Split(l.repl.Replace(ctx, state, rr, label), ","))
+	case ednsLabel:
+		return appendEDNS(b, state.Req.IsEdns0())
+	case ecsLabel:
+		if ecs := clientSubnet(state.Req.IsEdns0()); ecs != nil {
+			return appendJSONString(b, ecs.String())
+		}
+		return append(b, "null"...)
+	case metadataLabel:
+		return appendMetadata(ctx, b)
+	case answerLabel:
+		if rr == nil || rr.Msg == nil {
+			return append(b, "null"...)
+		}
+		answers := make([]string, len(rr.Msg.Answer))
+		for i, a := range rr.Msg.Answer {
+			answers[i] = a.String()
+		}
+		return appendJSONStrings(b, answers)
+	}
+
+	if strings.HasPrefix(label, "{/") {
+		if fm := metadata.ValueFunc(ctx, label[2:len(label)-1]); fm != nil {
+			return appendJSONString(b, fm())
+		}
+		return append(b, "null"...)
+	}
+	return appendJSONString(b, l.repl.Replace(ctx, state, rr, label))
+}
+
+// appendEDNS appends the EDNS0 information of the query as an object, or null if the query
+// has no OPT record.
+func appendEDNS(b []byte, opt *dns.OPT) []byte {
+	if opt == nil {
+		return append(b, "null"...)
+	}
+	b = append(b, `{"version":`...)
+	b = strconv.AppendInt(b, int64(opt.Version()), 10)
+	b = append(b, `,"bufsize":`...)
+	b = strconv.AppendInt(b, int64(opt.UDPSize()), 10)
+	b = append(b, `,"do":`...)
+	b = strconv.AppendBool(b, opt.Do())
+	b = append(b, `,"options":[`...)
+	for i, o := range opt.Option {
+		if i > 0 {
+			b = append(b, ',')
+		}
+		b = append(b, `{"code":`...)
+		b = strconv.AppendInt(b, int64(o.Option()), 10)
+		b = append(b, `,"value":`...)
+		b = appendJSONString(b, o.String())
+		b = append(b, '}')
+	}
+	return append(b, "]}"...)
+}
+
+// clientSubnet returns the EDNS0 Client Subnet in opt as a network, or nil if there isn't one.
+func clientSubnet(opt *dns.OPT) *net.IPNet {
+	if opt == nil {
+		return nil
+	}
+	for _, o := range opt.Option {
+		if e, ok := o.(*dns.EDNS0_SUBNET); ok {
+			bits := 32
+			if e.Family == 2 {
+				bits = 128
+			}
+			return &net.IPNet{IP: e.Address, Mask: net.CIDRMask(int(e.SourceNetmask), bits)}
+		}
+	}
+	return nil
+}
+
+// appendMetadata appends all metadata labels present in ctx as an object, sorted by label.
+func appendMetadata(ctx context.Context, b []byte) []byte {
+	labels := metadata.Labels(ctx)
+	sort.Strings(labels)
+
+	b = append(b, '{')
+	for i, label := range labels {
+		if i > 0 {
+			b = append(b, ',')
+		}
+		b = appendJSONString(b, label)
+		b = append(b, ':')
+		b = appendJSONString(b, metadata.ValueFunc(ctx, label)())
+	}
+	return append(b, '}')
+}
+
+func appendJSONStrings(b []byte, s []string) []byte {
+	b = append(b, '[')
+	for i := range s {
+		if i > 0 {
+			b = append(b, ',')
+		}
+		b = appendJSONString(b, s[i])
+	}
+	return append(b, ']')
+}
+
+func appendJSONString(b []byte, s string) []byte {
+	buf, _ := json.Marshal(s) // marshalling a string can't fail
+	return append(b, buf...)
+}
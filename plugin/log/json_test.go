@@ -0,0 +1,155 @@
+package log
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/coredns/caddy"
+	"github.com/coredns/coredns/plugin/metadata"
+	"github.com/coredns/coredns/plugin/pkg/dnstest"
+	"github.com/coredns/coredns/plugin/pkg/replacer"
+	"github.com/coredns/coredns/plugin/pkg/response"
+	"github.com/coredns/coredns/plugin/test"
+	"github.com/coredns/coredns/request"
+
+	"github.com/miekg/dns"
+)
+
+func TestLogParseJSON(t *testing.T) {
+	tests := []struct {
+		input     string
+		shouldErr bool
+		fields    []jsonField
+	}{
+		{`log {
+			json
+		}`, false, nil},
+		{`log {
+			json {name} client={remote} ns={/kubernetes/client-namespace} {>id}
+		}`, false, []jsonField{
+			{Key: "name", Label: "{name}"},
+			{Key: "client", Label: "{remote}"},
+			{Key: "ns", Label: "{/kubernetes/client-namespace}"},
+			{Key: "id", Label: "{>id}"},
+		}},
+		{`log {
+			json name
+		}`, true, nil},
+		{`log {
+			json ={name}
+		}`, true, nil},
+		{`log {
+			json {name}
+			json {type}
+		}`, true, nil},
+	}
+	for i, tc := range tests {
+		c := caddy.NewTestController("dns", tc.input)
+		rules, err := logParse(c)
+		if err == nil && tc.shouldErr {
+			t.Errorf("Test %d: expected error, got none", i)
+			continue
+		}
+		if err != nil && !tc.shouldErr {
+			t.Errorf("Test %d: expected no error, got %s", i, err)
+			continue
+		}
+		if tc.shouldErr {
+			continue
+		}
+		if tc.fields == nil {
+			if len(rules[0].JSON) != len(DefaultJSONFields) {
+				t.Errorf("Test %d: expected %d default fields, got %d", i, len(DefaultJSONFields), len(rules[0].JSON))
+			}
+			continue
+		}
+		if len(rules[0].JSON) != len(tc.fields) {
+			t.Fatalf("Test %d: expected %d fields, got %d", i, len(tc.fields), len(rules[0].JSON))
+		}
+		for j := range tc.fields {
+			if rules[0].JSON[j] != tc.fields[j] {
+				t.Errorf("Test %d: expected field %v, got %v", i, tc.fields[j], rules[0].JSON[j])
+			}
+		}
+	}
+}
+
+func TestLoggedJSON(t *testing.T) {
+	fields := []jsonField{}
+	for _, f := range append(DefaultJSONFields, "client={remote}", "{answer}", "{/test/missing}") {
+		jf, err := parseJSONField(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fields = append(fields, jf)
+	}
+
+	var out bytes.Buffer
+	logger := Logger{
+		Rules: []Rule{{
+			NameScope: ".",
+			Class:     map[response.Class]struct{}{response.All: {}},
+			JSON:      fields,
+			Output:    newOutput(&stdoutSink{json: true, w: &out}, 0),
+		}},
+		Next: test.HandlerFunc(func(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (int, error) {
+			m := new(dns.Msg)
+			m.SetReply(r)
+			m.Answer = []dns.RR{test.A("example.org. 300 IN A 127.0.0.53")}
+			w.WriteMsg(m)
+			return dns.RcodeSuccess, nil
+		}),
+		repl: replacer.New(),
+	}
+
+	ctx := metadata.ContextWithMetadata(context.TODO())
+	metadata.SetValueFunc(ctx, "test/label", func() string { return "value" })
+
+	r := new(dns.Msg)
+	r.SetQuestion("example.org.", dns.TypeA)
+	r.SetEdns0(4096, true)
+	o := r.IsEdns0()
+	o.Option = append(o.Option, &dns.EDNS0_SUBNET{Code: dns.EDNS0SUBNET, Family: 1, SourceNetmask: 24, Address: []byte{10, 0, 0, 0}})
+
+	rec := dnstest.NewRecorder(&test.ResponseWriter{})
+	logger.ServeDNS(ctx, rec, r)
+
+	var record map[string]interface{}
+	if err := json.Unmarshal(out.Bytes(), &record); err != nil {
+		t.Fatalf("Failed to decode JSON record %q: %s", out.String(), err)
+	}
+
+	state := request.Request{W: rec, Req: r}
+	expect := map[string]interface{}{
+		"remote":       state.IP(),
+		"client":       state.IP(),
+		"port":         float64(40212),
+		"type":         "A",
+		"name":         "example.org.",
+		"proto":        "udp",
+		"do":           true,
+		"bufsize":      float64(4096),
+		"rcode":        "NOERROR",
+		"ecs":          "10.0.0.0/24",
+		"test/missing": nil,
+	}
+	for k, v := range expect {
+		if record[k] != v {
+			t.Errorf("Expected %q to be %v, got %v", k, v, record[k])
+		}
+	}
+	if md, ok := record["metadata"].(map[string]interface{}); !ok || md["test/label"] != "value" {
+		t.Errorf("Expected metadata to contain test/label, got %v", record["metadata"])
+	}
+	if answer, ok := record["answer"].([]interface{}); !ok || len(answer) != 1 {
+		t.Errorf("Expected a single answer, got %v", record["answer"])
+	}
+	if flags, ok := record["rflags"].([]interface{}); !ok || len(flags) == 0 || flags[0] != "qr" {
+		t.Errorf("Expected rflags to start with qr, got %v", record["rflags"])
+	}
+	if _, ok := record["duration"].(float64); !ok {
+		t.Errorf("Expected duration to be a number, got %v", record["duration"])
+	}
+}
@@ -7,7 +7,6 @@ import (
 
 	"github.com/coredns/coredns/plugin"
 	"github.com/coredns/coredns/plugin/pkg/dnstest"
-	clog "github.com/coredns/coredns/plugin/pkg/log"
 	"github.com/coredns/coredns/plugin/pkg/replacer"
 	"github.com/coredns/coredns/plugin/pkg/response"
 	"github.com/coredns/coredns/request"
@@ -39,14 +38,33 @@ func (l Logger) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg)
 		// and we shouldn't have an empty rule.Class.
 		_, ok := rule.Class[response.All]
 		var ok1 bool
-		if !ok {
+		class := response.All
+		if !ok || rule.Sample != nil {
 			tpe, _ := response.Typify(rrw.Msg, time.Now().UTC())
-			class := response.Classify(tpe)
+			class = response.Classify(tpe)
 			_, ok1 = rule.Class[class]
 		}
 		if ok || ok1 {
-			logstr := l.repl.Replace(ctx, state, rrw, rule.Format)
-			clog.Infof(logstr)
+			if rule.Sample != nil && !rule.Sample.Keep(rrw.Rcode, class) {
+				SampledCount.Inc()
+				return rc, err
+			}
+
+			var b []byte
+			if rule.JSON != nil {
+				b = l.appendJSON(ctx, make([]byte, 0, 512), state, rrw, rule.JSON)
+			} else {
+				b = []byte(l.repl.Replace(ctx, state, rrw, rule.Format))
+			}
+
+			switch {
+			case rule.Output != nil:
+				rule.Output.write(b)
+			case rule.JSON != nil:
+				stdoutJSON.write(b)
+			default:
+				stdoutText.write(b)
+			}
 		}
 
 		return rc, err
@@ -55,6 +73,12 @@ func (l Logger) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg)
 	return plugin.NextOrFailure(l.Name(), l.Next, ctx, w, r)
 }
 
+// Unbuffered standard output, used by rules that don't have an output configured.
+var (
+	stdoutText = newOutput(&stdoutSink{}, 0)
+	stdoutJSON = newOutput(&stdoutSink{json: true}, 0)
+)
+
 // Name implements the Handler interface.
 func (l Logger) Name() string { return "log" }
 
@@ -63,6 +87,12 @@ type Rule struct {
 	NameScope string
 	Class     map[response.Class]struct{}
 	Format    string
+	// JSON holds the fields of a structured log record, when nil Format is used.
+	JSON []jsonField
+	// Sample, when not nil, decides which of the matching queries are logged.
+	Sample *sampler
+	// Output is where the records are written, when nil they are written to standard output.
+	Output *output
 }
 
 const (
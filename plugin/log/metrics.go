@@ -0,0 +1,33 @@
+package log
+
+import (
+	"github.com/coredns/coredns/plugin"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Variables declared for monitoring.
+var (
+	// DroppedCount is the number of log records that could not be written to a sink.
+	DroppedCount = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: plugin.Namespace,
+		Subsystem: "log",
+		Name:      "dropped_records_total",
+		Help:      "Counter of log records that were dropped per sink and reason.",
+	}, []string{"sink", "reason"})
+	// Lag is the time between a record being created and it being written to a sink.
+	Lag = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: plugin.Namespace,
+		Subsystem: "log",
+		Name:      "sink_lag_seconds",
+		Help:      "Time between creating the last written log record and writing it to the sink.",
+	}, []string{"sink"})
+	// SampledCount is the number of log records that were not created because of sampling.
+	SampledCount = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: plugin.Namespace,
+		Subsystem: "log",
+		Name:      "sampled_out_total",
+		Help:      "Counter of queries that were not logged because of sampling.",
+	})
+)
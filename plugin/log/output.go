@@ -0,0 +1,96 @@
+package log
+
+import (
+	"sync"
+	"time"
+
+	clog "github.com/coredns/coredns/plugin/pkg/log"
+)
+
+// output sends records to a sink. Without a buffer records are written synchronously, while
+// the query is being served. With a buffer they are queued and written by a separate goroutine;
+// when the queue is full new records are dropped.
+type output struct {
+	sink sink
+	size int // buffer size, 0 means unbuffered
+
+	mu     sync.RWMutex // protects queue against writes after stop
+	queue  chan record
+	closed bool
+	wg     sync.WaitGroup
+}
+
+type record struct {
+	b []byte
+	t time.Time
+}
+
+func newOutput(s sink, size int) *output {
+	return &output{sink: s, size: size, queue: make(chan record, size)}
+}
+
+// write sends b to the sink, or queues it when the output is buffered.
+func (o *output) write(b []byte) {
+	r := record{b: b, t: time.Now()}
+	if o.size == 0 {
+		o.emit(r)
+		return
+	}
+
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+	if o.closed {
+		DroppedCount.WithLabelValues(o.sink.String(), "closed").Inc()
+		return
+	}
+	select {
+	case o.queue <- r:
+	default:
+		DroppedCount.WithLabelValues(o.sink.String(), "buffer_full").Inc()
+	}
+}
+
+func (o *output) emit(r record) {
+	if err := o.sink.Write(r.b); err != nil {
+		if err == errSinkDown {
+			DroppedCount.WithLabelValues(o.sink.String(), "sink_down").Inc()
+			return
+		}
+		DroppedCount.WithLabelValues(o.sink.String(), "write_error").Inc()
+		clog.Errorf("Failed to write log record to %s: %s", o.sink, err)
+		return
+	}
+	Lag.WithLabelValues(o.sink.String()).Set(time.Since(r.t).Seconds())
+}
+
+// start prepares the sink and starts the goroutine that writes buffered records.
+func (o *output) start() error {
+	if s, ok := o.sink.(starter); ok {
+		if err := s.start(); err != nil {
+			return err
+		}
+	}
+	if o.size == 0 {
+		return nil
+	}
+	o.wg.Add(1)
+	go func() {
+		defer o.wg.Done()
+		for r := range o.queue {
+			o.emit(r)
+		}
+	}()
+	return nil
+}
+
+// stop writes all records still in the buffer and closes the sink.
+func (o *output) stop() error {
+	if o.size > 0 {
+		o.mu.Lock()
+		o.closed = true
+		close(o.queue)
+		o.mu.Unlock()
+		o.wg.Wait()
+	}
+	return o.sink.Close()
+}
@@ -0,0 +1,41 @@
+package log
+
+import (
+	"sync/atomic"
+
+	"github.com/coredns/coredns/plugin/pkg/response"
+)
+
+// sampler decides whether a response is logged. By default 1 in rate responses are logged,
+// this can be overridden for specific rcodes. Errors (see response.Error) are always logged,
+// unless there is an rcode specific rule for them.
+type sampler struct {
+	rate   uint64
+	count  uint64
+	rcodes map[int]*rcodeRate
+}
+
+type rcodeRate struct {
+	rate  uint64
+	count uint64
+}
+
+// Keep returns true if the response with rcode and class should be logged.
+func (s *sampler) Keep(rcode int, class response.Class) bool {
+	if r, ok := s.rcodes[rcode]; ok {
+		return keep(&r.count, r.rate)
+	}
+	if class == response.Error {
+		return true
+	}
+	return keep(&s.count, s.rate)
+}
+
+// keep increments counter and returns true for every rate'th call. A rate of zero means nothing
+// is kept.
+func keep(counter *uint64, rate uint64) bool {
+	if rate == 0 {
+		return false
+	}
+	return (atomic.AddUint64(counter, 1)-1)%rate == 0
+}
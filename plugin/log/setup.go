@@ -1,6 +1,8 @@
 package log
 
 import (
+	"fmt"
+	"strconv"
 	"strings"
 
 	"github.com/coredns/caddy"
@@ -20,6 +22,17 @@ func setup(c *caddy.Controller) error {
 		return plugin.Error("log", err)
 	}
 
+	outputs := map[*output]struct{}{}
+	for _, r := range rules {
+		if r.Output != nil {
+			outputs[r.Output] = struct{}{}
+		}
+	}
+	for o := range outputs {
+		c.OnStartup(o.start)
+		c.OnShutdown(o.stop)
+	}
+
 	dnsserver.GetConfig(c).AddPlugin(func(next plugin.Handler) plugin.Handler {
 		return Logger{Next: next, Rules: rules, repl: replacer.New()}
 	})
@@ -70,6 +83,12 @@ func logParse(c *caddy.Controller) ([]Rule, error) {
 
 		// Class refinements in an extra block.
 		classes := make(map[response.Class]struct{})
+		var (
+			fields []jsonField
+			smpl   *sampler
+			to     []string
+			buffer int
+		)
 		for c.NextBlock() {
 			switch c.Val() {
 			// class followed by combinations of all, denial, error and success.
@@ -85,18 +104,177 @@ func logParse(c *caddy.Controller) ([]Rule, error) {
 					}
 					classes[cls] = struct{}{}
 				}
+			// json followed by an optional list of fields, either LABEL or KEY=LABEL.
+			case "json":
+				if fields != nil {
+					return nil, c.Err("json can only be specified once")
+				}
+				args := c.RemainingArgs()
+				if len(args) == 0 {
+					args = DefaultJSONFields
+				}
+				fields = make([]jsonField, 0, len(args))
+				for _, a := range args {
+					f, err := parseJSONField(a)
+					if err != nil {
+						return nil, c.Err(err.Error())
+					}
+					fields = append(fields, f)
+				}
+			// sample [RCODE] RATE, log 1 in RATE queries.
+			case "sample":
+				args := c.RemainingArgs()
+				if len(args) == 0 || len(args) > 2 {
+					return nil, c.ArgErr()
+				}
+				rate, err := strconv.ParseUint(args[len(args)-1], 10, 64)
+				if err != nil {
+					return nil, c.Errf("invalid sample rate %q", args[len(args)-1])
+				}
+				if smpl == nil {
+					smpl = &sampler{rate: 1, rcodes: map[int]*rcodeRate{}}
+				}
+				if len(args) == 1 {
+					smpl.rate = rate
+					break
+				}
+				rcode, ok := dns.StringToRcode[strings.ToUpper(args[0])]
+				if !ok {
+					return nil, c.Errf("invalid rcode %q", args[0])
+				}
+				smpl.rcodes[rcode] = &rcodeRate{rate: rate}
+			// buffer SIZE, write records asynchronously through a buffer of SIZE records.
+			case "buffer":
+				if !c.NextArg() {
+					return nil, c.ArgErr()
+				}
+				n, err := strconv.Atoi(c.Val())
+				if err != nil || n < 0 {
+					return nil, c.Errf("invalid buffer size %q", c.Val())
+				}
+				buffer = n
+				if c.NextArg() {
+					return nil, c.ArgErr()
+				}
+			// to SINK [ARGS...]
+			case "to":
+				if to != nil {
+					return nil, c.Err("to can only be specified once")
+				}
+				to = c.RemainingArgs()
+				if len(to) == 0 {
+					return nil, c.ArgErr()
+				}
 			default:
 				return nil, c.ArgErr()
 			}
 		}
+
+		var out *output
+		if to != nil || buffer > 0 {
+			if to == nil {
+				to = []string{"stdout"}
+			}
+			s, err := parseSink(to, fields != nil)
+			if err != nil {
+				return nil, c.Err(err.Error())
+			}
+			if _, ok := s.(*netSink); ok && buffer == 0 {
+				// Writing to the network must not stall queries.
+				buffer = defaultNetBuffer
+			}
+			out = newOutput(s, buffer)
+		}
+
 		if len(classes) == 0 {
 			classes[response.All] = struct{}{}
 		}
 
 		for i := len(rules) - 1; i >= length; i-- {
 			rules[i].Class = classes
+			rules[i].JSON = fields
+			rules[i].Sample = smpl
+			rules[i].Output = out
 		}
 	}
 
 	return rules, nil
 }
+
+// parseSink creates the sink described by args, which is the argument list of the to option.
+func parseSink(args []string, json bool) (sink, error) {
+	switch args[0] {
+	case "stdout":
+		if len(args) != 1 {
+			return nil, fmt.Errorf("stdout takes no arguments")
+		}
+		return &stdoutSink{json: json}, nil
+	case "file":
+		if len(args) < 2 || len(args) > 4 {
+			return nil, fmt.Errorf("file requires a path, and an optional maximum size and number of backups")
+		}
+		var (
+			maxSize int64
+			backups int
+			err     error
+		)
+		if len(args) > 2 {
+			if maxSize, err = parseSize(args[2]); err != nil {
+				return nil, err
+			}
+		}
+		if len(args) > 3 {
+			if backups, err = strconv.Atoi(args[3]); err != nil || backups < 0 {
+				return nil, fmt.Errorf("invalid number of backups %q", args[3])
+			}
+		}
+		return newFileSink(args[1], maxSize, backups), nil
+	case "syslog":
+		if len(args) < 2 || len(args) > 3 {
+			return nil, fmt.Errorf("syslog requires an address, and an optional tag")
+		}
+		network, address := "udp", args[1]
+		if i := strings.Index(address, "://"); i > 0 {
+			network, address = address[:i], address[i+3:]
+		}
+		if network != "udp" && network != "tcp" {
+			return nil, fmt.Errorf("unsupported syslog transport %q", network)
+		}
+		tag := "coredns"
+		if len(args) == 3 {
+			tag = args[2]
+		}
+		return newSyslogSink(network, address, tag), nil
+	case "unix":
+		if len(args) != 2 {
+			return nil, fmt.Errorf("unix requires a socket path")
+		}
+		return newUnixSink(args[1]), nil
+	}
+	return nil, fmt.Errorf("unknown sink %q", args[0])
+}
+
+// parseSize parses a size in bytes, optionally suffixed with K, M or G.
+func parseSize(s string) (int64, error) {
+	if len(s) == 0 {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+	mult := int64(1)
+	switch strings.ToUpper(s[len(s)-1:]) {
+	case "K":
+		mult = 1 << 10
+	case "M":
+		mult = 1 << 20
+	case "G":
+		mult = 1 << 30
+	}
+	num := s
+	if mult > 1 {
+		num = s[:len(s)-1]
+	}
+	n, err := strconv.ParseInt(num, 10, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+	return n * mult, nil
+}
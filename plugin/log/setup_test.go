@@ -1,11 +1,14 @@
 package log
 
 import (
+	"os"
 	"reflect"
 	"testing"
 
 	"github.com/coredns/caddy"
 	"github.com/coredns/coredns/plugin/pkg/response"
+
+	"github.com/miekg/dns"
 )
 
 func TestLogParse(t *testing.T) {
@@ -183,3 +186,119 @@ func TestLogParse(t *testing.T) {
 		}
 	}
 }
+
+func TestLogParseOutput(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		input     string
+		shouldErr bool
+		sink      string
+		buffer    int
+	}{
+		{`log`, false, "", 0},
+		{`log {
+			buffer 100
+		}`, false, "stdout", 100},
+		{`log {
+			to file ` + dir + `/query.log 10M 3
+			buffer 1000
+		}`, false, "file://" + dir + "/query.log", 1000},
+		{`log {
+			to syslog tcp://127.0.0.1:514 coredns
+		}`, false, "tcp://127.0.0.1:514", defaultNetBuffer},
+		{`log {
+			to syslog 127.0.0.1:514
+		}`, false, "udp://127.0.0.1:514", defaultNetBuffer},
+		{`log {
+			to unix /run/coredns.sock
+		}`, false, "unix:///run/coredns.sock", defaultNetBuffer},
+		{`log {
+			to unix /run/coredns.sock
+			buffer 10
+		}`, false, "unix:///run/coredns.sock", 10},
+		{`log {
+			to syslog quic://127.0.0.1:514
+		}`, true, "", 0},
+		{`log {
+			to file ` + dir + `/query.log 10X
+		}`, true, "", 0},
+		{`log {
+			to file ` + dir + `/query.log ""
+		}`, true, "", 0},
+		{`log {
+			to pipe
+		}`, true, "", 0},
+		{`log {
+			to stdout
+			to stdout
+		}`, true, "", 0},
+		{`log {
+			buffer -1
+		}`, true, "", 0},
+	}
+	for i, test := range tests {
+		c := caddy.NewTestController("dns", test.input)
+		rules, err := logParse(c)
+		if err == nil && test.shouldErr {
+			t.Errorf("Test %d: expected error, got none", i)
+			continue
+		}
+		if err != nil && !test.shouldErr {
+			t.Errorf("Test %d: expected no error, got %s", i, err)
+			continue
+		}
+		if test.shouldErr {
+			continue
+		}
+		out := rules[0].Output
+		if test.sink == "" {
+			if out != nil {
+				t.Errorf("Test %d: expected no output, got %s", i, out.sink)
+			}
+			continue
+		}
+		if out.sink.String() != test.sink {
+			t.Errorf("Test %d: expected sink %s, got %s", i, test.sink, out.sink)
+		}
+		if out.size != test.buffer {
+			t.Errorf("Test %d: expected buffer size %d, got %d", i, test.buffer, out.size)
+		}
+		out.stop()
+	}
+
+	// Sinks are only opened when the server starts.
+	if _, err := os.Stat(dir + "/query.log"); !os.IsNotExist(err) {
+		t.Errorf("Expected the log file not to be created while parsing, got %v", err)
+	}
+}
+
+func TestLogParseSample(t *testing.T) {
+	c := caddy.NewTestController("dns", `log {
+		sample 100
+		sample nxdomain 10
+	}`)
+	rules, err := logParse(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := rules[0].Sample
+	if s.rate != 100 {
+		t.Errorf("Expected rate 100, got %d", s.rate)
+	}
+	if r, ok := s.rcodes[dns.RcodeNameError]; !ok || r.rate != 10 {
+		t.Errorf("Expected NXDOMAIN rate 10, got %v", s.rcodes)
+	}
+
+	for _, input := range []string{`log {
+		sample
+	}`, `log {
+		sample often
+	}`, `log {
+		sample nope 10
+	}`} {
+		c := caddy.NewTestController("dns", input)
+		if _, err := logParse(c); err == nil {
+			t.Errorf("Expected error for %q", input)
+		}
+	}
+}
@@ -0,0 +1,249 @@
+package log
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	clog "github.com/coredns/coredns/plugin/pkg/log"
+)
+
+// sink is a destination for log records. Write is called with a single record that does not
+// include a trailing newline; sinks add whatever framing they need.
+type sink interface {
+	Write(b []byte) error
+	Close() error
+	// String returns the name of the sink as used in metrics.
+	String() string
+}
+
+// starter is implemented by sinks that need to acquire resources, such as opening a file, before
+// records are written to them. It is called when the server starts.
+type starter interface {
+	start() error
+}
+
+// stdoutSink writes records to standard output. Text records go through the CoreDNS logger
+// so they get the usual [INFO] prefix, JSON records are written as-is.
+type stdoutSink struct {
+	json bool
+	w    io.Writer // defaults to os.Stdout
+}
+
+func (s *stdoutSink) Write(b []byte) error {
+	if !s.json {
+		clog.Info(string(b))
+		return nil
+	}
+	w := s.w
+	if w == nil {
+		w = os.Stdout
+	}
+	_, err := w.Write(append(b, '\n'))
+	return err
+}
+
+func (s *stdoutSink) Close() error   { return nil }
+func (s *stdoutSink) String() string { return "stdout" }
+
+// fileSink appends records to a file. When maxSize is set the file is rotated once it would
+// grow beyond it, keeping backups old files named path.1 (newest) to path.N (oldest).
+type fileSink struct {
+	path    string
+	maxSize int64
+	backups int
+
+	mu   sync.Mutex
+	f    *os.File
+	size int64
+}
+
+// newFileSink returns a file sink for path. The file is only opened by start, so nothing is
+// created while the configuration is parsed.
+func newFileSink(path string, maxSize int64, backups int) *fileSink {
+	return &fileSink{path: path, maxSize: maxSize, backups: backups}
+}
+
+func (s *fileSink) start() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.f != nil {
+		return nil
+	}
+	return s.open()
+}
+
+func (s *fileSink) open() error {
+	f, err := os.OpenFile(s.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	s.f, s.size = f, fi.Size()
+	return nil
+}
+
+func (s *fileSink) rotate() error {
+	if err := s.f.Close(); err != nil {
+		return err
+	}
+	if s.backups == 0 {
+		if err := os.Truncate(s.path, 0); err != nil {
+			return err
+		}
+		return s.open()
+	}
+	for i := s.backups - 1; i > 0; i-- {
+		// Older backups may not exist (yet), so errors are ignored here.
+		os.Rename(s.path+"."+strconv.Itoa(i), s.path+"."+strconv.Itoa(i+1))
+	}
+	if err := os.Rename(s.path, s.path+".1"); err != nil {
+		return err
+	}
+	return s.open()
+}
+
+func (s *fileSink) Write(b []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.f == nil {
+		// A previous rotation failed, try to recover.
+		if err := s.open(); err != nil {
+			return err
+		}
+	}
+	n := int64(len(b) + 1)
+	if s.maxSize > 0 && s.size > 0 && s.size+n > s.maxSize {
+		if err := s.rotate(); err != nil {
+			s.f = nil
+			return err
+		}
+	}
+	_, err := s.f.Write(append(b, '\n'))
+	s.size += n
+	return err
+}
+
+func (s *fileSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.f == nil {
+		return nil
+	}
+	err := s.f.Close()
+	s.f = nil
+	return err
+}
+
+func (s *fileSink) String() string { return "file://" + s.path }
+
+// netSink writes records to a network connection, which is (re)established on demand. After a
+// write error the connection is closed and a new one is dialed for the next record. When dialing
+// fails, records are dropped until the next dial, which is done after a backoff. Network sinks are
+// always written to through a buffer, see defaultNetBuffer.
+type netSink struct {
+	network string
+	address string
+	frame   func(b []byte) []byte
+
+	mu      sync.Mutex
+	conn    net.Conn
+	backoff time.Duration // time to wait after the last failed dial
+	next    time.Time     // don't dial before this time
+}
+
+const (
+	dialTimeout  = 2 * time.Second
+	writeTimeout = 2 * time.Second
+
+	minDialBackoff = 1 * time.Second
+	maxDialBackoff = 30 * time.Second
+
+	// defaultNetBuffer is the buffer size of network sinks when none is configured.
+	defaultNetBuffer = 1000
+)
+
+// errSinkDown is returned by netSink.Write for records dropped while waiting to dial again.
+var errSinkDown = errors.New("sink is down")
+
+func (s *netSink) Write(b []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.conn == nil {
+		if time.Now().Before(s.next) {
+			return errSinkDown
+		}
+		conn, err := net.DialTimeout(s.network, s.address, dialTimeout)
+		if err != nil {
+			s.backoff *= 2
+			if s.backoff < minDialBackoff {
+				s.backoff = minDialBackoff
+			}
+			if s.backoff > maxDialBackoff {
+				s.backoff = maxDialBackoff
+			}
+			s.next = time.Now().Add(s.backoff)
+			return err
+		}
+		s.conn, s.backoff = conn, 0
+	}
+	s.conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+	if _, err := s.conn.Write(s.frame(b)); err != nil {
+		s.conn.Close()
+		s.conn = nil
+		return err
+	}
+	return nil
+}
+
+func (s *netSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.conn == nil {
+		return nil
+	}
+	err := s.conn.Close()
+	s.conn = nil
+	return err
+}
+
+func (s *netSink) String() string { return s.network + "://" + s.address }
+
+// newUnixSink returns a sink that writes newline delimited records to a unix stream socket.
+func newUnixSink(path string) *netSink {
+	return &netSink{network: "unix", address: path, frame: func(b []byte) []byte { return append(b, '\n') }}
+}
+
+// syslogPriority is facility local0 (16) with severity informational (6).
+const syslogPriority = 16*8 + 6
+
+// newSyslogSink returns a sink that sends records as RFC 5424 syslog messages to address. With
+// TCP the messages are framed with octet counting as described in RFC 6587.
+func newSyslogSink(network, address, tag string) *netSink {
+	hostname, _ := os.Hostname()
+	if hostname == "" {
+		hostname = "-"
+	}
+	pid := strconv.Itoa(os.Getpid())
+
+	s := &netSink{network: network, address: address}
+	s.frame = func(b []byte) []byte {
+		msg := fmt.Sprintf("<%d>1 %s %s %s %s - - %s", syslogPriority, time.Now().UTC().Format(time.RFC3339Nano), hostname, tag, pid, b)
+		if network == "tcp" {
+			msg = strconv.Itoa(len(msg)) + " " + msg
+		}
+		return []byte(msg)
+	}
+	return s
+}
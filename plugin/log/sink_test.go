@@ -0,0 +1,135 @@
+package log
+
+import (
+	"bufio"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/coredns/coredns/plugin/pkg/response"
+)
+
+func TestSampler(t *testing.T) {
+	s := &sampler{rate: 3, rcodes: map[int]*rcodeRate{3: {rate: 2}, 5: {rate: 0}}}
+
+	kept := 0
+	for i := 0; i < 9; i++ {
+		if s.Keep(0, response.Success) {
+			kept++
+		}
+	}
+	if kept != 3 {
+		t.Errorf("Expected 3 of 9 NOERROR responses to be kept, got %d", kept)
+	}
+
+	kept = 0
+	for i := 0; i < 4; i++ {
+		if s.Keep(3, response.Denial) {
+			kept++
+		}
+	}
+	if kept != 2 {
+		t.Errorf("Expected 2 of 4 NXDOMAIN responses to be kept, got %d", kept)
+	}
+
+	for i := 0; i < 4; i++ {
+		if !s.Keep(2, response.Error) {
+			t.Errorf("Expected SERVFAIL response to always be kept")
+		}
+		if s.Keep(5, response.Error) {
+			t.Errorf("Expected REFUSED response to never be kept")
+		}
+	}
+}
+
+func TestFileSinkRotate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "query.log")
+	s := newFileSink(path, 10, 2)
+	if err := s.start(); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	for _, r := range []string{"record1", "record2", "record3", "record4"} {
+		if err := s.Write([]byte(r)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for file, expect := range map[string]string{path: "record4\n", path + ".1": "record3\n", path + ".2": "record2\n"} {
+		buf, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(buf) != expect {
+			t.Errorf("Expected %s to contain %q, got %q", file, expect, buf)
+		}
+	}
+}
+
+func TestBufferedOutput(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sock")
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	lines := make(chan string)
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		scanner := bufio.NewScanner(conn)
+		for scanner.Scan() {
+			lines <- scanner.Text()
+		}
+		close(lines)
+	}()
+
+	o := newOutput(newUnixSink(path), 10)
+	o.start()
+	o.write([]byte("first"))
+	o.write([]byte("second"))
+	o.stop()
+	o.write([]byte("after stop"))
+
+	var got []string
+	for line := range lines {
+		got = append(got, line)
+	}
+	if strings.Join(got, " ") != "first second" {
+		t.Errorf("Expected records %q, got %q", "first second", got)
+	}
+}
+
+func TestNetSinkBackoff(t *testing.T) {
+	s := newUnixSink(filepath.Join(t.TempDir(), "sock"))
+	if err := s.Write([]byte("first")); err == nil || err == errSinkDown {
+		t.Fatalf("Expected a dial error, got %v", err)
+	}
+	// Records are dropped without dialing until the backoff has passed.
+	if err := s.Write([]byte("second")); err != errSinkDown {
+		t.Errorf("Expected %v, got %v", errSinkDown, err)
+	}
+	if s.backoff != minDialBackoff {
+		t.Errorf("Expected a backoff of %s, got %s", minDialBackoff, s.backoff)
+	}
+}
+
+func TestSyslogFrame(t *testing.T) {
+	s := newSyslogSink("tcp", "localhost:514", "dns")
+	msg := string(s.frame([]byte("record")))
+
+	i := strings.IndexByte(msg, ' ')
+	if i < 0 || msg[i+1:i+6] != "<134>" {
+		t.Fatalf("Expected octet counted syslog message, got %q", msg)
+	}
+	if !strings.Contains(msg, " dns ") || !strings.HasSuffix(msg, " - - record") {
+		t.Errorf("Expected tag and record in syslog message, got %q", msg)
+	}
+}
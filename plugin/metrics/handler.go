@@ -5,8 +5,10 @@ import (
 	"path/filepath"
 
 	"github.com/coredns/coredns/plugin"
+	"github.com/coredns/coredns/plugin/metadata"
 	"github.com/coredns/coredns/plugin/metrics/vars"
 	"github.com/coredns/coredns/plugin/pkg/rcode"
+	"github.com/coredns/coredns/plugin/trace"
 	"github.com/coredns/coredns/request"
 
 	"github.com/miekg/dns"
@@ -34,7 +36,19 @@ func (m *Metrics) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg
 		rc = status
 	}
 	plugin := m.authoritativePlugin(rw.Caller)
-	vars.Report(WithServer(ctx), state, zone, rcode.ToString(rc), plugin, rw.Len, rw.Start)
+	server := WithServer(ctx)
+	traceID := ""
+	if m.exemplars {
+		if f := metadata.ValueFunc(ctx, trace.MetadataTraceID); f != nil {
+			traceID = f()
+		}
+	}
+	vars.ReportTraced(server, state, zone, rcode.ToString(rc), plugin, rw.Len, rw.Start, traceID)
+	if m.topK > 0 {
+		if t := heavyHitters.get(server); t != nil {
+			t.add(state, rc)
+		}
+	}
 
 	return status, err
 }
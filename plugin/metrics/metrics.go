@@ -34,6 +34,13 @@ type Metrics struct {
 	zoneMu    sync.RWMutex
 
 	plugins map[string]struct{} // all available plugins, used to determine which plugin made the client write
+
+	topK      int           // number of heavy hitters to track, 0 disables tracking
+	topWindow time.Duration // window over which heavy hitters are counted
+
+	otlp *otlpExporter // pushes metrics to an OpenTelemetry collector, if set
+
+	exemplars bool // record the trace ID of traced queries as exemplars
 }
 
 // New returns a new instance of Metrics with the given address.
@@ -95,7 +102,8 @@ func (m *Metrics) OnStartup() error {
 	m.lnSetup = true
 
 	m.mux = http.NewServeMux()
-	m.mux.Handle("/metrics", promhttp.HandlerFor(m.Reg, promhttp.HandlerOpts{}))
+	m.mux.Handle("/metrics", m.metricsHandler())
+	m.mux.Handle("/topk", heavyHitters)
 
 	// creating some helper variables to avoid data races on m.srv and m.ln
 	server := &http.Server{Handler: m.mux}
@@ -109,6 +117,20 @@ func (m *Metrics) OnStartup() error {
 	return nil
 }
 
+// metricsHandler returns the handler for /metrics. The OpenMetrics format, which is needed for
+// exemplars, is only offered if a server block on this address has exemplars enabled.
+func (m *Metrics) metricsHandler() http.Handler {
+	text := promhttp.HandlerFor(m.Reg, promhttp.HandlerOpts{})
+	openMetrics := promhttp.HandlerFor(m.Reg, promhttp.HandlerOpts{EnableOpenMetrics: true})
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if exemplarAddrs.has(m.Addr) {
+			openMetrics.ServeHTTP(w, r)
+			return
+		}
+		text.ServeHTTP(w, r)
+	})
+}
+
 // OnRestart stops the listener on reload.
 func (m *Metrics) OnRestart() error {
 	if !m.lnSetup {
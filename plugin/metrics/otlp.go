@@ -0,0 +1,316 @@
+package metrics
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	colmetricspb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	metricspb "go.opentelemetry.io/proto/otlp/metrics/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	defaultOTLPInterval = 30 * time.Second
+	otlpTimeout         = 10 * time.Second
+	otlpHTTPPath        = "/v1/metrics"
+)
+
+// otlpExporter periodically gathers the metrics from a registry, and pushes them with OTLP to an
+// OpenTelemetry collector. This works for networks where nothing can reach the /metrics endpoint.
+// The metrics keep their Prometheus names and labels; counters and histograms are exported as
+// cumulative sums and histograms.
+type otlpExporter struct {
+	protocol string // grpc or http
+	endpoint string // host:port, with an optional path for http
+	tls      bool
+	interval time.Duration
+	resource map[string]string
+
+	gatherer func() prometheus.Gatherer
+	start    time.Time
+
+	conn   *grpc.ClientConn
+	client colmetricspb.MetricsServiceClient
+
+	stop chan struct{}
+	wg   sync.WaitGroup
+}
+
+func newOTLPExporter(protocol, endpoint string) (*otlpExporter, error) {
+	e := &otlpExporter{protocol: protocol, interval: defaultOTLPInterval, resource: map[string]string{}}
+	switch {
+	case strings.HasPrefix(endpoint, "https://"):
+		e.endpoint, e.tls = endpoint[len("https://"):], true
+	case strings.HasPrefix(endpoint, "http://"):
+		e.endpoint = endpoint[len("http://"):]
+	default:
+		e.endpoint = endpoint
+	}
+	switch protocol {
+	case "grpc":
+		if strings.Contains(e.endpoint, "/") {
+			return nil, fmt.Errorf("otlp grpc endpoint can not have a path: %s", endpoint)
+		}
+	case "http":
+		if !strings.Contains(e.endpoint, "/") {
+			e.endpoint += otlpHTTPPath
+		}
+	default:
+		return nil, fmt.Errorf("unknown otlp protocol '%s', expected grpc or http", protocol)
+	}
+	return e, nil
+}
+
+// OnStartup starts pushing metrics.
+func (e *otlpExporter) OnStartup() error {
+	if e.protocol == "grpc" {
+		creds := insecure.NewCredentials()
+		if e.tls {
+			creds = credentials.NewTLS(&tls.Config{})
+		}
+		conn, err := grpc.Dial(e.endpoint, grpc.WithTransportCredentials(creds))
+		if err != nil {
+			return err
+		}
+		e.conn, e.client = conn, colmetricspb.NewMetricsServiceClient(conn)
+	}
+
+	e.start = time.Now()
+	e.stop = make(chan struct{})
+	e.wg.Add(1)
+	go func() {
+		defer e.wg.Done()
+		tick := time.NewTicker(e.interval)
+		defer tick.Stop()
+		for {
+			select {
+			case <-e.stop:
+				return
+			case <-tick.C:
+				if err := e.push(); err != nil {
+					log.Warningf("Failed to push metrics to %s: %s", e.endpoint, err)
+				}
+			}
+		}
+	}()
+	return nil
+}
+
+// OnShutdown pushes the metrics a final time and stops the exporter.
+func (e *otlpExporter) OnShutdown() error {
+	if e.stop == nil {
+		return nil
+	}
+	close(e.stop)
+	e.wg.Wait()
+	e.stop = nil
+
+	err := e.push()
+	if e.conn != nil {
+		e.conn.Close()
+	}
+	return err
+}
+
+// push gathers all metrics and sends them to the collector.
+func (e *otlpExporter) push() error {
+	mfs, err := e.gatherer().Gather()
+	if err != nil && len(mfs) == 0 {
+		return err
+	}
+	req := &colmetricspb.ExportMetricsServiceRequest{
+		ResourceMetrics: []*metricspb.ResourceMetrics{e.toOTLP(mfs, time.Now())},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), otlpTimeout)
+	defer cancel()
+
+	if e.protocol == "grpc" {
+		_, err := e.client.Export(ctx, req)
+		return err
+	}
+
+	buf, err := proto.Marshal(req)
+	if err != nil {
+		return err
+	}
+	scheme := "http://"
+	if e.tls {
+		scheme = "https://"
+	}
+	hreq, err := http.NewRequestWithContext(ctx, http.MethodPost, scheme+e.endpoint, bytes.NewReader(buf))
+	if err != nil {
+		return err
+	}
+	hreq.Header.Set("Content-Type", "application/x-protobuf")
+	resp, err := http.DefaultClient.Do(hreq)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(ioutil.Discard, resp.Body)
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("unexpected HTTP status: %s", resp.Status)
+	}
+	return nil
+}
+
+// toOTLP converts the gathered metric families to OTLP metrics.
+func (e *otlpExporter) toOTLP(mfs []*dto.MetricFamily, now time.Time) *metricspb.ResourceMetrics {
+	start, ts := uint64(e.start.UnixNano()), uint64(now.UnixNano())
+
+	metrics := make([]*metricspb.Metric, 0, len(mfs))
+	for _, mf := range mfs {
+		m := &metricspb.Metric{Name: mf.GetName(), Description: mf.GetHelp()}
+		switch mf.GetType() {
+		case dto.MetricType_COUNTER:
+			sum := &metricspb.Sum{AggregationTemporality: metricspb.AggregationTemporality_AGGREGATION_TEMPORALITY_CUMULATIVE, IsMonotonic: true}
+			for _, pm := range mf.Metric {
+				sum.DataPoints = append(sum.DataPoints, numberPoint(pm, pm.GetCounter().GetValue(), start, ts))
+			}
+			m.Data = &metricspb.Metric_Sum{Sum: sum}
+		case dto.MetricType_GAUGE:
+			gauge := &metricspb.Gauge{}
+			for _, pm := range mf.Metric {
+				gauge.DataPoints = append(gauge.DataPoints, numberPoint(pm, pm.GetGauge().GetValue(), start, ts))
+			}
+			m.Data = &metricspb.Metric_Gauge{Gauge: gauge}
+		case dto.MetricType_UNTYPED:
+			gauge := &metricspb.Gauge{}
+			for _, pm := range mf.Metric {
+				gauge.DataPoints = append(gauge.DataPoints, numberPoint(pm, pm.GetUntyped().GetValue(), start, ts))
+			}
+			m.Data = &metricspb.Metric_Gauge{Gauge: gauge}
+		case dto.MetricType_HISTOGRAM:
+			hist := &metricspb.Histogram{AggregationTemporality: metricspb.AggregationTemporality_AGGREGATION_TEMPORALITY_CUMULATIVE}
+			for _, pm := range mf.Metric {
+				hist.DataPoints = append(hist.DataPoints, histogramPoint(pm, start, ts))
+			}
+			m.Data = &metricspb.Metric_Histogram{Histogram: hist}
+		case dto.MetricType_SUMMARY:
+			summary := &metricspb.Summary{}
+			for _, pm := range mf.Metric {
+				s := pm.GetSummary()
+				dp := &metricspb.SummaryDataPoint{
+					Attributes:        attributes(pm.Label),
+					StartTimeUnixNano: start,
+					TimeUnixNano:      ts,
+					Count:             s.GetSampleCount(),
+					Sum:               s.GetSampleSum(),
+				}
+				for _, q := range s.Quantile {
+					dp.QuantileValues = append(dp.QuantileValues, &metricspb.SummaryDataPoint_ValueAtQuantile{Quantile: q.GetQuantile(), Value: q.GetValue()})
+				}
+				summary.DataPoints = append(summary.DataPoints, dp)
+			}
+			m.Data = &metricspb.Metric_Summary{Summary: summary}
+		default:
+			continue
+		}
+		metrics = append(metrics, m)
+	}
+
+	keys := make([]string, 0, len(e.resource))
+	for k := range e.resource {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	res := &resourcepb.Resource{}
+	for _, k := range keys {
+		res.Attributes = append(res.Attributes, stringAttribute(k, e.resource[k]))
+	}
+
+	return &metricspb.ResourceMetrics{
+		Resource: res,
+		ScopeMetrics: []*metricspb.ScopeMetrics{{
+			Scope:   &commonpb.InstrumentationScope{Name: "github.com/coredns/coredns/plugin/metrics"},
+			Metrics: metrics,
+		}},
+	}
+}
+
+func numberPoint(pm *dto.Metric, v float64, start, ts uint64) *metricspb.NumberDataPoint {
+	return &metricspb.NumberDataPoint{
+		Attributes:        attributes(pm.Label),
+		StartTimeUnixNano: start,
+		TimeUnixNano:      ts,
+		Value:             &metricspb.NumberDataPoint_AsDouble{AsDouble: v},
+	}
+}
+
+// histogramPoint converts a Prometheus histogram, which has cumulative bucket counts, to an OTLP data
+// point, which has a count per bucket and an implicit +Inf bucket at the end.
+func histogramPoint(pm *dto.Metric, start, ts uint64) *metricspb.HistogramDataPoint {
+	h := pm.GetHistogram()
+	sum := h.GetSampleSum()
+	dp := &metricspb.HistogramDataPoint{
+		Attributes:        attributes(pm.Label),
+		StartTimeUnixNano: start,
+		TimeUnixNano:      ts,
+		Count:             h.GetSampleCount(),
+		Sum:               &sum,
+	}
+	prev := uint64(0)
+	for _, b := range h.Bucket {
+		if b.Exemplar != nil {
+			dp.Exemplars = append(dp.Exemplars, exemplar(b.Exemplar))
+		}
+		if math.IsInf(b.GetUpperBound(), +1) {
+			continue
+		}
+		dp.ExplicitBounds = append(dp.ExplicitBounds, b.GetUpperBound())
+		dp.BucketCounts = append(dp.BucketCounts, b.GetCumulativeCount()-prev)
+		prev = b.GetCumulativeCount()
+	}
+	dp.BucketCounts = append(dp.BucketCounts, h.GetSampleCount()-prev)
+	return dp
+}
+
+// exemplar converts a Prometheus exemplar. A trace_id label holding a W3C trace ID becomes the
+// exemplar's trace ID, all other labels are kept as attributes.
+func exemplar(ex *dto.Exemplar) *metricspb.Exemplar {
+	e := &metricspb.Exemplar{
+		TimeUnixNano: uint64(ex.GetTimestamp().AsTime().UnixNano()),
+		Value:        &metricspb.Exemplar_AsDouble{AsDouble: ex.GetValue()},
+	}
+	for _, l := range ex.Label {
+		if l.GetName() == "trace_id" {
+			if id, err := hex.DecodeString(l.GetValue()); err == nil && len(id) == 16 {
+				e.TraceId = id
+				continue
+			}
+		}
+		e.FilteredAttributes = append(e.FilteredAttributes, stringAttribute(l.GetName(), l.GetValue()))
+	}
+	return e
+}
+
+func attributes(labels []*dto.LabelPair) []*commonpb.KeyValue {
+	attrs := make([]*commonpb.KeyValue, len(labels))
+	for i, l := range labels {
+		attrs[i] = stringAttribute(l.GetName(), l.GetValue())
+	}
+	return attrs
+}
+
+func stringAttribute(k, v string) *commonpb.KeyValue {
+	return &commonpb.KeyValue{Key: k, Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: v}}}
+}
@@ -0,0 +1,79 @@
+package metrics
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	colmetricspb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	metricspb "go.opentelemetry.io/proto/otlp/metrics/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestOTLPPush(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	counter := prometheus.NewCounterVec(prometheus.CounterOpts{Name: "test_requests_total", Help: "Requests."}, []string{"zone"})
+	hist := prometheus.NewHistogram(prometheus.HistogramOpts{Name: "test_duration_seconds", Help: "Duration.", Buckets: []float64{0.1, 1}})
+	reg.MustRegister(counter, hist)
+	counter.WithLabelValues("example.org.").Add(3)
+	hist.Observe(0.05)
+	hist.Observe(0.5)
+	hist.(prometheus.ExemplarObserver).ObserveWithExemplar(5, prometheus.Labels{"trace_id": "4bf92f3577b34da6a3ce929d0e0e4736"})
+
+	received := make(chan *colmetricspb.ExportMetricsServiceRequest, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != otlpHTTPPath || r.Header.Get("Content-Type") != "application/x-protobuf" {
+			t.Errorf("Unexpected request for %s with content type %s", r.URL.Path, r.Header.Get("Content-Type"))
+		}
+		buf, _ := ioutil.ReadAll(r.Body)
+		req := &colmetricspb.ExportMetricsServiceRequest{}
+		if err := proto.Unmarshal(buf, req); err != nil {
+			t.Errorf("Failed to decode request: %s", err)
+		}
+		received <- req
+	}))
+	defer srv.Close()
+
+	e, err := newOTLPExporter("http", srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e.gatherer = func() prometheus.Gatherer { return reg }
+	e.resource = map[string]string{"service.name": "coredns"}
+	if err := e.push(); err != nil {
+		t.Fatalf("Failed to push metrics: %s", err)
+	}
+
+	rm := (<-received).ResourceMetrics[0]
+	if attr := rm.Resource.Attributes[0]; attr.Key != "service.name" || attr.Value.GetStringValue() != "coredns" {
+		t.Errorf("Unexpected resource attribute %v", attr)
+	}
+	metrics := map[string]*metricspb.Metric{}
+	for _, m := range rm.ScopeMetrics[0].Metrics {
+		metrics[m.Name] = m
+	}
+
+	sum := metrics["test_requests_total"].GetSum()
+	if sum == nil || !sum.IsMonotonic || sum.DataPoints[0].GetAsDouble() != 3 {
+		t.Fatalf("Expected a monotonic sum of 3, got %v", metrics["test_requests_total"])
+	}
+	if attr := sum.DataPoints[0].Attributes[0]; attr.Key != "zone" || attr.Value.GetStringValue() != "example.org." {
+		t.Errorf("Unexpected attribute %v", attr)
+	}
+
+	dp := metrics["test_duration_seconds"].GetHistogram().DataPoints[0]
+	if dp.Count != 3 || len(dp.ExplicitBounds) != 2 {
+		t.Fatalf("Expected 3 observations in 2 buckets, got %v", dp)
+	}
+	expected := []uint64{1, 1, 1}
+	for i := range expected {
+		if dp.BucketCounts[i] != expected[i] {
+			t.Errorf("Expected bucket %d to have count %d, got %d", i, expected[i], dp.BucketCounts[i])
+		}
+	}
+	if len(dp.Exemplars) != 1 || len(dp.Exemplars[0].TraceId) != 16 {
+		t.Errorf("Expected an exemplar with a trace ID, got %v", dp.Exemplars)
+	}
+}
@@ -26,3 +26,30 @@ func (r *reg) getOrSet(addr string, pr *prometheus.Registry) *prometheus.Registr
 	r.r[addr] = pr
 	return pr
 }
+
+// addrs is a set of addresses.
+type addrs struct {
+	sync.RWMutex
+	a map[string]struct{}
+}
+
+func newAddrs() *addrs { return &addrs{a: make(map[string]struct{})} }
+
+func (a *addrs) add(addr string) {
+	a.Lock()
+	defer a.Unlock()
+	a.a[addr] = struct{}{}
+}
+
+func (a *addrs) has(addr string) bool {
+	a.RLock()
+	defer a.RUnlock()
+	_, ok := a.a[addr]
+	return ok
+}
+
+func (a *addrs) reset() {
+	a.Lock()
+	defer a.Unlock()
+	a.a = make(map[string]struct{})
+}
@@ -2,7 +2,11 @@ package metrics
 
 import (
 	"net"
+	"os"
 	"runtime"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/coredns/caddy"
 	"github.com/coredns/coredns/core/dnsserver"
@@ -11,12 +15,16 @@ import (
 	"github.com/coredns/coredns/plugin/metrics/vars"
 	clog "github.com/coredns/coredns/plugin/pkg/log"
 	"github.com/coredns/coredns/plugin/pkg/uniq"
+
+	"github.com/prometheus/client_golang/prometheus"
 )
 
 var (
 	log      = clog.NewWithPlugin("prometheus")
 	u        = uniq.New()
 	registry = newReg()
+	// exemplarAddrs are the metrics addresses that have a server block with exemplars enabled.
+	exemplarAddrs = newAddrs()
 )
 
 func init() { plugin.Register("prometheus", setup) }
@@ -27,6 +35,10 @@ func setup(c *caddy.Controller) error {
 		return plugin.Error("prometheus", err)
 	}
 	m.Reg = registry.getOrSet(m.Addr, m.Reg)
+	if m.exemplars {
+		exemplarAddrs.add(m.Addr)
+		c.OnRestartFailed(func() error { exemplarAddrs.add(m.Addr); return nil })
+	}
 
 	c.OnStartup(func() error { m.Reg = registry.getOrSet(m.Addr, m.Reg); u.Set(m.Addr, m.OnStartup); return nil })
 	c.OnRestartFailed(func() error { m.Reg = registry.getOrSet(m.Addr, m.Reg); u.Set(m.Addr, m.OnStartup); return nil })
@@ -55,8 +67,30 @@ func setup(c *caddy.Controller) error {
 		return nil
 	})
 
+	if m.topK > 0 {
+		m.MustRegister(heavyHitters)
+		c.OnStartup(func() error {
+			conf := dnsserver.GetConfig(c)
+			for _, h := range conf.ListenHosts {
+				addrstr := conf.Transport + "://" + net.JoinHostPort(h, conf.Port)
+				heavyHitters.set(addrstr, newTalkers(m.topK, m.topWindow))
+			}
+			return nil
+		})
+	}
+
+	if m.otlp != nil {
+		m.otlp.gatherer = func() prometheus.Gatherer { return m.Reg }
+		c.OnStartup(m.otlp.OnStartup)
+		c.OnRestartFailed(m.otlp.OnStartup)
+		c.OnRestart(m.otlp.OnShutdown)
+		c.OnFinalShutdown(m.otlp.OnShutdown)
+	}
+
 	c.OnRestart(m.OnRestart)
 	c.OnRestart(func() error { vars.PluginEnabled.Reset(); return nil })
+	c.OnRestart(func() error { heavyHitters.Reset(); return nil })
+	c.OnRestart(func() error { exemplarAddrs.reset(); return nil })
 	c.OnFinalShutdown(m.OnFinalShutdown)
 
 	// Initialize metrics.
@@ -97,9 +131,89 @@ func parse(c *caddy.Controller) (*Metrics, error) {
 		default:
 			return met, c.ArgErr()
 		}
+
+		resource := defaultResource()
+		for c.NextBlock() {
+			switch c.Val() {
+			case "topk":
+				args := c.RemainingArgs()
+				if len(args) == 0 || len(args) > 2 {
+					return met, c.ArgErr()
+				}
+				k, err := strconv.Atoi(args[0])
+				if err != nil || k <= 0 {
+					return met, c.Errf("invalid topk size '%s'", args[0])
+				}
+				met.topK = k
+				met.topWindow = defaultTopWindow
+				if len(args) == 2 {
+					met.topWindow, err = time.ParseDuration(args[1])
+					if err != nil || met.topWindow <= 0 {
+						return met, c.Errf("invalid topk window '%s'", args[1])
+					}
+				}
+			case "otlp":
+				args := c.RemainingArgs()
+				if len(args) < 2 || len(args) > 3 {
+					return met, c.ArgErr()
+				}
+				exp, err := newOTLPExporter(args[0], args[1])
+				if err != nil {
+					return met, c.Err(err.Error())
+				}
+				if len(args) == 3 {
+					exp.interval, err = time.ParseDuration(args[2])
+					if err != nil || exp.interval <= 0 {
+						return met, c.Errf("invalid otlp interval '%s'", args[2])
+					}
+				}
+				met.otlp = exp
+			case "exemplars":
+				if c.NextArg() {
+					return met, c.ArgErr()
+				}
+				met.exemplars = true
+			case "resource":
+				args := c.RemainingArgs()
+				if len(args) == 0 {
+					return met, c.ArgErr()
+				}
+				for _, a := range args {
+					i := strings.Index(a, "=")
+					if i <= 0 {
+						return met, c.Errf("invalid resource attribute '%s', expected KEY=VALUE", a)
+					}
+					resource[a[:i]] = a[i+1:]
+				}
+			default:
+				return met, c.Errf("unknown property '%s'", c.Val())
+			}
+		}
+		if c.Val() == "}" && met.topK == 0 && met.otlp == nil && !met.exemplars {
+			return met, c.Err("empty block")
+		}
+		if met.otlp != nil {
+			met.otlp.resource = resource
+		}
 	}
 	return met, nil
 }
 
-// defaultAddr is the address the where the metrics are exported by default.
-const defaultAddr = "localhost:9153"
+// defaultResource returns the default resource attributes of metrics pushed with OTLP.
+func defaultResource() map[string]string {
+	r := map[string]string{
+		"service.name":    "coredns",
+		"service.version": coremain.CoreVersion,
+	}
+	if h, err := os.Hostname(); err == nil {
+		r["host.name"] = h
+	}
+	return r
+}
+
+const (
+	// defaultAddr is the address the where the metrics are exported by default.
+	defaultAddr = "localhost:9153"
+	// defaultTopWindow is the default window over which heavy hitters are counted.
+	defaultTopWindow = time.Minute
+)
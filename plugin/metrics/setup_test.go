@@ -2,6 +2,7 @@ package metrics
 
 import (
 	"testing"
+	"time"
 
 	"github.com/coredns/caddy"
 )
@@ -15,10 +16,23 @@ func TestPrometheusParse(t *testing.T) {
 		// oks
 		{`prometheus`, false, "localhost:9153"},
 		{`prometheus localhost:53`, false, "localhost:53"},
+		{"prometheus {\n topk 10\n}", false, "localhost:9153"},
+		{"prometheus localhost:53 {\n topk 10 5m\n}", false, "localhost:53"},
+		{"prometheus {\n otlp grpc otel-collector:4317\n}", false, "localhost:9153"},
+		{"prometheus {\n otlp http https://otel-collector:4318/otlp/v1/metrics 10s\n resource site=ams1 region=eu\n}", false, "localhost:9153"},
+		{"prometheus {\n exemplars\n}", false, "localhost:9153"},
 		// fails
 		{`prometheus {}`, true, ""},
 		{`prometheus /foo`, true, ""},
 		{`prometheus a b c`, true, ""},
+		{"prometheus {\n topk\n}", true, ""},
+		{"prometheus {\n topk 0\n}", true, ""},
+		{"prometheus {\n topk 10 -1s\n}", true, ""},
+		{"prometheus {\n junk\n}", true, ""},
+		{"prometheus {\n exemplars yes\n}", true, ""},
+		{"prometheus {\n otlp udp localhost:4317\n}", true, ""},
+		{"prometheus {\n otlp grpc localhost:4317 0s\n}", true, ""},
+		{"prometheus {\n otlp grpc localhost:4317\n resource site\n}", true, ""},
 	}
 	for i, test := range tests {
 		c := caddy.NewTestController("dns", test.input)
@@ -40,3 +54,23 @@ func TestPrometheusParse(t *testing.T) {
 		}
 	}
 }
+
+func TestPrometheusParseOTLP(t *testing.T) {
+	c := caddy.NewTestController("dns", "prometheus {\n resource site=ams1\n otlp http otel-collector:4318 1m\n}")
+	m, err := parse(c)
+	if err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+	if m.otlp == nil {
+		t.Fatal("Expected an OTLP exporter")
+	}
+	if m.otlp.endpoint != "otel-collector:4318/v1/metrics" || m.otlp.tls {
+		t.Errorf("Unexpected endpoint %s (tls %t)", m.otlp.endpoint, m.otlp.tls)
+	}
+	if m.otlp.interval != time.Minute {
+		t.Errorf("Expected interval 1m, got %s", m.otlp.interval)
+	}
+	if m.otlp.resource["site"] != "ams1" || m.otlp.resource["service.name"] != "coredns" {
+		t.Errorf("Unexpected resource attributes %v", m.otlp.resource)
+	}
+}
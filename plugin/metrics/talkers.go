@@ -0,0 +1,199 @@
+package metrics
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/coredns/coredns/plugin"
+	"github.com/coredns/coredns/plugin/metrics/topk"
+	"github.com/coredns/coredns/request"
+
+	"github.com/miekg/dns"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// The things we track the heavy hitters of.
+const (
+	topQName = iota
+	topClient
+	topNXDomain
+	numTop
+)
+
+var topNames = [numTop]string{"qname", "client", "nxdomain"}
+
+// topkFactor is how many more keys are counted than are reported, this keeps the reported counts
+// accurate even when the top keys are only slightly more frequent than the rest.
+const topkFactor = 10
+
+// talkers tracks the heavy hitters of a single server in fixed windows. What is reported is the last
+// complete window, or the current one until the first window is complete.
+type talkers struct {
+	k      int
+	window time.Duration
+
+	mu    sync.Mutex
+	start time.Time // start of the current window
+	cur   [numTop]*topk.TopK
+	prev  [numTop]*topk.TopK // nil until a window has completed
+}
+
+func newTalkers(k int, window time.Duration) *talkers {
+	t := &talkers{k: k, window: window, start: time.Now()}
+	t.cur = t.newSet()
+	return t
+}
+
+func (t *talkers) newSet() [numTop]*topk.TopK {
+	var s [numTop]*topk.TopK
+	for i := range s {
+		s[i] = topk.New(t.k * topkFactor)
+	}
+	return s
+}
+
+// rotate starts a new window if the current one has passed. The caller must hold t.mu.
+func (t *talkers) rotate(now time.Time) {
+	d := now.Sub(t.start)
+	if d < t.window {
+		return
+	}
+	if d < 2*t.window {
+		t.prev = t.cur
+	} else {
+		// Nothing was seen during the last complete window.
+		t.prev = t.newSet()
+	}
+	t.cur = t.newSet()
+	t.start = t.start.Add(d - d%t.window)
+}
+
+// add counts the request in state, which was answered with rcode.
+func (t *talkers) add(state request.Request, rcode int) {
+	t.mu.Lock()
+	t.rotate(time.Now())
+	cur := t.cur
+	t.mu.Unlock()
+
+	cur[topQName].Add(state.Name())
+	cur[topClient].Add(state.IP())
+	if rcode == dns.RcodeNameError {
+		cur[topNXDomain].Add(state.Name())
+	}
+}
+
+// top returns the top k of what.
+func (t *talkers) top(what int) []topk.Item {
+	t.mu.Lock()
+	t.rotate(time.Now())
+	s := t.cur
+	if t.prev[what] != nil {
+		s = t.prev
+	}
+	t.mu.Unlock()
+
+	return s[what].Top(t.k)
+}
+
+// topTalkers holds the heavy hitter trackers of all servers that have them enabled, keyed by the
+// server address as returned by WithServer.
+type topTalkers struct {
+	mu sync.RWMutex
+	m  map[string]*talkers
+}
+
+var heavyHitters = &topTalkers{m: make(map[string]*talkers)}
+
+func (tt *topTalkers) get(server string) *talkers {
+	tt.mu.RLock()
+	defer tt.mu.RUnlock()
+	return tt.m[server]
+}
+
+func (tt *topTalkers) set(server string, t *talkers) {
+	tt.mu.Lock()
+	defer tt.mu.Unlock()
+	if _, ok := tt.m[server]; !ok {
+		tt.m[server] = t
+	}
+}
+
+// Reset removes all trackers.
+func (tt *topTalkers) Reset() {
+	tt.mu.Lock()
+	defer tt.mu.Unlock()
+	tt.m = make(map[string]*talkers)
+}
+
+func (tt *topTalkers) all() map[string]*talkers {
+	tt.mu.RLock()
+	defer tt.mu.RUnlock()
+	m := make(map[string]*talkers, len(tt.m))
+	for k, v := range tt.m {
+		m[k] = v
+	}
+	return m
+}
+
+var topDescs = [numTop]*prometheus.Desc{
+	prometheus.NewDesc(prometheus.BuildFQName(plugin.Namespace, "dns", "top_qname_requests"),
+		"Estimated number of requests for the most queried names during the last window.",
+		[]string{"server", "name"}, nil),
+	prometheus.NewDesc(prometheus.BuildFQName(plugin.Namespace, "dns", "top_client_requests"),
+		"Estimated number of requests from the most active clients during the last window.",
+		[]string{"server", "client"}, nil),
+	prometheus.NewDesc(prometheus.BuildFQName(plugin.Namespace, "dns", "top_nxdomain_responses"),
+		"Estimated number of NXDOMAIN responses for the most queried non-existent names during the last window.",
+		[]string{"server", "name"}, nil),
+}
+
+// Describe implements the prometheus.Collector interface.
+func (tt *topTalkers) Describe(ch chan<- *prometheus.Desc) {
+	for _, d := range topDescs {
+		ch <- d
+	}
+}
+
+// Collect implements the prometheus.Collector interface. Only the top k of each server is
+// exported, which keeps the number of series bounded.
+func (tt *topTalkers) Collect(ch chan<- prometheus.Metric) {
+	for server, t := range tt.all() {
+		for what, d := range topDescs {
+			for _, item := range t.top(what) {
+				ch <- prometheus.MustNewConstMetric(d, prometheus.GaugeValue, float64(item.Count), server, item.Key)
+			}
+		}
+	}
+}
+
+// ServeHTTP serves the heavy hitters of all servers as JSON. The optional n parameter limits the
+// number of entries returned for each list.
+func (tt *topTalkers) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	n := -1
+	if s := r.URL.Query().Get("n"); s != "" {
+		var err error
+		if n, err = strconv.Atoi(s); err != nil || n < 0 {
+			http.Error(w, "invalid n", http.StatusBadRequest)
+			return
+		}
+	}
+
+	resp := map[string]map[string][]topk.Item{}
+	for server, t := range tt.all() {
+		lists := make(map[string][]topk.Item, numTop)
+		for what, name := range topNames {
+			items := t.top(what)
+			if n >= 0 && len(items) > n {
+				items = items[:n]
+			}
+			lists[name] = items
+		}
+		resp[server] = lists
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
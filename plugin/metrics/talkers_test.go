@@ -0,0 +1,90 @@
+package metrics
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/coredns/coredns/plugin/metrics/topk"
+	"github.com/coredns/coredns/plugin/test"
+	"github.com/coredns/coredns/request"
+
+	"github.com/miekg/dns"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestTalkers(t *testing.T) {
+	tk := newTalkers(2, time.Minute)
+	add := func(name string, n int, rcode int) {
+		state := request.Request{W: &test.ResponseWriter{}, Req: new(dns.Msg).SetQuestion(name, dns.TypeA)}
+		for i := 0; i < n; i++ {
+			tk.add(state, rcode)
+		}
+	}
+	add("a.example.org.", 5, dns.RcodeSuccess)
+	add("b.example.org.", 3, dns.RcodeNameError)
+	add("c.example.org.", 1, dns.RcodeNameError)
+
+	// The first window has not completed yet, so the current one is reported.
+	expectTop(t, tk.top(topQName), []topk.Item{{Key: "a.example.org.", Count: 5}, {Key: "b.example.org.", Count: 3}})
+	expectTop(t, tk.top(topNXDomain), []topk.Item{{Key: "b.example.org.", Count: 3}, {Key: "c.example.org.", Count: 1}})
+	expectTop(t, tk.top(topClient), []topk.Item{{Key: "10.240.0.1", Count: 9}})
+
+	// Complete the window, and add to the new one.
+	tk.start = tk.start.Add(-time.Minute)
+	add("c.example.org.", 1, dns.RcodeSuccess)
+	expectTop(t, tk.top(topQName), []topk.Item{{Key: "a.example.org.", Count: 5}, {Key: "b.example.org.", Count: 3}})
+
+	// Nothing seen during the last complete window.
+	tk.start = tk.start.Add(-2 * time.Minute)
+	expectTop(t, tk.top(topQName), []topk.Item{})
+}
+
+func TestTopTalkersExport(t *testing.T) {
+	tt := &topTalkers{m: map[string]*talkers{}}
+	tk := newTalkers(1, time.Minute)
+	tt.set("dns://:53", tk)
+	state := request.Request{W: &test.ResponseWriter{}, Req: new(dns.Msg).SetQuestion("example.org.", dns.TypeA)}
+	tk.add(state, dns.RcodeNameError)
+	tk.add(state, dns.RcodeNameError)
+
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(tt)
+	expected := `
+# HELP coredns_dns_top_nxdomain_responses Estimated number of NXDOMAIN responses for the most queried non-existent names during the last window.
+# TYPE coredns_dns_top_nxdomain_responses gauge
+coredns_dns_top_nxdomain_responses{name="example.org.",server="dns://:53"} 2
+`
+	if err := testutil.GatherAndCompare(reg, strings.NewReader(expected), "coredns_dns_top_nxdomain_responses"); err != nil {
+		t.Error(err)
+	}
+
+	rec := httptest.NewRecorder()
+	tt.ServeHTTP(rec, httptest.NewRequest("GET", "/topk?n=1", nil))
+	resp := map[string]map[string][]topk.Item{}
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("Failed to decode response: %s", err)
+	}
+	expectTop(t, resp["dns://:53"]["client"], []topk.Item{{Key: "10.240.0.1", Count: 2}})
+
+	rec = httptest.NewRecorder()
+	tt.ServeHTTP(rec, httptest.NewRequest("GET", "/topk?n=x", nil))
+	if rec.Code != 400 {
+		t.Errorf("Expected status 400 for an invalid n, got %d", rec.Code)
+	}
+}
+
+func expectTop(t *testing.T, got, expected []topk.Item) {
+	t.Helper()
+	if len(got) != len(expected) {
+		t.Fatalf("Expected %d items, got %d: %v", len(expected), len(got), got)
+	}
+	for i := range expected {
+		if got[i] != expected[i] {
+			t.Errorf("Expected item %d to be %v, got %v", i, expected[i], got[i])
+		}
+	}
+}
@@ -0,0 +1,117 @@
+// Package topk finds the most frequent keys in a stream using bounded memory. It implements the
+// Space-Saving algorithm from "Efficient Computation of Frequent and Top-k Elements in Data Streams"
+// (Metwally, Agrawal and El Abbadi): at most size keys are counted, and when a new key arrives while
+// all slots are taken, it replaces the key with the lowest count and inherits that count as its
+// maximum error.
+package topk
+
+import (
+	"container/heap"
+	"sort"
+	"sync"
+)
+
+// Item is a key with its estimated count. The true count lies between Count-Err and Count.
+type Item struct {
+	Key   string `json:"key"`
+	Count uint64 `json:"count"`
+	Err   uint64 `json:"error"`
+}
+
+// TopK tracks the most frequent keys it has seen. It is safe for concurrent use.
+type TopK struct {
+	size int
+
+	sync.Mutex
+	items map[string]*entry
+	h     minHeap
+}
+
+type entry struct {
+	Item
+	index int // index in the heap
+}
+
+// New returns a new TopK that counts at most size keys. Counts for the top k keys are more
+// accurate when size is (a lot) larger than k.
+func New(size int) *TopK {
+	return &TopK{size: size, items: make(map[string]*entry, size), h: make(minHeap, 0, size)}
+}
+
+// Add counts one occurrence of key.
+func (t *TopK) Add(key string) {
+	t.Lock()
+	defer t.Unlock()
+
+	if e, ok := t.items[key]; ok {
+		e.Count++
+		heap.Fix(&t.h, e.index)
+		return
+	}
+	if len(t.h) < t.size {
+		e := &entry{Item: Item{Key: key, Count: 1}}
+		t.items[key] = e
+		heap.Push(&t.h, e)
+		return
+	}
+
+	// Replace the least frequent key, the new key might have been seen that many times before.
+	e := t.h[0]
+	delete(t.items, e.Key)
+	e.Key, e.Err = key, e.Count
+	e.Count++
+	t.items[key] = e
+	heap.Fix(&t.h, 0)
+}
+
+// Top returns the k most frequent keys, most frequent first. Ties are ordered by key.
+func (t *TopK) Top(k int) []Item {
+	t.Lock()
+	items := make([]Item, len(t.h))
+	for i, e := range t.h {
+		items[i] = e.Item
+	}
+	t.Unlock()
+
+	sort.Slice(items, func(i, j int) bool {
+		if items[i].Count != items[j].Count {
+			return items[i].Count > items[j].Count
+		}
+		return items[i].Key < items[j].Key
+	})
+	if len(items) > k {
+		items = items[:k]
+	}
+	return items
+}
+
+// Len returns the number of keys being counted.
+func (t *TopK) Len() int {
+	t.Lock()
+	defer t.Unlock()
+	return len(t.h)
+}
+
+// minHeap implements heap.Interface, the entry with the lowest count is at the root.
+type minHeap []*entry
+
+func (h minHeap) Len() int           { return len(h) }
+func (h minHeap) Less(i, j int) bool { return h[i].Count < h[j].Count }
+func (h minHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *minHeap) Push(x interface{}) {
+	e := x.(*entry)
+	e.index = len(*h)
+	*h = append(*h, e)
+}
+
+func (h *minHeap) Pop() interface{} {
+	old := *h
+	e := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return e
+}
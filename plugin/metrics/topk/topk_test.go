@@ -0,0 +1,55 @@
+package topk
+
+import (
+	"strconv"
+	"testing"
+)
+
+func TestTopK(t *testing.T) {
+	tk := New(3)
+	for i := 0; i < 10; i++ {
+		tk.Add("a.")
+	}
+	for i := 0; i < 5; i++ {
+		tk.Add("b.")
+	}
+	tk.Add("c.")
+	tk.Add("d.") // replaces c.
+
+	if tk.Len() != 3 {
+		t.Fatalf("Expected 3 keys, got %d", tk.Len())
+	}
+	top := tk.Top(2)
+	expected := []Item{{Key: "a.", Count: 10}, {Key: "b.", Count: 5}}
+	if len(top) != len(expected) {
+		t.Fatalf("Expected %d items, got %d", len(expected), len(top))
+	}
+	for i := range expected {
+		if top[i] != expected[i] {
+			t.Errorf("Expected item %d to be %v, got %v", i, expected[i], top[i])
+		}
+	}
+
+	last := tk.Top(3)[2]
+	if last.Key != "d." || last.Count != 2 || last.Err != 1 {
+		t.Errorf("Expected d. with count 2 and error 1, got %v", last)
+	}
+}
+
+func TestTopKHeavyHitter(t *testing.T) {
+	tk := New(10)
+	// One heavy hitter hidden in a lot of noise, which does not fit.
+	for i := 0; i < 1000; i++ {
+		tk.Add(strconv.Itoa(i) + ".example.org.")
+		if i%4 == 0 {
+			tk.Add("heavy.example.org.")
+		}
+	}
+	top := tk.Top(1)
+	if top[0].Key != "heavy.example.org." {
+		t.Fatalf("Expected heavy.example.org. on top, got %s", top[0].Key)
+	}
+	if top[0].Count-top[0].Err > 250 || top[0].Count < 250 {
+		t.Errorf("Expected count bounds to contain 250, got count %d, error %d", top[0].Count, top[0].Err)
+	}
+}
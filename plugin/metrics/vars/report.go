@@ -4,12 +4,20 @@ import (
 	"time"
 
 	"github.com/coredns/coredns/request"
+
+	"github.com/prometheus/client_golang/prometheus"
 )
 
 // Report reports the metrics data associated with request. This function is exported because it is also
 // called from core/dnsserver to report requests hitting the server that should not be handled and are thus
 // not sent down the plugin chain.
 func Report(server string, req request.Request, zone, rcode, plugin string, size int, start time.Time) {
+	ReportTraced(server, req, zone, rcode, plugin, size, start, "")
+}
+
+// ReportTraced is like Report, but when traceID is not empty the request duration is recorded with
+// an exemplar that links it to the trace of the request.
+func ReportTraced(server string, req request.Request, zone, rcode, plugin string, size int, start time.Time, traceID string) {
 	// Proto and Family.
 	net := req.Proto()
 	fam := "1"
@@ -24,7 +32,12 @@ func Report(server string, req request.Request, zone, rcode, plugin string, size
 	qType := qTypeString(req.QType())
 	RequestCount.WithLabelValues(server, zone, net, fam, qType).Inc()
 
-	RequestDuration.WithLabelValues(server, zone).Observe(time.Since(start).Seconds())
+	duration := RequestDuration.WithLabelValues(server, zone)
+	if eo, ok := duration.(prometheus.ExemplarObserver); ok && traceID != "" {
+		eo.ObserveWithExemplar(time.Since(start).Seconds(), prometheus.Labels{"trace_id": traceID})
+	} else {
+		duration.Observe(time.Since(start).Seconds())
+	}
 
 	ResponseSize.WithLabelValues(server, zone, net).Observe(float64(size))
 	RequestSize.WithLabelValues(server, zone, net).Observe(float64(req.Len()))
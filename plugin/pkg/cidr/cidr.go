@@ -1,4 +1,5 @@
-// Package cidr contains functions that deal with classless reverse zones in the DNS.
+// Package cidr contains functions that deal with classless reverse zones in the DNS, and that parse
+// networks.
 package cidr
 
 import (
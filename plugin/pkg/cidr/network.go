@@ -0,0 +1,41 @@
+package cidr
+
+import (
+	"fmt"
+	"net"
+	"strings"
+)
+
+// ParseNetwork parses s, which is a CIDR or an IP address. An IP address is returned as a network
+// with just that address.
+func ParseNetwork(s string) (*net.IPNet, error) {
+	if !strings.Contains(s, "/") {
+		ip := net.ParseIP(s)
+		if ip == nil {
+			return nil, fmt.Errorf("invalid network %q", s)
+		}
+		bits := 8 * net.IPv6len
+		if ip.To4() != nil {
+			ip, bits = ip.To4(), 8*net.IPv4len
+		}
+		return &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)}, nil
+	}
+	_, n, err := net.ParseCIDR(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid network %q: %s", s, err)
+	}
+	return n, nil
+}
+
+// ParseNetworks parses each of args with ParseNetwork.
+func ParseNetworks(args []string) ([]*net.IPNet, error) {
+	networks := make([]*net.IPNet, 0, len(args))
+	for _, a := range args {
+		n, err := ParseNetwork(a)
+		if err != nil {
+			return nil, err
+		}
+		networks = append(networks, n)
+	}
+	return networks, nil
+}
@@ -0,0 +1,41 @@
+package cidr
+
+import "testing"
+
+func TestParseNetwork(t *testing.T) {
+	tests := []struct {
+		in       string
+		expected string
+		err      bool
+	}{
+		{"10.240.0.0/16", "10.240.0.0/16", false},
+		{"10.240.1.1/16", "10.240.0.0/16", false},
+		{"192.0.2.1", "192.0.2.1/32", false},
+		{"2001:db8::/32", "2001:db8::/32", false},
+		{"2001:db8::1", "2001:db8::1/128", false},
+		{"::ffff:192.0.2.1", "192.0.2.1/32", false},
+		{"192.0.2.300", "", true},
+		{"10.0.0.0/33", "", true},
+		{"example.org", "", true},
+	}
+	for i, tc := range tests {
+		n, err := ParseNetwork(tc.in)
+		if tc.err {
+			if err == nil {
+				t.Errorf("Test %d: expected error for %q, got %s", i, tc.in, n)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Test %d: expected no error, got %s", i, err)
+			continue
+		}
+		if n.String() != tc.expected {
+			t.Errorf("Test %d: expected %s, got %s", i, tc.expected, n)
+		}
+	}
+
+	if _, err := ParseNetworks([]string{"10.0.0.0/8", "nope"}); err == nil {
+		t.Errorf("Expected error for an invalid network")
+	}
+}
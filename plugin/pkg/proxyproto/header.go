@@ -0,0 +1,191 @@
+// Package proxyproto implements version 1 and 2 of the PROXY protocol, as used by load balancers to
+// pass on the address of the client. See https://www.haproxy.org/download/2.5/doc/proxy-protocol.txt.
+package proxyproto
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+)
+
+var (
+	sigV1 = []byte("PROXY ")
+	sigV2 = []byte("\r\n\r\n\x00\r\nQUIT\n")
+)
+
+const (
+	maxV1Len = 107 // maximum length of a version 1 header, including the CRLF
+	v2HdrLen = 16  // length of the fixed part of a version 2 header
+)
+
+// ErrNoHeader is returned when the data doesn't start with a PROXY protocol header.
+var ErrNoHeader = errors.New("no PROXY protocol header")
+
+// Header is a PROXY protocol header.
+type Header struct {
+	// Local is true if the connection was made by the proxy itself, e.g. for a health check. The
+	// addresses are not set then.
+	Local bool
+
+	SourceIP        net.IP
+	SourcePort      int
+	DestinationIP   net.IP
+	DestinationPort int
+}
+
+// Parse parses the header at the start of b, which must hold all of it. It returns the header and
+// its length, i.e. the data after the header starts at b[n:]. If b doesn't start with a header,
+// ErrNoHeader is returned.
+func Parse(b []byte) (h *Header, n int, err error) {
+	switch {
+	case bytes.HasPrefix(b, sigV2):
+		return parseV2(b)
+	case bytes.HasPrefix(b, sigV1):
+		i := bytes.Index(b, []byte("\r\n"))
+		if i < 0 || i+2 > maxV1Len {
+			return nil, 0, errors.New("version 1 header is not terminated")
+		}
+		h, err := parseV1(string(b[:i]))
+		return h, i + 2, err
+	}
+	return nil, 0, ErrNoHeader
+}
+
+// Read reads the header from the start of r. If r doesn't start with a header ErrNoHeader is
+// returned, and nothing is read from r.
+func Read(r *bufio.Reader) (*Header, error) {
+	// Peeking this far doesn't block on valid traffic without a header, a DNS message over TCP, a
+	// TLS handshake and an HTTP/2 preface are all longer.
+	b, err := r.Peek(len(sigV2))
+	switch {
+	case bytes.Equal(b, sigV2):
+		hdr, err := r.Peek(v2HdrLen)
+		if err != nil {
+			return nil, err
+		}
+		n := v2HdrLen + int(binary.BigEndian.Uint16(hdr[14:16]))
+		all, err := r.Peek(n)
+		if err != nil {
+			return nil, err
+		}
+		h, _, err := parseV2(all)
+		if err != nil {
+			return nil, err
+		}
+		r.Discard(n)
+		return h, nil
+	case bytes.HasPrefix(b, sigV1):
+		var line []byte
+		for len(line) < maxV1Len {
+			c, err := r.ReadByte()
+			if err != nil {
+				return nil, err
+			}
+			line = append(line, c)
+			if c == '\n' {
+				break
+			}
+		}
+		if !bytes.HasSuffix(line, []byte("\r\n")) {
+			return nil, errors.New("version 1 header is not terminated")
+		}
+		return parseV1(string(line[:len(line)-2]))
+	case err != nil && (bytes.HasPrefix(sigV2, b) || bytes.HasPrefix(sigV1, b)):
+		// The data ended in what may have been a header.
+		return nil, err
+	}
+	return nil, ErrNoHeader
+}
+
+// parseV1 parses a version 1 header without its CRLF, e.g. "PROXY TCP4 192.0.2.1 192.0.2.2 53000 53".
+func parseV1(line string) (*Header, error) {
+	fields := strings.Split(line, " ")
+	if len(fields) < 2 {
+		return nil, errors.New("invalid version 1 header")
+	}
+	switch fields[1] {
+	case "UNKNOWN":
+		return &Header{Local: true}, nil
+	case "TCP4", "TCP6":
+	default:
+		return nil, fmt.Errorf("unknown protocol %q in version 1 header", fields[1])
+	}
+	if len(fields) != 6 {
+		return nil, errors.New("invalid version 1 header")
+	}
+
+	h := &Header{SourceIP: net.ParseIP(fields[2]), DestinationIP: net.ParseIP(fields[3])}
+	if h.SourceIP == nil || h.DestinationIP == nil {
+		return nil, errors.New("invalid address in version 1 header")
+	}
+	if (h.SourceIP.To4() != nil) != (fields[1] == "TCP4") {
+		return nil, errors.New("address doesn't match the protocol of the version 1 header")
+	}
+	var err error
+	if h.SourcePort, err = parsePort(fields[4]); err != nil {
+		return nil, err
+	}
+	if h.DestinationPort, err = parsePort(fields[5]); err != nil {
+		return nil, err
+	}
+	return h, nil
+}
+
+func parsePort(s string) (int, error) {
+	p, err := strconv.ParseUint(s, 10, 16)
+	if err != nil {
+		return 0, fmt.Errorf("invalid port %q in version 1 header", s)
+	}
+	return int(p), nil
+}
+
+func parseV2(b []byte) (*Header, int, error) {
+	if len(b) < v2HdrLen {
+		return nil, 0, errors.New("version 2 header too short")
+	}
+	n := v2HdrLen + int(binary.BigEndian.Uint16(b[14:16]))
+	if len(b) < n {
+		return nil, 0, errors.New("version 2 header too short")
+	}
+	if b[12]>>4 != 2 {
+		return nil, 0, fmt.Errorf("unknown version %d in header", b[12]>>4)
+	}
+	switch b[12] & 0xf {
+	case 0: // LOCAL
+		return &Header{Local: true}, n, nil
+	case 1: // PROXY
+	default:
+		return nil, 0, fmt.Errorf("unknown command %d in version 2 header", b[12]&0xf)
+	}
+
+	addrs := b[v2HdrLen:n]
+	switch b[13] >> 4 {
+	case 1: // AF_INET
+		if len(addrs) < 12 {
+			return nil, 0, errors.New("version 2 header too short for IPv4 addresses")
+		}
+		return &Header{
+			SourceIP:        net.IP(append([]byte{}, addrs[0:4]...)),
+			DestinationIP:   net.IP(append([]byte{}, addrs[4:8]...)),
+			SourcePort:      int(binary.BigEndian.Uint16(addrs[8:10])),
+			DestinationPort: int(binary.BigEndian.Uint16(addrs[10:12])),
+		}, n, nil
+	case 2: // AF_INET6
+		if len(addrs) < 36 {
+			return nil, 0, errors.New("version 2 header too short for IPv6 addresses")
+		}
+		return &Header{
+			SourceIP:        net.IP(append([]byte{}, addrs[0:16]...)),
+			DestinationIP:   net.IP(append([]byte{}, addrs[16:32]...)),
+			SourcePort:      int(binary.BigEndian.Uint16(addrs[32:34])),
+			DestinationPort: int(binary.BigEndian.Uint16(addrs[34:36])),
+		}, n, nil
+	}
+	// AF_UNSPEC and AF_UNIX, there is no address we can use.
+	return &Header{Local: true}, n, nil
+}
@@ -0,0 +1,115 @@
+package proxyproto
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"net"
+	"strings"
+	"testing"
+)
+
+// v2 returns h as a version 2 header, with the protocol set to proto (1 is stream, 2 datagram).
+func v2(h *Header, proto byte) []byte {
+	b := append([]byte{}, sigV2...)
+	if h.Local {
+		return append(b, 0x20, 0, 0, 0)
+	}
+	src, dst := h.SourceIP.To4(), h.DestinationIP.To4()
+	fam := byte(0x10)
+	if src == nil || dst == nil {
+		src, dst = h.SourceIP.To16(), h.DestinationIP.To16()
+		fam = 0x20
+	}
+	b = append(b, 0x21, fam|proto, 0, 0)
+	binary.BigEndian.PutUint16(b[14:], uint16(2*len(src)+4))
+	b = append(b, src...)
+	b = append(b, dst...)
+	b = append(b, byte(h.SourcePort>>8), byte(h.SourcePort), byte(h.DestinationPort>>8), byte(h.DestinationPort))
+	return b
+}
+
+func TestParse(t *testing.T) {
+	h4 := &Header{SourceIP: net.ParseIP("192.0.2.1"), SourcePort: 53000, DestinationIP: net.ParseIP("192.0.2.2"), DestinationPort: 53}
+	h6 := &Header{SourceIP: net.ParseIP("2001:db8::1"), SourcePort: 53000, DestinationIP: net.ParseIP("2001:db8::2"), DestinationPort: 53}
+	withTLV := v2(h4, 1)
+	withTLV[15] += 4
+	withTLV = append(withTLV, 0x04, 0, 1, 0) // PP2_TYPE_NOOP
+
+	tests := []struct {
+		name   string
+		data   []byte
+		header *Header
+		err    bool
+	}{
+		{"v1 ipv4", []byte("PROXY TCP4 192.0.2.1 192.0.2.2 53000 53\r\n"), h4, false},
+		{"v1 ipv6", []byte("PROXY TCP6 2001:db8::1 2001:db8::2 53000 53\r\n"), h6, false},
+		{"v1 unknown", []byte("PROXY UNKNOWN ffff::1 ffff::2 1 2\r\n"), &Header{Local: true}, false},
+		{"v2 ipv4", v2(h4, 1), h4, false},
+		{"v2 ipv6", v2(h6, 2), h6, false},
+		{"v2 local", v2(&Header{Local: true}, 0), &Header{Local: true}, false},
+		{"v2 tlv", withTLV, h4, false},
+		{"no header", []byte("\x00\x1d\x12\x34\x01\x00\x00\x01\x00\x00\x00\x00\x00\x00"), nil, true},
+		{"v1 bad protocol", []byte("PROXY UDP4 192.0.2.1 192.0.2.2 53000 53\r\n"), nil, true},
+		{"v1 bad address", []byte("PROXY TCP4 192.0.2 192.0.2.2 53000 53\r\n"), nil, true},
+		{"v1 wrong family", []byte("PROXY TCP4 2001:db8::1 2001:db8::2 53000 53\r\n"), nil, true},
+		{"v1 bad port", []byte("PROXY TCP4 192.0.2.1 192.0.2.2 65536 53\r\n"), nil, true},
+		{"v1 not terminated", []byte("PROXY TCP4 192.0.2.1 192.0.2.2 53000 53" + strings.Repeat(" ", 100)), nil, true},
+		{"v2 bad version", append(append([]byte{}, sigV2...), 0x11, 0x11, 0, 0), nil, true},
+		{"v2 short", v2(h4, 1)[:20], nil, true},
+	}
+
+	for _, tc := range tests {
+		// The data after the header must be left alone.
+		data := append(append([]byte{}, tc.data...), "payload"...)
+
+		h, n, err := Parse(data)
+		if tc.err {
+			if err == nil {
+				t.Errorf("Test %q: expected error from Parse, got none", tc.name)
+			}
+		} else {
+			if err != nil {
+				t.Errorf("Test %q: expected no error from Parse, got %s", tc.name, err)
+				continue
+			}
+			if !equal(h, tc.header) || string(data[n:]) != "payload" {
+				t.Errorf("Test %q: expected %+v, got %+v and %q", tc.name, tc.header, h, data[n:])
+			}
+		}
+
+		r := bufio.NewReader(bytes.NewReader(data))
+		h, err = Read(r)
+		if tc.err {
+			if err == nil {
+				t.Errorf("Test %q: expected error from Read, got none", tc.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Test %q: expected no error from Read, got %s", tc.name, err)
+			continue
+		}
+		rest, _ := r.Peek(r.Buffered())
+		if !equal(h, tc.header) || string(rest) != "payload" {
+			t.Errorf("Test %q: expected %+v, got %+v and %q", tc.name, tc.header, h, rest)
+		}
+	}
+}
+
+func TestReadNoHeader(t *testing.T) {
+	data := "\x00\x1d\x12\x34\x01\x00\x00\x01\x00\x00\x00\x00\x00\x00"
+	r := bufio.NewReader(strings.NewReader(data))
+	if _, err := Read(r); err != ErrNoHeader {
+		t.Fatalf("Expected ErrNoHeader, got %v", err)
+	}
+	rest, _ := r.Peek(r.Buffered())
+	if string(rest) != data {
+		t.Errorf("Expected the data to be left unread, got %q", rest)
+	}
+}
+
+func equal(a, b *Header) bool {
+	return a.Local == b.Local && a.SourceIP.Equal(b.SourceIP) && a.SourcePort == b.SourcePort &&
+		a.DestinationIP.Equal(b.DestinationIP) && a.DestinationPort == b.DestinationPort
+}
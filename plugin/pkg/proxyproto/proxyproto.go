@@ -0,0 +1,209 @@
+package proxyproto
+
+import (
+	"bufio"
+	"net"
+	"sync"
+	"time"
+)
+
+// DefaultTimeout is how long to wait for the header of a connection.
+const DefaultTimeout = 5 * time.Second
+
+// Policy decides which peers may send a PROXY protocol header. Headers from other peers are not
+// looked at, so they can't spoof their address.
+type Policy struct {
+	Trusted []*net.IPNet
+	// Timeout is how long to wait for the header of a connection, when its address is needed before
+	// it is read from. Defaults to DefaultTimeout.
+	Timeout time.Duration
+}
+
+// trusted returns true if addr may send a header.
+func (p *Policy) trusted(addr net.Addr) bool {
+	var ip net.IP
+	switch a := addr.(type) {
+	case *net.TCPAddr:
+		ip = a.IP
+	case *net.UDPAddr:
+		ip = a.IP
+	default:
+		return false
+	}
+	for _, n := range p.Trusted {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// Equal returns true if p and q are the same policy. Either may be nil, for no policy.
+func (p *Policy) Equal(q *Policy) bool {
+	if p == nil || q == nil {
+		return p == q
+	}
+	if p.Timeout != q.Timeout || len(p.Trusted) != len(q.Trusted) {
+		return false
+	}
+	for i := range p.Trusted {
+		if p.Trusted[i].String() != q.Trusted[i].String() {
+			return false
+		}
+	}
+	return true
+}
+
+// Listener returns l, with the remote address of connections from trusted peers taken from the
+// header they send.
+func (p *Policy) Listener(l net.Listener) net.Listener { return &listener{Listener: l, policy: p} }
+
+type listener struct {
+	net.Listener
+	policy *Policy
+}
+
+// Accept implements net.Listener. The header is read when the connection is first used, so a slow
+// peer doesn't block the accepting of other connections.
+func (l *listener) Accept() (net.Conn, error) {
+	c, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	if !l.policy.trusted(c.RemoteAddr()) {
+		return c, nil
+	}
+	return &conn{Conn: c, r: bufio.NewReader(c), timeout: l.policy.Timeout}, nil
+}
+
+// conn is a connection from a trusted peer.
+type conn struct {
+	net.Conn
+	r       *bufio.Reader
+	timeout time.Duration
+
+	once   sync.Once
+	remote net.Addr
+	err    error
+}
+
+// readHeader reads the header, if the peer sent one.
+func (c *conn) readHeader() {
+	h, err := Read(c.r)
+	switch {
+	case err == ErrNoHeader:
+	case err != nil:
+		c.err = err
+		c.Conn.Close()
+	case !h.Local:
+		c.remote = &net.TCPAddr{IP: h.SourceIP, Port: h.SourcePort}
+	}
+}
+
+// Read implements net.Conn.
+func (c *conn) Read(b []byte) (int, error) {
+	c.once.Do(c.readHeader)
+	if c.err != nil {
+		return 0, c.err
+	}
+	return c.r.Read(b)
+}
+
+// RemoteAddr implements net.Conn, it returns the address of the client as sent in the header.
+func (c *conn) RemoteAddr() net.Addr {
+	c.once.Do(func() {
+		// Nothing was read yet, so there is no deadline of the caller to keep.
+		timeout := c.timeout
+		if timeout == 0 {
+			timeout = DefaultTimeout
+		}
+		c.Conn.SetReadDeadline(time.Now().Add(timeout))
+		c.readHeader()
+		c.Conn.SetReadDeadline(time.Time{})
+	})
+	if c.remote != nil {
+		return c.remote
+	}
+	return c.Conn.RemoteAddr()
+}
+
+// PacketConn returns pc, with the source address of datagrams from trusted peers taken from the
+// header they start with. Replies are sent to the peer, without a header.
+func (p *Policy) PacketConn(pc net.PacketConn) net.PacketConn {
+	return &packetConn{PacketConn: pc, policy: p, peers: make(map[*net.UDPAddr]peer)}
+}
+
+type packetConn struct {
+	net.PacketConn
+	policy *Policy
+
+	// peers maps the client addresses handed out by ReadFrom to the proxy they came from. The
+	// addresses are compared by identity, as each datagram gets its own.
+	mu    sync.Mutex
+	peers map[*net.UDPAddr]peer
+	swept time.Time
+}
+
+type peer struct {
+	addr net.Addr
+	read time.Time
+}
+
+// peerTTL is how long a peer is kept to send a reply to.
+const peerTTL = time.Minute
+
+// ReadFrom implements net.PacketConn. Datagrams from trusted peers with an invalid header are dropped.
+func (pc *packetConn) ReadFrom(b []byte) (int, net.Addr, error) {
+	for {
+		n, addr, err := pc.PacketConn.ReadFrom(b)
+		if err != nil || !pc.policy.trusted(addr) {
+			return n, addr, err
+		}
+		h, hlen, err := Parse(b[:n])
+		if err == ErrNoHeader {
+			return n, addr, nil
+		}
+		if err != nil {
+			continue
+		}
+		n = copy(b, b[hlen:n])
+		if h.Local {
+			return n, addr, nil
+		}
+
+		client := &net.UDPAddr{IP: h.SourceIP, Port: h.SourcePort}
+		now := time.Now()
+		pc.mu.Lock()
+		pc.peers[client] = peer{addr: addr, read: now}
+		pc.sweep(now)
+		pc.mu.Unlock()
+		return n, client, nil
+	}
+}
+
+// sweep removes peers of datagrams that weren't replied to. The caller must hold pc.mu.
+func (pc *packetConn) sweep(now time.Time) {
+	if now.Sub(pc.swept) < peerTTL {
+		return
+	}
+	pc.swept = now
+	for a, p := range pc.peers {
+		if now.Sub(p.read) > peerTTL {
+			delete(pc.peers, a)
+		}
+	}
+}
+
+// WriteTo implements net.PacketConn. Replies to clients behind a proxy are sent to the proxy.
+func (pc *packetConn) WriteTo(b []byte, addr net.Addr) (int, error) {
+	if a, ok := addr.(*net.UDPAddr); ok {
+		pc.mu.Lock()
+		p, found := pc.peers[a]
+		delete(pc.peers, a)
+		pc.mu.Unlock()
+		if found {
+			addr = p.addr
+		}
+	}
+	return pc.PacketConn.WriteTo(b, addr)
+}
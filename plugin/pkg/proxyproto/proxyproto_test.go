@@ -0,0 +1,176 @@
+package proxyproto
+
+import (
+	"io"
+	"net"
+	"testing"
+	"time"
+)
+
+func policy(cidr string) *Policy {
+	_, n, _ := net.ParseCIDR(cidr)
+	return &Policy{Trusted: []*net.IPNet{n}, Timeout: time.Second}
+}
+
+var client = &Header{SourceIP: net.ParseIP("198.51.100.1"), SourcePort: 5300, DestinationIP: net.ParseIP("127.0.0.1"), DestinationPort: 53}
+
+func TestListener(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  *Policy
+		send    []byte // sent before "query"
+		remote  string // empty if the actual address
+		payload string
+	}{
+		{"trusted", policy("127.0.0.0/8"), v2(client, 1), "198.51.100.1:5300", "query"},
+		{"trusted v1", policy("127.0.0.0/8"), []byte("PROXY TCP4 198.51.100.1 127.0.0.1 5300 53\r\n"), "198.51.100.1:5300", "query"},
+		{"trusted without header", policy("127.0.0.0/8"), nil, "", "query"},
+		{"trusted local", policy("127.0.0.0/8"), v2(&Header{Local: true}, 0), "", "query"},
+		{"untrusted", policy("192.0.2.0/24"), v2(client, 1), "", string(v2(client, 1)) + "query"},
+	}
+
+	for _, tc := range tests {
+		l, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		pl := tc.policy.Listener(l)
+
+		go func() {
+			c, err := net.Dial("tcp", l.Addr().String())
+			if err != nil {
+				return
+			}
+			defer c.Close()
+			c.Write(append(append([]byte{}, tc.send...), "query"...))
+		}()
+
+		c, err := pl.Accept()
+		if err != nil {
+			t.Fatal(err)
+		}
+		remote := c.RemoteAddr()
+		if _, ok := remote.(*net.TCPAddr); !ok {
+			t.Errorf("Test %q: expected a *net.TCPAddr, got %T", tc.name, remote)
+		}
+		if tc.remote != "" && remote.String() != tc.remote {
+			t.Errorf("Test %q: expected remote %s, got %s", tc.name, tc.remote, remote)
+		}
+		if tc.remote == "" && remote.(*net.TCPAddr).IP.String() != "127.0.0.1" {
+			t.Errorf("Test %q: expected the actual remote, got %s", tc.name, remote)
+		}
+		b, _ := io.ReadAll(c)
+		if string(b) != tc.payload {
+			t.Errorf("Test %q: expected payload %q, got %q", tc.name, tc.payload, b)
+		}
+		c.Close()
+		l.Close()
+	}
+}
+
+func TestListenerInvalidHeader(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	pl := policy("127.0.0.0/8").Listener(l)
+
+	go func() {
+		c, err := net.Dial("tcp", l.Addr().String())
+		if err != nil {
+			return
+		}
+		defer c.Close()
+		c.Write([]byte("PROXY TCP4 198.51.100 127.0.0.1 5300 53\r\nquery"))
+	}()
+	c, err := pl.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	if _, err := c.Read(make([]byte, 10)); err == nil {
+		t.Error("Expected an error reading from a connection with an invalid header")
+	}
+}
+
+func TestPacketConn(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	pc := policy("127.0.0.0/8").PacketConn(conn)
+
+	proxy, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer proxy.Close()
+	proxy.SetReadDeadline(time.Now().Add(5 * time.Second))
+
+	// An invalid header is dropped, the next datagram is read.
+	proxy.WriteTo(append(v2(client, 2)[:20], "query"...), conn.LocalAddr())
+	proxy.WriteTo(append(v2(client, 2), "query"...), conn.LocalAddr())
+
+	buf := make([]byte, 512)
+	n, addr, err := pc.ReadFrom(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(buf[:n]) != "query" {
+		t.Errorf("Expected %q, got %q", "query", buf[:n])
+	}
+	if a, ok := addr.(*net.UDPAddr); !ok || a.String() != "198.51.100.1:5300" {
+		t.Errorf("Expected *net.UDPAddr 198.51.100.1:5300, got %T %s", addr, addr)
+	}
+
+	// The reply goes to the proxy.
+	if _, err := pc.WriteTo([]byte("reply"), addr); err != nil {
+		t.Fatal(err)
+	}
+	n, _, err = proxy.ReadFrom(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(buf[:n]) != "reply" {
+		t.Errorf("Expected %q, got %q", "reply", buf[:n])
+	}
+
+	// Datagrams without a header are left alone.
+	proxy.WriteTo([]byte("query"), conn.LocalAddr())
+	n, addr, err = pc.ReadFrom(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(buf[:n]) != "query" || addr.String() != proxy.LocalAddr().String() {
+		t.Errorf("Expected %q from %s, got %q from %s", "query", proxy.LocalAddr(), buf[:n], addr)
+	}
+}
+
+func TestPacketConnUntrusted(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	pc := policy("192.0.2.0/24").PacketConn(conn)
+
+	proxy, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer proxy.Close()
+
+	data := append(v2(client, 2), "query"...)
+	proxy.WriteTo(data, conn.LocalAddr())
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	buf := make([]byte, 512)
+	n, addr, err := pc.ReadFrom(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(buf[:n]) != string(data) || addr.String() != proxy.LocalAddr().String() {
+		t.Errorf("Expected the datagram from %s unchanged, got %q from %s", proxy.LocalAddr(), buf[:n], addr)
+	}
+}
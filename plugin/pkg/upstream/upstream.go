@@ -4,8 +4,10 @@ package upstream
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/coredns/coredns/core/dnsserver"
+	"github.com/coredns/coredns/plugin/dnstap"
 	"github.com/coredns/coredns/plugin/pkg/nonwriter"
 	"github.com/coredns/coredns/request"
 
@@ -28,8 +30,13 @@ func (u *Upstream) Lookup(ctx context.Context, state request.Request, name strin
 	}
 	req := state.NewWithQuestion(name, typ)
 
+	start := time.Now()
 	nw := nonwriter.New(state.W)
 	server.ServeDNS(ctx, nw, req.Req)
 
+	if tapPlugin, ok := dnstap.FromContext(ctx); ok {
+		tapPlugin.TapResolver(ctx, state, req.Req, nw.Msg, start)
+	}
+
 	return nw.Msg, nil
 }
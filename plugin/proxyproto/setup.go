@@ -0,0 +1,71 @@
+// Package proxyproto makes the listeners of a server accept PROXY protocol headers from trusted peers.
+package proxyproto
+
+import (
+	"time"
+
+	"github.com/coredns/caddy"
+	"github.com/coredns/coredns/core/dnsserver"
+	"github.com/coredns/coredns/plugin"
+	"github.com/coredns/coredns/plugin/pkg/cidr"
+	"github.com/coredns/coredns/plugin/pkg/proxyproto"
+)
+
+func init() { plugin.Register("proxyproto", setup) }
+
+func setup(c *caddy.Controller) error {
+	config := dnsserver.GetConfig(c)
+	if config.ProxyProtocol != nil {
+		return plugin.Error("proxyproto", c.Err("PROXY protocol already configured for this server instance"))
+	}
+	p, err := parse(c)
+	if err != nil {
+		return plugin.Error("proxyproto", err)
+	}
+	config.ProxyProtocol = p
+	return nil
+}
+
+func parse(c *caddy.Controller) (*proxyproto.Policy, error) {
+	p := &proxyproto.Policy{Timeout: proxyproto.DefaultTimeout}
+	i := 0
+	for c.Next() {
+		if i > 0 {
+			return nil, plugin.ErrOnce
+		}
+		i++
+		if len(c.RemainingArgs()) != 0 {
+			return nil, c.ArgErr()
+		}
+		for c.NextBlock() {
+			switch c.Val() {
+			case "allow":
+				args := c.RemainingArgs()
+				if len(args) == 0 {
+					return nil, c.ArgErr()
+				}
+				networks, err := cidr.ParseNetworks(args)
+				if err != nil {
+					return nil, c.Err(err.Error())
+				}
+				p.Trusted = append(p.Trusted, networks...)
+			case "timeout":
+				args := c.RemainingArgs()
+				if len(args) != 1 {
+					return nil, c.ArgErr()
+				}
+				d, err := time.ParseDuration(args[0])
+				if err != nil || d <= 0 {
+					return nil, c.Errf("invalid timeout '%s'", args[0])
+				}
+				p.Timeout = d
+			default:
+				return nil, c.Errf("unknown property '%s'", c.Val())
+			}
+		}
+	}
+	if len(p.Trusted) == 0 {
+		return nil, c.Err("no trusted networks, use allow")
+	}
+	return p, nil
+}
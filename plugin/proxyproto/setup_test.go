@@ -0,0 +1,79 @@
+package proxyproto
+
+import (
+	"testing"
+	"time"
+
+	"github.com/coredns/caddy"
+	"github.com/coredns/coredns/core/dnsserver"
+)
+
+func TestSetup(t *testing.T) {
+	tests := []struct {
+		input     string
+		shouldErr bool
+		trusted   []string
+		timeout   time.Duration
+	}{
+		{`proxyproto {
+			allow 10.0.0.0/8 192.0.2.1 2001:db8::/32
+		}`, false, []string{"10.0.0.0/8", "192.0.2.1/32", "2001:db8::/32"}, 5 * time.Second},
+		{`proxyproto {
+			allow 10.0.0.0/8
+			allow ::1
+			timeout 2s
+		}`, false, []string{"10.0.0.0/8", "::1/128"}, 2 * time.Second},
+		// errors
+		{`proxyproto`, true, nil, 0},
+		{`proxyproto 10.0.0.0/8`, true, nil, 0},
+		{`proxyproto {
+			allow
+		}`, true, nil, 0},
+		{`proxyproto {
+			allow 10.0.0.0/33
+		}`, true, nil, 0},
+		{`proxyproto {
+			allow example.org
+		}`, true, nil, 0},
+		{`proxyproto {
+			allow 10.0.0.0/8
+			timeout 0s
+		}`, true, nil, 0},
+		{`proxyproto {
+			allow 10.0.0.0/8
+			require
+		}`, true, nil, 0},
+		{`proxyproto {
+			allow 10.0.0.0/8
+		}
+		proxyproto {
+			allow 10.0.0.0/8
+		}`, true, nil, 0},
+	}
+
+	for i, tc := range tests {
+		c := caddy.NewTestController("dns", tc.input)
+		err := setup(c)
+		if tc.shouldErr {
+			if err == nil {
+				t.Errorf("Test %d: expected error, got none", i)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("Test %d: expected no error, got %s", i, err)
+		}
+		p := dnsserver.GetConfig(c).ProxyProtocol
+		if len(p.Trusted) != len(tc.trusted) {
+			t.Fatalf("Test %d: expected %v, got %v", i, tc.trusted, p.Trusted)
+		}
+		for j, n := range p.Trusted {
+			if n.String() != tc.trusted[j] {
+				t.Errorf("Test %d: expected %s, got %s", i, tc.trusted[j], n)
+			}
+		}
+		if p.Timeout != tc.timeout {
+			t.Errorf("Test %d: expected timeout %s, got %s", i, tc.timeout, p.Timeout)
+		}
+	}
+}
@@ -122,6 +122,16 @@ func setup(c *caddy.Controller) error {
 			}
 		}
 
+		if dnsserver.DryRun(c) {
+			// Don't contact AWS when only validating.
+			h := &Route53{Fall: fall}
+			dnsserver.GetConfig(c).AddPlugin(func(next plugin.Handler) plugin.Handler {
+				h.Next = next
+				return h
+			})
+			continue
+		}
+
 		session, err := session.NewSession(&aws.Config{})
 		if err != nil {
 			return plugin.Error("route53", err)
@@ -0,0 +1,16 @@
+package rpz
+
+import (
+	"github.com/coredns/coredns/plugin"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// hitCount is the number of queries that matched a policy rule.
+var hitCount = promauto.NewCounterVec(prometheus.CounterOpts{
+	Namespace: plugin.Namespace,
+	Subsystem: "rpz",
+	Name:      "hits_total",
+	Help:      "Counter of queries that matched a response policy rule.",
+}, []string{"server", "zone", "trigger", "action"})
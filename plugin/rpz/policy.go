@@ -0,0 +1,209 @@
+package rpz
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/coredns/coredns/plugin/file"
+	"github.com/coredns/coredns/plugin/file/tree"
+
+	"github.com/miekg/dns"
+)
+
+// The triggers of a policy rule.
+const (
+	triggerQName   = "qname"
+	triggerIP      = "ip"
+	triggerNSDName = "nsdname"
+	triggerNSIP    = "nsip"
+)
+
+// The labels under the policy zone's origin that hold the rules of the non-QNAME triggers.
+const (
+	labelIP      = "rpz-ip."
+	labelNSDName = "rpz-nsdname."
+	labelNSIP    = "rpz-nsip."
+)
+
+type action int
+
+// The actions of a policy rule.
+const (
+	actionNXDomain action = iota
+	actionNoData
+	actionPassthru
+	actionDrop
+	actionTCPOnly
+	actionLocalData
+)
+
+func (a action) String() string {
+	switch a {
+	case actionNXDomain:
+		return "nxdomain"
+	case actionNoData:
+		return "nodata"
+	case actionPassthru:
+		return "passthru"
+	case actionDrop:
+		return "drop"
+	case actionTCPOnly:
+		return "tcp-only"
+	}
+	return "local-data"
+}
+
+// policyAction returns the action encoded in the records of a rule. The special actions are CNAMEs to
+// the root, the wildcard or one of the rpz-* names, anything else is local data.
+func policyAction(rrs []dns.RR) action {
+	for _, rr := range rrs {
+		cname, ok := rr.(*dns.CNAME)
+		if !ok {
+			continue
+		}
+		switch strings.ToLower(cname.Target) {
+		case ".":
+			return actionNXDomain
+		case "*.":
+			return actionNoData
+		case "rpz-passthru.":
+			return actionPassthru
+		case "rpz-drop.":
+			return actionDrop
+		case "rpz-tcp-only.":
+			return actionTCPOnly
+		}
+	}
+	return actionLocalData
+}
+
+// hit is a matching rule.
+type hit struct {
+	zone    string
+	trigger string
+	action  action
+	rrs     []dns.RR
+}
+
+// policyZone is a response policy zone, loaded from a file or transferred from a primary.
+type policyZone struct {
+	name string
+	z    *file.Zone
+}
+
+func (p *policyZone) tree() *tree.Tree {
+	p.z.RLock()
+	defer p.z.RUnlock()
+	return p.z.Tree
+}
+
+// rule returns the records of the rule at name, which is relative to the origin of the policy zone.
+func (p *policyZone) rule(t *tree.Tree, name string) []dns.RR {
+	e, ok := t.Search(name + p.name)
+	if !ok {
+		return nil
+	}
+	return e.All()
+}
+
+// matchName returns the rule for name under label, an exact match is preferred over the closest
+// wildcard.
+func (p *policyZone) matchName(t *tree.Tree, name, label string) []dns.RR {
+	name = strings.ToLower(dns.Fqdn(name))
+	if name == "." {
+		return nil
+	}
+	if rrs := p.rule(t, name+label); rrs != nil {
+		return rrs
+	}
+	for _, i := range dns.Split(name)[1:] {
+		if rrs := p.rule(t, "*."+name[i:]+label); rrs != nil {
+			return rrs
+		}
+	}
+	return p.rule(t, "*."+label)
+}
+
+// matchIP returns the rule with the longest prefix that contains ip under label.
+func (p *policyZone) matchIP(t *tree.Tree, ip net.IP, label string) []dns.RR {
+	bits := 8 * net.IPv6len
+	if ip4 := ip.To4(); ip4 != nil {
+		ip, bits = ip4, 8*net.IPv4len
+	}
+	for ones := bits; ones > 0; ones-- {
+		if rrs := p.rule(t, ipName(ip.Mask(net.CIDRMask(ones, bits)), ones)+label); rrs != nil {
+			return rrs
+		}
+	}
+	return nil
+}
+
+// ipName returns the name that encodes the prefix ip/ones in a policy zone. For IPv4 these are the
+// prefix length followed by the reversed octets, for IPv6 the prefix length followed by the reversed
+// 16 bit groups, where "zz" stands for the longest run of zero groups.
+func ipName(ip net.IP, ones int) string {
+	if ip4 := ip.To4(); ip4 != nil {
+		return fmt.Sprintf("%d.%d.%d.%d.%d.", ones, ip4[3], ip4[2], ip4[1], ip4[0])
+	}
+	groups := strings.Split(strings.Trim(strings.Replace(ip.String(), "::", ":zz:", 1), ":"), ":")
+	for i, j := 0, len(groups)-1; i < j; i, j = i+1, j-1 {
+		groups[i], groups[j] = groups[j], groups[i]
+	}
+	return fmt.Sprintf("%d.%s.", ones, strings.Join(groups, "."))
+}
+
+// matchQName returns the QNAME rule for qname, or nil.
+func (p *policyZone) matchQName(qname string) *hit {
+	if rrs := p.matchName(p.tree(), qname, ""); rrs != nil {
+		return &hit{zone: p.name, trigger: triggerQName, action: policyAction(rrs), rrs: rrs}
+	}
+	return nil
+}
+
+// matchResponse returns the first rule that matches the addresses in the answer (IP), the name
+// servers in the authority section (NSDNAME) or their addresses in the additional section (NSIP)
+// of res, or nil.
+func (p *policyZone) matchResponse(res *dns.Msg) *hit {
+	t := p.tree()
+	for _, rr := range res.Answer {
+		if ip := address(rr); ip != nil {
+			if rrs := p.matchIP(t, ip, labelIP); rrs != nil {
+				return &hit{zone: p.name, trigger: triggerIP, action: policyAction(rrs), rrs: rrs}
+			}
+		}
+	}
+
+	nsnames := map[string]bool{}
+	for _, rr := range res.Ns {
+		if ns, ok := rr.(*dns.NS); ok {
+			nsnames[strings.ToLower(ns.Ns)] = true
+			if rrs := p.matchName(t, ns.Ns, labelNSDName); rrs != nil {
+				return &hit{zone: p.name, trigger: triggerNSDName, action: policyAction(rrs), rrs: rrs}
+			}
+		}
+	}
+
+	for _, rr := range res.Extra {
+		if !nsnames[strings.ToLower(rr.Header().Name)] {
+			continue
+		}
+		if ip := address(rr); ip != nil {
+			if rrs := p.matchIP(t, ip, labelNSIP); rrs != nil {
+				return &hit{zone: p.name, trigger: triggerNSIP, action: policyAction(rrs), rrs: rrs}
+			}
+		}
+	}
+	return nil
+}
+
+// address returns the address of an A or AAAA record, or nil for other records.
+func address(rr dns.RR) net.IP {
+	switch rr := rr.(type) {
+	case *dns.A:
+		return rr.A
+	case *dns.AAAA:
+		return rr.AAAA
+	}
+	return nil
+}
@@ -0,0 +1,136 @@
+// Package rpz implements a plugin that applies DNS response policy zones (RPZ).
+//
+// See: https://datatracker.ietf.org/doc/html/draft-vixie-dnsop-dns-rpz
+package rpz
+
+import (
+	"context"
+	"strings"
+
+	"github.com/coredns/coredns/plugin"
+	"github.com/coredns/coredns/plugin/metrics"
+	"github.com/coredns/coredns/plugin/pkg/nonwriter"
+	"github.com/coredns/coredns/plugin/pkg/upstream"
+	"github.com/coredns/coredns/request"
+
+	"github.com/miekg/dns"
+)
+
+// RPZ is a plugin that rewrites responses according to response policy zones.
+type RPZ struct {
+	Next  plugin.Handler
+	Zones []string
+
+	policies []*policyZone // in order of precedence
+	upstream *upstream.Upstream
+}
+
+// ServeDNS implements the plugin.Handler interface.
+func (rpz *RPZ) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (int, error) {
+	state := request.Request{W: w, Req: r}
+	if plugin.Zones(rpz.Zones).Matches(state.Name()) == "" {
+		return plugin.NextOrFailure(rpz.Name(), rpz.Next, ctx, w, r)
+	}
+
+	// QNAME triggers are applied before the query is resolved.
+	for _, p := range rpz.policies {
+		if h := p.matchQName(state.Name()); h != nil {
+			return rpz.apply(ctx, w, state, h, nil)
+		}
+	}
+
+	nw := nonwriter.New(w)
+	rcode, err := plugin.NextOrFailure(rpz.Name(), rpz.Next, ctx, nw, r)
+	if nw.Msg == nil {
+		return rcode, err
+	}
+
+	for _, p := range rpz.policies {
+		if h := p.matchResponse(nw.Msg); h != nil {
+			return rpz.apply(ctx, w, state, h, nw.Msg)
+		}
+	}
+	w.WriteMsg(nw.Msg)
+	return rcode, err
+}
+
+// apply applies the action of h. Res is the response to the query, or nil if the query hasn't
+// been resolved yet.
+func (rpz *RPZ) apply(ctx context.Context, w dns.ResponseWriter, state request.Request, h *hit, res *dns.Msg) (int, error) {
+	hitCount.WithLabelValues(metrics.WithServer(ctx), h.zone, h.trigger, h.action.String()).Inc()
+	log.Infof("%s %s/%s: %s trigger in %s, action %s", state.IP(), state.Name(), state.Type(), h.trigger, h.zone, h.action)
+
+	switch h.action {
+	case actionDrop:
+		return dns.RcodeSuccess, nil
+	case actionTCPOnly:
+		if state.Proto() == "udp" {
+			m := new(dns.Msg)
+			m.SetReply(state.Req)
+			m.Truncated = true
+			w.WriteMsg(m)
+			return dns.RcodeSuccess, nil
+		}
+		fallthrough
+	case actionPassthru:
+		if res == nil {
+			return plugin.NextOrFailure(rpz.Name(), rpz.Next, ctx, w, state.Req)
+		}
+		w.WriteMsg(res)
+		return res.Rcode, nil
+	}
+
+	m := new(dns.Msg)
+	m.SetReply(state.Req)
+	m.RecursionAvailable = true
+	switch h.action {
+	case actionNXDomain:
+		m.Rcode = dns.RcodeNameError
+	case actionLocalData:
+		m.Answer = rpz.localData(ctx, state, h.rrs)
+	}
+	w.WriteMsg(m)
+	return m.Rcode, nil
+}
+
+// localData returns the answer synthesized from the records of a rule. A CNAME is followed, where a
+// CNAME to *.example.net is a CNAME to the query name under example.net.
+func (rpz *RPZ) localData(ctx context.Context, state request.Request, rrs []dns.RR) []dns.RR {
+	qname, qtype := state.Name(), state.QType()
+
+	var (
+		answer []dns.RR
+		cname  *dns.CNAME
+	)
+	for _, rr := range rrs {
+		switch {
+		case rr.Header().Rrtype == dns.TypeCNAME:
+			cname = dns.Copy(rr).(*dns.CNAME)
+		case qtype == dns.TypeANY || rr.Header().Rrtype == qtype:
+			rr = dns.Copy(rr)
+			rr.Header().Name = qname
+			answer = append(answer, rr)
+		}
+	}
+	if len(answer) > 0 || cname == nil {
+		return answer
+	}
+
+	cname.Hdr.Name = qname
+	if strings.HasPrefix(cname.Target, "*.") {
+		cname.Target = qname + cname.Target[2:]
+	}
+	answer = append(answer, cname)
+	if qtype == dns.TypeCNAME || rpz.upstream == nil {
+		return answer
+	}
+	m, err := rpz.upstream.Lookup(ctx, state, cname.Target, qtype)
+	if err != nil {
+		log.Debugf("Failed to look up %s/%s: %s", cname.Target, state.Type(), err)
+		return answer
+	}
+	return append(answer, m.Answer...)
+}
+
+// Name implements the plugin.Handler interface.
+func (rpz *RPZ) Name() string { return "rpz" }
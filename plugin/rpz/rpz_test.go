@@ -0,0 +1,199 @@
+package rpz
+
+import (
+	"context"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/coredns/coredns/plugin/file"
+	"github.com/coredns/coredns/plugin/pkg/dnstest"
+	"github.com/coredns/coredns/plugin/test"
+
+	"github.com/miekg/dns"
+)
+
+const policyZoneFile = `$TTL 3600
+$ORIGIN rpz.example.
+@                 IN SOA  ns.rpz.example. admin.rpz.example. 1 3600 600 86400 60
+@                 IN NS   ns.rpz.example.
+bad.example.org   IN CNAME .
+*.bad.example.org IN CNAME .
+empty.example.org IN CNAME *.
+ok.bad.example.org IN CNAME rpz-passthru.
+drop.example.org  IN CNAME rpz-drop.
+tcp.example.org   IN CNAME rpz-tcp-only.
+local.example.org IN A    192.0.2.1
+local.example.org IN TXT  "blocked"
+walled.example.org IN CNAME *.garden.example.net.
+24.0.2.0.198.rpz-ip IN CNAME .
+32.99.2.0.198.rpz-ip IN CNAME rpz-passthru.
+48.zz.1.db8.2001.rpz-ip IN CNAME *.
+ns.evil.example.rpz-nsdname IN CNAME .
+32.1.113.0.203.rpz-nsip IN CNAME rpz-drop.
+`
+
+func newTestRPZ(t *testing.T, next test.Handler) *RPZ {
+	z, err := file.Parse(strings.NewReader(policyZoneFile), "rpz.example.", "stdin", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &RPZ{
+		Next:     next,
+		Zones:    []string{"."},
+		policies: []*policyZone{{name: "rpz.example.", z: z}},
+	}
+}
+
+// answer returns a handler that answers every query with rrs.
+func answer(rrs ...dns.RR) test.Handler {
+	return test.HandlerFunc(func(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (int, error) {
+		m := new(dns.Msg)
+		m.SetReply(r)
+		for _, rr := range rrs {
+			switch rr.Header().Rrtype {
+			case dns.TypeNS:
+				m.Ns = append(m.Ns, rr)
+			default:
+				if rr.Header().Name == r.Question[0].Name {
+					m.Answer = append(m.Answer, rr)
+				} else {
+					m.Extra = append(m.Extra, rr)
+				}
+			}
+		}
+		w.WriteMsg(m)
+		return dns.RcodeSuccess, nil
+	})
+}
+
+func TestRPZQName(t *testing.T) {
+	rpz := newTestRPZ(t, answer(test.A("example.org. IN A 127.0.0.1")))
+
+	tests := []struct {
+		qname   string
+		qtype   uint16
+		proto   string
+		noReply bool
+		rcode   int
+		tc      bool
+		answer  []dns.RR
+	}{
+		{qname: "bad.example.org.", qtype: dns.TypeA, rcode: dns.RcodeNameError},
+		{qname: "BAD.example.org.", qtype: dns.TypeA, rcode: dns.RcodeNameError},
+		{qname: "a.b.bad.example.org.", qtype: dns.TypeA, rcode: dns.RcodeNameError},
+		{qname: "empty.example.org.", qtype: dns.TypeA, rcode: dns.RcodeSuccess},
+		{qname: "drop.example.org.", qtype: dns.TypeA, noReply: true},
+		{qname: "tcp.example.org.", qtype: dns.TypeA, proto: "udp", tc: true},
+		{qname: "local.example.org.", qtype: dns.TypeA, answer: []dns.RR{test.A("local.example.org. 3600 IN A 192.0.2.1")}},
+		{qname: "local.example.org.", qtype: dns.TypeTXT, answer: []dns.RR{test.TXT(`local.example.org. 3600 IN TXT "blocked"`)}},
+		{qname: "local.example.org.", qtype: dns.TypeMX},
+		{qname: "walled.example.org.", qtype: dns.TypeCNAME, answer: []dns.RR{test.CNAME("walled.example.org. 3600 IN CNAME walled.example.org.garden.example.net.")}},
+	}
+
+	for i, tc := range tests {
+		m := new(dns.Msg)
+		m.SetQuestion(tc.qname, tc.qtype)
+		w := &test.ResponseWriter{}
+		if tc.proto == "tcp" {
+			w.TCP = true
+		}
+		rec := dnstest.NewRecorder(w)
+		if _, err := rpz.ServeDNS(context.Background(), rec, m); err != nil {
+			t.Fatalf("Test %d: expected no error, got %s", i, err)
+		}
+		if tc.noReply {
+			if rec.Msg != nil {
+				t.Errorf("Test %d: expected no reply, got %s", i, rec.Msg)
+			}
+			continue
+		}
+		if rec.Msg == nil {
+			t.Fatalf("Test %d: expected a reply", i)
+		}
+		if rec.Msg.Rcode != tc.rcode {
+			t.Errorf("Test %d: expected rcode %d, got %d", i, tc.rcode, rec.Msg.Rcode)
+		}
+		if rec.Msg.Truncated != tc.tc {
+			t.Errorf("Test %d: expected truncated %t, got %t", i, tc.tc, rec.Msg.Truncated)
+		}
+		if err := test.Section(test.Case{Answer: tc.answer}, test.Answer, rec.Msg.Answer); err != nil {
+			t.Errorf("Test %d: %s", i, err)
+		}
+	}
+}
+
+func TestRPZPassthru(t *testing.T) {
+	rpz := newTestRPZ(t, answer(test.A("ok.bad.example.org. IN A 127.0.0.1")))
+
+	m := new(dns.Msg)
+	m.SetQuestion("ok.bad.example.org.", dns.TypeA)
+	rec := dnstest.NewRecorder(&test.ResponseWriter{})
+	rpz.ServeDNS(context.Background(), rec, m)
+	if rec.Msg.Rcode != dns.RcodeSuccess || len(rec.Msg.Answer) != 1 {
+		t.Errorf("Expected the upstream answer, got %s", rec.Msg)
+	}
+}
+
+func TestRPZResponse(t *testing.T) {
+	tests := []struct {
+		rrs     []dns.RR
+		noReply bool
+		rcode   int
+		answers int
+	}{
+		// IP
+		{rrs: []dns.RR{test.A("example.org. IN A 198.0.2.1")}, rcode: dns.RcodeNameError},
+		{rrs: []dns.RR{test.A("example.org. IN A 198.0.2.99")}, answers: 1}, // the longest prefix wins
+		{rrs: []dns.RR{test.AAAA("example.org. IN AAAA 2001:db8:1::1")}},
+		{rrs: []dns.RR{test.AAAA("example.org. IN AAAA 2001:db8:2::1")}, answers: 1},
+		{rrs: []dns.RR{test.A("example.org. IN A 127.0.0.1")}, answers: 1},
+		// NSDNAME
+		{rrs: []dns.RR{test.A("example.org. IN A 127.0.0.1"), test.NS("example.org. IN NS NS.evil.example.")}, rcode: dns.RcodeNameError},
+		// NSIP
+		{rrs: []dns.RR{test.A("example.org. IN A 127.0.0.1"), test.NS("example.org. IN NS ns.example.net."), test.A("ns.example.net. IN A 203.0.113.1")}, noReply: true},
+		{rrs: []dns.RR{test.A("example.org. IN A 127.0.0.1"), test.NS("example.org. IN NS ns.example.net."), test.A("ns.example.net. IN A 203.0.113.2")}, answers: 1},
+	}
+
+	for i, tc := range tests {
+		rpz := newTestRPZ(t, answer(tc.rrs...))
+		m := new(dns.Msg)
+		m.SetQuestion("example.org.", dns.TypeA)
+		rec := dnstest.NewRecorder(&test.ResponseWriter{})
+		if _, err := rpz.ServeDNS(context.Background(), rec, m); err != nil {
+			t.Fatalf("Test %d: expected no error, got %s", i, err)
+		}
+		if tc.noReply {
+			if rec.Msg != nil {
+				t.Errorf("Test %d: expected no reply, got %s", i, rec.Msg)
+			}
+			continue
+		}
+		if rec.Msg.Rcode != tc.rcode {
+			t.Errorf("Test %d: expected rcode %d, got %d", i, tc.rcode, rec.Msg.Rcode)
+		}
+		if len(rec.Msg.Answer) != tc.answers {
+			t.Errorf("Test %d: expected %d answers, got %d", i, tc.answers, len(rec.Msg.Answer))
+		}
+	}
+}
+
+func TestIPName(t *testing.T) {
+	tests := []struct {
+		ip       string
+		ones     int
+		expected string
+	}{
+		{"192.0.2.1", 32, "32.1.2.0.192."},
+		{"192.0.2.0", 24, "24.0.2.0.192."},
+		{"2001:db8::1", 128, "128.1.zz.db8.2001."},
+		{"2001:db8:1::", 48, "48.zz.1.db8.2001."},
+		{"::1", 128, "128.1.zz."},
+		{"2001:db8:0:1:1:1:1:1", 128, "128.1.1.1.1.1.0.db8.2001."},
+	}
+	for _, tc := range tests {
+		if got := ipName(net.ParseIP(tc.ip), tc.ones); got != tc.expected {
+			t.Errorf("Expected %s for %s/%d, got %s", tc.expected, tc.ip, tc.ones, got)
+		}
+	}
+}
@@ -0,0 +1,159 @@
+package rpz
+
+import (
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/coredns/caddy"
+	"github.com/coredns/coredns/core/dnsserver"
+	"github.com/coredns/coredns/plugin"
+	"github.com/coredns/coredns/plugin/file"
+	clog "github.com/coredns/coredns/plugin/pkg/log"
+	"github.com/coredns/coredns/plugin/pkg/parse"
+	"github.com/coredns/coredns/plugin/pkg/upstream"
+)
+
+var log = clog.NewWithPlugin("rpz")
+
+func init() { plugin.Register("rpz", setup) }
+
+func setup(c *caddy.Controller) error {
+	rpz, err := rpzParse(c)
+	if err != nil {
+		return plugin.Error("rpz", err)
+	}
+
+	for _, p := range rpz.policies {
+		p := p
+		if len(p.z.TransferFrom) == 0 {
+			c.OnStartup(func() error {
+				p.z.StartupOnce.Do(func() { p.z.Reload(nil) })
+				return nil
+			})
+			c.OnShutdown(p.z.OnShutdown)
+			continue
+		}
+
+		// Retrieve the zone and keep it up to date, like the secondary plugin does.
+		c.OnStartup(func() error {
+			p.z.StartupOnce.Do(func() {
+				go func() {
+					if p.z.TransferInRetry() {
+						p.z.Update()
+					}
+				}()
+			})
+			return nil
+		})
+		c.OnShutdown(p.z.OnShutdown)
+	}
+
+	dnsserver.GetConfig(c).AddPlugin(func(next plugin.Handler) plugin.Handler {
+		rpz.Next = next
+		return rpz
+	})
+
+	return nil
+}
+
+func rpzParse(c *caddy.Controller) (*RPZ, error) {
+	config := dnsserver.GetConfig(c)
+	rpz := &RPZ{upstream: upstream.New()}
+	reload := 1 * time.Minute
+	var openErr error
+
+	i := 0
+	for c.Next() {
+		if i > 0 {
+			return nil, plugin.ErrOnce
+		}
+		i++
+
+		rpz.Zones = plugin.OriginsFromArgsOrServerBlock(c.RemainingArgs(), c.ServerBlockKeys)
+
+		for c.NextBlock() {
+			switch c.Val() {
+			case "file":
+				// file POLICY FILE
+				args := c.RemainingArgs()
+				if len(args) != 2 {
+					return nil, c.ArgErr()
+				}
+				name := plugin.Host(args[0]).NormalizeExact()[0]
+				fileName := args[1]
+				if !filepath.IsAbs(fileName) && config.Root != "" {
+					fileName = filepath.Join(config.Root, fileName)
+				}
+
+				z := file.NewZone(name, fileName)
+				reader, err := os.Open(filepath.Clean(fileName))
+				if err != nil {
+					openErr = err
+				} else {
+					z, err = file.Parse(reader, name, fileName, 0)
+					reader.Close()
+					if err != nil {
+						return nil, err
+					}
+				}
+				rpz.policies = append(rpz.policies, &policyZone{name: name, z: z})
+
+			case "transfer":
+				// transfer POLICY ADDRESS...
+				args := c.RemainingArgs()
+				if len(args) < 2 {
+					return nil, c.ArgErr()
+				}
+				name := plugin.Host(args[0]).NormalizeExact()[0]
+				from, err := parse.HostPortOrFile(args[1:]...)
+				if err != nil {
+					return nil, err
+				}
+
+				z := file.NewZone(name, "stdin")
+				z.TransferFrom = from
+				z.IXFR = true
+				rpz.policies = append(rpz.policies, &policyZone{name: name, z: z})
+
+			case "reload":
+				args := c.RemainingArgs()
+				if len(args) != 1 {
+					return nil, c.ArgErr()
+				}
+				d, err := time.ParseDuration(args[0])
+				if err != nil {
+					return nil, c.Errf("invalid duration for reload '%s'", args[0])
+				}
+				if d < 0 {
+					return nil, c.Errf("invalid negative duration for reload '%s'", args[0])
+				}
+				reload = d
+
+			default:
+				return nil, c.Errf("unknown property '%s'", c.Val())
+			}
+		}
+	}
+
+	if openErr != nil {
+		if reload == 0 {
+			return nil, openErr
+		}
+		log.Warningf("Failed to open %q: trying again in %s", openErr, reload)
+	}
+	if len(rpz.policies) == 0 {
+		return nil, c.Errf("no policy zones")
+	}
+	seen := map[string]bool{}
+	for _, p := range rpz.policies {
+		if seen[p.name] {
+			return nil, c.Errf("duplicate policy zone '%s'", p.name)
+		}
+		seen[p.name] = true
+		if len(p.z.TransferFrom) == 0 {
+			p.z.ReloadInterval = reload
+		}
+	}
+	return rpz, nil
+}
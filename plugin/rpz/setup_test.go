@@ -0,0 +1,83 @@
+package rpz
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/coredns/caddy"
+)
+
+func TestSetup(t *testing.T) {
+	zoneFile := filepath.Join(t.TempDir(), "rpz.example.db")
+	if err := os.WriteFile(zoneFile, []byte(policyZoneFile), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		input     string
+		shouldErr bool
+		policies  []string
+	}{
+		{`rpz {
+			file rpz.example ` + zoneFile + `
+		}`, false, []string{"rpz.example."}},
+		{`rpz example.org {
+			transfer threats.example 10.0.0.1 10.0.0.2:5300
+			file rpz.example ` + zoneFile + `
+			reload 10s
+		}`, false, []string{"threats.example.", "rpz.example."}},
+		// fails
+		{`rpz`, true, nil},
+		{`rpz {
+			file rpz.example
+		}`, true, nil},
+		{`rpz {
+			transfer threats.example
+		}`, true, nil},
+		{`rpz {
+			transfer threats.example 10.0.0.1
+			transfer threats.example 10.0.0.2
+		}`, true, nil},
+		{`rpz {
+			file rpz.example /does/not/exist
+			reload 0
+		}`, true, nil},
+		{`rpz {
+			file rpz.example ` + zoneFile + `
+			reload -1s
+		}`, true, nil},
+		{`rpz {
+			file rpz.example ` + zoneFile + `
+			nxdomain
+		}`, true, nil},
+		{`rpz {
+			file rpz.example ` + zoneFile + `
+		}
+		rpz {
+			file rpz.example ` + zoneFile + `
+		}`, true, nil},
+	}
+
+	for i, test := range tests {
+		c := caddy.NewTestController("dns", test.input)
+		rpz, err := rpzParse(c)
+		if test.shouldErr && err == nil {
+			t.Errorf("Test %d: expected error but found none for input %s", i, test.input)
+		}
+		if !test.shouldErr && err != nil {
+			t.Errorf("Test %d: expected no error but found one for input %s, got: %v", i, test.input, err)
+		}
+		if test.shouldErr || err != nil {
+			continue
+		}
+		if len(rpz.policies) != len(test.policies) {
+			t.Fatalf("Test %d: expected %d policy zones, got %d", i, len(test.policies), len(rpz.policies))
+		}
+		for j, name := range test.policies {
+			if rpz.policies[j].name != name {
+				t.Errorf("Test %d: expected policy zone %s, got %s", i, name, rpz.policies[j].name)
+			}
+		}
+	}
+}
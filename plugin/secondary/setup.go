@@ -1,7 +1,10 @@
 package secondary
 
 import (
-	"time"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
 
 	"github.com/coredns/caddy"
 	"github.com/coredns/coredns/core/dnsserver"
@@ -30,26 +33,25 @@ func setup(c *caddy.Controller) error {
 			c.OnStartup(func() error {
 				z.StartupOnce.Do(func() {
 					go func() {
-						dur := time.Millisecond * 250
-						step := time.Duration(2)
-						max := time.Second * 10
-						for {
-							err := z.TransferIn()
-							if err == nil {
-								break
+						if z.Persist != "" {
+							ok, err := z.Load()
+							if err != nil {
+								log.Warningf("Failed to load '%s' from %s: %s", n, z.Persist, err)
 							}
-							log.Warningf("All '%s' masters failed to transfer, retrying in %s: %s", n, dur.String(), err)
-							time.Sleep(dur)
-							dur = step * dur
-							if dur > max {
-								dur = max
+							if ok {
+								log.Infof("Loaded '%s' from %s", n, z.Persist)
+								z.Update()
+								return
 							}
 						}
-						z.Update()
+						if z.TransferInRetry() {
+							z.Update()
+						}
 					}()
 				})
 				return nil
 			})
+			c.OnShutdown(z.OnShutdown)
 		}
 	}
 
@@ -63,6 +65,7 @@ func setup(c *caddy.Controller) error {
 func secondaryParse(c *caddy.Controller) (file.Zones, error) {
 	z := make(map[string]*file.Zone)
 	names := []string{}
+	config := dnsserver.GetConfig(c)
 	for c.Next() {
 
 		if c.Val() == "secondary" {
@@ -84,6 +87,21 @@ func secondaryParse(c *caddy.Controller) (file.Zones, error) {
 					if err != nil {
 						return file.Zones{}, err
 					}
+				case "directory":
+					args := c.RemainingArgs()
+					if len(args) != 1 {
+						return file.Zones{}, c.ArgErr()
+					}
+					dir := args[0]
+					if !filepath.IsAbs(dir) && config.Root != "" {
+						dir = filepath.Join(config.Root, dir)
+					}
+					if fi, err := os.Stat(dir); err != nil || !fi.IsDir() {
+						return file.Zones{}, c.Errf("directory '%s' does not exist", dir)
+					}
+					for _, origin := range origins {
+						z[origin].Persist = filepath.Join(dir, zoneFile(origin))
+					}
 				default:
 					return file.Zones{}, c.Errf("unknown property '%s'", c.Val())
 				}
@@ -91,6 +109,7 @@ func secondaryParse(c *caddy.Controller) (file.Zones, error) {
 				for _, origin := range origins {
 					if f != nil {
 						z[origin].TransferFrom = append(z[origin].TransferFrom, f...)
+						z[origin].IXFR = true
 					}
 					z[origin].Upstream = upstream.New()
 				}
@@ -99,3 +118,12 @@ func secondaryParse(c *caddy.Controller) (file.Zones, error) {
 	}
 	return file.Zones{Z: z, Names: names}, nil
 }
+
+// zoneFile returns the name of the file in which the zone origin is saved. Characters that can't be
+// in a file name, such as the '/' of RFC 2317 classless reverse zones, are escaped.
+func zoneFile(origin string) string {
+	if origin == "." {
+		return "root.zone"
+	}
+	return url.PathEscape(strings.TrimSuffix(origin, ".")) + ".zone"
+}
@@ -1,6 +1,7 @@
 package secondary
 
 import (
+	"path/filepath"
 	"testing"
 
 	"github.com/coredns/caddy"
@@ -58,6 +59,55 @@ func TestSecondaryParse(t *testing.T) {
 			if x := v.TransferFrom[0]; x != test.transferFrom {
 				t.Fatalf("Test %d transform from names don't match expected %q, but got %q", i, test.transferFrom, x)
 			}
+			if !v.IXFR {
+				t.Fatalf("Test %d expected incremental transfers to be enabled", i)
+			}
+		}
+	}
+}
+
+func TestSecondaryParseDirectory(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		input     string
+		shouldErr bool
+		persist   string
+	}{
+		{`secondary example.org {
+			transfer from 127.0.0.1
+			directory ` + dir + `
+		}`, false, filepath.Join(dir, "example.org.zone")},
+		{`secondary . {
+			directory ` + dir + `
+			transfer from 127.0.0.1
+		}`, false, filepath.Join(dir, "root.zone")},
+		{`secondary 0/25.2.0.192.in-addr.arpa {
+			transfer from 127.0.0.1
+			directory ` + dir + `
+		}`, false, filepath.Join(dir, "0%2F25.2.0.192.in-addr.arpa.zone")},
+		{`secondary example.org {
+			transfer from 127.0.0.1
+			directory /does/not/exist
+		}`, true, ""},
+		{`secondary example.org {
+			transfer from 127.0.0.1
+			directory
+		}`, true, ""},
+	}
+
+	for i, test := range tests {
+		c := caddy.NewTestController("dns", test.input)
+		s, err := secondaryParse(c)
+		if (err != nil) != test.shouldErr {
+			t.Fatalf("Test %d expected error %t, got %v", i, test.shouldErr, err)
+		}
+		if err != nil {
+			continue
+		}
+		for _, z := range s.Z {
+			if z.Persist != test.persist {
+				t.Errorf("Test %d expected zone to be saved in %q, got %q", i, test.persist, z.Persist)
+			}
 		}
 	}
 }
@@ -60,8 +60,10 @@ func write(w io.Writer, z *file.Zone) error {
 // the record types DNSKEY, RRSIG, CDNSKEY and CDS are *not* included in the returned
 // zone (if encountered).
 func Parse(f io.Reader, origin, fileName string) (*file.Zone, error) {
-	zp := dns.NewZoneParser(f, dns.Fqdn(origin), fileName)
-	zp.SetIncludeAllowed(true)
+	zp, err := file.NewZoneParser(f, origin, fileName)
+	if err != nil {
+		return nil, err
+	}
 	z := file.NewZone(origin, fileName)
 	seenSOA := false
 
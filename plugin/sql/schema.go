@@ -0,0 +1,169 @@
+package sql
+
+import (
+	"context"
+	database "database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/coredns/coredns/plugin"
+	"github.com/coredns/coredns/plugin/file"
+
+	"github.com/miekg/dns"
+)
+
+// schema has the queries that read the zones and the records from the database.
+type schema struct {
+	// zones returns the names of the zones.
+	zones string
+	// records returns the zone, name, TTL, type and content of all records. The content is the data
+	// of the record in the presentation format. An optional sixth column has the priority of MX and
+	// SRV records, which is then not in their content.
+	records string
+	// absolute is true if the names in the records are absolute, even without a final dot, as
+	// PowerDNS stores them. Otherwise names without a final dot are relative to the zone.
+	absolute bool
+}
+
+var schemas = map[string]schema{
+	"generic": {
+		zones:   "SELECT name FROM zones",
+		records: "SELECT zone, name, ttl, type, content FROM records",
+	},
+	"powerdns": {
+		zones: "SELECT name FROM domains",
+		records: "SELECT domains.name, records.name, records.ttl, records.type, records.content, records.prio " +
+			"FROM records JOIN domains ON records.domain_id = domains.id " +
+			"WHERE NOT records.disabled AND records.type IS NOT NULL AND records.type <> ''",
+		absolute: true,
+	},
+}
+
+// defaultTTL is the TTL of records that have none in the database.
+const defaultTTL = 3600
+
+// load reads all zones that are in s.origins from the database. Records that can't be parsed are
+// skipped, as are zones without a SOA record.
+func (s *SQL) load(ctx context.Context) (map[string]*file.Zone, error) {
+	zones := map[string]*file.Zone{}
+
+	rows, err := s.db.QueryContext(ctx, s.schema.zones)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query zones: %v", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, fmt.Errorf("failed to read zones: %v", err)
+		}
+		origin := dns.Fqdn(strings.ToLower(name))
+		if plugin.Zones(s.origins).Matches(origin) == "" {
+			continue
+		}
+		z := file.NewZone(origin, "")
+		z.Upstream = s.upstream
+		zones[origin] = z
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read zones: %v", err)
+	}
+
+	records, err := s.db.QueryContext(ctx, s.schema.records)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query records: %v", err)
+	}
+	defer records.Close()
+	columns, err := records.Columns()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read records: %v", err)
+	}
+	for records.Next() {
+		var (
+			zone, name, typ string
+			ttl, prio       database.NullInt64
+			content         database.NullString
+		)
+		dest := []interface{}{&zone, &name, &ttl, &typ, &content}
+		if len(columns) > len(dest) {
+			dest = append(dest, &prio)
+		}
+		if err := records.Scan(dest...); err != nil {
+			return nil, fmt.Errorf("failed to read records: %v", err)
+		}
+		origin := dns.Fqdn(strings.ToLower(zone))
+		z, ok := zones[origin]
+		if !ok {
+			continue
+		}
+		rr, err := s.schema.parse(origin, name, ttl, typ, withPrio(typ, content.String, prio))
+		if err != nil {
+			log.Warningf("Failed to parse record %q of type %s in zone %q: %v", name, typ, origin, err)
+			continue
+		}
+		if err := z.Insert(rr); err != nil {
+			log.Warningf("Failed to insert record %q of type %s in zone %q: %v", name, typ, origin, err)
+		}
+	}
+	if err := records.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read records: %v", err)
+	}
+
+	for origin, z := range zones {
+		if z.Apex.SOA == nil {
+			log.Warningf("Zone %q has no SOA record, not serving it", origin)
+			delete(zones, origin)
+		}
+	}
+	return zones, nil
+}
+
+// withPrio returns content with prio in front of it for MX and SRV records, if prio is set.
+func withPrio(typ, content string, prio database.NullInt64) string {
+	if !prio.Valid {
+		return content
+	}
+	switch strings.ToUpper(typ) {
+	case "MX", "SRV":
+		return fmt.Sprintf("%d %s", prio.Int64, content)
+	}
+	return content
+}
+
+// parse returns the record made from the columns of a row of the records query.
+func (sc schema) parse(origin, name string, ttl database.NullInt64, typ, content string) (dns.RR, error) {
+	if name == "" {
+		name = "@"
+	}
+	t := uint32(defaultTTL)
+	if ttl.Valid {
+		if ttl.Int64 < 0 {
+			return nil, fmt.Errorf("invalid TTL %d", ttl.Int64)
+		}
+		t = uint32(ttl.Int64)
+	}
+	if strings.ContainsAny(name+typ, " \t\r\n;()") || strings.ContainsAny(content, "\r\n") {
+		return nil, fmt.Errorf("invalid characters")
+	}
+
+	// Names in PowerDNS are absolute, with the root as origin the parser makes them so.
+	parseOrigin := origin
+	if sc.absolute {
+		parseOrigin = "."
+		if name == "@" {
+			name = origin
+		}
+	}
+	zp := dns.NewZoneParser(strings.NewReader(fmt.Sprintf("%s %d IN %s %s", name, t, typ, content)), parseOrigin, "")
+	rr, ok := zp.Next()
+	if err := zp.Err(); err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, fmt.Errorf("no record")
+	}
+	if !dns.IsSubDomain(origin, rr.Header().Name) {
+		return nil, fmt.Errorf("not in zone")
+	}
+	return rr, nil
+}
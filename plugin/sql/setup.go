@@ -0,0 +1,164 @@
+package sql
+
+import (
+	"context"
+	database "database/sql"
+	"time"
+
+	"github.com/coredns/caddy"
+	"github.com/coredns/coredns/core/dnsserver"
+	"github.com/coredns/coredns/plugin"
+	"github.com/coredns/coredns/plugin/pkg/cidr"
+	clog "github.com/coredns/coredns/plugin/pkg/log"
+	"github.com/coredns/coredns/plugin/transfer"
+
+	_ "github.com/go-sql-driver/mysql" // register the mysql driver
+	_ "github.com/lib/pq"              // register the postgres driver
+	_ "modernc.org/sqlite"             // register the sqlite driver
+)
+
+var log = clog.NewWithPlugin("sql")
+
+func init() { plugin.Register("sql", setup) }
+
+const defaultRefresh = time.Minute
+
+// loadTimeout is how long loading the zones from the database may take.
+const loadTimeout = 30 * time.Second
+
+// retryInterval is how often loading the zones is retried until it succeeds the first time.
+var retryInterval = 10 * time.Second
+
+// drivers maps the drivers that can be configured to the names they're registered with.
+var drivers = map[string]string{
+	"postgres": "postgres",
+	"mysql":    "mysql",
+	"sqlite":   "sqlite",
+}
+
+func setup(c *caddy.Controller) error {
+	s, err := parse(c)
+	if err != nil {
+		return plugin.Error("sql", err)
+	}
+
+	if dnsserver.DryRun(c) {
+		// Don't connect to the database when only validating.
+		s.db.Close()
+	} else {
+		ctx, cancel := context.WithCancel(context.Background())
+		c.OnStartup(func() error {
+			// get the transfer plugin, so we can send notifies when zones change.
+			if t := dnsserver.GetConfig(c).Handler("transfer"); t != nil {
+				s.transfer = t.(*transfer.Transfer) // if found this must be OK.
+			}
+			s.Run(ctx)
+			return nil
+		})
+		c.OnShutdown(func() error {
+			cancel()
+			return s.db.Close()
+		})
+	}
+
+	dnsserver.GetConfig(c).AddPlugin(func(next plugin.Handler) plugin.Handler {
+		s.Next = next
+		return s
+	})
+
+	return nil
+}
+
+func parse(c *caddy.Controller) (*SQL, error) {
+	var (
+		s                        *SQL
+		zonesQuery, recordsQuery string
+	)
+
+	i := 0
+	for c.Next() {
+		if i > 0 {
+			return nil, plugin.ErrOnce
+		}
+		i++
+
+		args := c.RemainingArgs()
+		if len(args) < 2 {
+			return nil, c.ArgErr()
+		}
+		driver, ok := drivers[args[0]]
+		if !ok {
+			return nil, c.Errf("unknown driver %q", args[0])
+		}
+		dsn := args[1]
+		s = New(nil, plugin.OriginsFromArgsOrServerBlock(args[2:], c.ServerBlockKeys))
+
+		for c.NextBlock() {
+			switch c.Val() {
+			case "schema":
+				if !c.NextArg() {
+					return nil, c.ArgErr()
+				}
+				sc, ok := schemas[c.Val()]
+				if !ok {
+					return nil, c.Errf("unknown schema %q", c.Val())
+				}
+				s.schema = sc
+				if c.NextArg() {
+					return nil, c.ArgErr()
+				}
+			case "zones_query":
+				if !c.NextArg() {
+					return nil, c.ArgErr()
+				}
+				zonesQuery = c.Val()
+			case "records_query":
+				if !c.NextArg() {
+					return nil, c.ArgErr()
+				}
+				recordsQuery = c.Val()
+			case "refresh":
+				if !c.NextArg() {
+					return nil, c.ArgErr()
+				}
+				d, err := time.ParseDuration(c.Val())
+				if err != nil {
+					return nil, c.Errf("invalid duration for refresh '%s'", c.Val())
+				}
+				if d < 0 {
+					return nil, c.Errf("invalid negative duration for refresh '%s'", c.Val())
+				}
+				s.refresh = d
+			case "notify":
+				args := c.RemainingArgs()
+				if len(args) == 0 {
+					return nil, c.ArgErr()
+				}
+				networks, err := cidr.ParseNetworks(args)
+				if err != nil {
+					return nil, c.Err(err.Error())
+				}
+				s.notify = append(s.notify, networks...)
+			case "fallthrough":
+				s.Fall.SetZonesFromArgs(c.RemainingArgs())
+			default:
+				return nil, c.Errf("unknown property '%s'", c.Val())
+			}
+		}
+		// The queries replace those of the schema, whatever the order they're given in.
+		if zonesQuery != "" {
+			s.schema.zones = zonesQuery
+		}
+		if recordsQuery != "" {
+			s.schema.records = recordsQuery
+		}
+
+		// This doesn't connect yet, it only checks the arguments.
+		db, err := database.Open(driver, dsn)
+		if err != nil {
+			return nil, c.Errf("failed to open database: %v", err)
+		}
+		s.db = db
+	}
+	return s, nil
+}
@@ -0,0 +1,104 @@
+package sql
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/coredns/caddy"
+)
+
+func TestSetup(t *testing.T) {
+	tests := []struct {
+		input       string
+		shouldErr   bool
+		expectedErr string
+	}{
+		{`sql sqlite /tmp/coredns.db`, false, ""},
+		{`sql postgres "host=localhost user=coredns dbname=dns sslmode=disable" example.org {
+			schema powerdns
+			refresh 30s
+			notify 10.240.0.0/24 192.0.2.1
+			fallthrough
+		}`, false, ""},
+		{`sql mysql "coredns:secret@tcp(localhost:3306)/dns" {
+			zones_query "SELECT name FROM domains"
+			records_query "SELECT zone, name, ttl, type, content FROM rrs"
+			refresh 0s
+		}`, false, ""},
+
+		{`sql`, true, "Wrong argument count"},
+		{`sql sqlite`, true, "Wrong argument count"},
+		{`sql oracle dsn`, true, `unknown driver "oracle"`},
+		{`sql sqlite /tmp/coredns.db {
+			schema bind
+		}`, true, `unknown schema "bind"`},
+		{`sql sqlite /tmp/coredns.db {
+			schema
+		}`, true, "Wrong argument count"},
+		{`sql sqlite /tmp/coredns.db {
+			refresh -1s
+		}`, true, "invalid negative duration"},
+		{`sql sqlite /tmp/coredns.db {
+			refresh often
+		}`, true, "invalid duration"},
+		{`sql sqlite /tmp/coredns.db {
+			notify
+		}`, true, "Wrong argument count"},
+		{`sql sqlite /tmp/coredns.db {
+			notify 10.240.0.300
+		}`, true, "invalid network"},
+		{`sql sqlite /tmp/coredns.db {
+			cache 1h
+		}`, true, "unknown property 'cache'"},
+		{"sql sqlite /tmp/coredns.db\nsql sqlite /tmp/coredns.db", true, "this plugin can only be used once per Server Block"},
+	}
+
+	for i, tc := range tests {
+		c := caddy.NewTestController("dns", tc.input)
+		s, err := parse(c)
+		if tc.shouldErr {
+			if err == nil {
+				t.Errorf("Test %d: expected error %q, got none", i, tc.expectedErr)
+			} else if !strings.Contains(err.Error(), tc.expectedErr) {
+				t.Errorf("Test %d: expected error %q, got %q", i, tc.expectedErr, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Test %d: expected no error, got %v", i, err)
+			continue
+		}
+		s.db.Close()
+	}
+}
+
+func TestSetupOptions(t *testing.T) {
+	c := caddy.NewTestController("dns", `sql postgres "host=localhost" example.org example.net {
+		records_query "SELECT zone, name, ttl, type, content FROM rrs"
+		schema powerdns
+		refresh 30s
+		notify 10.240.0.0/24 192.0.2.1
+	}`)
+	s, err := parse(c)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	defer s.db.Close()
+
+	if len(s.origins) != 2 || s.origins[0] != "example.org." || s.origins[1] != "example.net." {
+		t.Errorf("Expected origins example.org. and example.net., got %v", s.origins)
+	}
+	if s.schema.zones != schemas["powerdns"].zones || !s.schema.absolute {
+		t.Errorf("Expected the zones query of the powerdns schema")
+	}
+	if s.schema.records != "SELECT zone, name, ttl, type, content FROM rrs" {
+		t.Errorf("Expected the records query to be replaced, got %q", s.schema.records)
+	}
+	if s.refresh != 30*time.Second {
+		t.Errorf("Expected refresh of %s, got %s", 30*time.Second, s.refresh)
+	}
+	if len(s.notify) != 2 || s.notify[1].String() != "192.0.2.1/32" {
+		t.Errorf("Expected 2 notify networks, got %v", s.notify)
+	}
+}
@@ -0,0 +1,227 @@
+// Package sql implements a plugin that serves zones from a relational database.
+package sql
+
+import (
+	"context"
+	database "database/sql"
+	"net"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/coredns/coredns/plugin"
+	"github.com/coredns/coredns/plugin/file"
+	"github.com/coredns/coredns/plugin/pkg/fall"
+	"github.com/coredns/coredns/plugin/pkg/upstream"
+	"github.com/coredns/coredns/plugin/transfer"
+	"github.com/coredns/coredns/request"
+
+	"github.com/miekg/dns"
+)
+
+// SQL is a plugin that serves zones from a relational database. The zones are loaded in memory and
+// refreshed periodically, or when a NOTIFY is received.
+type SQL struct {
+	Next plugin.Handler
+	Fall fall.F
+
+	db       *database.DB
+	schema   schema
+	origins  []string      // only zones in these are served
+	refresh  time.Duration // 0 disables periodic refreshes
+	notify   []*net.IPNet  // networks NOTIFY messages are accepted from
+	upstream *upstream.Upstream
+	transfer *transfer.Transfer
+
+	zMu   sync.RWMutex
+	zones map[string]*file.Zone
+	names []string
+
+	reload chan struct{}
+}
+
+// New returns a new SQL that serves the zones in db that are in origins, using the generic schema.
+func New(db *database.DB, origins []string) *SQL {
+	return &SQL{
+		db:       db,
+		schema:   schemas["generic"],
+		origins:  origins,
+		refresh:  defaultRefresh,
+		upstream: upstream.New(),
+		zones:    map[string]*file.Zone{},
+		reload:   make(chan struct{}, 1),
+	}
+}
+
+// Run loads the zones and then keeps them up to date until ctx is done. If the first load fails, it
+// is retried in the background every retryInterval; until it succeeds no zones are served.
+func (s *SQL) Run(ctx context.Context) {
+	loaded := s.refreshZones(ctx)
+	go func() {
+		var tick <-chan time.Time
+		if s.refresh > 0 {
+			ticker := time.NewTicker(s.refresh)
+			defer ticker.Stop()
+			tick = ticker.C
+		}
+		for {
+			var retry <-chan time.Time
+			if !loaded {
+				retry = time.After(retryInterval)
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-tick:
+			case <-s.reload:
+			case <-retry:
+			}
+			loaded = s.refreshZones(ctx) || loaded
+		}
+	}()
+}
+
+// refreshZones updates the zones, giving up after loadTimeout. Failures are logged, it returns true
+// if the update succeeded.
+func (s *SQL) refreshZones(ctx context.Context) bool {
+	tctx, cancel := context.WithTimeout(ctx, loadTimeout)
+	defer cancel()
+	err := s.update(tctx)
+	if err != nil && ctx.Err() == nil {
+		log.Errorf("Failed to update zones: %v", err)
+	}
+	return err == nil
+}
+
+// update loads the zones from the database and replaces the zones served. Secondaries are notified of
+// zones with a new serial.
+func (s *SQL) update(ctx context.Context) error {
+	zones, err := s.load(ctx)
+	if err != nil {
+		return err
+	}
+	names := make([]string, 0, len(zones))
+	for origin := range zones {
+		names = append(names, origin)
+	}
+	sort.Strings(names)
+
+	s.zMu.Lock()
+	old := s.zones
+	s.zones = zones
+	s.names = names
+	s.zMu.Unlock()
+
+	for _, origin := range names {
+		serial := zones[origin].Apex.SOA.Serial
+		if z, ok := old[origin]; ok && z.Apex.SOA.Serial == serial {
+			continue
+		}
+		log.Infof("Loaded zone %q with %d SOA serial", origin, serial)
+		if s.transfer != nil {
+			go func(origin string) {
+				if err := s.transfer.Notify(origin); err != nil {
+					log.Warningf("Failed sending notifies: %s", err)
+				}
+			}(origin)
+		}
+	}
+	for origin := range old {
+		if _, ok := zones[origin]; !ok {
+			log.Infof("Removed zone %q", origin)
+		}
+	}
+	return nil
+}
+
+// ServeDNS implements the plugin.Handler interface.
+func (s *SQL) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (int, error) {
+	state := request.Request{W: w, Req: r}
+	qname := state.Name()
+
+	// A NOTIFY may be for a zone that is new in the database, so check the origins instead of the
+	// zones served.
+	if r.Opcode == dns.OpcodeNotify && plugin.Zones(s.origins).Matches(qname) != "" {
+		return s.notified(state)
+	}
+
+	s.zMu.RLock()
+	zone := plugin.Zones(s.names).Matches(qname)
+	z := s.zones[zone]
+	s.zMu.RUnlock()
+	if zone == "" || z == nil {
+		return plugin.NextOrFailure(s.Name(), s.Next, ctx, w, r)
+	}
+
+	// If transfer is not loaded, we'll see these, answer with refused (no transfer allowed).
+	if state.QType() == dns.TypeAXFR || state.QType() == dns.TypeIXFR {
+		return dns.RcodeRefused, nil
+	}
+
+	m := new(dns.Msg)
+	m.SetReply(r)
+	m.Authoritative = true
+	var result file.Result
+	m.Answer, m.Ns, m.Extra, result = z.Lookup(ctx, state, qname)
+
+	if result == file.NameError && s.Fall.Through(qname) {
+		return plugin.NextOrFailure(s.Name(), s.Next, ctx, w, r)
+	}
+
+	switch result {
+	case file.Success:
+	case file.NoData:
+	case file.NameError:
+		m.Rcode = dns.RcodeNameError
+	case file.Delegation:
+		m.Authoritative = false
+	case file.ServerFailure:
+		return dns.RcodeServerFailure, nil
+	}
+
+	w.WriteMsg(m)
+	return dns.RcodeSuccess, nil
+}
+
+// notified handles a NOTIFY, it starts a refresh of the zones if the NOTIFY comes from one of the
+// networks configured.
+func (s *SQL) notified(state request.Request) (int, error) {
+	ip := net.ParseIP(state.IP())
+	allowed := false
+	for _, n := range s.notify {
+		if n.Contains(ip) {
+			allowed = true
+			break
+		}
+	}
+	if !allowed {
+		log.Infof("Dropping notify from %s for %s", state.IP(), state.Name())
+		return dns.RcodeSuccess, nil
+	}
+
+	m := new(dns.Msg)
+	m.SetReply(state.Req)
+	m.Authoritative = true
+	state.W.WriteMsg(m)
+
+	log.Infof("Notify from %s for %s", state.IP(), state.Name())
+	select {
+	case s.reload <- struct{}{}:
+	default: // a refresh is pending already
+	}
+	return dns.RcodeSuccess, nil
+}
+
+// Transfer implements the transfer.Transferer interface.
+func (s *SQL) Transfer(zone string, serial uint32) (<-chan []dns.RR, error) {
+	s.zMu.RLock()
+	z, ok := s.zones[zone]
+	s.zMu.RUnlock()
+	if !ok || z == nil {
+		return nil, transfer.ErrNotAuthoritative
+	}
+	return z.Transfer(serial)
+}
+
+// Name implements the plugin.Handler interface.
+func (s *SQL) Name() string { return "sql" }
@@ -0,0 +1,377 @@
+package sql
+
+import (
+	"context"
+	database "database/sql"
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/coredns/coredns/plugin/pkg/dnstest"
+	"github.com/coredns/coredns/plugin/pkg/fall"
+	"github.com/coredns/coredns/plugin/test"
+	"github.com/coredns/coredns/plugin/transfer"
+
+	"github.com/miekg/dns"
+)
+
+const genericSchema = `
+CREATE TABLE zones (name TEXT PRIMARY KEY);
+CREATE TABLE records (zone TEXT, name TEXT, ttl INTEGER, type TEXT, content TEXT);
+INSERT INTO zones VALUES ('example.org'), ('example.net'), ('nosoa.org');
+INSERT INTO records VALUES
+  ('example.org', '@', 3600, 'SOA', 'ns1 hostmaster 2021010101 7200 3600 1209600 3600'),
+  ('example.org', '@', 3600, 'NS', 'ns1'),
+  ('example.org', '@', 3600, 'NS', 'ns2.example.net.'),
+  ('example.org', '', 3600, 'MX', '10 mx'),
+  ('example.org', 'ns1', 3600, 'A', '192.0.2.53'),
+  ('example.org', 'www', 300, 'A', '192.0.2.1'),
+  ('example.org', 'www', 300, 'AAAA', '2001:db8::1'),
+  ('example.org', 'web.example.org.', NULL, 'CNAME', 'www'),
+  ('example.org', '*.wild', 300, 'TXT', '"wild card"'),
+  ('example.org', 'sub', 3600, 'NS', 'ns.sub'),
+  ('example.org', 'ns.sub', 3600, 'A', '192.0.2.54'),
+  ('example.org', 'bad', 3600, 'A', '192.0.2.300'),
+  ('example.org', 'www.example.com.', 3600, 'A', '192.0.2.2'),
+  ('example.net', '@', 3600, 'SOA', 'ns1 hostmaster 1 7200 3600 1209600 3600'),
+  ('nosoa.org', 'www', 3600, 'A', '192.0.2.1');
+`
+
+func newDB(t *testing.T, ddl string) *database.DB {
+	t.Helper()
+	db, err := database.Open("sqlite", filepath.Join(t.TempDir(), "coredns.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if _, err := db.Exec(ddl); err != nil {
+		t.Fatalf("Failed to create database: %s", err)
+	}
+	return db
+}
+
+func newSQL(t *testing.T, ddl string, origins ...string) *SQL {
+	t.Helper()
+	s := New(newDB(t, ddl), origins)
+	s.Next = test.ErrorHandler()
+	if err := s.update(context.TODO()); err != nil {
+		t.Fatalf("Failed to load zones: %s", err)
+	}
+	return s
+}
+
+var exampleAuth = []dns.RR{
+	test.NS("example.org. 3600 IN NS ns1.example.org."),
+	test.NS("example.org. 3600 IN NS ns2.example.net."),
+}
+
+var exampleSOA = []dns.RR{
+	test.SOA("example.org. 3600 IN SOA ns1.example.org. hostmaster.example.org. 2021010101 7200 3600 1209600 3600"),
+}
+
+var sqlTestCases = []test.Case{
+	{
+		Qname: "example.org.", Qtype: dns.TypeSOA,
+		Answer: exampleSOA,
+		Ns:     exampleAuth,
+	},
+	{
+		Qname: "example.org.", Qtype: dns.TypeNS,
+		Answer: exampleAuth,
+		Extra:  []dns.RR{test.A("ns1.example.org. 3600 IN A 192.0.2.53")},
+	},
+	{
+		Qname: "example.org.", Qtype: dns.TypeMX,
+		Answer: []dns.RR{test.MX("example.org. 3600 IN MX 10 mx.example.org.")},
+		Ns:     exampleAuth,
+	},
+	{
+		Qname: "www.example.org.", Qtype: dns.TypeA,
+		Answer: []dns.RR{test.A("www.example.org. 300 IN A 192.0.2.1")},
+		Ns:     exampleAuth,
+	},
+	{
+		Qname: "web.example.org.", Qtype: dns.TypeAAAA,
+		Answer: []dns.RR{
+			test.CNAME("web.example.org. 3600 IN CNAME www.example.org."),
+			test.AAAA("www.example.org. 300 IN AAAA 2001:db8::1"),
+		},
+		Ns: exampleAuth,
+	},
+	{
+		Qname: "a.wild.example.org.", Qtype: dns.TypeTXT,
+		Answer: []dns.RR{test.TXT(`a.wild.example.org. 300 IN TXT "wild card"`)},
+		Ns:     exampleAuth,
+	},
+	{
+		Qname: "www.sub.example.org.", Qtype: dns.TypeA,
+		Ns:    []dns.RR{test.NS("sub.example.org. 3600 IN NS ns.sub.example.org.")},
+		Extra: []dns.RR{test.A("ns.sub.example.org. 3600 IN A 192.0.2.54")},
+	},
+	{
+		Qname: "www.example.org.", Qtype: dns.TypeMX,
+		Ns: exampleSOA,
+	},
+	{
+		Qname: "bad.example.org.", Qtype: dns.TypeA,
+		Rcode: dns.RcodeNameError,
+		Ns:    exampleSOA,
+	},
+}
+
+func TestServeDNS(t *testing.T) {
+	s := newSQL(t, genericSchema, ".")
+	ctx := context.TODO()
+
+	for i, tc := range sqlTestCases {
+		m := tc.Msg()
+		rec := dnstest.NewRecorder(&test.ResponseWriter{})
+		if _, err := s.ServeDNS(ctx, rec, m); err != nil {
+			t.Errorf("Test %d: expected no error, got %v", i, err)
+			continue
+		}
+		if err := test.SortAndCheck(rec.Msg, tc); err != nil {
+			t.Errorf("Test %d: %v", i, err)
+		}
+	}
+}
+
+func TestZones(t *testing.T) {
+	tests := []struct {
+		origins  []string
+		expected []string
+	}{
+		// nosoa.org has no SOA record and is never served.
+		{[]string{"."}, []string{"example.net.", "example.org."}},
+		{[]string{"org."}, []string{"example.org."}},
+		{[]string{"example.com."}, []string{}},
+	}
+	for i, tc := range tests {
+		s := newSQL(t, genericSchema, tc.origins...)
+		if len(s.names) != len(tc.expected) {
+			t.Errorf("Test %d: expected zones %v, got %v", i, tc.expected, s.names)
+			continue
+		}
+		for j := range s.names {
+			if s.names[j] != tc.expected[j] {
+				t.Errorf("Test %d: expected zones %v, got %v", i, tc.expected, s.names)
+			}
+		}
+	}
+}
+
+func TestFallthrough(t *testing.T) {
+	s := newSQL(t, genericSchema, ".")
+	s.Fall = fall.Root
+
+	m := new(dns.Msg)
+	m.SetQuestion("bad.example.org.", dns.TypeA)
+	rec := dnstest.NewRecorder(&test.ResponseWriter{})
+	// The ErrorHandler returns SERVFAIL.
+	if rcode, _ := s.ServeDNS(context.TODO(), rec, m); rcode != dns.RcodeServerFailure {
+		t.Errorf("Expected the query to fall through, got rcode %d", rcode)
+	}
+
+	m.SetQuestion("www.example.com.", dns.TypeA)
+	if rcode, _ := s.ServeDNS(context.TODO(), rec, m); rcode != dns.RcodeServerFailure {
+		t.Errorf("Expected the query for another zone to be passed on, got rcode %d", rcode)
+	}
+
+	// A referral is an answer, it doesn't fall through.
+	m.SetQuestion("www.sub.example.org.", dns.TypeA)
+	rec = dnstest.NewRecorder(&test.ResponseWriter{})
+	if rcode, _ := s.ServeDNS(context.TODO(), rec, m); rcode != dns.RcodeSuccess || rec.Msg == nil || len(rec.Msg.Ns) == 0 {
+		t.Errorf("Expected a referral for a delegated name, got rcode %d", rcode)
+	}
+}
+
+const powerDNSSchema = `
+CREATE TABLE domains (id INTEGER PRIMARY KEY, name VARCHAR(255) NOT NULL, master VARCHAR(128) DEFAULT NULL,
+  last_check INT DEFAULT NULL, type VARCHAR(8) NOT NULL, notified_serial INT DEFAULT NULL, account VARCHAR(40) DEFAULT NULL);
+CREATE TABLE records (id INTEGER PRIMARY KEY, domain_id INT DEFAULT NULL, name VARCHAR(255) DEFAULT NULL,
+  type VARCHAR(10) DEFAULT NULL, content VARCHAR(65535) DEFAULT NULL, ttl INT DEFAULT NULL, prio INT DEFAULT NULL,
+  disabled BOOL DEFAULT 0, ordername VARCHAR(255), auth BOOL DEFAULT 1);
+INSERT INTO domains (id, name, type) VALUES (1, 'example.org', 'NATIVE');
+INSERT INTO records (domain_id, name, type, content, ttl, prio, disabled) VALUES
+  (1, 'example.org', 'SOA', 'ns1.example.org hostmaster.example.org 2021010101 7200 3600 1209600 3600', 3600, 0, 0),
+  (1, 'example.org', 'NS', 'ns1.example.org', 3600, 0, 0),
+  (1, 'example.org', 'NS', 'ns2.example.net', 3600, 0, 0),
+  (1, 'example.org', 'MX', 'mx.example.org', 3600, 10, 0),
+  (1, '_sip._udp.example.org', 'SRV', '5 5060 sip.example.org', 3600, 20, 0),
+  (1, 'www.example.org', 'A', '192.0.2.1', 300, 0, 0),
+  (1, 'www.example.org', 'AAAA', '2001:db8::1', 300, 0, 0),
+  (1, 'web.example.org', 'CNAME', 'www.example.org', NULL, 0, 0),
+  (1, 'old.example.org', 'A', '192.0.2.9', 300, 0, 1),
+  (1, 'ent.example.org', NULL, NULL, NULL, NULL, 0);
+`
+
+func TestPowerDNSSchema(t *testing.T) {
+	s := New(newDB(t, powerDNSSchema), []string{"."})
+	s.schema = schemas["powerdns"]
+	s.Next = test.ErrorHandler()
+	if err := s.update(context.TODO()); err != nil {
+		t.Fatalf("Failed to load zones: %s", err)
+	}
+
+	tests := []test.Case{
+		{
+			Qname: "example.org.", Qtype: dns.TypeMX,
+			Answer: []dns.RR{test.MX("example.org. 3600 IN MX 10 mx.example.org.")},
+			Ns:     exampleAuth,
+		},
+		{
+			Qname: "_sip._udp.example.org.", Qtype: dns.TypeSRV,
+			Answer: []dns.RR{test.SRV("_sip._udp.example.org. 3600 IN SRV 20 5 5060 sip.example.org.")},
+			Ns:     exampleAuth,
+		},
+		{
+			Qname: "web.example.org.", Qtype: dns.TypeA,
+			Answer: []dns.RR{
+				test.CNAME("web.example.org. 3600 IN CNAME www.example.org."),
+				test.A("www.example.org. 300 IN A 192.0.2.1"),
+			},
+			Ns: exampleAuth,
+		},
+		{
+			Qname: "old.example.org.", Qtype: dns.TypeA,
+			Rcode: dns.RcodeNameError,
+			Ns:    exampleSOA,
+		},
+	}
+	for i, tc := range tests {
+		m := tc.Msg()
+		rec := dnstest.NewRecorder(&test.ResponseWriter{})
+		if _, err := s.ServeDNS(context.TODO(), rec, m); err != nil {
+			t.Errorf("Test %d: expected no error, got %v", i, err)
+			continue
+		}
+		if err := test.SortAndCheck(rec.Msg, tc); err != nil {
+			t.Errorf("Test %d: %v", i, err)
+		}
+	}
+}
+
+func TestTransfer(t *testing.T) {
+	s := newSQL(t, genericSchema, ".")
+
+	if _, err := s.Transfer("example.com.", 0); err != transfer.ErrNotAuthoritative {
+		t.Errorf("Expected %v, got %v", transfer.ErrNotAuthoritative, err)
+	}
+
+	ch, err := s.Transfer("example.org.", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var rrs []dns.RR
+	for r := range ch {
+		rrs = append(rrs, r...)
+	}
+	// SOA, 2 NS, 8 other records (bad is skipped, and www.example.com is not in the zone), and the SOA.
+	if len(rrs) != 12 {
+		t.Fatalf("Expected %d records, got %d: %v", 12, len(rrs), rrs)
+	}
+	if rrs[0].Header().Rrtype != dns.TypeSOA || rrs[len(rrs)-1].Header().Rrtype != dns.TypeSOA {
+		t.Errorf("Expected the transfer to start and end with the SOA record")
+	}
+
+	// IXFR with the current serial.
+	ch, err = s.Transfer("example.org.", 2021010101)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rrs = nil
+	for r := range ch {
+		rrs = append(rrs, r...)
+	}
+	if len(rrs) != 1 {
+		t.Errorf("Expected only the SOA record, got %v", rrs)
+	}
+}
+
+func TestRefresh(t *testing.T) {
+	s := New(newDB(t, genericSchema), []string{"."})
+	s.Next = test.ErrorHandler()
+	s.refresh = 0
+	_, network, _ := net.ParseCIDR("10.240.0.0/24")
+	s.notify = append(s.notify, network)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	s.Run(ctx)
+
+	if _, err := s.db.Exec(`INSERT INTO zones VALUES ('example.com');
+INSERT INTO records VALUES ('example.com', '@', 3600, 'SOA', 'ns1 hostmaster 1 7200 3600 1209600 3600'),
+  ('example.com', 'www', 3600, 'A', '192.0.2.80');`); err != nil {
+		t.Fatal(err)
+	}
+
+	m := new(dns.Msg)
+	m.SetQuestion("www.example.com.", dns.TypeA)
+	rec := dnstest.NewRecorder(&test.ResponseWriter{})
+	if rcode, _ := s.ServeDNS(ctx, rec, m); rcode != dns.RcodeServerFailure {
+		t.Fatalf("Expected example.com not to be served before a refresh, got rcode %d", rcode)
+	}
+
+	// A NOTIFY from an address that isn't allowed is dropped.
+	n := new(dns.Msg)
+	n.SetNotify("example.com.")
+	rec = dnstest.NewRecorder(&test.ResponseWriter{RemoteIP: "10.240.1.1"})
+	s.ServeDNS(ctx, rec, n)
+	if rec.Msg != nil {
+		t.Errorf("Expected no reply to a NOTIFY that isn't allowed, got %v", rec.Msg)
+	}
+	if len(s.reload) != 0 {
+		t.Errorf("Expected no refresh for a NOTIFY that isn't allowed")
+	}
+
+	rec = dnstest.NewRecorder(&test.ResponseWriter{RemoteIP: "10.240.0.1"})
+	s.ServeDNS(ctx, rec, n)
+	if rec.Msg == nil || !rec.Msg.Authoritative || rec.Msg.Opcode != dns.OpcodeNotify {
+		t.Fatalf("Expected an authoritative reply to the NOTIFY, got %v", rec.Msg)
+	}
+
+	for i := 0; ; i++ {
+		rec = dnstest.NewRecorder(&test.ResponseWriter{})
+		s.ServeDNS(ctx, rec, m)
+		if rec.Msg != nil && len(rec.Msg.Answer) == 1 {
+			break
+		}
+		if i == 100 {
+			t.Fatalf("Expected example.com to be served after the NOTIFY")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestRunRetry(t *testing.T) {
+	defer func(d time.Duration) { retryInterval = d }(retryInterval)
+	retryInterval = 10 * time.Millisecond
+
+	// Without the tables the first load fails, which must not stop the plugin.
+	s := New(newDB(t, "SELECT 1"), []string{"."})
+	s.Next = test.ErrorHandler()
+	s.refresh = 0
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	s.Run(ctx)
+
+	if _, err := s.db.Exec(genericSchema); err != nil {
+		t.Fatal(err)
+	}
+
+	m := new(dns.Msg)
+	m.SetQuestion("www.example.org.", dns.TypeA)
+	for i := 0; ; i++ {
+		rec := dnstest.NewRecorder(&test.ResponseWriter{})
+		s.ServeDNS(ctx, rec, m)
+		if rec.Msg != nil && len(rec.Msg.Answer) == 1 {
+			break
+		}
+		if i == 100 {
+			t.Fatalf("Expected example.org to be served after the load was retried")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,167 @@
+package template
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	clog "github.com/coredns/coredns/plugin/pkg/log"
+
+	"gopkg.in/yaml.v2"
+)
+
+var log = clog.NewWithPlugin("template")
+
+// dataFile holds the key/value data of a CSV, JSON or YAML file. Each key has one or more values.
+type dataFile struct {
+	path string
+
+	sync.RWMutex
+	values map[string][]string
+	mtime  time.Time
+	size   int64
+}
+
+// get returns the values of key.
+func (d *dataFile) get(key string) []string {
+	d.RLock()
+	defer d.RUnlock()
+	return d.values[key]
+}
+
+// read (re)reads the file if it changed since it was last read.
+func (d *dataFile) read() error {
+	file, err := os.Open(d.path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	stat, err := file.Stat()
+	if err != nil {
+		return err
+	}
+	d.RLock()
+	unchanged := d.mtime.Equal(stat.ModTime()) && d.size == stat.Size()
+	d.RUnlock()
+	if unchanged {
+		return nil
+	}
+
+	values, err := parseData(d.path, file)
+	if err != nil {
+		return err
+	}
+	log.Debugf("Parsed data file %s into %d entries", d.path, len(values))
+
+	d.Lock()
+	d.values = values
+	d.mtime = stat.ModTime()
+	d.size = stat.Size()
+	d.Unlock()
+	return nil
+}
+
+// parseData parses r in the format indicated by the extension of path.
+//
+// A CSV file has a key in the first column and its values in the remaining ones. JSON and YAML files
+// contain a single object (mapping), whose values are either scalars or lists of scalars.
+func parseData(path string, r io.Reader) (map[string][]string, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".csv":
+		cr := csv.NewReader(r)
+		cr.Comment = '#'
+		cr.FieldsPerRecord = -1
+		cr.TrimLeadingSpace = true
+		records, err := cr.ReadAll()
+		if err != nil {
+			return nil, err
+		}
+		values := make(map[string][]string, len(records))
+		for _, rec := range records {
+			values[rec[0]] = append(values[rec[0]], rec[1:]...)
+		}
+		return values, nil
+
+	case ".json":
+		var m map[string]interface{}
+		if err := json.NewDecoder(r).Decode(&m); err != nil {
+			return nil, err
+		}
+		return toValues(m)
+
+	case ".yaml", ".yml":
+		buf, err := ioutil.ReadAll(r)
+		if err != nil {
+			return nil, err
+		}
+		var m map[string]interface{}
+		if err := yaml.Unmarshal(buf, &m); err != nil {
+			return nil, err
+		}
+		return toValues(m)
+	}
+	return nil, fmt.Errorf("unknown data file format %q", filepath.Ext(path))
+}
+
+// dataFormats are the file extensions parseData understands.
+var dataFormats = map[string]bool{".csv": true, ".json": true, ".yaml": true, ".yml": true}
+
+func toValues(m map[string]interface{}) (map[string][]string, error) {
+	values := make(map[string][]string, len(m))
+	for k, v := range m {
+		switch v := v.(type) {
+		case []interface{}:
+			for _, e := range v {
+				s, err := scalar(k, e)
+				if err != nil {
+					return nil, err
+				}
+				values[k] = append(values[k], s)
+			}
+		default:
+			s, err := scalar(k, v)
+			if err != nil {
+				return nil, err
+			}
+			values[k] = []string{s}
+		}
+	}
+	return values, nil
+}
+
+func scalar(key string, v interface{}) (string, error) {
+	switch v.(type) {
+	case string, bool, int, float64:
+		return fmt.Sprint(v), nil
+	}
+	return "", fmt.Errorf("value of %q is not a string, number or list of those", key)
+}
+
+// dataSet are the data files of a template, by the name used in the Corefile.
+type dataSet map[string]*dataFile
+
+// lookup returns the first value of key in data file name, or the empty string.
+func (ds dataSet) lookup(name, key string) (string, error) {
+	values, err := ds.lookupAll(name, key)
+	if err != nil || len(values) == 0 {
+		return "", err
+	}
+	return values[0], nil
+}
+
+// lookupAll returns all values of key in data file name.
+func (ds dataSet) lookupAll(name, key string) ([]string, error) {
+	d, ok := ds[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown data file %q", name)
+	}
+	return d.get(key), nil
+}
@@ -0,0 +1,143 @@
+package template
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/coredns/caddy"
+	"github.com/coredns/coredns/plugin/metadata"
+	"github.com/coredns/coredns/plugin/pkg/dnstest"
+	"github.com/coredns/coredns/plugin/test"
+
+	"github.com/miekg/dns"
+)
+
+func TestParseData(t *testing.T) {
+	tests := []struct {
+		path      string
+		input     string
+		expected  map[string][]string
+		shouldErr bool
+	}{
+		{"hosts.csv", "# name, addresses\nweb,10.0.0.1,10.0.0.2\ndb, 10.0.0.3\n", map[string][]string{"web": {"10.0.0.1", "10.0.0.2"}, "db": {"10.0.0.3"}}, false},
+		{"hosts.json", `{"web": ["10.0.0.1", "10.0.0.2"], "db": "10.0.0.3", "weight": 10}`, map[string][]string{"web": {"10.0.0.1", "10.0.0.2"}, "db": {"10.0.0.3"}, "weight": {"10"}}, false},
+		{"hosts.yaml", "web:\n  - 10.0.0.1\n  - 10.0.0.2\ndb: 10.0.0.3\n", map[string][]string{"web": {"10.0.0.1", "10.0.0.2"}, "db": {"10.0.0.3"}}, false},
+		{"hosts.json", `{"web": {"a": "10.0.0.1"}}`, nil, true},
+		{"hosts.json", `["10.0.0.1"]`, nil, true},
+		{"hosts.txt", "web 10.0.0.1", nil, true},
+	}
+	for i, tc := range tests {
+		values, err := parseData(tc.path, strings.NewReader(tc.input))
+		if tc.shouldErr {
+			if err == nil {
+				t.Errorf("Test %d: expected error, got none", i)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Test %d: expected no error, got %s", i, err)
+			continue
+		}
+		if !reflect.DeepEqual(values, tc.expected) {
+			t.Errorf("Test %d: expected %v, got %v", i, tc.expected, values)
+		}
+	}
+}
+
+func TestDataReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "hosts.json")
+	if err := os.WriteFile(path, []byte(`{"web": "10.0.0.1"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	d := &dataFile{path: path}
+	if err := d.read(); err != nil {
+		t.Fatal(err)
+	}
+	if v := d.get("web"); len(v) != 1 || v[0] != "10.0.0.1" {
+		t.Fatalf("Expected 10.0.0.1, got %v", v)
+	}
+
+	if err := os.WriteFile(path, []byte(`{"web": ["10.0.0.2", "10.0.0.3"]}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	// Make sure the modification time differs, even on file systems with a coarse resolution.
+	os.Chtimes(path, time.Now(), time.Now().Add(time.Second))
+	if err := d.read(); err != nil {
+		t.Fatal(err)
+	}
+	if v := d.get("web"); len(v) != 2 || v[0] != "10.0.0.2" {
+		t.Fatalf("Expected 10.0.0.2 and 10.0.0.3, got %v", v)
+	}
+}
+
+func TestLookupConditions(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "hosts.json"), []byte(`{"web": ["10.0.0.1", "10.0.0.2"]}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	corefile := `template IN A example {
+		match ^(?P<host>[a-z]+)[.]example[.]$
+		metadata test/site ^ams$
+		client_subnet 10.240.0.0/16
+		ecs 10.240.0.1
+		data ` + filepath.Join(dir, "hosts.json") + `
+		answer "{{ range lookupAll ` + "`" + filepath.Join(dir, "hosts.json") + "`" + ` .Group.host }}
+			{{ $.Name }} 60 IN A {{ . }}
+			{{ end }}"
+	}
+	template IN A example {
+		rcode REFUSED
+	}`
+	c := caddy.NewTestController("dns", corefile)
+	handler, err := templateParse(c)
+	if err != nil {
+		t.Fatalf("Could not parse config: %s", err)
+	}
+	for _, d := range handler.Templates[0].data {
+		if err := d.read(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	handler.Next = test.NextHandler(rcodeFallthrough, nil)
+
+	tests := []struct {
+		site    string
+		ecs     string
+		rcode   int
+		answers int
+	}{
+		{site: "ams", rcode: dns.RcodeSuccess, answers: 2},
+		{site: "fra", rcode: dns.RcodeRefused},
+		{site: "ams", ecs: "192.0.2.0", rcode: dns.RcodeRefused},
+		{site: "fra", ecs: "10.240.1.0", rcode: dns.RcodeRefused},
+	}
+	for i, tc := range tests {
+		ctx := metadata.ContextWithMetadata(context.TODO())
+		site := tc.site
+		metadata.SetValueFunc(ctx, "test/site", func() string { return site })
+
+		r := new(dns.Msg).SetQuestion("web.example.", dns.TypeA)
+		if tc.ecs != "" {
+			r.SetEdns0(4096, false)
+			r.IsEdns0().Option = append(r.IsEdns0().Option, &dns.EDNS0_SUBNET{Code: dns.EDNS0SUBNET, Family: 1, SourceNetmask: 24, Address: net.ParseIP(tc.ecs).To4()})
+		}
+		rec := dnstest.NewRecorder(&test.ResponseWriter{})
+		code, err := handler.ServeDNS(ctx, rec, r)
+		if err != nil {
+			t.Fatalf("Test %d: expected no error, got %s", i, err)
+		}
+		if code != tc.rcode {
+			t.Errorf("Test %d: expected rcode %d, got %d", i, tc.rcode, code)
+		}
+		if tc.answers > 0 && len(rec.Msg.Answer) != tc.answers {
+			t.Errorf("Test %d: expected %d answers, got %d", i, tc.answers, len(rec.Msg.Answer))
+		}
+	}
+}
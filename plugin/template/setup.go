@@ -1,12 +1,16 @@
 package template
 
 import (
+	"path/filepath"
 	"regexp"
+	"strings"
 	gotmpl "text/template"
+	"time"
 
 	"github.com/coredns/caddy"
 	"github.com/coredns/coredns/core/dnsserver"
 	"github.com/coredns/coredns/plugin"
+	"github.com/coredns/coredns/plugin/pkg/cidr"
 	"github.com/coredns/coredns/plugin/pkg/upstream"
 
 	"github.com/miekg/dns"
@@ -20,6 +24,29 @@ func setupTemplate(c *caddy.Controller) error {
 		return plugin.Error("template", err)
 	}
 
+	for _, t := range handler.Templates {
+		t := t
+		if len(t.data) == 0 {
+			continue
+		}
+		stop := make(chan struct{})
+		c.OnStartup(func() error {
+			for _, d := range t.data {
+				if err := d.read(); err != nil {
+					return plugin.Error("template", err)
+				}
+			}
+			if t.reload > 0 {
+				go periodicDataUpdate(t, stop)
+			}
+			return nil
+		})
+		c.OnShutdown(func() error {
+			close(stop)
+			return nil
+		})
+	}
+
 	dnsserver.GetConfig(c).AddPlugin(func(next plugin.Handler) plugin.Handler {
 		handler.Next = next
 		return handler
@@ -28,8 +55,27 @@ func setupTemplate(c *caddy.Controller) error {
 	return nil
 }
 
+// periodicDataUpdate rereads the data files of t when they have changed, until stop is closed.
+func periodicDataUpdate(t template, stop chan struct{}) {
+	ticker := time.NewTicker(t.reload)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			for _, d := range t.data {
+				if err := d.read(); err != nil {
+					log.Warningf("Failed to reload data file %s: %s", d.path, err)
+				}
+			}
+		}
+	}
+}
+
 func templateParse(c *caddy.Controller) (handler Handler, err error) {
 	handler.Templates = make([]template, 0)
+	config := dnsserver.GetConfig(c)
 
 	for c.Next() {
 
@@ -51,7 +97,8 @@ func templateParse(c *caddy.Controller) (handler Handler, err error) {
 
 		zones := plugin.OriginsFromArgsOrServerBlock(c.RemainingArgs(), c.ServerBlockKeys)
 		handler.Zones = append(handler.Zones, zones...)
-		t := template{qclass: class, qtype: qtype, zones: zones}
+		t := template{qclass: class, qtype: qtype, zones: zones, data: dataSet{}, reload: defaultReload}
+		funcs := gotmpl.FuncMap{"lookup": t.data.lookup, "lookupAll": t.data.lookupAll}
 
 		t.regex = make([]*regexp.Regexp, 0)
 		templatePrefix := ""
@@ -81,7 +128,7 @@ func templateParse(c *caddy.Controller) (handler Handler, err error) {
 					return handler, c.ArgErr()
 				}
 				for _, answer := range args {
-					tmpl, err := gotmpl.New("answer").Parse(answer)
+					tmpl, err := gotmpl.New("answer").Funcs(funcs).Parse(answer)
 					if err != nil {
 						return handler, c.Errf("could not compile template: %s, %v", c.Val(), err)
 					}
@@ -94,7 +141,7 @@ func templateParse(c *caddy.Controller) (handler Handler, err error) {
 					return handler, c.ArgErr()
 				}
 				for _, additional := range args {
-					tmpl, err := gotmpl.New("additional").Parse(additional)
+					tmpl, err := gotmpl.New("additional").Funcs(funcs).Parse(additional)
 					if err != nil {
 						return handler, c.Errf("could not compile template: %s, %v\n", c.Val(), err)
 					}
@@ -107,7 +154,7 @@ func templateParse(c *caddy.Controller) (handler Handler, err error) {
 					return handler, c.ArgErr()
 				}
 				for _, authority := range args {
-					tmpl, err := gotmpl.New("authority").Parse(authority)
+					tmpl, err := gotmpl.New("authority").Funcs(funcs).Parse(authority)
 					if err != nil {
 						return handler, c.Errf("could not compile template: %s, %v\n", c.Val(), err)
 					}
@@ -127,6 +174,62 @@ func templateParse(c *caddy.Controller) (handler Handler, err error) {
 			case "fallthrough":
 				t.fall.SetZonesFromArgs(c.RemainingArgs())
 
+			case "metadata":
+				args := c.RemainingArgs()
+				if len(args) != 2 {
+					return handler, c.ArgErr()
+				}
+				r, err := regexp.Compile(args[1])
+				if err != nil {
+					return handler, c.Errf("could not parse regex: %s, %v", args[1], err)
+				}
+				t.meta = append(t.meta, metaCondition{label: args[0], regex: r})
+
+			case "client_subnet":
+				args := c.RemainingArgs()
+				if len(args) == 0 {
+					return handler, c.ArgErr()
+				}
+				networks, err := cidr.ParseNetworks(args)
+				if err != nil {
+					return handler, c.Errf("invalid client subnet: %v", err)
+				}
+				t.clients = append(t.clients, networks...)
+
+			case "ecs":
+				networks, err := cidr.ParseNetworks(c.RemainingArgs())
+				if err != nil {
+					return handler, c.Errf("invalid ecs source: %v", err)
+				}
+				t.ecs = true
+				t.ecsFrom = append(t.ecsFrom, networks...)
+
+			case "data":
+				args := c.RemainingArgs()
+				if len(args) == 0 {
+					return handler, c.ArgErr()
+				}
+				for _, name := range args {
+					path := name
+					if !filepath.IsAbs(path) && config.Root != "" {
+						path = filepath.Join(config.Root, path)
+					}
+					if !dataFormats[strings.ToLower(filepath.Ext(path))] {
+						return handler, c.Errf("unknown data file format %s, expected .csv, .json, .yaml or .yml", name)
+					}
+					t.data[name] = &dataFile{path: path}
+				}
+
+			case "reload":
+				if !c.NextArg() {
+					return handler, c.ArgErr()
+				}
+				d, err := time.ParseDuration(c.Val())
+				if err != nil || d < 0 {
+					return handler, c.Errf("invalid reload duration %s", c.Val())
+				}
+				t.reload = d
+
 			case "upstream":
 				// remove soon
 				c.RemainingArgs()
@@ -144,3 +247,6 @@ func templateParse(c *caddy.Controller) (handler Handler, err error) {
 
 	return
 }
+
+// defaultReload is the default interval at which data files are checked for changes.
+const defaultReload = 5 * time.Second
@@ -129,6 +129,32 @@ func TestSetupParse(t *testing.T) {
 			}`,
 			false,
 		},
+		{
+			`template IN A example {
+				metadata geoip/country/code ^(NL|BE)$
+				client_subnet 10.0.0.0/8 192.0.2.1 2001:db8::/32
+				ecs 10.0.0.53
+				data hosts.json hosts.csv
+				reload 0
+				answer "{{ .Name }} 60 IN A {{ lookup ` + "`hosts.json`" + ` .Name }}"
+			}`,
+			false,
+		},
+		{`template IN A example {
+				metadata geoip/country/code
+			}`, true},
+		{`template IN A example {
+				client_subnet 10.0.0.0/33
+			}`, true},
+		{`template IN A example {
+				ecs resolver
+			}`, true},
+		{`template IN A example {
+				data hosts.txt
+			}`, true},
+		{`template IN A example {
+				reload soon
+			}`, true},
 		{
 			`template IN MX example {
 					match ^ip-10-(?P<b>[0-9]*)-(?P<c>[0-9]*)-(?P<d>[0-9]*)[.]example[.]$
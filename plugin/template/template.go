@@ -3,9 +3,12 @@ package template
 import (
 	"bytes"
 	"context"
+	"net"
 	"regexp"
 	"strconv"
+	"strings"
 	gotmpl "text/template"
+	"time"
 
 	"github.com/coredns/coredns/plugin"
 	"github.com/coredns/coredns/plugin/metadata"
@@ -28,6 +31,10 @@ type template struct {
 	zones      []string
 	rcode      int
 	regex      []*regexp.Regexp
+	meta       []metaCondition
+	clients    []*net.IPNet
+	ecs        bool         // take the client address from the EDNS0 Client Subnet option
+	ecsFrom    []*net.IPNet // if set, only trust the option from these sources
 	answer     []*gotmpl.Template
 	additional []*gotmpl.Template
 	authority  []*gotmpl.Template
@@ -35,6 +42,14 @@ type template struct {
 	qtype      uint16
 	fall       fall.F
 	upstream   Upstreamer
+	data       dataSet
+	reload     time.Duration
+}
+
+// metaCondition requires the value of a metadata label to match a regular expression.
+type metaCondition struct {
+	label string
+	regex *regexp.Regexp
 }
 
 // Upstreamer looks up targets of CNAME templates
@@ -98,31 +113,33 @@ func (h Handler) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg)
 		msg.Rcode = template.rcode
 
 		for _, answer := range template.answer {
-			rr, err := executeRRTemplate(metrics.WithServer(ctx), "answer", answer, data)
+			rrs, err := executeRRTemplate(metrics.WithServer(ctx), "answer", answer, data)
 			if err != nil {
 				return dns.RcodeServerFailure, err
 			}
-			msg.Answer = append(msg.Answer, rr)
-			if template.upstream != nil && (state.QType() == dns.TypeA || state.QType() == dns.TypeAAAA) && rr.Header().Rrtype == dns.TypeCNAME {
-				if up, err := template.upstream.Lookup(ctx, state, rr.(*dns.CNAME).Target, state.QType()); err == nil && up != nil {
-					msg.Truncated = up.Truncated
-					msg.Answer = append(msg.Answer, up.Answer...)
+			for _, rr := range rrs {
+				msg.Answer = append(msg.Answer, rr)
+				if template.upstream != nil && (state.QType() == dns.TypeA || state.QType() == dns.TypeAAAA) && rr.Header().Rrtype == dns.TypeCNAME {
+					if up, err := template.upstream.Lookup(ctx, state, rr.(*dns.CNAME).Target, state.QType()); err == nil && up != nil {
+						msg.Truncated = up.Truncated
+						msg.Answer = append(msg.Answer, up.Answer...)
+					}
 				}
 			}
 		}
 		for _, additional := range template.additional {
-			rr, err := executeRRTemplate(metrics.WithServer(ctx), "additional", additional, data)
+			rrs, err := executeRRTemplate(metrics.WithServer(ctx), "additional", additional, data)
 			if err != nil {
 				return dns.RcodeServerFailure, err
 			}
-			msg.Extra = append(msg.Extra, rr)
+			msg.Extra = append(msg.Extra, rrs...)
 		}
 		for _, authority := range template.authority {
-			rr, err := executeRRTemplate(metrics.WithServer(ctx), "authority", authority, data)
+			rrs, err := executeRRTemplate(metrics.WithServer(ctx), "authority", authority, data)
 			if err != nil {
 				return dns.RcodeServerFailure, err
 			}
-			msg.Ns = append(msg.Ns, rr)
+			msg.Ns = append(msg.Ns, rrs...)
 		}
 
 		w.WriteMsg(msg)
@@ -135,19 +152,34 @@ func (h Handler) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg)
 // Name implements the plugin.Handler interface.
 func (h Handler) Name() string { return "template" }
 
-func executeRRTemplate(server, section string, template *gotmpl.Template, data *templateData) (dns.RR, error) {
+// executeRRTemplate executes template and parses the output as resource records. A template may
+// output any number of records, for instance by ranging over values. The output is parsed as a zone
+// file, so records may span lines in parentheses.
+func executeRRTemplate(server, section string, template *gotmpl.Template, data *templateData) ([]dns.RR, error) {
 	buffer := &bytes.Buffer{}
 	err := template.Execute(buffer, data)
 	if err != nil {
 		templateFailureCount.WithLabelValues(server, data.Zone, data.Class, data.Type, section, template.Tree.Root.String()).Inc()
 		return nil, err
 	}
-	rr, err := dns.NewRR(buffer.String())
-	if err != nil {
+	// Leading white space would make a line continue the owner name of the previous record, but
+	// templates are indented freely, so it is removed. Parse as dns.NewRR does, with a default TTL of
+	// 3600.
+	lines := strings.Split(buffer.String(), "\n")
+	for i := range lines {
+		lines[i] = strings.TrimLeft(lines[i], " \t")
+	}
+	zp := dns.NewZoneParser(strings.NewReader(strings.Join(lines, "\n")), ".", "")
+	zp.SetDefaultTTL(3600)
+	var rrs []dns.RR
+	for rr, ok := zp.Next(); ok; rr, ok = zp.Next() {
+		rrs = append(rrs, rr)
+	}
+	if err := zp.Err(); err != nil {
 		templateRRFailureCount.WithLabelValues(server, data.Zone, data.Class, data.Type, section, template.Tree.Root.String()).Inc()
-		return rr, err
+		return nil, err
 	}
-	return rr, nil
+	return rrs, nil
 }
 
 func (t template) match(ctx context.Context, state request.Request) (*templateData, bool, bool) {
@@ -165,6 +197,9 @@ func (t template) match(ctx context.Context, state request.Request) (*templateDa
 	if t.qtype != dns.TypeANY && q.Qtype != dns.TypeANY && q.Qtype != t.qtype {
 		return data, false, true
 	}
+	if !t.conditionsMatch(ctx, state) {
+		return data, false, true
+	}
 
 	for _, regex := range t.regex {
 		if !regex.MatchString(state.Name()) {
@@ -206,3 +241,54 @@ func (t template) match(ctx context.Context, state request.Request) (*templateDa
 
 	return data, false, t.fall.Through(state.Name())
 }
+
+// conditionsMatch returns true if the query satisfies the metadata and client subnet conditions of
+// the template.
+func (t template) conditionsMatch(ctx context.Context, state request.Request) bool {
+	for _, m := range t.meta {
+		f := metadata.ValueFunc(ctx, m.label)
+		if f == nil || !m.regex.MatchString(f()) {
+			return false
+		}
+	}
+	if len(t.clients) == 0 {
+		return true
+	}
+	ip := t.clientIP(state)
+	for _, n := range t.clients {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// clientIP returns the source address of the query. With ecs, it returns the address from the
+// EDNS0 Client Subnet option instead, if the query has one and comes from a trusted source.
+func (t template) clientIP(state request.Request) net.IP {
+	src := net.ParseIP(state.IP())
+	if !t.ecs || !t.trustECS(src) {
+		return src
+	}
+	if opt := state.Req.IsEdns0(); opt != nil {
+		for _, o := range opt.Option {
+			if e, ok := o.(*dns.EDNS0_SUBNET); ok {
+				return e.Address
+			}
+		}
+	}
+	return src
+}
+
+// trustECS returns true if the EDNS0 Client Subnet option of a query from src may be used.
+func (t template) trustECS(src net.IP) bool {
+	if len(t.ecsFrom) == 0 {
+		return true
+	}
+	for _, n := range t.ecsFrom {
+		if n.Contains(src) {
+			return true
+		}
+	}
+	return false
+}
@@ -3,15 +3,18 @@ package template
 import (
 	"context"
 	"fmt"
+	"net"
 	"regexp"
 	"testing"
 	gotmpl "text/template"
 
 	"github.com/coredns/caddy"
 	"github.com/coredns/coredns/plugin/metadata"
+	"github.com/coredns/coredns/plugin/pkg/cidr"
 	"github.com/coredns/coredns/plugin/pkg/dnstest"
 	"github.com/coredns/coredns/plugin/pkg/fall"
 	"github.com/coredns/coredns/plugin/test"
+	"github.com/coredns/coredns/request"
 
 	"github.com/miekg/dns"
 )
@@ -77,6 +80,18 @@ func TestHandler(t *testing.T) {
 		fall:   fall.Root,
 		zones:  []string{"."},
 	}
+	multiLineTemplate := template{
+		regex: []*regexp.Regexp{regexp.MustCompile("[.]example[.]$")},
+		answer: []*gotmpl.Template{gotmpl.Must(gotmpl.New("answer").Parse(`example. 60 IN SOA ns0.example. hostmaster.example. (
+				1 ; serial
+				7200 3600 1209600 60 )
+			{{ .Name }} 60 IN TXT ( "first"
+				"second" )`))},
+		qclass: dns.ClassANY,
+		qtype:  dns.TypeANY,
+		fall:   fall.Root,
+		zones:  []string{"."},
+	}
 	nonRRTemplate := template{
 		regex:  []*regexp.Regexp{regexp.MustCompile("[.]example[.]$")},
 		answer: []*gotmpl.Template{gotmpl.Must(gotmpl.New("answer").Parse("{{ .Name }}"))},
@@ -168,6 +183,25 @@ func TestHandler(t *testing.T) {
 				return nil
 			},
 		},
+		{
+			name:   "MultiLineTemplate",
+			tmpl:   multiLineTemplate,
+			qclass: dns.ClassINET,
+			qtype:  dns.TypeANY,
+			qname:  "test.example.",
+			verifyResponse: func(r *dns.Msg) error {
+				if len(r.Answer) != 2 {
+					return fmt.Errorf("expected 2 answers, got %v", len(r.Answer))
+				}
+				if soa, ok := r.Answer[0].(*dns.SOA); !ok || soa.Serial != 1 || soa.Minttl != 60 {
+					return fmt.Errorf("expected a SOA record with serial 1, got %v", r.Answer[0])
+				}
+				if txt, ok := r.Answer[1].(*dns.TXT); !ok || len(txt.Txt) != 2 || txt.Hdr.Name != "test.example." {
+					return fmt.Errorf("expected a TXT record with 2 strings, got %v", r.Answer[1])
+				}
+				return nil
+			},
+		},
 		{
 			name:         "NonRRTemplate",
 			tmpl:         nonRRTemplate,
@@ -593,3 +627,36 @@ func TestMultiSection(t *testing.T) {
 }
 
 const rcodeFallthrough = 3841 // reserved for private use, used to indicate a fallthrough
+
+func TestClientIP(t *testing.T) {
+	tests := []struct {
+		ecs     bool
+		ecsFrom []string
+		subnet  string
+		want    string
+	}{
+		{want: "10.240.0.1"},
+		{subnet: "192.0.2.0", want: "10.240.0.1"},
+		{ecs: true, want: "10.240.0.1"},
+		{ecs: true, subnet: "192.0.2.0", want: "192.0.2.0"},
+		{ecs: true, ecsFrom: []string{"10.0.0.0/8"}, subnet: "192.0.2.0", want: "192.0.2.0"},
+		{ecs: true, ecsFrom: []string{"198.51.100.0/24"}, subnet: "192.0.2.0", want: "10.240.0.1"},
+	}
+	for i, tc := range tests {
+		from, err := cidr.ParseNetworks(tc.ecsFrom)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tmpl := template{ecs: tc.ecs, ecsFrom: from}
+
+		r := new(dns.Msg).SetQuestion("example.", dns.TypeA)
+		if tc.subnet != "" {
+			r.SetEdns0(4096, false)
+			r.IsEdns0().Option = append(r.IsEdns0().Option, &dns.EDNS0_SUBNET{Code: dns.EDNS0SUBNET, Family: 1, SourceNetmask: 24, Address: net.ParseIP(tc.subnet).To4()})
+		}
+		state := request.Request{W: &test.ResponseWriter{}, Req: r}
+		if got := tmpl.clientIP(state); got.String() != tc.want {
+			t.Errorf("Test %d: expected client address %s, got %s", i, tc.want, got)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package tls
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/coredns/coredns/plugin"
+	"github.com/coredns/coredns/plugin/metadata"
+	"github.com/coredns/coredns/request"
+
+	"github.com/miekg/dns"
+)
+
+// TLS is the handler of the tls plugin, it adds the client certificate of the connection to the
+// metadata.
+type TLS struct {
+	Next plugin.Handler
+}
+
+// ServeDNS implements the plugin.Handler interface.
+func (t TLS) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (int, error) {
+	return plugin.NextOrFailure(t.Name(), t.Next, ctx, w, r)
+}
+
+// Metadata implements the metadata.Provider interface.
+func (t TLS) Metadata(ctx context.Context, state request.Request) context.Context {
+	cert := clientCertificate(state.W)
+	if cert == nil {
+		return ctx
+	}
+	metadata.SetValueFunc(ctx, "tls/client/cn", func() string { return cert.Subject.CommonName })
+	metadata.SetValueFunc(ctx, "tls/client/san", func() string { return strings.Join(names(cert)[1:], ",") })
+	return ctx
+}
+
+// Name implements the plugin.Handler interface.
+func (t TLS) Name() string { return "tls" }
+
+// clientCertificate returns the certificate the client presented on the connection of w, or nil.
+func clientCertificate(w dns.ResponseWriter) *x509.Certificate {
+	cs, ok := w.(dns.ConnectionStater)
+	if !ok {
+		return nil
+	}
+	state := cs.ConnectionState()
+	if state == nil || len(state.PeerCertificates) == 0 {
+		return nil
+	}
+	return state.PeerCertificates[0]
+}
+
+// names returns the common name and the subject alternative names of cert.
+func names(cert *x509.Certificate) []string {
+	n := []string{cert.Subject.CommonName}
+	n = append(n, cert.DNSNames...)
+	n = append(n, cert.EmailAddresses...)
+	for _, u := range cert.URIs {
+		n = append(n, u.String())
+	}
+	return n
+}
+
+// clientNames is the policy for client certificates: one of their names must match a pattern.
+type clientNames []string
+
+// verify implements tls.Config.VerifyConnection. It is called after the certificate is verified
+// against the CA bundle. Whether a client must present a certificate is up to client_auth.
+func (p clientNames) verify(state tls.ConnectionState) error {
+	if len(state.PeerCertificates) == 0 {
+		return nil
+	}
+	cert := state.PeerCertificates[0]
+	for _, n := range names(cert) {
+		if n == "" {
+			continue
+		}
+		for _, pattern := range p {
+			if ok, _ := path.Match(pattern, n); ok {
+				return nil
+			}
+		}
+	}
+	return fmt.Errorf("client certificate %q is not allowed", cert.Subject.CommonName)
+}
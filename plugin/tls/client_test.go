@@ -0,0 +1,107 @@
+package tls
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"testing"
+
+	"github.com/coredns/coredns/plugin/metadata"
+	"github.com/coredns/coredns/plugin/test"
+	"github.com/coredns/coredns/request"
+
+	"github.com/miekg/dns"
+)
+
+// tlsWriter is a ResponseWriter of a connection on which the client presented a certificate.
+type tlsWriter struct {
+	test.ResponseWriter
+	state *tls.ConnectionState
+}
+
+func (w *tlsWriter) ConnectionState() *tls.ConnectionState { return w.state }
+
+func parseCert(t *testing.T, b []byte) *x509.Certificate {
+	t.Helper()
+	block, _ := pem.Decode(b)
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert
+}
+
+func TestClientNames(t *testing.T) {
+	ca := newIssuer(t)
+	c1, _ := ca.issue(t, 1, "client1.example.org", nil)
+	c2, _ := ca.issue(t, 2, "client2", []string{"client2.example.net"})
+	c3, _ := ca.issue(t, 3, "", nil, "spiffe://cluster.local/ns/default/sa/web")
+	cert1, cert2, cert3 := parseCert(t, c1), parseCert(t, c2), parseCert(t, c3)
+
+	tests := []struct {
+		policy  clientNames
+		cert    *x509.Certificate
+		allowed bool
+	}{
+		{clientNames{"*.example.org"}, cert1, true},
+		{clientNames{"*.example.org"}, cert2, false},
+		{clientNames{"*.example.org", "client2.example.net"}, cert2, true},
+		{clientNames{"client2"}, cert2, true},
+		{clientNames{"spiffe://cluster.local/ns/*/sa/web"}, cert3, true},
+		{clientNames{"spiffe://cluster.local/ns/*/sa/db"}, cert3, false},
+		{clientNames{"*"}, cert3, false},
+		{clientNames{"*.example.org"}, nil, true},
+	}
+	for i, tc := range tests {
+		state := tls.ConnectionState{}
+		if tc.cert != nil {
+			state.PeerCertificates = []*x509.Certificate{tc.cert}
+		}
+		err := tc.policy.verify(state)
+		if tc.allowed && err != nil {
+			t.Errorf("Test %d: expected the certificate to be allowed, got %s", i, err)
+		}
+		if !tc.allowed && err == nil {
+			t.Errorf("Test %d: expected the certificate not to be allowed", i)
+		}
+	}
+}
+
+func TestMetadata(t *testing.T) {
+	ca := newIssuer(t)
+	c, _ := ca.issue(t, 1, "client.example.org", []string{"a.example.org", "b.example.org"}, "spiffe://cluster.local/ns/default/sa/web")
+	cert := parseCert(t, c)
+
+	r := new(dns.Msg)
+	r.SetQuestion("example.org.", dns.TypeA)
+
+	tests := []struct {
+		w   dns.ResponseWriter
+		cn  string
+		san string
+	}{
+		{&test.ResponseWriter{}, "", ""},
+		{&tlsWriter{state: &tls.ConnectionState{}}, "", ""},
+		{&tlsWriter{state: &tls.ConnectionState{PeerCertificates: []*x509.Certificate{cert}}},
+			"client.example.org", "a.example.org,b.example.org,spiffe://cluster.local/ns/default/sa/web"},
+	}
+	for i, tc := range tests {
+		ctx := metadata.ContextWithMetadata(context.TODO())
+		ctx = TLS{}.Metadata(ctx, request.Request{W: tc.w, Req: r})
+
+		cn, san := "", ""
+		if f := metadata.ValueFunc(ctx, "tls/client/cn"); f != nil {
+			cn = f()
+		}
+		if f := metadata.ValueFunc(ctx, "tls/client/san"); f != nil {
+			san = f()
+		}
+		if cn != tc.cn {
+			t.Errorf("Test %d: expected cn %q, got %q", i, tc.cn, cn)
+		}
+		if san != tc.san {
+			t.Errorf("Test %d: expected san %q, got %q", i, tc.san, san)
+		}
+	}
+}
@@ -0,0 +1,135 @@
+package tls
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// defaultReload is how often the certificate files are checked for changes.
+const defaultReload = time.Minute
+
+// certs holds the certificate and the CA bundle of the listeners. They are read from disk again when
+// the files change, so rotated certificates are used for new connections without a restart.
+type certs struct {
+	cert, key, ca string
+	reload        time.Duration
+
+	mu          sync.RWMutex
+	certificate *tls.Certificate
+	clientCAs   *x509.CertPool
+	pem         [3][]byte // contents of cert, key and ca as last loaded
+
+	stop chan struct{}
+	wg   sync.WaitGroup
+}
+
+func newCerts(cert, key, ca string) (*certs, error) {
+	c := &certs{cert: cert, key: key, ca: ca, reload: defaultReload}
+	if _, err := c.load(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// load reads the certificate files. It returns true if they changed since they were last loaded.
+func (c *certs) load() (bool, error) {
+	var pem [3][]byte
+	for i, path := range []string{c.cert, c.key, c.ca} {
+		if path == "" {
+			continue
+		}
+		b, err := os.ReadFile(filepath.Clean(path))
+		if err != nil {
+			return false, err
+		}
+		pem[i] = b
+	}
+
+	c.mu.RLock()
+	same := bytes.Equal(pem[0], c.pem[0]) && bytes.Equal(pem[1], c.pem[1]) && bytes.Equal(pem[2], c.pem[2])
+	c.mu.RUnlock()
+	if same {
+		return false, nil
+	}
+
+	certificate, err := tls.X509KeyPair(pem[0], pem[1])
+	if err != nil {
+		return false, fmt.Errorf("could not load TLS cert: %s", err)
+	}
+	var pool *x509.CertPool
+	if c.ca != "" {
+		pool = x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem[2]) {
+			return false, fmt.Errorf("could not read root certs from %s", c.ca)
+		}
+	}
+
+	c.mu.Lock()
+	c.certificate = &certificate
+	c.clientCAs = pool
+	c.pem = pem
+	c.mu.Unlock()
+	return true, nil
+}
+
+// configForClient returns a tls.Config.GetConfigForClient function, that returns base with the
+// current certificate and CA bundle.
+func (c *certs) configForClient(base *tls.Config) func(*tls.ClientHelloInfo) (*tls.Config, error) {
+	return func(*tls.ClientHelloInfo) (*tls.Config, error) {
+		cfg := base.Clone()
+		cfg.GetConfigForClient = nil
+
+		c.mu.RLock()
+		cfg.Certificates = []tls.Certificate{*c.certificate}
+		cfg.ClientCAs = c.clientCAs
+		c.mu.RUnlock()
+		return cfg, nil
+	}
+}
+
+// OnStartup starts checking the certificate files for changes.
+func (c *certs) OnStartup() error {
+	if c.reload == 0 {
+		return nil
+	}
+	c.stop = make(chan struct{})
+	c.wg.Add(1)
+	go func() {
+		defer c.wg.Done()
+		tick := time.NewTicker(c.reload)
+		defer tick.Stop()
+		for {
+			select {
+			case <-c.stop:
+				return
+			case <-tick.C:
+				changed, err := c.load()
+				if err != nil {
+					// A new certificate may have been written before its key, try again next time.
+					log.Warningf("Failed to reload certificate %s: %s", c.cert, err)
+					continue
+				}
+				if changed {
+					log.Infof("Reloaded certificate %s", c.cert)
+				}
+			}
+		}
+	}()
+	return nil
+}
+
+// OnShutdown stops checking the certificate files.
+func (c *certs) OnShutdown() error {
+	if c.stop != nil {
+		close(c.stop)
+		c.wg.Wait()
+		c.stop = nil
+	}
+	return nil
+}
@@ -0,0 +1,224 @@
+package tls
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// issuer is a CA that issues certificates for the tests.
+type issuer struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pem  []byte
+}
+
+func newIssuer(t *testing.T) *issuer {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+	return &issuer{cert: cert, key: key, pem: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})}
+}
+
+// issue returns a certificate and key in PEM format with the common name cn and the SANs in dnsNames.
+func (i *issuer) issue(t *testing.T, serial int64, cn string, dnsNames []string, uris ...string) (certPEM, keyPEM []byte) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: cn},
+		DNSNames:     dnsNames,
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	for _, u := range uris {
+		pu, _ := url.Parse(u)
+		tmpl.URIs = append(tmpl.URIs, pu)
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, i.cert, &key.PublicKey, i.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	k, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: k})
+}
+
+func writeFile(t *testing.T, path string, b []byte) {
+	t.Helper()
+	if err := os.WriteFile(path, b, 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// serverSerial returns the serial of the certificate the server on addr presents.
+func serverSerial(t *testing.T, addr string) int64 {
+	t.Helper()
+	conn, err := tls.Dial("tcp", addr, &tls.Config{InsecureSkipVerify: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	return conn.ConnectionState().PeerCertificates[0].SerialNumber.Int64()
+}
+
+func serve(t *testing.T, cfg *tls.Config) string {
+	t.Helper()
+	l, err := tls.Listen("tcp", "127.0.0.1:0", cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				conn.(*tls.Conn).Handshake()
+				conn.Close()
+			}()
+		}
+	}()
+	return l.Addr().String()
+}
+
+func TestCertsReload(t *testing.T) {
+	dir := t.TempDir()
+	ca := newIssuer(t)
+	certFile, keyFile, caFile := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem"), filepath.Join(dir, "ca.pem")
+	cert, key := ca.issue(t, 10, "dns.example.org", nil)
+	writeFile(t, certFile, cert)
+	writeFile(t, keyFile, key)
+	writeFile(t, caFile, ca.pem)
+
+	c, err := newCerts(certFile, keyFile, caFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	base := &tls.Config{}
+	base.GetConfigForClient = c.configForClient(base)
+	addr := serve(t, base)
+
+	if serial := serverSerial(t, addr); serial != 10 {
+		t.Errorf("Expected certificate with serial 10, got %d", serial)
+	}
+
+	if changed, err := c.load(); err != nil || changed {
+		t.Errorf("Expected no change, got %t, %v", changed, err)
+	}
+
+	// A new certificate without its new key is not used.
+	cert, key = ca.issue(t, 11, "dns.example.org", nil)
+	writeFile(t, certFile, cert)
+	if _, err := c.load(); err == nil {
+		t.Error("Expected an error for a certificate that doesn't match the key")
+	}
+	if serial := serverSerial(t, addr); serial != 10 {
+		t.Errorf("Expected certificate with serial 10, got %d", serial)
+	}
+
+	writeFile(t, keyFile, key)
+	if changed, err := c.load(); err != nil || !changed {
+		t.Fatalf("Expected the certificate to be reloaded, got %t, %v", changed, err)
+	}
+	if serial := serverSerial(t, addr); serial != 11 {
+		t.Errorf("Expected certificate with serial 11, got %d", serial)
+	}
+}
+
+func TestCertsReloadCA(t *testing.T) {
+	dir := t.TempDir()
+	ca := newIssuer(t)
+	certFile, keyFile, caFile := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem"), filepath.Join(dir, "ca.pem")
+	cert, key := ca.issue(t, 10, "dns.example.org", nil)
+	writeFile(t, certFile, cert)
+	writeFile(t, keyFile, key)
+	writeFile(t, caFile, ca.pem)
+
+	c, err := newCerts(certFile, keyFile, caFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	base := &tls.Config{ClientAuth: tls.RequireAndVerifyClientCert}
+	base.GetConfigForClient = c.configForClient(base)
+
+	// A client with a certificate of a new CA is only accepted once the CA bundle includes it.
+	ca2 := newIssuer(t)
+	clientCert, clientKey := ca2.issue(t, 20, "client.example.org", nil)
+	pair, err := tls.X509KeyPair(clientCert, clientKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := &tls.Config{InsecureSkipVerify: true, Certificates: []tls.Certificate{pair}}
+
+	l, err := tls.Listen("tcp", "127.0.0.1:0", base)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	handshake := func() error {
+		errc := make(chan error, 1)
+		go func() {
+			conn, err := l.Accept()
+			if err != nil {
+				errc <- err
+				return
+			}
+			errc <- conn.(*tls.Conn).Handshake()
+			conn.Close()
+		}()
+		if conn, err := tls.Dial("tcp", l.Addr().String(), client); err == nil {
+			conn.Close()
+		}
+		return <-errc
+	}
+
+	if err := handshake(); err == nil {
+		t.Error("Expected the client certificate to be rejected")
+	}
+	writeFile(t, caFile, append(append([]byte{}, ca.pem...), ca2.pem...))
+	if _, err := c.load(); err != nil {
+		t.Fatal(err)
+	}
+	if err := handshake(); err != nil {
+		t.Errorf("Expected the client certificate to be accepted, got %s", err)
+	}
+}
@@ -2,13 +2,18 @@ package tls
 
 import (
 	ctls "crypto/tls"
+	"path"
+	"time"
 
 	"github.com/coredns/caddy"
 	"github.com/coredns/coredns/core/dnsserver"
 	"github.com/coredns/coredns/plugin"
+	clog "github.com/coredns/coredns/plugin/pkg/log"
 	"github.com/coredns/coredns/plugin/pkg/tls"
 )
 
+var log = clog.NewWithPlugin("tls")
+
 func init() { plugin.Register("tls", setup) }
 
 func setup(c *caddy.Controller) error {
@@ -16,6 +21,9 @@ func setup(c *caddy.Controller) error {
 	if err != nil {
 		return plugin.Error("tls", err)
 	}
+	dnsserver.GetConfig(c).AddPlugin(func(next plugin.Handler) plugin.Handler {
+		return TLS{Next: next}
+	})
 	return nil
 }
 
@@ -32,6 +40,8 @@ func parseTLS(c *caddy.Controller) error {
 			return plugin.Error("tls", c.ArgErr())
 		}
 		clientAuth := ctls.NoClientCert
+		reload := defaultReload
+		var policy clientNames
 		for c.NextBlock() {
 			switch c.Val() {
 			case "client_auth":
@@ -53,10 +63,37 @@ func parseTLS(c *caddy.Controller) error {
 				default:
 					return c.Errf("unknown authentication type '%s'", authTypeArgs[0])
 				}
+			case "client_names":
+				patterns := c.RemainingArgs()
+				if len(patterns) == 0 {
+					return c.ArgErr()
+				}
+				for _, p := range patterns {
+					if _, err := path.Match(p, ""); err != nil {
+						return c.Errf("invalid pattern '%s': %s", p, err)
+					}
+				}
+				policy = append(policy, patterns...)
+			case "reload":
+				if !c.NextArg() {
+					return c.ArgErr()
+				}
+				d, err := time.ParseDuration(c.Val())
+				if err != nil || d < 0 {
+					return c.Errf("invalid reload duration '%s'", c.Val())
+				}
+				reload = d
+				if c.NextArg() {
+					return c.ArgErr()
+				}
 			default:
 				return c.Errf("unknown option '%s'", c.Val())
 			}
 		}
+		if policy != nil && clientAuth != ctls.VerifyClientCertIfGiven && clientAuth != ctls.RequireAndVerifyClientCert {
+			return c.Errf("client_names needs client_auth verify_if_given or require_and_verify")
+		}
+
 		tls, err := tls.NewTLSConfigFromArgs(args...)
 		if err != nil {
 			return err
@@ -64,6 +101,22 @@ func parseTLS(c *caddy.Controller) error {
 		tls.ClientAuth = clientAuth
 		// NewTLSConfigFromArgs only sets RootCAs, so we need to let ClientCAs refer to it.
 		tls.ClientCAs = tls.RootCAs
+		if policy != nil {
+			tls.VerifyConnection = policy.verify
+		}
+
+		ca := ""
+		if len(args) == 3 {
+			ca = args[2]
+		}
+		certs, err := newCerts(args[0], args[1], ca)
+		if err != nil {
+			return err
+		}
+		certs.reload = reload
+		tls.GetConfigForClient = certs.configForClient(tls)
+		c.OnStartup(certs.OnStartup)
+		c.OnShutdown(certs.OnShutdown)
 
 		config.TLSConfig = tls
 	}
@@ -23,8 +23,16 @@ func TestTLS(t *testing.T) {
 		{"tls test_cert.pem test_key.pem test_ca.pem {\nclient_auth require\n}", false, "", ""},
 		{"tls test_cert.pem test_key.pem test_ca.pem {\nclient_auth verify_if_given\n}", false, "", ""},
 		{"tls test_cert.pem test_key.pem test_ca.pem {\nclient_auth require_and_verify\n}", false, "", ""},
+		{"tls test_cert.pem test_key.pem test_ca.pem {\nclient_auth require_and_verify\nclient_names *.example.org client\n}", false, "", ""},
+		{"tls test_cert.pem test_key.pem test_ca.pem {\nreload 10s\n}", false, "", ""},
+		{"tls test_cert.pem test_key.pem test_ca.pem {\nreload 0\n}", false, "", ""},
 		// negative
 		{"tls test_cert.pem test_key.pem test_ca.pem {\nunknown\n}", true, "", "unknown option"},
+		{"tls test_cert.pem test_key.pem test_ca.pem {\nclient_names *.example.org\n}", true, "", "needs client_auth"},
+		{"tls test_cert.pem test_key.pem test_ca.pem {\nclient_auth verify_if_given\nclient_names\n}", true, "", "Wrong argument"},
+		{"tls test_cert.pem test_key.pem test_ca.pem {\nclient_auth verify_if_given\nclient_names [a\n}", true, "", "invalid pattern"},
+		{"tls test_cert.pem test_key.pem test_ca.pem {\nreload\n}", true, "", "Wrong argument"},
+		{"tls test_cert.pem test_key.pem test_ca.pem {\nreload -1s\n}", true, "", "invalid reload"},
 		// client_auth takes exactly one parameter, which must be one of known keywords.
 		{"tls test_cert.pem test_key.pem test_ca.pem {\nclient_auth\n}", true, "", "Wrong argument"},
 		{"tls test_cert.pem test_key.pem test_ca.pem {\nclient_auth none bogus\n}", true, "", "Wrong argument"},
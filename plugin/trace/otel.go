@@ -0,0 +1,160 @@
+package trace
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/coredns/coredns/plugin/pkg/log"
+
+	"github.com/miekg/dns"
+	ot "github.com/opentracing/opentracing-go"
+	otbridge "go.opentelemetry.io/otel/bridge/opentracing"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.10.0"
+)
+
+const (
+	traceparentHeader = "traceparent"
+	tracestateHeader  = "tracestate"
+)
+
+// setupOTLP sets up an OpenTelemetry tracer that exports spans with OTLP over gRPC (otlp) or
+// HTTP (otlphttp). The tracer is wrapped in an OpenTracing bridge, so the spans created by other
+// plugins (and the per plugin spans from plugin.NextOrFailure) are exported as well.
+func (t *trace) setupOTLP() error {
+	endpoint, insecure := t.Endpoint, true
+	switch {
+	case strings.HasPrefix(endpoint, "https://"):
+		endpoint, insecure = endpoint[len("https://"):], false
+	case strings.HasPrefix(endpoint, "http://"):
+		endpoint = endpoint[len("http://"):]
+	}
+
+	var client otlptrace.Client
+	switch t.EndpointType {
+	case "otlp":
+		opts := []otlptracegrpc.Option{otlptracegrpc.WithEndpoint(endpoint)}
+		if insecure {
+			opts = append(opts, otlptracegrpc.WithInsecure())
+		}
+		client = otlptracegrpc.NewClient(opts...)
+	case "otlphttp":
+		host, path := endpoint, ""
+		if i := strings.IndexByte(endpoint, '/'); i > 0 {
+			host, path = endpoint[:i], endpoint[i:]
+		}
+		opts := []otlptracehttp.Option{otlptracehttp.WithEndpoint(host)}
+		if path != "" {
+			opts = append(opts, otlptracehttp.WithURLPath(path))
+		}
+		if insecure {
+			opts = append(opts, otlptracehttp.WithInsecure())
+		}
+		client = otlptracehttp.NewClient(opts...)
+	}
+
+	exporter, err := otlptrace.New(context.Background(), client)
+	if err != nil {
+		return err
+	}
+
+	res := resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceNameKey.String(t.serviceName))
+	t.useProvider(sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		// Which queries are traced is decided in ServeDNS, but respect the decision of a remote parent.
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.AlwaysSample())),
+	))
+	return nil
+}
+
+// useProvider makes t create its spans with an OpenTelemetry tracer from p.
+func (t *trace) useProvider(p *sdktrace.TracerProvider) {
+	bridge, _ := otbridge.NewTracerPair(p.Tracer("github.com/coredns/coredns/plugin/trace"))
+	bridge.SetTextMapPropagator(propagation.TraceContext{})
+	bridge.SetWarningHandler(func(msg string) { log.Debug(msg) })
+
+	t.provider = p
+	t.tracer = bridge
+	t.tagSet = tagByProvider["otlp"]
+}
+
+// OnShutdown flushes and stops the OpenTelemetry exporter, if there is one.
+func (t *trace) OnShutdown() error {
+	if t.provider == nil {
+		return nil
+	}
+	return t.provider.Shutdown(context.Background())
+}
+
+// remoteParent returns the W3C trace context the client sent with the query, either in the HTTP
+// headers of a DoH request or in an EDNS0 local option, and whether the client sampled it. The
+// returned span context is nil if there isn't one, or if the plugin is not configured to use it.
+// Nothing is allocated for queries without a trace context.
+func (t *trace) remoteParent(w dns.ResponseWriter, r *dns.Msg) (ot.SpanContext, bool) {
+	var carrier http.Header
+	if t.traceparentDoH {
+		if hr, ok := w.(interface{ Request() *http.Request }); ok && hr.Request() != nil && hr.Request().Header.Get(traceparentHeader) != "" {
+			carrier = hr.Request().Header
+		}
+	}
+	if carrier == nil && t.traceparentEDNS0 != 0 {
+		if tp := ednsTraceparent(r, t.traceparentEDNS0); tp != "" {
+			carrier = http.Header{}
+			carrier.Set(traceparentHeader, tp)
+		}
+	}
+	if carrier == nil {
+		return nil, false
+	}
+
+	// traceparent is VERSION-TRACEID-PARENTID-FLAGS, bit 0 of the flags is "sampled".
+	parts := strings.Split(carrier.Get(traceparentHeader), "-")
+	if len(parts) != 4 {
+		return nil, false
+	}
+	flags, err := strconv.ParseUint(parts[3], 16, 8)
+	if err != nil {
+		return nil, false
+	}
+	// The OpenTracing bridge only extracts from HTTP headers.
+	spanCtx, err := t.tracer.Extract(ot.HTTPHeaders, ot.HTTPHeadersCarrier(carrier))
+	if err != nil {
+		return nil, false
+	}
+	return spanCtx, flags&1 == 1
+}
+
+// ednsTraceparent returns the traceparent in the EDNS0 local option with code in r, if any.
+func ednsTraceparent(r *dns.Msg, code uint16) string {
+	opt := r.IsEdns0()
+	if opt == nil {
+		return ""
+	}
+	for _, o := range opt.Option {
+		if l, ok := o.(*dns.EDNS0_LOCAL); ok && l.Code == code {
+			return string(l.Data)
+		}
+	}
+	return ""
+}
+
+// otelTraceID returns the trace ID of spanCtx, as a hex string.
+func (t *trace) otelTraceID(spanCtx ot.SpanContext) string {
+	h := http.Header{}
+	if err := t.tracer.Inject(spanCtx, ot.HTTPHeaders, ot.HTTPHeadersCarrier(h)); err != nil {
+		return ""
+	}
+	parts := strings.Split(h.Get(traceparentHeader), "-")
+	if len(parts) != 4 {
+		return ""
+	}
+	return parts[1]
+}
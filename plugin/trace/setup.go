@@ -8,6 +8,8 @@ import (
 	"github.com/coredns/caddy"
 	"github.com/coredns/coredns/core/dnsserver"
 	"github.com/coredns/coredns/plugin"
+
+	"github.com/miekg/dns"
 )
 
 func init() { plugin.Register("trace", setup) }
@@ -24,6 +26,7 @@ func setup(c *caddy.Controller) error {
 	})
 
 	c.OnStartup(t.OnStartup)
+	c.OnShutdown(t.OnShutdown)
 
 	return nil
 }
@@ -46,6 +49,11 @@ func traceParse(c *caddy.Controller) (*trace, error) {
 		case 0:
 			tr.EndpointType, tr.Endpoint, err = normalizeEndpoint(defEpType, "")
 		case 1:
+			if _, ok := supportedProviders[strings.ToLower(args[0])]; ok {
+				// Only the endpoint type is given, use its default endpoint.
+				tr.EndpointType, tr.Endpoint, err = normalizeEndpoint(strings.ToLower(args[0]), "")
+				break
+			}
 			tr.EndpointType, tr.Endpoint, err = normalizeEndpoint(defEpType, args[0])
 		case 2:
 			epType := strings.ToLower(args[0])
@@ -100,6 +108,32 @@ func traceParse(c *caddy.Controller) (*trace, error) {
 				if tr.datadogAnalyticsRate > 1 || tr.datadogAnalyticsRate < 0 {
 					return nil, fmt.Errorf("datadog analytics rate must be between 0 and 1, '%f' is not supported", tr.datadogAnalyticsRate)
 				}
+			case "traceparent":
+				if tr.EndpointType != "otlp" && tr.EndpointType != "otlphttp" {
+					return nil, fmt.Errorf("traceparent is only supported with the otlp and otlphttp endpoint types")
+				}
+				args := c.RemainingArgs()
+				if len(args) == 0 {
+					return nil, c.ArgErr()
+				}
+				switch args[0] {
+				case "doh":
+					if len(args) != 1 {
+						return nil, c.ArgErr()
+					}
+					tr.traceparentDoH = true
+				case "edns0":
+					if len(args) != 2 {
+						return nil, c.ArgErr()
+					}
+					code, err := strconv.ParseUint(args[1], 0, 16)
+					if err != nil || code < dns.EDNS0LOCALSTART || code > dns.EDNS0LOCALEND {
+						return nil, fmt.Errorf("invalid EDNS0 local option code '%s'", args[1])
+					}
+					tr.traceparentEDNS0 = uint16(code)
+				default:
+					return nil, c.Errf("unknown traceparent source '%s'", args[0])
+				}
 			}
 		}
 	}
@@ -125,8 +159,10 @@ func normalizeEndpoint(epType, ep string) (string, string, error) {
 }
 
 var supportedProviders = map[string]string{
-	"zipkin":  "localhost:9411",
-	"datadog": "localhost:8126",
+	"zipkin":   "localhost:9411",
+	"datadog":  "localhost:8126",
+	"otlp":     "localhost:4317",
+	"otlphttp": "localhost:4318",
 }
 
 const (
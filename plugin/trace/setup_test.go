@@ -27,10 +27,16 @@ func TestTraceParse(t *testing.T) {
 		{"trace {\n every 100\n service foobar\nclient_server\n}", false, "http://localhost:9411/api/v2/spans", 100, `foobar`, true},
 		{"trace {\n every 2\n client_server true\n}", false, "http://localhost:9411/api/v2/spans", 2, `coredns`, true},
 		{"trace {\n client_server false\n}", false, "http://localhost:9411/api/v2/spans", 1, `coredns`, false},
+		{`trace otlp`, false, "localhost:4317", 1, `coredns`, false},
+		{`trace otlphttp https://tempo:4318/otlp/v1/traces`, false, "https://tempo:4318/otlp/v1/traces", 1, `coredns`, false},
+		{"trace otlp {\n traceparent doh\n traceparent edns0 65001\n}", false, "localhost:4317", 1, `coredns`, false},
 		// fails
 		{`trace footype localhost:4321`, true, "", 1, "", false},
 		{"trace {\n every 2\n client_server junk\n}", true, "", 1, "", false},
 		{"trace datadog localhost {\n datadog_analytics_rate 2\n}", true, "", 1, "", false},
+		{"trace {\n traceparent doh\n}", true, "", 1, "", false},
+		{"trace otlp {\n traceparent edns0 10\n}", true, "", 1, "", false},
+		{"trace otlp {\n traceparent http\n}", true, "", 1, "", false},
 	}
 	for i, test := range tests {
 		c := caddy.NewTestController("dns", test.input)
@@ -64,3 +70,16 @@ func TestTraceParse(t *testing.T) {
 		}
 	}
 }
+
+func TestTraceParseTraceparent(t *testing.T) {
+	m, err := traceParse(caddy.NewTestController("dns", "trace otlp {\n traceparent doh\n traceparent edns0 0xfde9\n}"))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !m.traceparentDoH {
+		t.Errorf("Expected traceparent from DoH to be enabled")
+	}
+	if m.traceparentEDNS0 != 0xfde9 {
+		t.Errorf("Expected traceparent EDNS0 option code %d, got %d", 0xfde9, m.traceparentEDNS0)
+	}
+}
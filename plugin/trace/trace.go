@@ -1,4 +1,5 @@
-// Package trace implements OpenTracing-based tracing
+// Package trace implements OpenTracing-based tracing, with OpenTelemetry (OTLP) export through
+// the OpenTracing bridge.
 package trace
 
 import (
@@ -22,16 +23,17 @@ import (
 	zipkinot "github.com/openzipkin-contrib/zipkin-go-opentracing"
 	"github.com/openzipkin/zipkin-go"
 	zipkinhttp "github.com/openzipkin/zipkin-go/reporter/http"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace"
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/opentracer"
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
 )
 
-const (
-	defaultTopLevelSpanName = "servedns"
-	metaTraceIdKey          = "trace/traceid"
-)
+const defaultTopLevelSpanName = "servedns"
+
+// MetadataTraceID is the metadata label that holds the trace ID of a traced query.
+const MetadataTraceID = "trace/traceid"
 
 type traceTags struct {
 	Name   string
@@ -56,6 +58,14 @@ var tagByProvider = map[string]traceTags{
 		Proto:  "coredns.io@proto",
 		Remote: "coredns.io@remote",
 	},
+	// OpenTelemetry semantic conventions.
+	"otlp": {
+		Name:   "dns.question.name",
+		Type:   "dns.question.type",
+		Rcode:  "dns.response_code",
+		Proto:  "net.transport",
+		Remote: "net.peer.ip",
+	},
 }
 
 type trace struct {
@@ -72,6 +82,10 @@ type trace struct {
 	datadogAnalyticsRate float64
 	Once                 sync.Once
 	tagSet               traceTags
+
+	provider         *sdktrace.TracerProvider // only set for OTLP
+	traceparentDoH   bool
+	traceparentEDNS0 uint16
 }
 
 func (t *trace) Tracer() ot.Tracer {
@@ -95,6 +109,8 @@ func (t *trace) OnStartup() error {
 			)
 			t.tracer = tracer
 			t.tagSet = tagByProvider["datadog"]
+		case "otlp", "otlphttp":
+			err = t.setupOTLP()
 		default:
 			err = fmt.Errorf("unknown endpoint type: %s", t.EndpointType)
 		}
@@ -127,28 +143,42 @@ func (t *trace) Name() string { return "trace" }
 
 // ServeDNS implements the plugin.Handle interface.
 func (t *trace) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (int, error) {
-	trace := false
-	if t.every > 0 {
+	span := ot.SpanFromContext(ctx)
+	if span != nil {
+		return plugin.NextOrFailure(t.Name(), t.Next, ctx, w, r)
+	}
+
+	// A sampled trace context from the client is always continued, an unsampled one never is.
+	parent, trace := t.remoteParent(w, r)
+	if parent == nil && t.every > 0 {
 		queryNr := atomic.AddUint64(&t.count, 1)
 
 		if queryNr%t.every == 0 {
 			trace = true
 		}
 	}
-	span := ot.SpanFromContext(ctx)
-	if !trace || span != nil {
+	if !trace {
 		return plugin.NextOrFailure(t.Name(), t.Next, ctx, w, r)
 	}
 
 	req := request.Request{W: w, Req: r}
-	span = t.Tracer().StartSpan(defaultTopLevelSpanName)
+	// The span kind is given as a plain string tag, so the OpenTelemetry bridge picks it up too.
+	opts := []ot.StartSpanOption{ot.Tag{Key: string(otext.SpanKind), Value: string(otext.SpanKindRPCServerEnum)}}
+	if parent != nil {
+		opts = append(opts, ot.ChildOf(parent))
+	}
+	span = t.Tracer().StartSpan(defaultTopLevelSpanName, opts...)
 	defer span.Finish()
 
 	switch spanCtx := span.Context().(type) {
 	case zipkinot.SpanContext:
-		metadata.SetValueFunc(ctx, metaTraceIdKey, func() string { return spanCtx.TraceID.String() })
+		metadata.SetValueFunc(ctx, MetadataTraceID, func() string { return spanCtx.TraceID.String() })
 	case ddtrace.SpanContext:
-		metadata.SetValueFunc(ctx, metaTraceIdKey, func() string { return fmt.Sprint(spanCtx.TraceID()) })
+		metadata.SetValueFunc(ctx, MetadataTraceID, func() string { return fmt.Sprint(spanCtx.TraceID()) })
+	default:
+		if t.provider != nil {
+			metadata.SetValueFunc(ctx, MetadataTraceID, func() string { return t.otelTraceID(spanCtx) })
+		}
 	}
 
 	rw := dnstest.NewRecorder(w)
@@ -7,6 +7,7 @@ import (
 
 	"github.com/coredns/caddy"
 	"github.com/coredns/coredns/plugin"
+	"github.com/coredns/coredns/plugin/metadata"
 	"github.com/coredns/coredns/plugin/pkg/dnstest"
 	"github.com/coredns/coredns/plugin/pkg/rcode"
 	"github.com/coredns/coredns/plugin/test"
@@ -14,6 +15,9 @@ import (
 
 	"github.com/miekg/dns"
 	"github.com/opentracing/opentracing-go/mocktracer"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	oteltrace "go.opentelemetry.io/otel/trace"
 )
 
 func TestStartup(t *testing.T) {
@@ -131,3 +135,76 @@ func TestTrace(t *testing.T) {
 		})
 	}
 }
+
+func TestTraceRemoteParent(t *testing.T) {
+	const (
+		traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+		code    = dns.EDNS0LOCALSTART + 1
+	)
+	cases := []struct {
+		name        string
+		traceparent string
+		every       uint64
+		spans       int
+	}{
+		{name: "sampled parent", traceparent: "00-" + traceID + "-00f067aa0ba902b7-01", every: 0, spans: 1},
+		{name: "unsampled parent", traceparent: "00-" + traceID + "-00f067aa0ba902b7-00", every: 1, spans: 0},
+		{name: "invalid parent", traceparent: "junk", every: 1, spans: 1},
+		{name: "no parent", every: 0, spans: 0},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			exp := tracetest.NewInMemoryExporter()
+			tr := &trace{
+				Next:             test.ErrorHandler(),
+				every:            tc.every,
+				traceparentEDNS0: code,
+			}
+			tr.useProvider(sdktrace.NewTracerProvider(sdktrace.WithSyncer(exp)))
+
+			r := new(dns.Msg).SetQuestion("example.org.", dns.TypeA)
+			if tc.traceparent != "" {
+				r.SetEdns0(4096, false)
+				r.IsEdns0().Option = append(r.IsEdns0().Option, &dns.EDNS0_LOCAL{Code: code, Data: []byte(tc.traceparent)})
+			}
+			ctx := metadata.ContextWithMetadata(context.TODO())
+			tr.ServeDNS(ctx, dnstest.NewRecorder(&test.ResponseWriter{}), r)
+
+			var root []tracetest.SpanStub
+			for _, s := range exp.GetSpans() {
+				if s.Name == defaultTopLevelSpanName {
+					root = append(root, s)
+				}
+			}
+			if len(root) != tc.spans {
+				t.Fatalf("Expected %d root spans, got %d", tc.spans, len(root))
+			}
+			if tc.spans == 0 {
+				return
+			}
+			if tc.traceparent != "junk" && root[0].SpanContext.TraceID().String() != traceID {
+				t.Errorf("Expected trace ID %s, got %s", traceID, root[0].SpanContext.TraceID())
+			}
+			if root[0].SpanKind != oteltrace.SpanKindServer {
+				t.Errorf("Expected server span, got %s", root[0].SpanKind)
+			}
+			want := root[0].SpanContext.TraceID().String()
+			if got := metadata.ValueFunc(ctx, MetadataTraceID)(); got != want {
+				t.Errorf("Expected trace ID metadata %s, got %s", want, got)
+			}
+		})
+	}
+}
+
+func TestTraceRemoteParentNoAllocs(t *testing.T) {
+	tr := &trace{traceparentDoH: true, traceparentEDNS0: dns.EDNS0LOCALSTART}
+	tr.useProvider(sdktrace.NewTracerProvider())
+
+	r := new(dns.Msg).SetQuestion("example.org.", dns.TypeA)
+	r.SetEdns0(4096, false)
+	w := &test.ResponseWriter{}
+	allocs := testing.AllocsPerRun(100, func() { tr.remoteParent(w, r) })
+	if allocs != 0 {
+		t.Errorf("Expected no allocations for a query without a trace context, got %.0f", allocs)
+	}
+}
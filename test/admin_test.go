@@ -0,0 +1,154 @@
+package test
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/coredns/caddy"
+)
+
+// adminInstances keeps the last started instance, as a reload through admin makes a new one.
+var adminInstances = struct {
+	sync.Mutex
+	last *caddy.Instance
+}{}
+
+func init() {
+	caddy.RegisterEventHook("admintest", func(event caddy.EventName, info interface{}) error {
+		if event == caddy.InstanceStartupEvent {
+			adminInstances.Lock()
+			adminInstances.last = info.(*caddy.Instance)
+			adminInstances.Unlock()
+		}
+		return nil
+	})
+}
+
+type adminConfig struct {
+	Corefile struct {
+		SHA512 string `json:"sha512"`
+	} `json:"corefile"`
+	Rollback bool `json:"rollback_available"`
+	Servers  []struct {
+		Zone    string   `json:"zone"`
+		Plugins []string `json:"plugins"`
+	} `json:"servers"`
+}
+
+type adminResult struct {
+	OK      bool   `json:"ok"`
+	Changed bool   `json:"changed"`
+	Error   string `json:"error"`
+}
+
+func TestAdmin(t *testing.T) {
+	token := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(token, []byte("s3cret"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	const addr = "127.0.0.1:52184"
+	corefile := func(plugins string) string {
+		return fmt.Sprintf(`example.org:0 {
+		admin %s {
+			token_file %s
+		}
+		%s
+	}`, addr, token, plugins)
+	}
+
+	_, err := CoreDNSServer(corefile("whoami"))
+	if err != nil {
+		if strings.Contains(err.Error(), inUse) {
+			return
+		}
+		t.Fatalf("Could not get CoreDNS serving instance: %s", err)
+	}
+	defer func() {
+		adminInstances.Lock()
+		adminInstances.last.ShutdownCallbacks()
+		adminInstances.last.Stop()
+		adminInstances.Unlock()
+	}()
+
+	do := func(method, path, body string, v interface{}) int {
+		t.Helper()
+		req, _ := http.NewRequest(method, "http://"+addr+path, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer s3cret")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		if v != nil {
+			if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+				t.Fatal(err)
+			}
+		} else {
+			io.Copy(io.Discard, resp.Body)
+		}
+		return resp.StatusCode
+	}
+	plugins := func() []string {
+		t.Helper()
+		cfg := adminConfig{}
+		if code := do(http.MethodGet, "/config", "", &cfg); code != http.StatusOK {
+			t.Fatalf("Expected status %d, got %d", http.StatusOK, code)
+		}
+		if len(cfg.Servers) != 1 || cfg.Servers[0].Zone != "example.org." {
+			t.Fatalf("Expected one server for example.org., got %v", cfg.Servers)
+		}
+		return cfg.Servers[0].Plugins
+	}
+
+	resp, err := http.Get("http://" + addr + "/config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("Expected status %d without token, got %d", http.StatusUnauthorized, resp.StatusCode)
+	}
+
+	if p := plugins(); len(p) != 1 || p[0] != "whoami" {
+		t.Errorf("Expected plugins [whoami], got %v", p)
+	}
+
+	res := adminResult{}
+	if code := do(http.MethodPost, "/validate", corefile("whoami\n\t\tbogus"), &res); code != http.StatusUnprocessableEntity || res.OK || res.Error == "" {
+		t.Errorf("Expected invalid Corefile to fail validation, got %d: %+v", code, res)
+	}
+	res = adminResult{}
+	if code := do(http.MethodPost, "/reload", corefile("whoami\n\t\tbogus"), &res); code != http.StatusUnprocessableEntity || res.OK {
+		t.Errorf("Expected invalid Corefile to fail reload, got %d: %+v", code, res)
+	}
+	res = adminResult{}
+	if code := do(http.MethodPost, "/reload", corefile("whoami"), &res); code != http.StatusOK || !res.OK || res.Changed {
+		t.Errorf("Expected reload of the running Corefile to do nothing, got %d: %+v", code, res)
+	}
+	if code := do(http.MethodPost, "/rollback", "", nil); code != http.StatusConflict {
+		t.Errorf("Expected status %d for rollback without previous Corefile, got %d", http.StatusConflict, code)
+	}
+
+	res = adminResult{}
+	if code := do(http.MethodPost, "/reload", corefile("log\n\t\twhoami"), &res); code != http.StatusOK || !res.OK || !res.Changed {
+		t.Fatalf("Expected reload to succeed, got %d: %+v", code, res)
+	}
+	if p := plugins(); len(p) != 2 || p[0] != "log" || p[1] != "whoami" {
+		t.Errorf("Expected plugins [log whoami], got %v", p)
+	}
+
+	res = adminResult{}
+	if code := do(http.MethodPost, "/rollback", "", &res); code != http.StatusOK || !res.OK || !res.Changed {
+		t.Fatalf("Expected rollback to succeed, got %d: %+v", code, res)
+	}
+	if p := plugins(); len(p) != 1 || p[0] != "whoami" {
+		t.Errorf("Expected plugins [whoami] after rollback, got %v", p)
+	}
+}
@@ -2,6 +2,7 @@ package test
 
 import (
 	"context"
+	"crypto/tls"
 	"testing"
 	"time"
 
@@ -9,6 +10,7 @@ import (
 
 	"github.com/miekg/dns"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 )
 
 func TestGrpc(t *testing.T) {
@@ -55,3 +57,44 @@ func TestGrpc(t *testing.T) {
 		t.Errorf("Expected 2 RRs in additional section, but got %d", len(d.Extra))
 	}
 }
+
+func TestGrpcTLS(t *testing.T) {
+	corefile := `grpc://.:0 {
+		tls ../plugin/tls/test_cert.pem ../plugin/tls/test_key.pem
+		whoami
+	}`
+
+	g, _, tcp, err := CoreDNSServerAndPorts(corefile)
+	if err != nil {
+		t.Fatalf("Could not get CoreDNS serving instance: %s", err)
+	}
+	defer g.Stop()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	creds := credentials.NewTLS(&tls.Config{InsecureSkipVerify: true})
+	conn, err := grpc.DialContext(ctx, tcp, grpc.WithTransportCredentials(creds), grpc.WithBlock())
+	if err != nil {
+		t.Fatalf("Expected no error but got: %s", err)
+	}
+	defer conn.Close()
+
+	client := pb.NewDnsServiceClient(conn)
+
+	m := new(dns.Msg)
+	m.SetQuestion("whoami.example.org.", dns.TypeA)
+	msg, _ := m.Pack()
+
+	reply, err := client.Query(context.TODO(), &pb.DnsPacket{Msg: msg})
+	if err != nil {
+		t.Fatalf("Expected no error but got: %s", err)
+	}
+
+	d := new(dns.Msg)
+	if err := d.Unpack(reply.Msg); err != nil {
+		t.Errorf("Expected no error but got: %s", err)
+	}
+	if d.Rcode != dns.RcodeSuccess {
+		t.Errorf("Expected success but got %d", d.Rcode)
+	}
+}
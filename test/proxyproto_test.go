@@ -0,0 +1,142 @@
+package test
+
+import (
+	"encoding/binary"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// proxyHeader returns a PROXY protocol version 2 header for the client 198.51.100.1:5300.
+func proxyHeader(proto byte) []byte {
+	b := []byte("\r\n\r\n\x00\r\nQUIT\n")
+	b = append(b, 0x21, 0x10|proto, 0, 12)
+	b = append(b, 198, 51, 100, 1, 127, 0, 0, 1)
+	return append(b, 0x14, 0xb4, 0, 53)
+}
+
+func whoamiIP(t *testing.T, m *dns.Msg) string {
+	t.Helper()
+	for _, rr := range m.Extra {
+		switch rr := rr.(type) {
+		case *dns.A:
+			return rr.A.String()
+		case *dns.AAAA:
+			return rr.AAAA.String()
+		}
+	}
+	t.Fatalf("Expected an address in the additional section, got %v", m)
+	return ""
+}
+
+func TestProxyProtocol(t *testing.T) {
+	corefile := `example.org:0 {
+		proxyproto {
+			allow 127.0.0.1 ::1
+		}
+		whoami
+	}`
+
+	i, udp, tcp, err := CoreDNSServerAndPorts(corefile)
+	if err != nil {
+		t.Fatalf("Could not get CoreDNS serving instance: %s", err)
+	}
+	defer i.Stop()
+
+	m := new(dns.Msg)
+	m.SetQuestion("example.org.", dns.TypeA)
+	query, _ := m.Pack()
+
+	// UDP, the header is prepended to the datagram and the reply has none.
+	conn, err := net.Dial("udp", udp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	if _, err := conn.Write(append(proxyHeader(2), query...)); err != nil {
+		t.Fatal(err)
+	}
+	buf := make([]byte, 512)
+	n, err := conn.Read(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := new(dns.Msg)
+	if err := r.Unpack(buf[:n]); err != nil {
+		t.Fatal(err)
+	}
+	if ip := whoamiIP(t, r); ip != "198.51.100.1" {
+		t.Errorf("Expected client 198.51.100.1 over UDP, got %s", ip)
+	}
+
+	// TCP, the header starts the connection.
+	tconn, err := net.Dial("tcp", tcp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tconn.Close()
+	tconn.SetDeadline(time.Now().Add(5 * time.Second))
+	msg := make([]byte, 2)
+	binary.BigEndian.PutUint16(msg, uint16(len(query)))
+	if _, err := tconn.Write(append(append(proxyHeader(1), msg...), query...)); err != nil {
+		t.Fatal(err)
+	}
+	c := &dns.Conn{Conn: tconn}
+	r, err = c.ReadMsg()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ip := whoamiIP(t, r); ip != "198.51.100.1" {
+		t.Errorf("Expected client 198.51.100.1 over TCP, got %s", ip)
+	}
+
+	// Queries without a header are answered as usual.
+	r, err = dns.Exchange(m, udp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ip := whoamiIP(t, r); ip == "198.51.100.1" {
+		t.Errorf("Expected the actual client, got %s", ip)
+	}
+}
+
+func TestProxyProtocolUntrusted(t *testing.T) {
+	corefile := `example.org:0 {
+		proxyproto {
+			allow 192.0.2.0/24
+		}
+		whoami
+	}`
+
+	i, udp, _, err := CoreDNSServerAndPorts(corefile)
+	if err != nil {
+		t.Fatalf("Could not get CoreDNS serving instance: %s", err)
+	}
+	defer i.Stop()
+
+	m := new(dns.Msg)
+	m.SetQuestion("example.org.", dns.TypeA)
+	query, _ := m.Pack()
+
+	conn, err := net.Dial("udp", udp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	// The header is not looked at, the datagram is taken to be a DNS message that starts with it.
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	conn.Write(append(proxyHeader(2), query...))
+	buf := make([]byte, 512)
+	n, err := conn.Read(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := new(dns.Msg)
+	r.Unpack(buf[:n])
+	if r.Rcode == dns.RcodeSuccess || r.Id != 0x0d0a {
+		t.Errorf("Expected an error for the header as a message, got %v", r)
+	}
+}
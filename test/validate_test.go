@@ -0,0 +1,109 @@
+package test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/coredns/coredns/core/dnsserver"
+)
+
+func TestValidate(t *testing.T) {
+	corefile := `example.org example.net {
+		bind 127.0.0.1
+		log
+		whoami
+	}
+
+	. {
+		forward . 8.8.8.8
+		cache
+		route53 example.org.:Z1
+	}
+
+	example.com {
+		etcd
+		grpc . 127.0.0.1:50051
+	}
+
+	cluster.local {
+		kubernetes cluster.local
+	}`
+
+	blocks, errs := dnsserver.Validate(NewInput(corefile))
+	if len(errs) > 0 {
+		t.Fatalf("Expected no errors, got %v", errs)
+	}
+	if len(blocks) != 4 {
+		t.Fatalf("Expected 4 server blocks, got %d", len(blocks))
+	}
+
+	tests := []struct {
+		zones   []string
+		listen  []string
+		plugins []string
+	}{
+		{[]string{"example.org.", "example.net."}, []string{"127.0.0.1"}, []string{"log", "whoami"}},
+		{[]string{"."}, []string{""}, []string{"cache", "route53", "forward"}},
+		{[]string{"example.com."}, []string{""}, []string{"etcd", "grpc"}},
+		{[]string{"cluster.local."}, []string{""}, []string{"kubernetes"}},
+	}
+	for i, tc := range tests {
+		zones := []string{}
+		for _, cfg := range blocks[i] {
+			zones = append(zones, cfg.Zone)
+		}
+		if strings.Join(zones, " ") != strings.Join(tc.zones, " ") {
+			t.Errorf("Block %d: expected zones %v, got %v", i, tc.zones, zones)
+		}
+		if strings.Join(blocks[i][0].ListenHosts, " ") != strings.Join(tc.listen, " ") {
+			t.Errorf("Block %d: expected listen hosts %v, got %v", i, tc.listen, blocks[i][0].ListenHosts)
+		}
+		if strings.Join(blocks[i][0].Plugins(), " ") != strings.Join(tc.plugins, " ") {
+			t.Errorf("Block %d: expected plugins %v, got %v", i, tc.plugins, blocks[i][0].Plugins())
+		}
+	}
+}
+
+func TestValidateErrors(t *testing.T) {
+	// Other tests change the default port, which is in one of the errors.
+	defer func(port string) { dnsserver.Port = port }(dnsserver.Port)
+	dnsserver.Port = dnsserver.DefaultPort
+
+	tests := []struct {
+		corefile string
+		errs     []string
+	}{
+		{`. {
+			forward . notanip
+			cache {
+				bogus
+			}
+			whoami extra
+		}`, []string{"Corefile:2 - plugin/forward", "Corefile:4 - ", "Corefile:6 - "}},
+		{`. {
+			forward . 8.8.8.8
+		}
+		example.org {
+			bogus
+		}`, []string{"Corefile:5 - Error during parsing: Unknown directive 'bogus'"}},
+		{`. {
+			whoami
+		}
+		. {
+			whoami
+		}`, []string{"cannot serve dns://.:53 - it is already defined"}},
+	}
+
+	for i, tc := range tests {
+		_, errs := dnsserver.Validate(NewInput(tc.corefile))
+		if len(errs) != len(tc.errs) {
+			t.Errorf("Test %d: expected %d errors, got %d: %v", i, len(tc.errs), len(errs), errs)
+			continue
+		}
+		for j, err := range errs {
+			if !strings.Contains(err.Error(), tc.errs[j]) {
+				t.Errorf("Test %d: expected error %d to contain %q, got %q", i, j, tc.errs[j], err)
+			}
+		}
+	}
+}